@@ -0,0 +1,186 @@
+package api
+
+import (
+	"errors"
+	"mindwarp/db"
+	"mindwarp/logger"
+	"mindwarp/types"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultQuestionSeconds = 60
+	minQuestionSeconds     = 5
+	maxQuestionSeconds     = 600
+)
+
+// AsyncRequest turns a new game into an async one. Every player must be done
+// by Deadline and gets QuestionSeconds, a minute by default, per question.
+type AsyncRequest struct {
+	Deadline        *time.Time `json:"deadline" binding:"required"`
+	QuestionSeconds int        `json:"questionSeconds"`
+}
+
+// toAsyncSettings validates req. A game without async settings is live and
+// yields nil. A scheduled game's deadline must come after its start.
+func (req *AsyncRequest) toAsyncSettings(now time.Time, schedule *types.GameSchedule) (*types.AsyncSettings, error) {
+	if req == nil {
+		return nil, nil
+	}
+
+	questionSeconds := req.QuestionSeconds
+	if questionSeconds == 0 {
+		questionSeconds = defaultQuestionSeconds
+	}
+	if questionSeconds < minQuestionSeconds || questionSeconds > maxQuestionSeconds {
+		return nil, errors.New("questionSeconds must be between 5 and 600")
+	}
+
+	starts := now
+	if schedule != nil {
+		starts = schedule.StartsAt
+	}
+	if !req.Deadline.After(starts) {
+		return nil, errors.New("deadline must be after the game starts")
+	}
+
+	return &types.AsyncSettings{
+		Deadline:        req.Deadline.UTC(),
+		QuestionSeconds: questionSeconds,
+	}, nil
+}
+
+type AsyncAnswerRequest struct {
+	Response  string   `json:"response"`
+	OptionIDs []string `json:"optionIds"`
+}
+
+// asyncError maps async play rule violations to client errors.
+func asyncError(c *gin.Context, err error, failCode string) {
+	switch {
+	case errors.Is(err, db.ErrGameNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: GAME_NOT_FOUND_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrQuestionNotInGame):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: QUESTION_NOT_FOUND_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrNotAsyncGame):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: NOT_ASYNC_GAME_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrUserNotInGame):
+		c.JSON(http.StatusForbidden, ErrorResponse{Code: ASYNC_RULE_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrWrongQuestionType),
+		errors.Is(err, db.ErrInvalidOption),
+		errors.Is(err, db.ErrQuestionNotOpened):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: ASYNC_RULE_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrAlreadyAnswered):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: ASYNC_RULE_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrDeadlinePassed):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: DEADLINE_PASSED_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrGameNotInProgress):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: GAME_NOT_IN_PROGRESS_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrGameNotFinished):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: GAME_NOT_FINISHED_ERROR, Message: err.Error()})
+	default:
+		logger.Errorf("Async game error: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: failCode, Message: err.Error()})
+	}
+}
+
+// hideAsyncProgress keeps an unfinished async game from giving anything away:
+// viewers see only their own answers and scores, and only the questions they
+// have already answered. Everyone, the host included, sees the full board
+// once the game finishes.
+func hideAsyncProgress(game *types.GameClient, viewerID string) {
+	if game.Mode != string(types.GameModeAsync) || game.IsFinished {
+		return
+	}
+
+	for i := range game.Rounds {
+		for j := range game.Rounds[i].Themes {
+			for k := range game.Rounds[i].Themes[j].Questions {
+				question := &game.Rounds[i].Themes[j].Questions[k]
+				own, answered := question.AnsweredBy[viewerID]
+				question.AnsweredBy = map[string]types.AnsweredByClient{}
+				if answered {
+					question.AnsweredBy[viewerID] = own
+					continue
+				}
+
+				question.Text = ""
+				question.Answer = ""
+				question.Media = nil
+				question.Options = nil
+			}
+		}
+	}
+
+	for i := range game.Users {
+		if game.Users[i].ID != viewerID {
+			game.Users[i].RoundScore = map[string]int16{}
+		}
+	}
+	for i := range game.Teams {
+		game.Teams[i].RoundScore = map[string]int16{}
+		game.Teams[i].Score = 0
+	}
+}
+
+// OpenAsyncQuestion shows the caller a question of an async game and starts
+// their timer on it.
+func (s *Server) OpenAsyncQuestion(c *gin.Context) {
+	question, err := s.Db.OpenAsyncQuestion(c.Request.Context(), c.Param("id"), c.Param("questionId"), currentUserID(c))
+	if err != nil {
+		asyncError(c, err, FAIL_OPEN_QUESTION_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, question)
+}
+
+// SubmitAsyncAnswer answers a question the caller opened. The server judges
+// it and times it from when the question was opened.
+func (s *Server) SubmitAsyncAnswer(c *gin.Context) {
+	var reqBody AsyncAnswerRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	answer, err := s.Db.SubmitAsyncAnswer(c.Request.Context(), c.Param("id"), c.Param("questionId"), currentUserID(c), reqBody.Response, reqBody.OptionIDs)
+	if err != nil {
+		asyncError(c, err, FAIL_SUBMIT_ASYNC_ANSWER_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, answer)
+}
+
+func (s *Server) GetAsyncProgress(c *gin.Context) {
+	progress, err := s.Db.GetAsyncProgress(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		asyncError(c, err, FAIL_GET_ASYNC_PROGRESS_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, progress)
+}
+
+// GetAsyncComparison compares every player's answers once an async game has
+// finished. The standings are served by GetGameResults as for live games.
+func (s *Server) GetAsyncComparison(c *gin.Context) {
+	comparison, err := s.Db.GetAsyncComparison(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		asyncError(c, err, FAIL_GET_ASYNC_COMPARISON_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, comparison)
+}
+
+func (s *Server) AddAsyncRoutes(group *gin.RouterGroup) {
+	group.POST("/games/:id/async/questions/:questionId/open", s.OpenAsyncQuestion)
+	group.POST("/games/:id/async/questions/:questionId/answer", s.SubmitAsyncAnswer)
+	group.GET("/games/:id/async/progress", s.GetAsyncProgress)
+	group.GET("/games/:id/async/comparison", s.GetAsyncComparison)
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"errors"
+	"mindwarp/db"
+	"mindwarp/logger"
+	"mindwarp/types"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AttemptRequest struct {
+	UserID        string `json:"userId" binding:"required"`
+	IsCorrect     *bool  `json:"isCorrect" binding:"required"`
+	TimeAnswered  *int   `json:"timeAnswered" binding:"omitempty,min=0"`
+	ContributorID string `json:"contributorId"`
+}
+
+// attemptError maps attempt-sequence rule violations to client errors.
+func attemptError(c *gin.Context, err error, failCode string) {
+	switch {
+	case errors.Is(err, db.ErrQuestionNotInGame):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: QUESTION_NOT_FOUND_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrUserNotInGame),
+		errors.Is(err, db.ErrWrongQuestionType),
+		errors.Is(err, db.ErrQuestionNotAssigned),
+		errors.Is(err, db.ErrNotAssignee),
+		errors.Is(err, db.ErrNotTeamCaptain),
+		errors.Is(err, db.ErrInvalidContributor):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: ATTEMPT_RULE_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrGameNotInProgress):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: GAME_NOT_IN_PROGRESS_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrQuestionResolved), errors.Is(err, db.ErrAlreadyAnswered):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: ATTEMPT_RULE_ERROR, Message: err.Error()})
+	default:
+		logger.Errorf("Attempt error: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: failCode, Message: err.Error()})
+	}
+}
+
+// RecordAttempt lets the host judge the next player's try at a question.
+func (s *Server) RecordAttempt(c *gin.Context) {
+	var reqBody AttemptRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	gameID := c.Param("id")
+	if !s.requireGameHost(c, gameID) {
+		return
+	}
+
+	attempts, err := s.Db.RecordAttempt(c.Request.Context(), types.AttemptServer{
+		GameID:        gameID,
+		QuestionID:    c.Param("questionId"),
+		UserID:        reqBody.UserID,
+		IsCorrect:     *reqBody.IsCorrect,
+		TimeAnswered:  reqBody.TimeAnswered,
+		ContributorID: reqBody.ContributorID,
+	})
+	if err != nil {
+		attemptError(c, err, FAIL_RECORD_ATTEMPT_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, attempts)
+}
+
+func (s *Server) GetQuestionAttempts(c *gin.Context) {
+	attempts, err := s.Db.GetQuestionAttempts(c.Request.Context(), c.Param("id"), c.Param("questionId"), currentUserID(c))
+	if err != nil {
+		attemptError(c, err, FAIL_GET_ATTEMPTS_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, attempts)
+}
+
+// ResolveQuestion lets the host close a question nobody else wants to try.
+func (s *Server) ResolveQuestion(c *gin.Context) {
+	gameID := c.Param("id")
+	if !s.requireGameHost(c, gameID) {
+		return
+	}
+
+	if err := s.Db.ResolveQuestion(c.Request.Context(), gameID, c.Param("questionId")); err != nil {
+		attemptError(c, err, FAIL_RESOLVE_QUESTION_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Question resolved"})
+}
+
+func (s *Server) AddAttemptRoutes(group *gin.RouterGroup) {
+	group.GET("/games/:id/questions/:questionId/attempts", s.GetQuestionAttempts)
+	group.POST("/games/:id/questions/:questionId/attempts", s.RecordAttempt)
+	group.POST("/games/:id/questions/:questionId/resolve", s.ResolveQuestion)
+}
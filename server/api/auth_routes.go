@@ -15,12 +15,16 @@ import (
 type loginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=8"`
+	// InviteToken is the token from an emailed game invite. The invite is
+	// attached to the account once it is logged in.
+	InviteToken string `json:"inviteToken"`
 }
 
 type registerRequest struct {
-	Username string `json:"username" binding:"required,min=2,max=32"`
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=8"`
+	Username    string `json:"username" binding:"required,min=2,max=32"`
+	Email       string `json:"email" binding:"required,email"`
+	Password    string `json:"password" binding:"required,min=8"`
+	InviteToken string `json:"inviteToken"`
 }
 
 func validateRequest(c *gin.Context, err error) (ErrorResponse, error) {
@@ -141,7 +145,18 @@ func (s *Server) handleLogin(c *gin.Context, req loginRequest) {
 		true,                             // httpOnly
 	)
 
-	c.JSON(http.StatusOK, gin.H{"message": "Login successful"})
+	response := gin.H{"message": "Login successful"}
+	if req.InviteToken != "" {
+		// A stale invite link must not stop the login itself
+		gameID, code := s.claimInviteToken(c, req.InviteToken, user.ID)
+		if code != "" {
+			response["inviteError"] = code
+		} else {
+			response["invitedGameId"] = gameID
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 func (s *Server) Login(c *gin.Context) {
@@ -193,7 +208,7 @@ func (s *Server) Register(c *gin.Context) {
 	}
 
 	logger.Infof("User created successfully. Logging in... %s %s", user.Email, user.Name)
-	s.handleLogin(c, loginRequest{Email: user.Email, Password: req.Password})
+	s.handleLogin(c, loginRequest{Email: user.Email, Password: req.Password, InviteToken: req.InviteToken})
 }
 
 func (s *Server) Logout(c *gin.Context) {
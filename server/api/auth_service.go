@@ -79,5 +79,61 @@ func (s *AuthService) ValidateToken(tokenString string) (string, error) {
 		return "", errors.New("token expired")
 	}
 
+	// Invite tokens name an invite, not a user
+	if len(claims.Audience) > 0 {
+		return "", errors.New("invalid token")
+	}
+
+	return claims.Subject, nil
+}
+
+// inviteAudience marks tokens that are only good for claiming an email
+// invite.
+const inviteAudience = "email_invite"
+
+// GenerateInviteToken signs the id of an email invite. The token lapses
+// together with the invite.
+func (s *AuthService) GenerateInviteToken(inviteID string, expiresAt time.Time) (string, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return "", errors.New("JWT_SECRET is not set")
+	}
+
+	claims := jwt.RegisteredClaims{
+		Subject:   inviteID,
+		Audience:  jwt.ClaimStrings{inviteAudience},
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+}
+
+// ValidateInviteToken returns the email invite id a token was issued for.
+func (s *AuthService) ValidateInviteToken(tokenString string) (string, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return "", errors.New("JWT_SECRET is not set")
+	}
+
+	var claims jwt.RegisteredClaims
+	token, err := jwt.ParseWithClaims(
+		tokenString,
+		&claims,
+		func(token *jwt.Token) (interface{}, error) {
+			if token.Method != jwt.SigningMethodHS256 {
+				return nil, jwt.ErrTokenUnverifiable
+			}
+			return []byte(secret), nil
+		},
+		jwt.WithAudience(inviteAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return "", err
+	}
+	if !token.Valid {
+		return "", errors.New("invalid token")
+	}
+
 	return claims.Subject, nil
 }
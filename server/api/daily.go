@@ -0,0 +1,186 @@
+package api
+
+import (
+	"errors"
+	"mindwarp/daily"
+	"mindwarp/db"
+	"mindwarp/logger"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultDailyLeaderboardLimit = 50
+	maxDailyLeaderboardLimit     = 200
+)
+
+type DailyAnswerRequest struct {
+	QuestionID string   `json:"questionId" binding:"required,uuid"`
+	Response   string   `json:"response"`
+	OptionIDs  []string `json:"optionIds" binding:"dive,uuid"`
+}
+
+// dailyError maps daily challenge rule violations to client errors.
+func dailyError(c *gin.Context, err error, failCode string) {
+	switch {
+	case errors.Is(err, db.ErrDailyChallengeNotFound),
+		errors.Is(err, db.ErrNoDailyQuestions):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: DAILY_CHALLENGE_NOT_FOUND_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrDailyAttemptNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: DAILY_ATTEMPT_NOT_FOUND_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrQuestionNotInChallenge):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: QUESTION_NOT_FOUND_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrInvalidOption):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: DAILY_RULE_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrDailyAlreadyPlayed),
+		errors.Is(err, db.ErrDailyAttemptFinished),
+		errors.Is(err, db.ErrAlreadyAnswered):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: DAILY_RULE_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrDailyTimeUp):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: DEADLINE_PASSED_ERROR, Message: err.Error()})
+	default:
+		logger.Errorf("Daily challenge error: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: failCode, Message: err.Error()})
+	}
+}
+
+// dailyDay reads the day query parameter, today in UTC by default. It writes
+// the error response and returns false if the day is malformed.
+func (s *Server) dailyDay(c *gin.Context) (string, bool) {
+	day := c.Query("day")
+	if day == "" {
+		return daily.Day(s.clock()), true
+	}
+	if _, err := time.Parse(time.DateOnly, day); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: "day must be a date in YYYY-MM-DD format"})
+		return "", false
+	}
+	return day, true
+}
+
+// GetDailyChallenge describes a day's challenge and the caller's attempt at
+// it. Today's challenge is picked on first request if the scheduler has not
+// picked it yet.
+func (s *Server) GetDailyChallenge(c *gin.Context) {
+	day, ok := s.dailyDay(c)
+	if !ok {
+		return
+	}
+	now := s.clock()
+	if day == daily.Day(now) {
+		if err := s.Db.EnsureDailyChallenge(c.Request.Context(), day); err != nil {
+			dailyError(c, err, FAIL_GET_DAILY_CHALLENGE_ERROR)
+			return
+		}
+	}
+
+	challenge, err := s.Db.GetDailyChallenge(c.Request.Context(), day, currentUserID(c), now)
+	if err != nil {
+		dailyError(c, err, FAIL_GET_DAILY_CHALLENGE_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, challenge)
+}
+
+// StartDailyAttempt starts the caller's one attempt at today's challenge and
+// serves its questions. The clock runs from this request.
+func (s *Server) StartDailyAttempt(c *gin.Context) {
+	now := s.clock()
+	day := daily.Day(now)
+	if err := s.Db.EnsureDailyChallenge(c.Request.Context(), day); err != nil {
+		dailyError(c, err, FAIL_START_DAILY_ATTEMPT_ERROR)
+		return
+	}
+
+	attempt, err := s.Db.StartDailyAttempt(c.Request.Context(), day, currentUserID(c), now)
+	if err != nil {
+		dailyError(c, err, FAIL_START_DAILY_ATTEMPT_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, attempt)
+}
+
+func (s *Server) GetDailyAttempt(c *gin.Context) {
+	day, ok := s.dailyDay(c)
+	if !ok {
+		return
+	}
+
+	attempt, err := s.Db.GetDailyAttempt(c.Request.Context(), day, currentUserID(c), s.clock())
+	if err != nil {
+		dailyError(c, err, FAIL_GET_DAILY_CHALLENGE_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, attempt)
+}
+
+// SubmitDailyAnswer answers a question of the caller's running attempt. An
+// attempt started just before midnight is answered after it by passing its
+// day.
+func (s *Server) SubmitDailyAnswer(c *gin.Context) {
+	var reqBody DailyAnswerRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+	day, ok := s.dailyDay(c)
+	if !ok {
+		return
+	}
+
+	answer, err := s.Db.SubmitDailyAnswer(c.Request.Context(), day, currentUserID(c), reqBody.QuestionID, reqBody.Response, reqBody.OptionIDs, s.clock())
+	if err != nil {
+		dailyError(c, err, FAIL_SUBMIT_DAILY_ANSWER_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, answer)
+}
+
+func (s *Server) FinishDailyAttempt(c *gin.Context) {
+	day, ok := s.dailyDay(c)
+	if !ok {
+		return
+	}
+
+	attempt, err := s.Db.FinishDailyAttempt(c.Request.Context(), day, currentUserID(c), s.clock())
+	if err != nil {
+		dailyError(c, err, FAIL_FINISH_DAILY_ATTEMPT_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, attempt)
+}
+
+func (s *Server) GetDailyLeaderboard(c *gin.Context) {
+	day, ok := s.dailyDay(c)
+	if !ok {
+		return
+	}
+	offset, limit, ok := pageParams(c, defaultDailyLeaderboardLimit, maxDailyLeaderboardLimit)
+	if !ok {
+		return
+	}
+
+	board, err := s.Db.GetDailyLeaderboard(c.Request.Context(), day, currentUserID(c), offset, limit)
+	if err != nil {
+		dailyError(c, err, FAIL_GET_DAILY_LEADERBOARD_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, board)
+}
+
+func (s *Server) AddDailyRoutes(group *gin.RouterGroup) {
+	group.GET("/daily", s.GetDailyChallenge)
+	group.POST("/daily/attempt", s.StartDailyAttempt)
+	group.GET("/daily/attempt", s.GetDailyAttempt)
+	group.POST("/daily/attempt/answers", s.SubmitDailyAnswer)
+	group.POST("/daily/attempt/finish", s.FinishDailyAttempt)
+	group.GET("/daily/leaderboard", s.GetDailyLeaderboard)
+}
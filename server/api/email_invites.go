@@ -0,0 +1,164 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"mindwarp/db"
+	"mindwarp/logger"
+	"mindwarp/mail"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultAppURL = "https://mindwarp.games"
+
+type EmailInviteRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// newMailer sends mail through SMTP_ADDR when it is set and logs it
+// otherwise.
+func newMailer() mail.Mailer {
+	addr := os.Getenv("SMTP_ADDR")
+	if addr == "" {
+		return mail.LogMailer{}
+	}
+	return mail.NewSMTPMailer(addr, os.Getenv("SMTP_FROM"), os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+}
+
+// appURL reads APP_URL, the address of the web app used in links sent out.
+func appURL() string {
+	if value := os.Getenv("APP_URL"); value != "" {
+		return value
+	}
+	return defaultAppURL
+}
+
+// inviteLink is the page an emailed invite opens: registration, carrying the
+// token that attaches the invite to the new account.
+func inviteLink(token string) string {
+	return appURL() + "/register?invite=" + url.QueryEscape(token)
+}
+
+// SendEmailInvite invites someone by email who may not have an account yet.
+func (s *Server) SendEmailInvite(c *gin.Context) {
+	var reqBody EmailInviteRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	gameID := c.Param("id")
+	if !s.requireGameHost(c, gameID) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	invite, err := s.Db.CreateEmailInvite(ctx, gameID, reqBody.Email, currentUserID(c))
+	if err != nil {
+		inviteError(c, err, FAIL_SEND_GAME_INVITE_ERROR)
+		return
+	}
+
+	token, err := s.AuthService().GenerateInviteToken(invite.ID, invite.ExpiresAt)
+	if err == nil {
+		err = s.sendInviteMail(c, gameID, reqBody.Email, token)
+	}
+	if err != nil {
+		logger.Errorf("Failed to mail game invite: %v", err)
+		if delErr := s.Db.DeleteEmailInvite(ctx, invite.ID); delErr != nil {
+			logger.Errorf("Failed to delete unsent email invite: %v", delErr)
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_SEND_INVITE_EMAIL_ERROR, Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": invite.ID, "expiresAt": invite.ExpiresAt})
+}
+
+func (s *Server) sendInviteMail(c *gin.Context, gameID string, email string, token string) error {
+	ctx := c.Request.Context()
+	host, err := s.Db.GetUserByID(currentUserID(c))
+	if err != nil {
+		return fmt.Errorf("failed to get host: %w", err)
+	}
+	gameName, err := s.Db.GetGameName(ctx, gameID)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: fmt.Sprintf("%s invited you to play %s", host.Name, gameName),
+		Body: fmt.Sprintf(
+			"%s invited you to the trivia game %q on Mindwarp.\n\nCreate an account or log in with this link to join:\n%s\n",
+			host.Name, gameName, inviteLink(token),
+		),
+	})
+}
+
+func (s *Server) RevokeEmailInvite(c *gin.Context) {
+	gameID := c.Param("id")
+	if !s.requireGameHost(c, gameID) {
+		return
+	}
+
+	if err := s.Db.RevokeEmailInvite(c.Request.Context(), gameID, c.Param("inviteId")); err != nil {
+		inviteError(c, err, FAIL_REVOKE_GAME_INVITE_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email invite revoked"})
+}
+
+func (s *Server) GetEmailInvites(c *gin.Context) {
+	gameID := c.Param("id")
+	if !s.requireGameHost(c, gameID) {
+		return
+	}
+
+	invites, err := s.Db.GetEmailInvites(c.Request.Context(), gameID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_GET_INVITE_HISTORY_ERROR, Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, invites)
+}
+
+// claimInviteToken attaches the email invite behind token to the user who
+// just registered or logged in. It returns the invited game, or an error code
+// to report next to the otherwise successful login.
+func (s *Server) claimInviteToken(c *gin.Context, token string, userID string) (string, string) {
+	inviteID, err := s.AuthService().ValidateInviteToken(token)
+	if err != nil {
+		return "", INVALID_INVITE_TOKEN_ERROR
+	}
+
+	gameID, err := s.Db.ClaimEmailInvite(c.Request.Context(), inviteID, userID)
+	switch {
+	case errors.Is(err, db.ErrInviteNotFound):
+		return "", INVITE_NOT_FOUND_ERROR
+	case errors.Is(err, db.ErrInviteExpired):
+		return "", INVITE_EXPIRED_ERROR
+	case errors.Is(err, db.ErrInviteRevoked):
+		return "", INVITE_REVOKED_ERROR
+	case errors.Is(err, db.ErrInviteNotPending):
+		return "", INVITE_NOT_PENDING_ERROR
+	case errors.Is(err, db.ErrGameClosed):
+		return "", GAME_CLOSED_ERROR
+	case err != nil:
+		logger.Errorf("Failed to claim email invite: %v", err)
+		return "", FAIL_CLAIM_INVITE_ERROR
+	}
+	return gameID, ""
+}
+
+func (s *Server) AddEmailInviteRoutes(group *gin.RouterGroup) {
+	group.GET("/games/:id/invites/email", s.GetEmailInvites)
+	group.POST("/games/:id/invites/email", s.SendEmailInvite)
+	group.POST("/games/:id/invites/email/:inviteId/revoke", s.RevokeEmailInvite)
+}
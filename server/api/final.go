@@ -0,0 +1,152 @@
+package api
+
+import (
+	"errors"
+	"mindwarp/db"
+	"mindwarp/logger"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OpenFinalRoundRequest struct {
+	QuestionID      string `json:"questionId" binding:"required"`
+	DurationSeconds int    `json:"durationSeconds" binding:"required,min=10,max=3600"`
+}
+
+type FinalWagerRequest struct {
+	Wager *int `json:"wager" binding:"required,min=0"`
+}
+
+type FinalAnswerRequest struct {
+	Answer string `json:"answer" binding:"required"`
+}
+
+type RevealFinalWagerRequest struct {
+	UserID    string `json:"userId" binding:"required"`
+	IsCorrect *bool  `json:"isCorrect" binding:"required"`
+}
+
+// finalRoundError maps final-round rule violations to client errors.
+func finalRoundError(c *gin.Context, err error, failCode string) {
+	switch {
+	case errors.Is(err, db.ErrNotFinalRound), errors.Is(err, db.ErrQuestionNotInRound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: FINAL_ROUND_NOT_FOUND_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrUserNotInGame):
+		c.JSON(http.StatusForbidden, ErrorResponse{Code: FINAL_ROUND_RULE_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrGameNotInProgress):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: GAME_NOT_IN_PROGRESS_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrFinalNotOpen),
+		errors.Is(err, db.ErrFinalDeadlinePassed),
+		errors.Is(err, db.ErrFinalDeadlineNotPassed),
+		errors.Is(err, db.ErrFinalAlreadyRevealed),
+		errors.Is(err, db.ErrWagerTooHigh),
+		errors.Is(err, db.ErrWagerNotFound):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: FINAL_ROUND_RULE_ERROR, Message: err.Error()})
+	default:
+		logger.Errorf("Final round error: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: failCode, Message: err.Error()})
+	}
+}
+
+func (s *Server) OpenFinalRound(c *gin.Context) {
+	gameID := c.Param("id")
+	var reqBody OpenFinalRoundRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	if !s.requireGameHost(c, gameID) {
+		return
+	}
+
+	duration := time.Duration(reqBody.DurationSeconds) * time.Second
+	err := s.Db.OpenFinalRound(c.Request.Context(), gameID, c.Param("roundId"), reqBody.QuestionID, duration)
+	if err != nil {
+		finalRoundError(c, err, FAIL_OPEN_FINAL_ROUND_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Final round opened"})
+}
+
+func (s *Server) PlaceFinalWager(c *gin.Context) {
+	var reqBody FinalWagerRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	err := s.Db.PlaceFinalWager(c.Request.Context(), c.Param("id"), c.Param("roundId"), currentUserID(c), *reqBody.Wager)
+	if err != nil {
+		finalRoundError(c, err, FAIL_PLACE_FINAL_WAGER_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Wager placed"})
+}
+
+func (s *Server) SubmitFinalAnswer(c *gin.Context) {
+	var reqBody FinalAnswerRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	err := s.Db.SubmitFinalAnswer(c.Request.Context(), c.Param("id"), c.Param("roundId"), currentUserID(c), reqBody.Answer)
+	if err != nil {
+		finalRoundError(c, err, FAIL_SUBMIT_FINAL_ANSWER_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Answer submitted"})
+}
+
+func (s *Server) RevealFinalWager(c *gin.Context) {
+	gameID := c.Param("id")
+	var reqBody RevealFinalWagerRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	if !s.requireGameHost(c, gameID) {
+		return
+	}
+
+	err := s.Db.RevealFinalWager(c.Request.Context(), gameID, c.Param("roundId"), reqBody.UserID, *reqBody.IsCorrect)
+	if err != nil {
+		finalRoundError(c, err, FAIL_REVEAL_FINAL_WAGER_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Wager revealed"})
+}
+
+func (s *Server) GetFinalRound(c *gin.Context) {
+	gameID := c.Param("id")
+	creatorID, err := s.Db.GetGameCreatorID(c.Request.Context(), gameID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: GAME_NOT_FOUND_ERROR, Message: err.Error()})
+		return
+	}
+
+	viewerID := currentUserID(c)
+	final, err := s.Db.GetFinalRound(c.Request.Context(), gameID, c.Param("roundId"), viewerID, creatorID == viewerID)
+	if err != nil {
+		finalRoundError(c, err, FAIL_GET_FINAL_ROUND_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, final)
+}
+
+func (s *Server) AddFinalRoundRoutes(group *gin.RouterGroup) {
+	group.GET("/games/:id/final/:roundId", s.GetFinalRound)
+	group.POST("/games/:id/final/:roundId/open", s.OpenFinalRound)
+	group.POST("/games/:id/final/:roundId/wager", s.PlaceFinalWager)
+	group.POST("/games/:id/final/:roundId/answer", s.SubmitFinalAnswer)
+	group.POST("/games/:id/final/:roundId/reveal", s.RevealFinalWager)
+}
@@ -1,43 +1,144 @@
 package api
 
 import (
+	"errors"
+	"mindwarp/db"
 	"mindwarp/logger"
+	"mindwarp/scoring"
 	"mindwarp/types"
 	"net/http"
+	"slices"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
+type ScoreAdjustmentRequest struct {
+	UserID  string `json:"userId" binding:"required"`
+	RoundID string `json:"roundId" binding:"required"`
+	Points  int16  `json:"points" binding:"required"`
+	Reason  string `json:"reason"`
+}
+
+// FinishGameRequest names the sudden-death winner when one is needed. In team
+// games it is the id of the winning team.
+type FinishGameRequest struct {
+	SuddenDeathWinnerID string `json:"suddenDeathWinnerId"`
+}
+
+// CreateGameFromTemplateRequest starts a game from a template. Rounds,
+// themes and questions always come from the template on the server. With a
+// schedule the game waits until its start time before its lobby opens, and
+// with async settings it is played in correspondence mode. Casual games do
+// not affect ratings.
+type CreateGameFromTemplateRequest struct {
+	TemplateID    string                 `json:"templateId" binding:"required,uuid"`
+	Name          string                 `json:"name" binding:"required"`
+	Invitees      []string               `json:"invitees" binding:"dive,uuid"`
+	RoundIDs      []string               `json:"roundIds" binding:"dive,uuid"`
+	ShuffleThemes bool                   `json:"shuffleThemes"`
+	MinPlayers    int                    `json:"minPlayers" binding:"omitempty,min=1"`
+	TieBreakers   []string               `json:"tieBreakers"`
+	Rules         *types.GameRulesClient `json:"rules"`
+	Schedule      *ScheduleRequest       `json:"schedule"`
+	Async         *AsyncRequest          `json:"async"`
+	Casual        bool                   `json:"casual"`
+}
+
+// InviteRequest answers an invite of the current user. GameID and UserID are
+// ignored; both are taken from the invite.
 type InviteRequest struct {
-	InviteID string `json:"inviteId"`
+	InviteID string `json:"inviteId" binding:"required"`
 	GameID   string `json:"gameId,omitempty"`
 	UserID   string `json:"userId,omitempty"`
 }
 
-func (s *Server) CreateGame(c *gin.Context) {
-	var gameBody types.GameClient
-	if err := c.ShouldBindJSON(&gameBody); err != nil {
-		logger.Errorf("Failed to bind JSON: %v", err)
+func (s *Server) CreateGameFromTemplate(c *gin.Context) {
+	var reqBody CreateGameFromTemplateRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
 		return
 	}
 
-	game, rounds, themes, questions, users, answers, err := MapGameClientToCreate(gameBody)
+	tieBreakers := reqBody.TieBreakers
+	if len(tieBreakers) == 0 {
+		tieBreakers = fromTieBreakers(scoring.DefaultTieBreakers)
+	}
+	if err := scoring.ValidateTieBreakers(toTieBreakers(tieBreakers)); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_TIE_BREAKERS_ERROR, Message: err.Error()})
+		return
+	}
 
+	minPlayers := reqBody.MinPlayers
+	if minPlayers == 0 {
+		minPlayers = defaultMinPlayers
+	}
+
+	templateRules, err := s.Db.GetGameTemplateRules(c.Request.Context(), reqBody.TemplateID)
 	if err != nil {
-		logger.Errorf("Failed to map game client to db: %v", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_MAP_GAME_CLIENT_TO_DB_ERROR, Message: err.Error()})
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: TEMPLATE_NOT_FOUND_ERROR, Message: err.Error()})
+		return
+	}
+	rules := templateRules.Apply(reqBody.Rules)
+	if err := rules.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_GAME_RULES_ERROR, Message: err.Error()})
+		return
+	}
+
+	schedule, err := reqBody.Schedule.toGameSchedule(time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_SCHEDULE_ERROR, Message: err.Error()})
 		return
 	}
 
-	err = s.Db.CreateGame(c.Request.Context(), game, rounds, themes, questions, users, answers)
+	async, err := reqBody.Async.toAsyncSettings(time.Now(), schedule)
 	if err != nil {
-		logger.Errorf("Failed to create game: %v", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_ASYNC_SETTINGS_ERROR, Message: err.Error()})
+		return
+	}
+
+	game := types.GameServer{
+		ID:          uuid.NewString(),
+		Name:        reqBody.Name,
+		CreatorID:   currentUserID(c),
+		TemplateID:  reqBody.TemplateID,
+		TieBreakers: tieBreakers,
+		Rules:       rules,
+		MinPlayers:  minPlayers,
+		Schedule:    schedule,
+		Async:       async,
+		Casual:      reqBody.Casual,
+	}
+	if async != nil {
+		game.Mode = types.GameModeAsync
+	}
+
+	users := []types.UserServer{{ID: game.CreatorID}}
+	for _, invitee := range reqBody.Invitees {
+		if !slices.ContainsFunc(users, func(user types.UserServer) bool { return user.ID == invitee }) {
+			users = append(users, types.UserServer{ID: invitee})
+		}
+	}
+
+	err = s.Db.CreateGameFromTemplate(c.Request.Context(), game, types.TemplateCopyOptions{
+		RoundIDs:      reqBody.RoundIDs,
+		ShuffleThemes: reqBody.ShuffleThemes,
+	}, users)
+	switch {
+	case errors.Is(err, db.ErrTemplateNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: TEMPLATE_NOT_FOUND_ERROR, Message: err.Error()})
+		return
+	case errors.Is(err, db.ErrInvalidRoundSelection):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	case err != nil:
+		logger.Errorf("Failed to create game from template: %v", err)
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_CREATE_GAME_ERROR, Message: err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Game created successfully"})
+	c.JSON(http.StatusOK, gin.H{"id": game.ID})
 }
 
 func (s *Server) GetGameById(c *gin.Context) {
@@ -51,6 +152,14 @@ func (s *Server) GetGameById(c *gin.Context) {
 		return
 	}
 
+	for _, g := range game {
+		hideUnjudgedOptions(g, currentUserID(c))
+		hideAsyncProgress(g, currentUserID(c))
+	}
+	if len(game) == 1 {
+		setVersionHeader(c, game[0].Version)
+	}
+
 	c.JSON(http.StatusOK, game)
 }
 
@@ -78,6 +187,11 @@ func (s *Server) GetActiveGamesByUserId(c *gin.Context) {
 		return
 	}
 
+	for _, game := range games {
+		hideUnjudgedOptions(game, currentUserID(c))
+		hideAsyncProgress(game, currentUserID(c))
+	}
+
 	c.JSON(http.StatusOK, games)
 }
 
@@ -96,12 +210,17 @@ func (s *Server) GetFinishedGamesByUserId(c *gin.Context) {
 	c.JSON(http.StatusOK, games)
 }
 
+// RemoveUserFromGame takes a player out of a game. Players may leave by
+// themselves; removing anyone else needs the host.
 func (s *Server) RemoveUserFromGame(c *gin.Context) {
 	var reqBody AddUserToGameRequest
 	if err := c.ShouldBindJSON(&reqBody); err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
 		return
 	}
+	if reqBody.UserID != currentUserID(c) && !s.requireGameHost(c, reqBody.GameID) {
+		return
+	}
 
 	err := s.Db.RemoveUserFromGame(c.Request.Context(), reqBody.GameID, reqBody.UserID)
 	if err != nil {
@@ -130,9 +249,9 @@ func (s *Server) AcceptGameInvite(c *gin.Context) {
 		return
 	}
 
-	err := s.Db.AcceptGameInvite(c.Request.Context(), reqBody.InviteID, reqBody.GameID, reqBody.UserID)
+	err := s.Db.AcceptGameInvite(c.Request.Context(), reqBody.InviteID, currentUserID(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_ACCEPT_GAME_INVITE_ERROR, Message: err.Error()})
+		inviteError(c, err, FAIL_ACCEPT_GAME_INVITE_ERROR)
 		return
 	}
 
@@ -146,9 +265,9 @@ func (s *Server) DeclineGameInvite(c *gin.Context) {
 		return
 	}
 
-	err := s.Db.DeclineGameInvite(c.Request.Context(), reqBody.InviteID)
+	err := s.Db.DeclineGameInvite(c.Request.Context(), reqBody.InviteID, currentUserID(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_DECLINE_GAME_INVITE_ERROR, Message: err.Error()})
+		inviteError(c, err, FAIL_DECLINE_GAME_INVITE_ERROR)
 		return
 	}
 
@@ -168,49 +287,202 @@ func (s *Server) DeleteGame(c *gin.Context) {
 
 func (s *Server) FinishGame(c *gin.Context) {
 	gameID := c.Param("id")
-	winningUserID := c.Param("userId")
-	err := s.Db.FinishGame(c.Request.Context(), gameID, winningUserID)
+
+	var reqBody FinishGameRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+			return
+		}
+	}
+
+	if !s.requireGameHost(c, gameID) {
+		return
+	}
+
+	_, tied, err := s.Db.FinishGame(c.Request.Context(), gameID, reqBody.SuddenDeathWinnerID)
+	if errors.Is(err, scoring.ErrSuddenDeathRequired) {
+		c.JSON(http.StatusConflict, ErrorResponse{Code: SUDDEN_DEATH_REQUIRED_ERROR, Message: err.Error(), Details: gin.H{"tiedUserIds": tied}})
+		return
+	}
+	if errors.Is(err, scoring.ErrInvalidSuddenDeathWinner) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error(), Details: gin.H{"tiedUserIds": tied}})
+		return
+	}
+	if errors.Is(err, db.ErrGameAlreadyFinished) {
+		c.JSON(http.StatusConflict, ErrorResponse{Code: GAME_ALREADY_FINISHED_ERROR, Message: err.Error()})
+		return
+	}
+	if errors.Is(err, db.ErrInvalidTransition) {
+		c.JSON(http.StatusConflict, ErrorResponse{Code: INVALID_STATUS_TRANSITION_ERROR, Message: err.Error()})
+		return
+	}
 	if err != nil {
+		logger.Errorf("Failed to finish game: %v", err)
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_FINISH_GAME_ERROR, Message: err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Game finished"})
+	results, err := s.Db.GetGameResults(c.Request.Context(), gameID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_GET_GAME_RESULTS_ERROR, Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
 }
 
+func (s *Server) GetGameResults(c *gin.Context) {
+	gameID := c.Param("id")
+	results, err := s.Db.GetGameResults(c.Request.Context(), gameID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_GET_GAME_RESULTS_ERROR, Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// UpdateGame stores the host's bulk update of the game in the path; the id
+// in the body is ignored.
 func (s *Server) UpdateGame(c *gin.Context) {
+	gameID := c.Param("id")
 	var gameBody types.GameClient
 	if err := c.ShouldBindJSON(&gameBody); err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
 		return
 	}
+	if !s.requireGameHost(c, gameID) {
+		return
+	}
 
-	game, users, answers, err := MapGameClientToUpdate(gameBody)
+	game, answers, err := MapGameClientToUpdate(gameBody)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_MAP_GAME_CLIENT_TO_DB_ERROR, Message: err.Error()})
 		return
 	}
+	game.ID = gameID
+
+	version, ok := expectedVersion(c, gameBody.Version)
+	if !ok {
+		c.JSON(http.StatusPreconditionRequired, ErrorResponse{Code: VERSION_REQUIRED_ERROR, Message: "Send the game version you last read in If-Match or the version field"})
+		return
+	}
+	game.Version = version
 
-	err = s.Db.UpdateGameAndGameUsers(c.Request.Context(), game, users, answers)
+	version, err = s.Db.UpdateGameAndGameUsers(c.Request.Context(), game, answers)
+	if errors.Is(err, db.ErrVersionConflict) {
+		s.versionConflict(c, game.ID, err)
+		return
+	}
+	if errors.Is(err, db.ErrNotTeamCaptain) || errors.Is(err, db.ErrInvalidContributor) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: TEAM_RULE_ERROR, Message: err.Error()})
+		return
+	}
+	if errors.Is(err, db.ErrQuestionNotInGame) {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: QUESTION_NOT_FOUND_ERROR, Message: err.Error()})
+		return
+	}
+	if errors.Is(err, db.ErrUserNotInGame) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: GAME_MUTATION_RULE_ERROR, Message: err.Error()})
+		return
+	}
+	if errors.Is(err, db.ErrGameNotInProgress) {
+		c.JSON(http.StatusConflict, ErrorResponse{Code: GAME_NOT_IN_PROGRESS_ERROR, Message: err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_UPDATE_GAME_ERROR, Message: err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Game updated"})
+	setVersionHeader(c, version)
+	c.JSON(http.StatusOK, gin.H{"message": "Game updated", "version": version})
+}
+
+// requireGameHost aborts with 403 unless the caller created the game.
+func (s *Server) requireGameHost(c *gin.Context, gameID string) bool {
+	creatorID, err := s.Db.GetGameCreatorID(c.Request.Context(), gameID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: GAME_NOT_FOUND_ERROR, Message: err.Error()})
+		return false
+	}
+
+	if creatorID != currentUserID(c) {
+		c.JSON(http.StatusForbidden, ErrorResponse{Code: NOT_GAME_HOST_ERROR, Message: "Only the game host can do this"})
+		return false
+	}
+
+	return true
+}
+
+func (s *Server) AddScoreAdjustment(c *gin.Context) {
+	gameID := c.Param("id")
+	var reqBody ScoreAdjustmentRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	mutation, ok := gameMutation(c)
+	if !ok || !s.requireGameHost(c, gameID) {
+		return
+	}
+
+	event, err := s.Db.AddScoreAdjustment(c.Request.Context(), types.ScoreAdjustmentServer{
+		GameID:    gameID,
+		UserID:    reqBody.UserID,
+		RoundID:   reqBody.RoundID,
+		Points:    reqBody.Points,
+		Reason:    reqBody.Reason,
+		CreatedBy: currentUserID(c),
+	}, mutation.Version)
+	if errors.Is(err, db.ErrUserNotInGame) || errors.Is(err, db.ErrRoundNotInGame) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_SCORE_ADJUSTMENT_ERROR, Message: err.Error()})
+		return
+	}
+	if errors.Is(err, db.ErrVersionConflict) {
+		s.versionConflict(c, gameID, err)
+		return
+	}
+	if err != nil {
+		logger.Errorf("Failed to add score adjustment: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_ADD_SCORE_ADJUSTMENT_ERROR, Message: err.Error()})
+		return
+	}
+
+	mutationApplied(c, event)
+}
+
+func (s *Server) GetScoreAdjustments(c *gin.Context) {
+	gameID := c.Param("id")
+	adjustments, err := s.Db.GetScoreAdjustmentsByGameId(c.Request.Context(), gameID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_GET_SCORE_ADJUSTMENTS_ERROR, Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, adjustments)
 }
 
 func (s *Server) AddGameRoutes(group *gin.RouterGroup) {
-	group.POST("/games/create", s.CreateGame)
+	group.POST("/games", s.CreateGameFromTemplate)
 	group.GET("/games/:id", s.GetGameById)
 	group.DELETE("/games/delete/:id", s.DeleteGame)
+	// Deprecated: send the whole game only for compatibility, the focused
+	// endpoints in AddGameMutationRoutes change one thing at a time.
 	group.POST("/games/update/:id", s.UpdateGame)
+	group.POST("/games/update/:id/merge", s.MergeGame)
+	group.POST("/games/finish/:id", s.FinishGame)
+	// Deprecated: the winner is computed on the server, userId is ignored.
 	group.POST("/games/finish/:id/:userId", s.FinishGame)
+	group.GET("/games/:id/results", s.GetGameResults)
 	group.GET("/games/active/user/:userId", s.GetActiveGamesByUserId)
 	group.GET("/games/finished/user/:userId", s.GetFinishedGamesByUserId)
 	group.POST("/games/remove-user", s.RemoveUserFromGame)
-	group.POST("/games/add-user", s.AddUserToGame)
 	group.GET("/games/invites/user/:userId", s.GetGameInvitesByUserId)
 	group.POST("/games/invites/accept", s.AcceptGameInvite)
 	group.POST("/games/invites/decline", s.DeclineGameInvite)
+	group.GET("/games/:id/adjustments", s.GetScoreAdjustments)
+	group.POST("/games/:id/adjustments", s.AddScoreAdjustment)
 }
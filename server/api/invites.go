@@ -0,0 +1,98 @@
+package api
+
+import (
+	"errors"
+	"mindwarp/db"
+	"mindwarp/logger"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SendInviteRequest struct {
+	UserID string `json:"userId" binding:"required,uuid"`
+}
+
+// inviteError maps invite lifecycle violations to client errors.
+func inviteError(c *gin.Context, err error, failCode string) {
+	switch {
+	case errors.Is(err, db.ErrGameNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: GAME_NOT_FOUND_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrInviteNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: INVITE_NOT_FOUND_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrInviteExpired):
+		c.JSON(http.StatusGone, ErrorResponse{Code: INVITE_EXPIRED_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrInviteRevoked):
+		c.JSON(http.StatusGone, ErrorResponse{Code: INVITE_REVOKED_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrInviteNotPending):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: INVITE_NOT_PENDING_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrInviteExists):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: INVITE_EXISTS_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrAlreadyInGame):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: ALREADY_IN_GAME_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrGameClosed):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: GAME_CLOSED_ERROR, Message: err.Error()})
+	default:
+		logger.Errorf("Invite error: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: failCode, Message: err.Error()})
+	}
+}
+
+// SendGameInvite invites a player, or invites them again after an earlier
+// invite was declined, expired or revoked.
+func (s *Server) SendGameInvite(c *gin.Context) {
+	var reqBody SendInviteRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	gameID := c.Param("id")
+	if !s.requireGameHost(c, gameID) {
+		return
+	}
+
+	inviteID, err := s.Db.SendGameInvite(c.Request.Context(), gameID, reqBody.UserID, currentUserID(c))
+	if err != nil {
+		inviteError(c, err, FAIL_SEND_GAME_INVITE_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": inviteID})
+}
+
+func (s *Server) RevokeGameInvite(c *gin.Context) {
+	gameID := c.Param("id")
+	if !s.requireGameHost(c, gameID) {
+		return
+	}
+
+	if err := s.Db.RevokeGameInvite(c.Request.Context(), gameID, c.Param("inviteId")); err != nil {
+		inviteError(c, err, FAIL_REVOKE_GAME_INVITE_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Game invite revoked"})
+}
+
+// GetGameInviteHistory shows the host every invite of the game.
+func (s *Server) GetGameInviteHistory(c *gin.Context) {
+	gameID := c.Param("id")
+	if !s.requireGameHost(c, gameID) {
+		return
+	}
+
+	invites, err := s.Db.GetGameInviteHistory(c.Request.Context(), gameID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_GET_INVITE_HISTORY_ERROR, Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, invites)
+}
+
+func (s *Server) AddInviteRoutes(group *gin.RouterGroup) {
+	group.GET("/games/:id/invites", s.GetGameInviteHistory)
+	group.POST("/games/:id/invites", s.SendGameInvite)
+	group.POST("/games/:id/invites/:inviteId/revoke", s.RevokeGameInvite)
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"errors"
+	"mindwarp/db"
+	"mindwarp/logger"
+	"mindwarp/types"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultLeaderboardLimit = 50
+	maxLeaderboardLimit     = 200
+)
+
+// leaderboardError maps leaderboard lookups to client errors.
+func leaderboardError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, db.ErrTemplateNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: TEMPLATE_NOT_FOUND_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrUnknownLeaderboard):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_LEADERBOARD_ERROR, Message: err.Error()})
+	default:
+		logger.Errorf("Leaderboard error: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_GET_LEADERBOARD_ERROR, Message: err.Error()})
+	}
+}
+
+// GetLeaderboard ranks players over all time or over the month or week
+// containing date, today by default. period is all_time, monthly or weekly
+// and metric is wins, score_share or accuracy.
+func (s *Server) GetLeaderboard(c *gin.Context) {
+	period := types.LeaderboardPeriod(c.DefaultQuery("period", string(types.PeriodAllTime)))
+	switch period {
+	case types.PeriodAllTime, types.PeriodMonthly, types.PeriodWeekly:
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_LEADERBOARD_ERROR, Message: "period must be all_time, monthly or weekly"})
+		return
+	}
+	metric := types.LeaderboardMetric(c.DefaultQuery("metric", string(types.MetricWins)))
+
+	day := s.clock()
+	if date := c.Query("date"); date != "" {
+		parsed, err := time.Parse(time.DateOnly, date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: "date must be a date in YYYY-MM-DD format"})
+			return
+		}
+		day = parsed
+	}
+	offset, limit, ok := pageParams(c, defaultLeaderboardLimit, maxLeaderboardLimit)
+	if !ok {
+		return
+	}
+
+	board, err := s.Db.GetLeaderboard(c.Request.Context(), period, metric, day, currentUserID(c), offset, limit)
+	if err != nil {
+		leaderboardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, board)
+}
+
+// GetTemplateLeaderboard ranks the best scores ever made on a template.
+func (s *Server) GetTemplateLeaderboard(c *gin.Context) {
+	offset, limit, ok := pageParams(c, defaultLeaderboardLimit, maxLeaderboardLimit)
+	if !ok {
+		return
+	}
+
+	board, err := s.Db.GetTemplateLeaderboard(c.Request.Context(), c.Param("id"), currentUserID(c), offset, limit)
+	if err != nil {
+		leaderboardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, board)
+}
+
+func (s *Server) AddLeaderboardRoutes(group *gin.RouterGroup) {
+	group.GET("/leaderboards", s.GetLeaderboard)
+	group.GET("/leaderboards/templates/:id", s.GetTemplateLeaderboard)
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"mindwarp/db"
+	"mindwarp/logger"
+	"mindwarp/types"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultMinPlayers  = 2
+	defaultAbandonDays = 14
+	sweepInterval      = time.Hour
+)
+
+type GameStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=lobby in_progress paused abandoned"`
+}
+
+// SetGameStatus opens a scheduled game early, starts, pauses, resumes or
+// abandons a game. Games are finished through FinishGame.
+func (s *Server) SetGameStatus(c *gin.Context) {
+	var reqBody GameStatusRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	gameID := c.Param("id")
+	if !s.requireGameHost(c, gameID) {
+		return
+	}
+
+	err := s.Db.SetGameStatus(c.Request.Context(), gameID, types.GameStatus(reqBody.Status))
+	switch {
+	case errors.Is(err, db.ErrInvalidTransition):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: INVALID_STATUS_TRANSITION_ERROR, Message: err.Error()})
+		return
+	case errors.Is(err, db.ErrNotEnoughPlayers):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: NOT_ENOUGH_PLAYERS_ERROR, Message: err.Error()})
+		return
+	case err != nil:
+		logger.Errorf("Failed to set game status: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_SET_GAME_STATUS_ERROR, Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": reqBody.Status})
+}
+
+// abandonAfter reads GAME_ABANDON_DAYS, the number of idle days after which
+// an unfinished game is abandoned.
+func abandonAfter() time.Duration {
+	days := defaultAbandonDays
+	if value := os.Getenv("GAME_ABANDON_DAYS"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			logger.Errorf("Invalid GAME_ABANDON_DAYS %q, using %d", value, defaultAbandonDays)
+		} else {
+			days = parsed
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// runGameSweeper abandons idle games and expires lapsed invites once at
+// startup and then every sweepInterval until ctx is done.
+func (s *Server) runGameSweeper(ctx context.Context) {
+	idle := abandonAfter()
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		gameIDs, err := s.Db.AbandonStaleGames(ctx, idle)
+		if err != nil {
+			logger.Errorf("Failed to abandon stale games: %v", err)
+		} else if len(gameIDs) > 0 {
+			logger.Infof("Abandoned %d stale game(s): %v", len(gameIDs), gameIDs)
+		}
+
+		expired, err := s.Db.ExpireGameInvites(ctx)
+		if err != nil {
+			logger.Errorf("Failed to expire game invites: %v", err)
+		} else if expired > 0 {
+			logger.Infof("Expired %d game invite(s)", expired)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) AddLifecycleRoutes(group *gin.RouterGroup) {
+	group.POST("/games/:id/status", s.SetGameStatus)
+}
@@ -1,24 +1,38 @@
 package api
 
 import (
+	"context"
 	"mindwarp/db"
+	"mindwarp/mail"
+	"mindwarp/media"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Server struct {
-	port        string
-	router      *gin.Engine
-	Db          *db.DB
-	authService *AuthService
+	port          string
+	router        *gin.Engine
+	Db            *db.DB
+	authService   *AuthService
+	mediaStore    media.MediaStore
+	mailer        mail.Mailer
+	notifications *notificationHub
+	// clock tells the time for day-bound features such as the daily
+	// challenge, so they can be driven by a fake clock.
+	clock func() time.Time
 }
 
 func NewServer() *Server {
 	return &Server{
-		port:        "0.0.0.0:8080",
-		router:      gin.Default(),
-		authService: NewAuthService(),
-		Db:          db.CreateDB(),
+		port:          "0.0.0.0:8080",
+		router:        gin.Default(),
+		authService:   NewAuthService(),
+		Db:            db.CreateDB(),
+		mediaStore:    newMediaStore(),
+		mailer:        newMailer(),
+		notifications: newNotificationHub(),
+		clock:         time.Now,
 	}
 }
 
@@ -34,8 +48,32 @@ func (s *Server) Start() {
 	s.AddGameTemplateRoutes(protected)
 	s.AddGameRoutes(protected)
 	s.AddCountRoutes(protected)
+	s.AddFinalRoundRoutes(protected)
+	s.AddSpecialQuestionRoutes(protected)
+	s.AddMediaRoutes(protected)
+	s.AddMultipleChoiceRoutes(protected)
+	s.AddTeamRoutes(protected)
+	s.AddAttemptRoutes(protected)
+	s.AddLifecycleRoutes(protected)
+	s.AddGameMutationRoutes(protected)
+	s.AddInviteRoutes(protected)
+	s.AddEmailInviteRoutes(protected)
+	s.AddNotificationRoutes(protected)
+	s.AddScheduleRoutes(protected)
+	s.AddAsyncRoutes(protected)
+	s.AddPracticeRoutes(protected)
+	s.AddStudyRoutes(protected)
+	s.AddDailyRoutes(protected)
+	s.AddTournamentRoutes(protected)
+	s.AddRatingRoutes(protected)
+	s.AddStatsRoutes(protected)
+	s.AddLeaderboardRoutes(protected)
 	// s.FillDb()
 
+	go s.runGameSweeper(context.Background())
+	go s.listenNotifications(context.Background())
+	go s.runScheduler(context.Background())
+
 	s.router.Run(s.port)
 }
 
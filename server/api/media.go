@@ -0,0 +1,194 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"mindwarp/db"
+	"mindwarp/logger"
+	"mindwarp/media"
+	"mindwarp/types"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newMediaStore opens the local media directory, MEDIA_DIR or ./uploads.
+func newMediaStore() media.MediaStore {
+	dir := os.Getenv("MEDIA_DIR")
+	if dir == "" {
+		dir = "uploads"
+	}
+
+	store, err := media.NewLocalStore(dir)
+	if err != nil {
+		logger.Errorf("unable to open media store: %s", err)
+		os.Exit(1)
+	}
+	return store
+}
+
+func mediaToClient(m types.MediaServer) types.MediaClient {
+	client := types.MediaClient{
+		ID:       m.ID,
+		MimeType: m.MimeType,
+		Kind:     m.Kind,
+		Size:     m.Size,
+		Width:    m.Width,
+		Height:   m.Height,
+		URL:      db.MediaURL(m.ID),
+	}
+	if m.ThumbnailID != nil {
+		client.ThumbnailURL = db.MediaURL(*m.ThumbnailID)
+	}
+	return client
+}
+
+func infoToMedia(info media.Info, uploadedBy string) types.MediaServer {
+	return types.MediaServer{
+		ID:         info.ID,
+		MimeType:   info.MimeType,
+		Kind:       string(info.Kind),
+		Size:       info.Size,
+		Width:      info.Width,
+		Height:     info.Height,
+		UploadedBy: uploadedBy,
+	}
+}
+
+// UploadMedia stores the multipart "file" field. The type is sniffed from the
+// contents and the response carries the id to attach to questions.
+func (s *Server) UploadMedia(c *gin.Context) {
+	// Leave room for the multipart envelope around the file itself
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, media.MaxUploadSize+1<<20)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Code: MEDIA_TOO_LARGE_ERROR, Message: media.ErrTooLarge.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+	defer file.Close()
+
+	info, err := media.Ingest(c.Request.Context(), s.mediaStore, file)
+	switch {
+	case errors.Is(err, media.ErrUnsupportedType):
+		c.JSON(http.StatusUnsupportedMediaType, ErrorResponse{Code: UNSUPPORTED_MEDIA_TYPE_ERROR, Message: err.Error()})
+		return
+	case errors.Is(err, media.ErrTooLarge), errors.Is(err, media.ErrTooManyPixels):
+		c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Code: MEDIA_TOO_LARGE_ERROR, Message: err.Error()})
+		return
+	case errors.Is(err, media.ErrEmpty), errors.Is(err, media.ErrInvalidImage):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	case err != nil:
+		logger.Errorf("Failed to store media: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_UPLOAD_MEDIA_ERROR, Message: err.Error()})
+		return
+	}
+
+	userID := currentUserID(c)
+	record := infoToMedia(info, userID)
+	if info.Thumbnail != nil {
+		if err := s.Db.SaveMedia(c.Request.Context(), infoToMedia(*info.Thumbnail, userID)); err != nil {
+			logger.Errorf("Failed to save thumbnail: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_UPLOAD_MEDIA_ERROR, Message: err.Error()})
+			return
+		}
+		record.ThumbnailID = &info.Thumbnail.ID
+	}
+
+	if err := s.Db.SaveMedia(c.Request.Context(), record); err != nil {
+		logger.Errorf("Failed to save media: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_UPLOAD_MEDIA_ERROR, Message: err.Error()})
+		return
+	}
+
+	// Re-read so a re-upload reports the thumbnail stored the first time
+	saved, err := s.Db.GetMediaByID(c.Request.Context(), info.ID)
+	if err != nil {
+		logger.Errorf("Failed to get media: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_UPLOAD_MEDIA_ERROR, Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, mediaToClient(saved))
+}
+
+func (s *Server) GetMediaInfo(c *gin.Context) {
+	m, err := s.Db.GetMediaByID(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, db.ErrMediaNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: MEDIA_NOT_FOUND_ERROR, Message: err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Errorf("Failed to get media: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_GET_MEDIA_ERROR, Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, mediaToClient(m))
+}
+
+// ServeMedia streams a stored file. Content never changes for a given id, so
+// it can be cached forever; range requests are honoured for audio seeking.
+func (s *Server) ServeMedia(c *gin.Context) {
+	id := c.Param("id")
+	if !media.ValidID(id) {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: MEDIA_NOT_FOUND_ERROR, Message: db.ErrMediaNotFound.Error()})
+		return
+	}
+
+	m, err := s.Db.GetMediaByID(c.Request.Context(), id)
+	if errors.Is(err, db.ErrMediaNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: MEDIA_NOT_FOUND_ERROR, Message: err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Errorf("Failed to get media: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_GET_MEDIA_ERROR, Message: err.Error()})
+		return
+	}
+
+	file, err := s.mediaStore.Open(c.Request.Context(), id)
+	if errors.Is(err, media.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: MEDIA_NOT_FOUND_ERROR, Message: err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Errorf("Failed to open media: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_GET_MEDIA_ERROR, Message: err.Error()})
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Type", m.MimeType)
+	c.Header("Cache-Control", "private, max-age=31536000, immutable")
+	c.Header("X-Content-Type-Options", "nosniff")
+
+	if seeker, ok := file.(io.ReadSeeker); ok {
+		http.ServeContent(c.Writer, c.Request, "", m.CreatedAt, seeker)
+		return
+	}
+
+	c.Status(http.StatusOK)
+	if _, err := io.Copy(c.Writer, file); err != nil {
+		logger.Errorf("Failed to stream media: %v", err)
+	}
+}
+
+func (s *Server) AddMediaRoutes(group *gin.RouterGroup) {
+	group.POST("/media", s.UploadMedia)
+	group.GET("/media/:id", s.ServeMedia)
+	group.GET("/media/:id/info", s.GetMediaInfo)
+}
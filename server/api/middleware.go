@@ -1,6 +1,8 @@
 package api
 
 import (
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -22,3 +24,24 @@ func (s *Server) AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// currentUserID returns the id of the authenticated caller set by AuthMiddleware.
+func currentUserID(c *gin.Context) string {
+	return c.GetString("currentUserID")
+}
+
+// requireAdmin lets only administrators through.
+func (s *Server) requireAdmin(c *gin.Context) bool {
+	user, err := s.Db.GetUserByID(currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_GET_CURRENT_USER_ERROR, Message: err.Error()})
+		return false
+	}
+
+	if !user.IsAdmin {
+		c.JSON(http.StatusForbidden, ErrorResponse{Code: NOT_ADMIN_ERROR, Message: "Only administrators can do this"})
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"errors"
+	"mindwarp/db"
+	"mindwarp/logger"
+	"mindwarp/types"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CurrentQuestionRequest struct {
+	QuestionID string `json:"questionId" binding:"required"`
+}
+
+type AnswerRequest struct {
+	IsCorrect     *bool  `json:"isCorrect" binding:"required"`
+	TimeAnswered  uint16 `json:"timeAnswered"`
+	ContributorID string `json:"contributorId"`
+}
+
+type CurrentPickerRequest struct {
+	UserID string `json:"userId" binding:"required"`
+}
+
+type RenameGameRequest struct {
+	Name string `json:"name" binding:"required,max=200"`
+}
+
+// mutationError maps the focused game endpoints' rule violations to client
+// errors.
+func (s *Server) mutationError(c *gin.Context, gameID string, err error, failCode string) {
+	switch {
+	case errors.Is(err, db.ErrVersionConflict):
+		s.versionConflict(c, gameID, err)
+	case errors.Is(err, db.ErrGameNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: GAME_NOT_FOUND_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrQuestionNotInGame):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: QUESTION_NOT_FOUND_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrAnswerNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: ANSWER_NOT_FOUND_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrUserNotInGame),
+		errors.Is(err, db.ErrWrongQuestionType),
+		errors.Is(err, db.ErrNotTeamCaptain),
+		errors.Is(err, db.ErrInvalidContributor):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: GAME_MUTATION_RULE_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrGameNotInProgress):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: GAME_NOT_IN_PROGRESS_ERROR, Message: err.Error()})
+	default:
+		logger.Errorf("Game mutation error: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: failCode, Message: err.Error()})
+	}
+}
+
+// gameMutation reads the target game and the optional If-Match version of a
+// focused change. Unlike the bulk update the version may be left out, since
+// these changes do not overwrite the rest of the game.
+func gameMutation(c *gin.Context) (types.GameMutation, bool) {
+	mutation := types.GameMutation{GameID: c.Param("id"), ActorID: currentUserID(c)}
+	if c.GetHeader("If-Match") == "" {
+		return mutation, true
+	}
+
+	version, ok := expectedVersion(c, 0)
+	if !ok {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: "If-Match must be a game version"})
+		return mutation, false
+	}
+	mutation.Version = version
+	return mutation, true
+}
+
+func mutationApplied(c *gin.Context, event types.GameEventServer) {
+	setVersionHeader(c, event.Version)
+	c.JSON(http.StatusOK, event.Client())
+}
+
+func (s *Server) SetCurrentQuestion(c *gin.Context) {
+	var reqBody CurrentQuestionRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	mutation, ok := gameMutation(c)
+	if !ok || !s.requireGameHost(c, mutation.GameID) {
+		return
+	}
+
+	event, err := s.Db.SetCurrentQuestion(c.Request.Context(), mutation, reqBody.QuestionID)
+	if err != nil {
+		s.mutationError(c, mutation.GameID, err, FAIL_SET_CURRENT_QUESTION_ERROR)
+		return
+	}
+
+	mutationApplied(c, event)
+}
+
+// RecordAnswer stores or corrects a single player's answer.
+func (s *Server) RecordAnswer(c *gin.Context) {
+	var reqBody AnswerRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	mutation, ok := gameMutation(c)
+	if !ok || !s.requireGameHost(c, mutation.GameID) {
+		return
+	}
+
+	event, err := s.Db.RecordAnswer(c.Request.Context(), mutation, types.AnswerServer{
+		QuestionID:    c.Param("questionId"),
+		UserID:        c.Param("userId"),
+		IsCorrect:     *reqBody.IsCorrect,
+		TimeAnswered:  reqBody.TimeAnswered,
+		ContributorID: reqBody.ContributorID,
+	})
+	if err != nil {
+		s.mutationError(c, mutation.GameID, err, FAIL_RECORD_ANSWER_ERROR)
+		return
+	}
+
+	mutationApplied(c, event)
+}
+
+func (s *Server) ClearAnswer(c *gin.Context) {
+	mutation, ok := gameMutation(c)
+	if !ok || !s.requireGameHost(c, mutation.GameID) {
+		return
+	}
+
+	event, err := s.Db.ClearAnswer(c.Request.Context(), mutation, c.Param("questionId"), c.Param("userId"))
+	if err != nil {
+		s.mutationError(c, mutation.GameID, err, FAIL_CLEAR_ANSWER_ERROR)
+		return
+	}
+
+	mutationApplied(c, event)
+}
+
+func (s *Server) SetCurrentPicker(c *gin.Context) {
+	var reqBody CurrentPickerRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	mutation, ok := gameMutation(c)
+	if !ok || !s.requireGameHost(c, mutation.GameID) {
+		return
+	}
+
+	event, err := s.Db.SetCurrentPicker(c.Request.Context(), mutation, reqBody.UserID)
+	if err != nil {
+		s.mutationError(c, mutation.GameID, err, FAIL_SET_CURRENT_PICKER_ERROR)
+		return
+	}
+
+	mutationApplied(c, event)
+}
+
+func (s *Server) RenameGame(c *gin.Context) {
+	var reqBody RenameGameRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	mutation, ok := gameMutation(c)
+	if !ok || !s.requireGameHost(c, mutation.GameID) {
+		return
+	}
+
+	event, err := s.Db.RenameGame(c.Request.Context(), mutation, reqBody.Name)
+	if err != nil {
+		s.mutationError(c, mutation.GameID, err, FAIL_RENAME_GAME_ERROR)
+		return
+	}
+
+	mutationApplied(c, event)
+}
+
+// GetGameEvents lists the changes made after the version given in since, so a
+// client can catch up without reloading the whole game.
+func (s *Server) GetGameEvents(c *gin.Context) {
+	since := 0
+	if value := c.Query("since"); value != "" {
+		var err error
+		if since, err = strconv.Atoi(value); err != nil || since < 0 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: "since must be a game version"})
+			return
+		}
+	}
+
+	events, err := s.Db.GetGameEvents(c.Request.Context(), c.Param("id"), since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_GET_GAME_EVENTS_ERROR, Message: err.Error()})
+		return
+	}
+
+	result := make([]types.GameEventClient, len(events))
+	for i, event := range events {
+		result[i] = event.Client()
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (s *Server) AddGameMutationRoutes(group *gin.RouterGroup) {
+	group.GET("/games/:id/events", s.GetGameEvents)
+	group.PUT("/games/:id/current-question", s.SetCurrentQuestion)
+	group.PUT("/games/:id/current-picker", s.SetCurrentPicker)
+	group.PUT("/games/:id/name", s.RenameGame)
+	group.PUT("/games/:id/answers/:questionId/:userId", s.RecordAnswer)
+	group.DELETE("/games/:id/answers/:questionId/:userId", s.ClearAnswer)
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"io"
+	"mindwarp/logger"
+	"mindwarp/types"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultNotificationLimit = 20
+	maxNotificationLimit     = 100
+	streamHeartbeat          = 30 * time.Second
+	listenRetryDelay         = 5 * time.Second
+)
+
+type MarkNotificationsReadRequest struct {
+	IDs []string `json:"ids" binding:"dive,uuid"`
+	All bool     `json:"all"`
+}
+
+// notificationHub fans committed notifications out to the open streams of
+// their users.
+type notificationHub struct {
+	mu      sync.Mutex
+	streams map[string]map[chan types.NotificationClient]struct{}
+}
+
+func newNotificationHub() *notificationHub {
+	return &notificationHub{streams: make(map[string]map[chan types.NotificationClient]struct{})}
+}
+
+// subscribe opens a stream for userID. The returned function closes it.
+func (h *notificationHub) subscribe(userID string) (chan types.NotificationClient, func()) {
+	ch := make(chan types.NotificationClient, 16)
+
+	h.mu.Lock()
+	if h.streams[userID] == nil {
+		h.streams[userID] = make(map[chan types.NotificationClient]struct{})
+	}
+	h.streams[userID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.streams[userID], ch)
+		if len(h.streams[userID]) == 0 {
+			delete(h.streams, userID)
+		}
+		h.mu.Unlock()
+	}
+}
+
+// publish hands n to every stream of its user. A stream that is not keeping
+// up misses it; the client catches up from the inbox.
+func (h *notificationHub) publish(n types.NotificationServer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.streams[n.UserID] {
+		select {
+		case ch <- n.Client():
+		default:
+		}
+	}
+}
+
+// listenNotifications relays committed notifications to the hub until ctx is
+// done, reconnecting when the listening connection fails.
+func (s *Server) listenNotifications(ctx context.Context) {
+	for {
+		err := s.Db.ListenNotifications(ctx, s.notifications.publish)
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Errorf("Notification listener stopped, retrying: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+func (s *Server) GetNotifications(c *gin.Context) {
+	offset, limit, ok := pageParams(c, defaultNotificationLimit, maxNotificationLimit)
+	if !ok {
+		return
+	}
+	unreadOnly := c.Query("unread") == "true"
+
+	page, err := s.Db.GetNotifications(c.Request.Context(), currentUserID(c), unreadOnly, offset, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_GET_NOTIFICATIONS_ERROR, Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// MarkNotificationsRead marks the listed notifications, or with all set every
+// notification, of the current user as read.
+func (s *Server) MarkNotificationsRead(c *gin.Context) {
+	var reqBody MarkNotificationsReadRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+	if len(reqBody.IDs) == 0 && !reqBody.All {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: "Send the ids to mark or set all"})
+		return
+	}
+
+	ids := reqBody.IDs
+	if reqBody.All {
+		ids = nil
+	}
+
+	marked, err := s.Db.MarkNotificationsRead(c.Request.Context(), currentUserID(c), ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_MARK_NOTIFICATIONS_READ_ERROR, Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"marked": marked})
+}
+
+// StreamNotifications pushes the current user's new notifications as
+// Server-Sent Events. A comment is sent every streamHeartbeat to keep proxies
+// from closing the idle connection.
+func (s *Server) StreamNotifications(c *gin.Context) {
+	ch, unsubscribe := s.notifications.subscribe(currentUserID(c))
+	defer unsubscribe()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case n := <-ch:
+			c.SSEvent("notification", n)
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func (s *Server) AddNotificationRoutes(group *gin.RouterGroup) {
+	group.GET("/notifications", s.GetNotifications)
+	group.POST("/notifications/read", s.MarkNotificationsRead)
+	group.GET("/notifications/stream", s.StreamNotifications)
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"errors"
+	"mindwarp/db"
+	"mindwarp/logger"
+	"mindwarp/types"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPracticeLimit = 20
+	maxPracticeLimit     = 100
+)
+
+// CreatePracticeRequest starts a solo session on a template, or with
+// fromReview on the questions the player keeps missing.
+type CreatePracticeRequest struct {
+	TemplateID string   `json:"templateId" binding:"required_without=FromReview,omitempty,uuid"`
+	RoundIDs   []string `json:"roundIds" binding:"dive,uuid"`
+	FromReview bool     `json:"fromReview"`
+	Shuffle    bool     `json:"shuffle"`
+	Limit      int      `json:"limit" binding:"omitempty,min=1,max=200"`
+}
+
+type GradePracticeRequest struct {
+	IsCorrect *bool `json:"isCorrect" binding:"required"`
+}
+
+// practiceError maps practice rule violations to client errors.
+func practiceError(c *gin.Context, err error, failCode string) {
+	switch {
+	case errors.Is(err, db.ErrPracticeNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: PRACTICE_NOT_FOUND_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrTemplateNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: TEMPLATE_NOT_FOUND_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrReviewItemNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: REVIEW_ITEM_NOT_FOUND_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrNoPracticeQuestions):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: PRACTICE_RULE_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrPracticeFinished),
+		errors.Is(err, db.ErrNotCurrentQuestion),
+		errors.Is(err, db.ErrAnswerNotRevealed):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: PRACTICE_RULE_ERROR, Message: err.Error()})
+	default:
+		logger.Errorf("Practice error: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: failCode, Message: err.Error()})
+	}
+}
+
+func (s *Server) CreatePracticeSession(c *gin.Context) {
+	var reqBody CreatePracticeRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	session, err := s.Db.CreatePracticeSession(c.Request.Context(), types.PracticeOptions{
+		UserID:     currentUserID(c),
+		TemplateID: reqBody.TemplateID,
+		RoundIDs:   reqBody.RoundIDs,
+		FromReview: reqBody.FromReview,
+		Shuffle:    reqBody.Shuffle,
+		Limit:      reqBody.Limit,
+	})
+	if err != nil {
+		practiceError(c, err, FAIL_CREATE_PRACTICE_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusCreated, session)
+}
+
+func (s *Server) GetPracticeSessions(c *gin.Context) {
+	offset, limit, ok := pageParams(c, defaultPracticeLimit, maxPracticeLimit)
+	if !ok {
+		return
+	}
+
+	sessions, err := s.Db.GetPracticeSessions(c.Request.Context(), currentUserID(c), offset, limit)
+	if err != nil {
+		practiceError(c, err, FAIL_GET_PRACTICE_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+func (s *Server) GetPracticeSession(c *gin.Context) {
+	session, err := s.Db.GetPracticeSession(c.Request.Context(), c.Param("id"), currentUserID(c))
+	if err != nil {
+		practiceError(c, err, FAIL_GET_PRACTICE_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// NextPracticeQuestion serves the question the player is on, or 204 once the
+// session is over.
+func (s *Server) NextPracticeQuestion(c *gin.Context) {
+	question, err := s.Db.NextPracticeQuestion(c.Request.Context(), c.Param("id"), currentUserID(c))
+	if err != nil {
+		practiceError(c, err, FAIL_GET_PRACTICE_ERROR)
+		return
+	}
+	if question == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, question)
+}
+
+func (s *Server) RevealPracticeQuestion(c *gin.Context) {
+	question, err := s.Db.RevealPracticeQuestion(c.Request.Context(), c.Param("id"), c.Param("questionId"), currentUserID(c))
+	if err != nil {
+		practiceError(c, err, FAIL_REVEAL_PRACTICE_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, question)
+}
+
+// GradePracticeQuestion records whether the player got the revealed question
+// right.
+func (s *Server) GradePracticeQuestion(c *gin.Context) {
+	var reqBody GradePracticeRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	session, err := s.Db.GradePracticeQuestion(c.Request.Context(), c.Param("id"), c.Param("questionId"), currentUserID(c), *reqBody.IsCorrect)
+	if err != nil {
+		practiceError(c, err, FAIL_GRADE_PRACTICE_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+func (s *Server) FinishPracticeSession(c *gin.Context) {
+	session, err := s.Db.FinishPracticeSession(c.Request.Context(), c.Param("id"), currentUserID(c))
+	if err != nil {
+		practiceError(c, err, FAIL_FINISH_PRACTICE_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+func (s *Server) GetReviewList(c *gin.Context) {
+	offset, limit, ok := pageParams(c, defaultPracticeLimit, maxPracticeLimit)
+	if !ok {
+		return
+	}
+
+	items, err := s.Db.GetReviewList(c.Request.Context(), currentUserID(c), offset, limit)
+	if err != nil {
+		practiceError(c, err, FAIL_GET_REVIEW_LIST_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+func (s *Server) DismissReviewItem(c *gin.Context) {
+	if err := s.Db.DismissReviewItem(c.Request.Context(), currentUserID(c), c.Param("questionId")); err != nil {
+		practiceError(c, err, FAIL_DISMISS_REVIEW_ITEM_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Question removed from review list"})
+}
+
+func (s *Server) AddPracticeRoutes(group *gin.RouterGroup) {
+	group.POST("/practice", s.CreatePracticeSession)
+	group.GET("/practice", s.GetPracticeSessions)
+	group.GET("/practice/review", s.GetReviewList)
+	group.DELETE("/practice/review/:questionId", s.DismissReviewItem)
+	group.GET("/practice/sessions/:id", s.GetPracticeSession)
+	group.GET("/practice/sessions/:id/next", s.NextPracticeQuestion)
+	group.POST("/practice/sessions/:id/questions/:questionId/reveal", s.RevealPracticeQuestion)
+	group.POST("/practice/sessions/:id/questions/:questionId/grade", s.GradePracticeQuestion)
+	group.POST("/practice/sessions/:id/finish", s.FinishPracticeSession)
+}
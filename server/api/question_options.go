@@ -0,0 +1,84 @@
+package api
+
+import (
+	"errors"
+	"mindwarp/db"
+	"mindwarp/logger"
+	"mindwarp/types"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ChoiceAnswerRequest struct {
+	OptionIDs    []string `json:"optionIds" binding:"required,min=1"`
+	TimeAnswered uint16   `json:"timeAnswered"`
+}
+
+// hideUnjudgedOptions strips which options are correct, and the answer text,
+// from multiple-choice questions the viewer has not been judged on yet. The
+// other players' answers would give the correct options away as well, so
+// they are left out too. The host, and everyone once the game is finished or
+// all players have answered, sees the full question.
+func hideUnjudgedOptions(game *types.GameClient, viewerID string) {
+	if game.IsFinished || game.CreatorID == viewerID {
+		return
+	}
+
+	for i := range game.Rounds {
+		for j := range game.Rounds[i].Themes {
+			for k := range game.Rounds[i].Themes[j].Questions {
+				question := &game.Rounds[i].Themes[j].Questions[k]
+				if question.Type != string(types.QuestionMultipleChoice) {
+					continue
+				}
+				if _, answered := question.AnsweredBy[viewerID]; answered || len(question.AnsweredBy) >= len(game.Users) {
+					continue
+				}
+
+				question.Answer = ""
+				question.AnsweredBy = map[string]types.AnsweredByClient{}
+				for l := range question.Options {
+					question.Options[l].IsCorrect = nil
+				}
+			}
+		}
+	}
+}
+
+func (s *Server) SubmitChoiceAnswer(c *gin.Context) {
+	var reqBody ChoiceAnswerRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	isCorrect, err := s.Db.SubmitChoiceAnswer(c.Request.Context(), c.Param("id"), c.Param("questionId"), currentUserID(c), reqBody.OptionIDs, reqBody.TimeAnswered)
+	switch {
+	case errors.Is(err, db.ErrQuestionNotInGame):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: QUESTION_NOT_FOUND_ERROR, Message: err.Error()})
+		return
+	case errors.Is(err, db.ErrUserNotInGame), errors.Is(err, db.ErrNotTeamCaptain):
+		c.JSON(http.StatusForbidden, ErrorResponse{Code: MULTIPLE_CHOICE_RULE_ERROR, Message: err.Error()})
+		return
+	case errors.Is(err, db.ErrNotMultipleChoice), errors.Is(err, db.ErrInvalidOption):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: MULTIPLE_CHOICE_RULE_ERROR, Message: err.Error()})
+		return
+	case errors.Is(err, db.ErrGameNotInProgress):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: GAME_NOT_IN_PROGRESS_ERROR, Message: err.Error()})
+		return
+	case errors.Is(err, db.ErrAlreadyAnswered):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: MULTIPLE_CHOICE_RULE_ERROR, Message: err.Error()})
+		return
+	case err != nil:
+		logger.Errorf("Failed to submit choice answer: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_SUBMIT_CHOICE_ANSWER_ERROR, Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"isCorrect": isCorrect})
+}
+
+func (s *Server) AddMultipleChoiceRoutes(group *gin.RouterGroup) {
+	group.POST("/games/:id/questions/:questionId/choice", s.SubmitChoiceAnswer)
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"errors"
+	"mindwarp/db"
+	"mindwarp/logger"
+	"mindwarp/types"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultRatingLimit        = 50
+	maxRatingLimit            = 200
+	defaultSoftenFactor       = 0.5
+	defaultRatingHistoryLimit = 20
+)
+
+// CreateSeasonRequest starts a new rating season. CarryOver is soften by
+// default, keeping SoftenFactor, a half by default, of each rating's
+// distance from the starting rating.
+type CreateSeasonRequest struct {
+	Name         string   `json:"name" binding:"required"`
+	CarryOver    string   `json:"carryOver" binding:"omitempty,oneof=reset soften"`
+	SoftenFactor *float64 `json:"softenFactor" binding:"omitempty,min=0,max=1"`
+}
+
+// ratingError maps rating errors to client errors.
+func ratingError(c *gin.Context, err error, failCode string) {
+	switch {
+	case errors.Is(err, db.ErrSeasonNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: SEASON_NOT_FOUND_ERROR, Message: err.Error()})
+	default:
+		logger.Errorf("Rating error: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: failCode, Message: err.Error()})
+	}
+}
+
+// seasonParam reads the season query parameter, empty for the current
+// season. It writes the error response and returns false if it is malformed.
+func seasonParam(c *gin.Context) (string, bool) {
+	seasonID := c.Query("season")
+	if seasonID == "" {
+		return "", true
+	}
+	if err := uuid.Validate(seasonID); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: "season must be a season id"})
+		return "", false
+	}
+	return seasonID, true
+}
+
+func (s *Server) GetSeasons(c *gin.Context) {
+	seasons, err := s.Db.GetSeasons(c.Request.Context())
+	if err != nil {
+		ratingError(c, err, FAIL_GET_SEASONS_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, seasons)
+}
+
+// CreateSeason ends the current season and starts a new one. Only
+// administrators may do this.
+func (s *Server) CreateSeason(c *gin.Context) {
+	var reqBody CreateSeasonRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+	if !s.requireAdmin(c) {
+		return
+	}
+
+	season := types.SeasonServer{
+		ID:           uuid.NewString(),
+		Name:         reqBody.Name,
+		CarryOver:    types.SeasonCarryOver(reqBody.CarryOver),
+		SoftenFactor: defaultSoftenFactor,
+	}
+	if season.CarryOver == "" {
+		season.CarryOver = types.SeasonSoften
+	}
+	if reqBody.SoftenFactor != nil {
+		season.SoftenFactor = *reqBody.SoftenFactor
+	}
+
+	created, err := s.Db.CreateSeason(c.Request.Context(), season)
+	if err != nil {
+		ratingError(c, err, FAIL_CREATE_SEASON_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// GetRatingLeaderboard ranks the players of a season, the current one
+// unless season is given, by rating.
+func (s *Server) GetRatingLeaderboard(c *gin.Context) {
+	seasonID, ok := seasonParam(c)
+	if !ok {
+		return
+	}
+	offset, limit, ok := pageParams(c, defaultRatingLimit, maxRatingLimit)
+	if !ok {
+		return
+	}
+
+	board, err := s.Db.GetRatingLeaderboard(c.Request.Context(), seasonID, currentUserID(c), offset, limit)
+	if err != nil {
+		ratingError(c, err, FAIL_GET_RATING_LEADERBOARD_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, board)
+}
+
+// GetPlayerRating returns a player's rating in a season and how their games
+// moved it.
+func (s *Server) GetPlayerRating(c *gin.Context) {
+	seasonID, ok := seasonParam(c)
+	if !ok {
+		return
+	}
+	offset, limit, ok := pageParams(c, defaultRatingHistoryLimit, maxRatingLimit)
+	if !ok {
+		return
+	}
+
+	player, err := s.Db.GetPlayerRating(c.Request.Context(), seasonID, c.Param("userId"), offset, limit)
+	if err != nil {
+		ratingError(c, err, FAIL_GET_PLAYER_RATING_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, player)
+}
+
+func (s *Server) AddRatingRoutes(group *gin.RouterGroup) {
+	group.GET("/seasons", s.GetSeasons)
+	group.POST("/seasons", s.CreateSeason)
+	group.GET("/ratings/leaderboard", s.GetRatingLeaderboard)
+	group.GET("/ratings/users/:userId", s.GetPlayerRating)
+}
@@ -0,0 +1,290 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mindwarp/daily"
+	"mindwarp/db"
+	"mindwarp/logger"
+	"mindwarp/types"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	schedulerInterval   = time.Minute
+	defaultGameDuration = 2 * time.Hour
+	maxReminders        = 5
+	maxReminderOffset   = 14 * 24 * 60
+)
+
+var defaultReminderOffsets = []int{24 * 60, 60}
+
+// ScheduleRequest sets when a game starts. Timezone is an IANA name used to
+// show the time to players and defaults to UTC. ReminderOffsets are minutes
+// before the start and default to a day and an hour.
+type ScheduleRequest struct {
+	ScheduledAt     *time.Time `json:"scheduledAt"`
+	Timezone        string     `json:"timezone"`
+	ReminderOffsets []int      `json:"reminderOffsets"`
+}
+
+// toGameSchedule validates req. A request without a start time clears the
+// schedule and yields nil.
+func (req *ScheduleRequest) toGameSchedule(now time.Time) (*types.GameSchedule, error) {
+	if req == nil || req.ScheduledAt == nil {
+		return nil, nil
+	}
+	if !req.ScheduledAt.After(now) {
+		return nil, errors.New("scheduledAt must be in the future")
+	}
+
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", req.Timezone)
+	}
+
+	offsets := req.ReminderOffsets
+	if offsets == nil {
+		offsets = defaultReminderOffsets
+	}
+	reminders := make([]int, 0, len(offsets))
+	for _, offset := range offsets {
+		if offset < 1 || offset > maxReminderOffset {
+			return nil, fmt.Errorf("reminder offsets must be between 1 and %d minutes", maxReminderOffset)
+		}
+		if !slices.Contains(reminders, offset) {
+			reminders = append(reminders, offset)
+		}
+	}
+	if len(reminders) > maxReminders {
+		return nil, fmt.Errorf("at most %d reminders are allowed", maxReminders)
+	}
+	slices.Sort(reminders)
+	slices.Reverse(reminders)
+
+	return &types.GameSchedule{
+		StartsAt:        req.ScheduledAt.UTC(),
+		Timezone:        timezone,
+		ReminderOffsets: reminders,
+	}, nil
+}
+
+// SetGameSchedule schedules a game that has not started yet, moves it to a
+// new time or, without scheduledAt, opens its lobby right away.
+func (s *Server) SetGameSchedule(c *gin.Context) {
+	var reqBody ScheduleRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	gameID := c.Param("id")
+	if !s.requireGameHost(c, gameID) {
+		return
+	}
+
+	schedule, err := reqBody.toGameSchedule(time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_SCHEDULE_ERROR, Message: err.Error()})
+		return
+	}
+
+	err = s.Db.SetGameSchedule(c.Request.Context(), gameID, schedule)
+	switch {
+	case errors.Is(err, db.ErrGameNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: GAME_NOT_FOUND_ERROR, Message: err.Error()})
+		return
+	case errors.Is(err, db.ErrInvalidTransition):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: INVALID_STATUS_TRANSITION_ERROR, Message: "Only games that have not started can be scheduled"})
+		return
+	case err != nil:
+		logger.Errorf("Failed to set game schedule: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_SET_SCHEDULE_ERROR, Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"schedule": schedule.Client()})
+}
+
+// runScheduler sends due reminders, opens the lobby of games whose start
+// time has come and times out and finishes async games, once at startup and
+// then every schedulerInterval until ctx is done.
+func (s *Server) runScheduler(ctx context.Context) {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		reminded, err := s.Db.SendDueReminders(ctx)
+		if err != nil {
+			logger.Errorf("Failed to send game reminders: %v", err)
+		} else if reminded > 0 {
+			logger.Infof("Sent reminders for %d scheduled game(s)", reminded)
+		}
+
+		gameIDs, err := s.Db.OpenScheduledGames(ctx)
+		if err != nil {
+			logger.Errorf("Failed to open scheduled games: %v", err)
+		} else if len(gameIDs) > 0 {
+			logger.Infof("Opened the lobby of %d scheduled game(s): %v", len(gameIDs), gameIDs)
+		}
+
+		finished, err := s.Db.SweepAsyncGames(ctx)
+		if err != nil {
+			logger.Errorf("Failed to sweep async games: %v", err)
+		} else if len(finished) > 0 {
+			logger.Infof("Finished %d async game(s): %v", len(finished), finished)
+		}
+
+		now := s.clock()
+		if err := s.Db.EnsureDailyChallenge(ctx, daily.Day(now)); err != nil && !errors.Is(err, db.ErrNoDailyQuestions) {
+			logger.Errorf("Failed to create the daily challenge: %v", err)
+		}
+		expired, err := s.Db.FinishExpiredDailyAttempts(ctx, now)
+		if err != nil {
+			logger.Errorf("Failed to finish expired daily attempts: %v", err)
+		} else if expired > 0 {
+			logger.Infof("Finished %d expired daily challenge attempt(s)", expired)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetGameCalendar exports one scheduled game the caller takes part in as an
+// iCalendar file.
+func (s *Server) GetGameCalendar(c *gin.Context) {
+	games, err := s.Db.GetScheduledGames(c.Request.Context(), currentUserID(c), c.Param("id"))
+	if err != nil {
+		logger.Errorf("Failed to get scheduled game: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_GET_CALENDAR_ERROR, Message: err.Error()})
+		return
+	}
+	if len(games) == 0 {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: SCHEDULED_GAME_NOT_FOUND, Message: "Game is not scheduled"})
+		return
+	}
+
+	writeCalendar(c, "game-"+games[0].ID+".ics", games)
+}
+
+// GetMyCalendar exports every scheduled game the caller hosts, plays in or is
+// invited to as one iCalendar feed.
+func (s *Server) GetMyCalendar(c *gin.Context) {
+	games, err := s.Db.GetScheduledGames(c.Request.Context(), currentUserID(c), "")
+	if err != nil {
+		logger.Errorf("Failed to get scheduled games: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_GET_CALENDAR_ERROR, Message: err.Error()})
+		return
+	}
+
+	writeCalendar(c, "mindwarp.ics", games)
+}
+
+func writeCalendar(c *gin.Context, filename string, games []types.ScheduledGameServer) {
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(buildCalendar(games, time.Now())))
+}
+
+// buildCalendar renders games as an RFC 5545 calendar with one event per
+// game and one alarm per reminder. Times are written in UTC; the game's
+// timezone is kept as a hint for clients.
+func buildCalendar(games []types.ScheduledGameServer, now time.Time) string {
+	var b strings.Builder
+	line := func(format string, args ...any) {
+		b.WriteString(foldICSLine(fmt.Sprintf(format, args...)))
+		b.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//Mindwarp//Scheduled games//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:Mindwarp")
+	if len(games) == 1 {
+		line("X-WR-TIMEZONE:%s", games[0].Schedule.Timezone)
+	}
+
+	for _, game := range games {
+		start := game.Schedule.StartsAt.UTC()
+		line("BEGIN:VEVENT")
+		line("UID:%s@mindwarp.games", game.ID)
+		line("DTSTAMP:%s", icsTime(now))
+		line("DTSTART:%s", icsTime(start))
+		line("DTEND:%s", icsTime(start.Add(defaultGameDuration)))
+		line("SUMMARY:%s", escapeICSText(game.Name))
+		description := "Mindwarp game"
+		if game.HostName != "" {
+			description += " hosted by " + game.HostName
+		}
+		line("DESCRIPTION:%s", escapeICSText(description+" ("+game.Schedule.Timezone+")"))
+		line("URL:%s/games/%s", appURL(), game.ID)
+		if game.Status == types.GameAbandoned {
+			line("STATUS:CANCELLED")
+		} else {
+			line("STATUS:CONFIRMED")
+		}
+		for _, offset := range game.Schedule.ReminderOffsets {
+			line("BEGIN:VALARM")
+			line("ACTION:DISPLAY")
+			line("DESCRIPTION:%s", escapeICSText(game.Name))
+			line("TRIGGER:-PT%dM", offset)
+			line("END:VALARM")
+		}
+		line("END:VEVENT")
+	}
+
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+func icsTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+var icsTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeICSText(text string) string {
+	return icsTextEscaper.Replace(text)
+}
+
+// foldICSLine splits content lines longer than 75 octets, never inside a
+// UTF-8 sequence, as RFC 5545 requires.
+func foldICSLine(line string) string {
+	const limit = 75
+	if len(line) <= limit {
+		return line
+	}
+
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
+
+func (s *Server) AddScheduleRoutes(group *gin.RouterGroup) {
+	group.PUT("/games/:id/schedule", s.SetGameSchedule)
+	group.GET("/games/:id/calendar.ics", s.GetGameCalendar)
+	group.GET("/me/calendar.ics", s.GetMyCalendar)
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"errors"
+	"mindwarp/db"
+	"mindwarp/logger"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AssignCatInBagRequest struct {
+	AssigneeID string `json:"assigneeId" binding:"required"`
+}
+
+type AuctionBidRequest struct {
+	Amount int `json:"amount" binding:"required,min=1"`
+}
+
+// specialQuestionError maps cat-in-the-bag and auction rule violations to
+// client errors.
+func specialQuestionError(c *gin.Context, err error, failCode string) {
+	switch {
+	case errors.Is(err, db.ErrQuestionNotInGame):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: QUESTION_NOT_FOUND_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrNotPicker), errors.Is(err, db.ErrUserNotInGame):
+		c.JSON(http.StatusForbidden, ErrorResponse{Code: SPECIAL_QUESTION_RULE_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrWrongQuestionType),
+		errors.Is(err, db.ErrInvalidAssignee),
+		errors.Is(err, db.ErrBidTooLow),
+		errors.Is(err, db.ErrBidTooHigh):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: SPECIAL_QUESTION_RULE_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrGameNotInProgress):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: GAME_NOT_IN_PROGRESS_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrQuestionAlreadyPlayed), errors.Is(err, db.ErrNoBids):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: SPECIAL_QUESTION_RULE_ERROR, Message: err.Error()})
+	default:
+		logger.Errorf("Special question error: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: failCode, Message: err.Error()})
+	}
+}
+
+func (s *Server) AssignCatInBag(c *gin.Context) {
+	var reqBody AssignCatInBagRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	err := s.Db.AssignCatInBag(c.Request.Context(), c.Param("id"), c.Param("questionId"), currentUserID(c), reqBody.AssigneeID)
+	if err != nil {
+		specialQuestionError(c, err, FAIL_ASSIGN_CAT_IN_BAG_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Question assigned"})
+}
+
+func (s *Server) PlaceAuctionBid(c *gin.Context) {
+	var reqBody AuctionBidRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	err := s.Db.PlaceAuctionBid(c.Request.Context(), c.Param("id"), c.Param("questionId"), currentUserID(c), reqBody.Amount)
+	if err != nil {
+		specialQuestionError(c, err, FAIL_PLACE_AUCTION_BID_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bid placed"})
+}
+
+func (s *Server) CloseAuction(c *gin.Context) {
+	gameID := c.Param("id")
+	if !s.requireGameHost(c, gameID) {
+		return
+	}
+
+	play, err := s.Db.CloseAuction(c.Request.Context(), gameID, c.Param("questionId"))
+	if err != nil {
+		specialQuestionError(c, err, FAIL_CLOSE_AUCTION_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, play)
+}
+
+func (s *Server) GetAuctionBids(c *gin.Context) {
+	bids, err := s.Db.GetAuctionBids(c.Request.Context(), c.Param("id"), c.Param("questionId"))
+	if err != nil {
+		specialQuestionError(c, err, FAIL_GET_AUCTION_BIDS_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, bids)
+}
+
+func (s *Server) AddSpecialQuestionRoutes(group *gin.RouterGroup) {
+	group.POST("/games/:id/questions/:questionId/cat", s.AssignCatInBag)
+	group.GET("/games/:id/questions/:questionId/bids", s.GetAuctionBids)
+	group.POST("/games/:id/questions/:questionId/bids", s.PlaceAuctionBid)
+	group.POST("/games/:id/questions/:questionId/auction/close", s.CloseAuction)
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"errors"
+	"mindwarp/db"
+	"mindwarp/logger"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetPlayerStats returns the statistics of a player's finished games.
+func (s *Server) GetPlayerStats(c *gin.Context) {
+	userID := c.Param("userId")
+	if err := uuid.Validate(userID); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: "userId must be a user id"})
+		return
+	}
+
+	stats, err := s.Db.GetPlayerStats(c.Request.Context(), userID)
+	if errors.Is(err, db.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: USER_NOT_FOUND_ERROR, Message: err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Errorf("Failed to get player stats: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_GET_PLAYER_STATS_ERROR, Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+func (s *Server) AddStatsRoutes(group *gin.RouterGroup) {
+	group.GET("/stats/users/:userId", s.GetPlayerStats)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"mindwarp/db"
+	"mindwarp/logger"
+	"mindwarp/srs"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultStudyLimit = 20
+	maxStudyLimit     = 100
+)
+
+// ReviewStudyRequest grades a recall from 0 (blackout) to 5 (perfect); 3 and
+// up counts as remembered.
+type ReviewStudyRequest struct {
+	Quality *int `json:"quality" binding:"required,min=0,max=5"`
+}
+
+// studyError maps study schedule errors to client errors.
+func studyError(c *gin.Context, err error, failCode string) {
+	switch {
+	case errors.Is(err, db.ErrStudyCardNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: STUDY_CARD_NOT_FOUND_ERROR, Message: err.Error()})
+	case errors.Is(err, srs.ErrInvalidQuality):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+	default:
+		logger.Errorf("Study error: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: failCode, Message: err.Error()})
+	}
+}
+
+// endOfDay returns the start of the day after now in loc.
+func endOfDay(now time.Time, loc *time.Location) time.Time {
+	local := now.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, loc)
+}
+
+// GetDueStudyCards lists the caller's cards due by the end of today. The day
+// is taken in the IANA timezone given as tz, UTC by default.
+func (s *Server) GetDueStudyCards(c *gin.Context) {
+	loc, err := time.LoadLocation(c.DefaultQuery("tz", "UTC"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_TIMEZONE_ERROR, Message: fmt.Sprintf("unknown timezone %q", c.Query("tz"))})
+		return
+	}
+	offset, limit, ok := pageParams(c, defaultStudyLimit, maxStudyLimit)
+	if !ok {
+		return
+	}
+
+	queue, err := s.Db.GetDueStudyCards(c.Request.Context(), currentUserID(c), endOfDay(time.Now(), loc), offset, limit)
+	if err != nil {
+		studyError(c, err, FAIL_GET_STUDY_CARDS_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, queue)
+}
+
+// ReviewStudyCard records how well the caller recalled a card and returns it
+// with its next due date.
+func (s *Server) ReviewStudyCard(c *gin.Context) {
+	var reqBody ReviewStudyRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	card, err := s.Db.ReviewStudyCard(c.Request.Context(), c.Param("id"), currentUserID(c), *reqBody.Quality, time.Now())
+	if err != nil {
+		studyError(c, err, FAIL_REVIEW_STUDY_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, card)
+}
+
+func (s *Server) AddStudyRoutes(group *gin.RouterGroup) {
+	group.GET("/study/due", s.GetDueStudyCards)
+	group.POST("/study/cards/:id/review", s.ReviewStudyCard)
+}
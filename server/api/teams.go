@@ -0,0 +1,147 @@
+package api
+
+import (
+	"errors"
+	"mindwarp/db"
+	"mindwarp/logger"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateTeamRequest struct {
+	Name string `json:"name" binding:"required,max=64"`
+}
+
+type UpdateTeamRequest struct {
+	Name      string `json:"name" binding:"max=64"`
+	CaptainID string `json:"captainId"`
+}
+
+type TeamMemberRequest struct {
+	UserID string `json:"userId" binding:"required"`
+}
+
+// teamError maps team rule violations to client errors.
+func teamError(c *gin.Context, err error, failCode string) {
+	switch {
+	case errors.Is(err, db.ErrTeamNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: TEAM_NOT_FOUND_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrTeamNameTaken):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: TEAM_NAME_TAKEN_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrUserNotInGame), errors.Is(err, db.ErrCaptainNotInTeam):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: TEAM_RULE_ERROR, Message: err.Error()})
+	default:
+		logger.Errorf("Team error: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: failCode, Message: err.Error()})
+	}
+}
+
+func (s *Server) CreateTeam(c *gin.Context) {
+	gameID := c.Param("id")
+	var reqBody CreateTeamRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	if !s.requireGameHost(c, gameID) {
+		return
+	}
+
+	teamID, err := s.Db.CreateTeam(c.Request.Context(), gameID, reqBody.Name)
+	if err != nil {
+		teamError(c, err, FAIL_CREATE_TEAM_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": teamID})
+}
+
+func (s *Server) UpdateTeam(c *gin.Context) {
+	gameID := c.Param("id")
+	var reqBody UpdateTeamRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	if !s.requireGameHost(c, gameID) {
+		return
+	}
+
+	err := s.Db.UpdateTeam(c.Request.Context(), gameID, c.Param("teamId"), reqBody.Name, reqBody.CaptainID)
+	if err != nil {
+		teamError(c, err, FAIL_UPDATE_TEAM_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team updated"})
+}
+
+func (s *Server) DeleteTeam(c *gin.Context) {
+	gameID := c.Param("id")
+	if !s.requireGameHost(c, gameID) {
+		return
+	}
+
+	if err := s.Db.DeleteTeam(c.Request.Context(), gameID, c.Param("teamId")); err != nil {
+		teamError(c, err, FAIL_DELETE_TEAM_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team deleted"})
+}
+
+func (s *Server) AssignTeamMember(c *gin.Context) {
+	gameID := c.Param("id")
+	var reqBody TeamMemberRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	if !s.requireGameHost(c, gameID) {
+		return
+	}
+
+	if err := s.Db.AssignTeamMember(c.Request.Context(), gameID, c.Param("teamId"), reqBody.UserID); err != nil {
+		teamError(c, err, FAIL_ASSIGN_TEAM_MEMBER_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Player assigned to team"})
+}
+
+func (s *Server) RemoveTeamMember(c *gin.Context) {
+	gameID := c.Param("id")
+	if !s.requireGameHost(c, gameID) {
+		return
+	}
+
+	if err := s.Db.RemoveTeamMember(c.Request.Context(), gameID, c.Param("teamId"), c.Param("userId")); err != nil {
+		teamError(c, err, FAIL_REMOVE_TEAM_MEMBER_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Player removed from team"})
+}
+
+func (s *Server) GetTeams(c *gin.Context) {
+	teams, err := s.Db.GetTeamsByGameId(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		teamError(c, err, FAIL_GET_TEAMS_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, teams)
+}
+
+func (s *Server) AddTeamRoutes(group *gin.RouterGroup) {
+	group.GET("/games/:id/teams", s.GetTeams)
+	group.POST("/games/:id/teams", s.CreateTeam)
+	group.POST("/games/:id/teams/:teamId/update", s.UpdateTeam)
+	group.DELETE("/games/:id/teams/:teamId", s.DeleteTeam)
+	group.POST("/games/:id/teams/:teamId/members", s.AssignTeamMember)
+	group.DELETE("/games/:id/teams/:teamId/members/:userId", s.RemoveTeamMember)
+}
@@ -108,6 +108,11 @@ func (s *Server) CreateGameTemplate(c *gin.Context) {
 		return
 	}
 
+	if err := gameTemplate.Rules.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_GAME_RULES_ERROR, Message: err.Error()})
+		return
+	}
+
 	err = s.Db.CreateCompleteGameTemplate(c.Request.Context(), gameTemplate, rounds, themes, questions)
 	if err != nil {
 		logger.Errorf("Failed to create game template: %v", err)
@@ -143,6 +148,11 @@ func (s *Server) UpdateGameTemplate(c *gin.Context) {
 		return
 	}
 
+	if err := gameTemplate.Rules.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_GAME_RULES_ERROR, Message: err.Error()})
+		return
+	}
+
 	err = s.Db.UpdateGameTemplate(c.Request.Context(), gameTemplate, rounds, themes, questions)
 	if err != nil {
 		logger.Errorf("Failed to update game template: %v", err)
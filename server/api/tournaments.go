@@ -0,0 +1,279 @@
+package api
+
+import (
+	"errors"
+	"mindwarp/db"
+	"mindwarp/logger"
+	"mindwarp/scoring"
+	"mindwarp/tournament"
+	"mindwarp/types"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultTournamentLimit = 20
+	maxTournamentLimit     = 100
+)
+
+// CreateTournamentRequest sets up a tournament whose matches are games of
+// TemplateID. TieBreakers default to the usual ones, with a sudden-death
+// question last in elimination formats so every match has a winner.
+type CreateTournamentRequest struct {
+	Name        string                 `json:"name" binding:"required"`
+	TemplateID  string                 `json:"templateId" binding:"required,uuid"`
+	Format      string                 `json:"format" binding:"required,oneof=single_elimination double_elimination round_robin"`
+	RoundIDs    []string               `json:"roundIds" binding:"dive,uuid"`
+	TieBreakers []string               `json:"tieBreakers"`
+	Rules       *types.GameRulesClient `json:"rules"`
+}
+
+type TournamentParticipantsRequest struct {
+	UserIDs []string `json:"userIds" binding:"required,min=1,dive,uuid"`
+}
+
+// SeedTournamentRequest seeds UserIDs first, in order, and the remaining
+// participants after them; Shuffle seeds everyone at random instead.
+type SeedTournamentRequest struct {
+	UserIDs []string `json:"userIds" binding:"dive,uuid"`
+	Shuffle bool     `json:"shuffle"`
+}
+
+// MatchResultRequest settles a match by hand. An empty WinnerID records a
+// draw, which only a round robin allows.
+type MatchResultRequest struct {
+	WinnerID string `json:"winnerId" binding:"omitempty,uuid"`
+}
+
+// tournamentError maps tournament rule violations to client errors.
+func tournamentError(c *gin.Context, err error, failCode string) {
+	switch {
+	case errors.Is(err, db.ErrTournamentNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: TOURNAMENT_NOT_FOUND_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrTemplateNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: TEMPLATE_NOT_FOUND_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrMatchNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: MATCH_NOT_FOUND_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrParticipantNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: PARTICIPANT_NOT_FOUND_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrUnknownParticipant),
+		errors.Is(err, db.ErrInvalidSeeding),
+		errors.Is(err, db.ErrWinnerNotInMatch),
+		errors.Is(err, tournament.ErrTooFewPlayers),
+		errors.Is(err, tournament.ErrTooManyPlayers):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: TOURNAMENT_RULE_ERROR, Message: err.Error()})
+	case errors.Is(err, db.ErrRegistrationClosed),
+		errors.Is(err, db.ErrTournamentNotRunning),
+		errors.Is(err, db.ErrMatchNotReady):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: TOURNAMENT_RULE_ERROR, Message: err.Error()})
+	default:
+		logger.Errorf("Tournament error: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: failCode, Message: err.Error()})
+	}
+}
+
+// requireTournamentHost lets only the organizer of a tournament through.
+func (s *Server) requireTournamentHost(c *gin.Context, tournamentID string) bool {
+	creatorID, err := s.Db.GetTournamentCreator(c.Request.Context(), tournamentID)
+	if err != nil {
+		tournamentError(c, err, FAIL_GET_TOURNAMENT_ERROR)
+		return false
+	}
+
+	if creatorID != currentUserID(c) {
+		c.JSON(http.StatusForbidden, ErrorResponse{Code: NOT_TOURNAMENT_HOST_ERROR, Message: "Only the tournament organizer can do this"})
+		return false
+	}
+
+	return true
+}
+
+func (s *Server) CreateTournament(c *gin.Context) {
+	var reqBody CreateTournamentRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	format := tournament.Format(reqBody.Format)
+	tieBreakers := reqBody.TieBreakers
+	if len(tieBreakers) == 0 {
+		tieBreakers = fromTieBreakers(scoring.DefaultTieBreakers)
+		if format != tournament.RoundRobin {
+			tieBreakers = append(tieBreakers, string(scoring.TieBreakSuddenDeath))
+		}
+	}
+	if err := scoring.ValidateTieBreakers(toTieBreakers(tieBreakers)); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_TIE_BREAKERS_ERROR, Message: err.Error()})
+		return
+	}
+
+	templateRules, err := s.Db.GetGameTemplateRules(c.Request.Context(), reqBody.TemplateID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: TEMPLATE_NOT_FOUND_ERROR, Message: err.Error()})
+		return
+	}
+	rules := templateRules.Apply(reqBody.Rules)
+	if err := rules.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_GAME_RULES_ERROR, Message: err.Error()})
+		return
+	}
+
+	t := types.TournamentServer{
+		ID:          uuid.NewString(),
+		Name:        reqBody.Name,
+		CreatorID:   currentUserID(c),
+		TemplateID:  reqBody.TemplateID,
+		RoundIDs:    reqBody.RoundIDs,
+		TieBreakers: tieBreakers,
+		Rules:       rules,
+		Format:      string(format),
+	}
+	if err := s.Db.CreateTournament(c.Request.Context(), t); err != nil {
+		tournamentError(c, err, FAIL_CREATE_TOURNAMENT_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": t.ID})
+}
+
+func (s *Server) GetTournaments(c *gin.Context) {
+	offset, limit, ok := pageParams(c, defaultTournamentLimit, maxTournamentLimit)
+	if !ok {
+		return
+	}
+
+	tournaments, err := s.Db.GetTournaments(c.Request.Context(), currentUserID(c), offset, limit)
+	if err != nil {
+		tournamentError(c, err, FAIL_GET_TOURNAMENT_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, tournaments)
+}
+
+func (s *Server) GetTournament(c *gin.Context) {
+	t, err := s.Db.GetTournament(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		tournamentError(c, err, FAIL_GET_TOURNAMENT_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, t)
+}
+
+// AddTournamentParticipants registers players while registration is open.
+func (s *Server) AddTournamentParticipants(c *gin.Context) {
+	var reqBody TournamentParticipantsRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+	tournamentID := c.Param("id")
+	if !s.requireTournamentHost(c, tournamentID) {
+		return
+	}
+
+	if err := s.Db.AddTournamentParticipants(c.Request.Context(), tournamentID, reqBody.UserIDs); err != nil {
+		tournamentError(c, err, FAIL_UPDATE_TOURNAMENT_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Participants registered"})
+}
+
+// RemoveTournamentParticipant withdraws a player before the start. Players
+// may withdraw themselves; anyone else needs the organizer.
+func (s *Server) RemoveTournamentParticipant(c *gin.Context) {
+	tournamentID, userID := c.Param("id"), c.Param("userId")
+	if userID != currentUserID(c) && !s.requireTournamentHost(c, tournamentID) {
+		return
+	}
+
+	if err := s.Db.RemoveTournamentParticipant(c.Request.Context(), tournamentID, userID); err != nil {
+		tournamentError(c, err, FAIL_UPDATE_TOURNAMENT_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Participant removed"})
+}
+
+func (s *Server) SeedTournament(c *gin.Context) {
+	var reqBody SeedTournamentRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+	tournamentID := c.Param("id")
+	if !s.requireTournamentHost(c, tournamentID) {
+		return
+	}
+
+	if err := s.Db.SeedTournament(c.Request.Context(), tournamentID, reqBody.UserIDs, reqBody.Shuffle); err != nil {
+		tournamentError(c, err, FAIL_UPDATE_TOURNAMENT_ERROR)
+		return
+	}
+
+	s.GetTournament(c)
+}
+
+// StartTournament closes registration, generates the bracket and creates
+// the first games.
+func (s *Server) StartTournament(c *gin.Context) {
+	tournamentID := c.Param("id")
+	if !s.requireTournamentHost(c, tournamentID) {
+		return
+	}
+
+	if err := s.Db.StartTournament(c.Request.Context(), tournamentID); err != nil {
+		tournamentError(c, err, FAIL_START_TOURNAMENT_ERROR)
+		return
+	}
+
+	s.GetTournament(c)
+}
+
+// RecordMatchResult lets the organizer settle a match whose game will not be
+// finished, such as a no-show.
+func (s *Server) RecordMatchResult(c *gin.Context) {
+	var reqBody MatchResultRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+	tournamentID := c.Param("id")
+	if !s.requireTournamentHost(c, tournamentID) {
+		return
+	}
+
+	if err := s.Db.RecordMatchResult(c.Request.Context(), tournamentID, c.Param("matchId"), reqBody.WinnerID); err != nil {
+		tournamentError(c, err, FAIL_RECORD_MATCH_RESULT_ERROR)
+		return
+	}
+
+	s.GetTournament(c)
+}
+
+func (s *Server) GetTournamentStandings(c *gin.Context) {
+	standings, err := s.Db.GetTournamentStandings(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		tournamentError(c, err, FAIL_GET_TOURNAMENT_ERROR)
+		return
+	}
+
+	c.JSON(http.StatusOK, standings)
+}
+
+func (s *Server) AddTournamentRoutes(group *gin.RouterGroup) {
+	group.POST("/tournaments", s.CreateTournament)
+	group.GET("/tournaments", s.GetTournaments)
+	group.GET("/tournaments/:id", s.GetTournament)
+	group.POST("/tournaments/:id/participants", s.AddTournamentParticipants)
+	group.DELETE("/tournaments/:id/participants/:userId", s.RemoveTournamentParticipant)
+	group.PUT("/tournaments/:id/seeds", s.SeedTournament)
+	group.POST("/tournaments/:id/start", s.StartTournament)
+	group.POST("/tournaments/:id/matches/:matchId/result", s.RecordMatchResult)
+	group.GET("/tournaments/:id/standings", s.GetTournamentStandings)
+}
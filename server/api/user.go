@@ -60,22 +60,6 @@ func (s *Server) GetUserBySearch(c *gin.Context) {
 	c.JSON(http.StatusOK, users)
 }
 
-func (s *Server) AddUserToGame(c *gin.Context) {
-	var reqBody AddUserToGameRequest
-	if err := c.ShouldBindJSON(&reqBody); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
-		return
-	}
-
-	err := s.Db.AddUserToGame(c.Request.Context(), reqBody.GameID, reqBody.UserID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_ADD_USER_TO_GAME_ERROR, Message: err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "User added to game"})
-}
-
 func (s *Server) AddUserRoutes(group *gin.RouterGroup) {
 	group.GET("/me", s.GetCurrentUser)
 	group.GET("/users", s.GetAllUsers)
@@ -1,8 +1,16 @@
 package api
 
 import (
+	"fmt"
+	"mindwarp/media"
+	"mindwarp/scoring"
 	"mindwarp/types"
+	"net/http"
+	"strconv"
 	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 const (
@@ -23,7 +31,6 @@ const (
 	FAIL_GET_USER_BY_ID_ERROR       = "FAIL_GET_USER_BY_ID_ERROR"
 	FAIL_GET_USERS_ERROR            = "FAIL_GET_USERS_ERROR"
 	FAIL_GET_USERS_SEARCH_ERROR     = "FAIL_GET_USERS_SEARCH_ERROR"
-	FAIL_ADD_USER_TO_GAME_ERROR     = "FAIL_ADD_USER_TO_GAME_ERROR"
 
 	FAIL_ACCESS_TOKEN_PARSE_ERROR  = "FAIL_ACCESS_TOKEN_PARSE_ERROR"
 	FAIL_REFRESH_TOKEN_PARSE_ERROR = "FAIL_REFRESH_TOKEN_PARSE_ERROR"
@@ -59,6 +66,153 @@ const (
 	FAIL_FINISH_GAME_ERROR                  = "FAIL_FINISH_GAME_ERROR"
 	FAIL_UPDATE_GAME_ERROR                  = "FAIL_UPDATE_GAME_ERROR"
 	FAIL_GET_COUNTS_ERROR                   = "FAIL_GET_COUNTS_ERROR"
+	GAME_NOT_FOUND_ERROR                    = "GAME_NOT_FOUND"
+	NOT_GAME_HOST_ERROR                     = "NOT_GAME_HOST"
+	INVALID_SCORE_ADJUSTMENT_ERROR          = "INVALID_SCORE_ADJUSTMENT"
+	FAIL_ADD_SCORE_ADJUSTMENT_ERROR         = "FAIL_ADD_SCORE_ADJUSTMENT_ERROR"
+	FAIL_GET_SCORE_ADJUSTMENTS_ERROR        = "FAIL_GET_SCORE_ADJUSTMENTS_ERROR"
+	INVALID_TIE_BREAKERS_ERROR              = "INVALID_TIE_BREAKERS"
+	INVALID_GAME_RULES_ERROR                = "INVALID_GAME_RULES"
+	SUDDEN_DEATH_REQUIRED_ERROR             = "SUDDEN_DEATH_REQUIRED"
+	GAME_ALREADY_FINISHED_ERROR             = "GAME_ALREADY_FINISHED"
+	GAME_NOT_IN_PROGRESS_ERROR              = "GAME_NOT_IN_PROGRESS"
+	INVALID_STATUS_TRANSITION_ERROR         = "INVALID_STATUS_TRANSITION"
+	NOT_ENOUGH_PLAYERS_ERROR                = "NOT_ENOUGH_PLAYERS"
+	FAIL_SET_GAME_STATUS_ERROR              = "FAIL_SET_GAME_STATUS_ERROR"
+	TEMPLATE_NOT_FOUND_ERROR                = "TEMPLATE_NOT_FOUND"
+	VERSION_REQUIRED_ERROR                  = "VERSION_REQUIRED"
+	VERSION_CONFLICT_ERROR                  = "VERSION_CONFLICT"
+	FAIL_MERGE_GAME_ERROR                   = "FAIL_MERGE_GAME_ERROR"
+	FAIL_GET_GAME_RESULTS_ERROR             = "FAIL_GET_GAME_RESULTS_ERROR"
+
+	FINAL_ROUND_NOT_FOUND_ERROR    = "FINAL_ROUND_NOT_FOUND"
+	FINAL_ROUND_RULE_ERROR         = "FINAL_ROUND_RULE"
+	FAIL_OPEN_FINAL_ROUND_ERROR    = "FAIL_OPEN_FINAL_ROUND_ERROR"
+	FAIL_PLACE_FINAL_WAGER_ERROR   = "FAIL_PLACE_FINAL_WAGER_ERROR"
+	FAIL_SUBMIT_FINAL_ANSWER_ERROR = "FAIL_SUBMIT_FINAL_ANSWER_ERROR"
+	FAIL_REVEAL_FINAL_WAGER_ERROR  = "FAIL_REVEAL_FINAL_WAGER_ERROR"
+	FAIL_GET_FINAL_ROUND_ERROR     = "FAIL_GET_FINAL_ROUND_ERROR"
+
+	QUESTION_NOT_FOUND_ERROR     = "QUESTION_NOT_FOUND"
+	SPECIAL_QUESTION_RULE_ERROR  = "SPECIAL_QUESTION_RULE"
+	FAIL_ASSIGN_CAT_IN_BAG_ERROR = "FAIL_ASSIGN_CAT_IN_BAG_ERROR"
+	FAIL_PLACE_AUCTION_BID_ERROR = "FAIL_PLACE_AUCTION_BID_ERROR"
+	FAIL_CLOSE_AUCTION_ERROR     = "FAIL_CLOSE_AUCTION_ERROR"
+	FAIL_GET_AUCTION_BIDS_ERROR  = "FAIL_GET_AUCTION_BIDS_ERROR"
+
+	MEDIA_NOT_FOUND_ERROR        = "MEDIA_NOT_FOUND"
+	MEDIA_TOO_LARGE_ERROR        = "MEDIA_TOO_LARGE"
+	UNSUPPORTED_MEDIA_TYPE_ERROR = "UNSUPPORTED_MEDIA_TYPE"
+	FAIL_UPLOAD_MEDIA_ERROR      = "FAIL_UPLOAD_MEDIA_ERROR"
+	FAIL_GET_MEDIA_ERROR         = "FAIL_GET_MEDIA_ERROR"
+
+	MULTIPLE_CHOICE_RULE_ERROR      = "MULTIPLE_CHOICE_RULE"
+	FAIL_SUBMIT_CHOICE_ANSWER_ERROR = "FAIL_SUBMIT_CHOICE_ANSWER_ERROR"
+
+	TEAM_NOT_FOUND_ERROR          = "TEAM_NOT_FOUND"
+	TEAM_NAME_TAKEN_ERROR         = "TEAM_NAME_TAKEN"
+	TEAM_RULE_ERROR               = "TEAM_RULE"
+	FAIL_CREATE_TEAM_ERROR        = "FAIL_CREATE_TEAM_ERROR"
+	FAIL_UPDATE_TEAM_ERROR        = "FAIL_UPDATE_TEAM_ERROR"
+	FAIL_DELETE_TEAM_ERROR        = "FAIL_DELETE_TEAM_ERROR"
+	FAIL_ASSIGN_TEAM_MEMBER_ERROR = "FAIL_ASSIGN_TEAM_MEMBER_ERROR"
+	FAIL_REMOVE_TEAM_MEMBER_ERROR = "FAIL_REMOVE_TEAM_MEMBER_ERROR"
+	FAIL_GET_TEAMS_ERROR          = "FAIL_GET_TEAMS_ERROR"
+
+	ATTEMPT_RULE_ERROR          = "ATTEMPT_RULE"
+	FAIL_RECORD_ATTEMPT_ERROR   = "FAIL_RECORD_ATTEMPT_ERROR"
+	FAIL_GET_ATTEMPTS_ERROR     = "FAIL_GET_ATTEMPTS_ERROR"
+	FAIL_RESOLVE_QUESTION_ERROR = "FAIL_RESOLVE_QUESTION_ERROR"
+
+	INVITE_NOT_FOUND_ERROR        = "INVITE_NOT_FOUND"
+	INVITE_EXPIRED_ERROR          = "INVITE_EXPIRED"
+	INVITE_REVOKED_ERROR          = "INVITE_REVOKED"
+	INVITE_NOT_PENDING_ERROR      = "INVITE_NOT_PENDING"
+	INVITE_EXISTS_ERROR           = "INVITE_EXISTS"
+	ALREADY_IN_GAME_ERROR         = "ALREADY_IN_GAME"
+	GAME_CLOSED_ERROR             = "GAME_CLOSED"
+	FAIL_SEND_GAME_INVITE_ERROR   = "FAIL_SEND_GAME_INVITE_ERROR"
+	FAIL_REVOKE_GAME_INVITE_ERROR = "FAIL_REVOKE_GAME_INVITE_ERROR"
+	FAIL_GET_INVITE_HISTORY_ERROR = "FAIL_GET_INVITE_HISTORY_ERROR"
+	FAIL_SEND_INVITE_EMAIL_ERROR  = "FAIL_SEND_INVITE_EMAIL_ERROR"
+	INVALID_INVITE_TOKEN_ERROR    = "INVALID_INVITE_TOKEN"
+	FAIL_CLAIM_INVITE_ERROR       = "FAIL_CLAIM_INVITE_ERROR"
+
+	FAIL_GET_NOTIFICATIONS_ERROR       = "FAIL_GET_NOTIFICATIONS_ERROR"
+	FAIL_MARK_NOTIFICATIONS_READ_ERROR = "FAIL_MARK_NOTIFICATIONS_READ_ERROR"
+
+	ANSWER_NOT_FOUND_ERROR          = "ANSWER_NOT_FOUND"
+	GAME_MUTATION_RULE_ERROR        = "GAME_MUTATION_RULE"
+	FAIL_SET_CURRENT_QUESTION_ERROR = "FAIL_SET_CURRENT_QUESTION_ERROR"
+	FAIL_RECORD_ANSWER_ERROR        = "FAIL_RECORD_ANSWER_ERROR"
+	FAIL_CLEAR_ANSWER_ERROR         = "FAIL_CLEAR_ANSWER_ERROR"
+	FAIL_SET_CURRENT_PICKER_ERROR   = "FAIL_SET_CURRENT_PICKER_ERROR"
+	FAIL_RENAME_GAME_ERROR          = "FAIL_RENAME_GAME_ERROR"
+	FAIL_GET_GAME_EVENTS_ERROR      = "FAIL_GET_GAME_EVENTS_ERROR"
+
+	INVALID_SCHEDULE_ERROR   = "INVALID_SCHEDULE"
+	FAIL_SET_SCHEDULE_ERROR  = "FAIL_SET_SCHEDULE_ERROR"
+	FAIL_GET_CALENDAR_ERROR  = "FAIL_GET_CALENDAR_ERROR"
+	SCHEDULED_GAME_NOT_FOUND = "SCHEDULED_GAME_NOT_FOUND"
+
+	INVALID_ASYNC_SETTINGS_ERROR    = "INVALID_ASYNC_SETTINGS"
+	NOT_ASYNC_GAME_ERROR            = "NOT_ASYNC_GAME"
+	ASYNC_RULE_ERROR                = "ASYNC_RULE"
+	DEADLINE_PASSED_ERROR           = "DEADLINE_PASSED"
+	GAME_NOT_FINISHED_ERROR         = "GAME_NOT_FINISHED"
+	FAIL_OPEN_QUESTION_ERROR        = "FAIL_OPEN_QUESTION_ERROR"
+	FAIL_SUBMIT_ASYNC_ANSWER_ERROR  = "FAIL_SUBMIT_ASYNC_ANSWER_ERROR"
+	FAIL_GET_ASYNC_PROGRESS_ERROR   = "FAIL_GET_ASYNC_PROGRESS_ERROR"
+	FAIL_GET_ASYNC_COMPARISON_ERROR = "FAIL_GET_ASYNC_COMPARISON_ERROR"
+
+	PRACTICE_NOT_FOUND_ERROR       = "PRACTICE_NOT_FOUND"
+	PRACTICE_RULE_ERROR            = "PRACTICE_RULE"
+	REVIEW_ITEM_NOT_FOUND_ERROR    = "REVIEW_ITEM_NOT_FOUND"
+	FAIL_CREATE_PRACTICE_ERROR     = "FAIL_CREATE_PRACTICE_ERROR"
+	FAIL_GET_PRACTICE_ERROR        = "FAIL_GET_PRACTICE_ERROR"
+	FAIL_REVEAL_PRACTICE_ERROR     = "FAIL_REVEAL_PRACTICE_ERROR"
+	FAIL_GRADE_PRACTICE_ERROR      = "FAIL_GRADE_PRACTICE_ERROR"
+	FAIL_FINISH_PRACTICE_ERROR     = "FAIL_FINISH_PRACTICE_ERROR"
+	FAIL_GET_REVIEW_LIST_ERROR     = "FAIL_GET_REVIEW_LIST_ERROR"
+	FAIL_DISMISS_REVIEW_ITEM_ERROR = "FAIL_DISMISS_REVIEW_ITEM_ERROR"
+
+	STUDY_CARD_NOT_FOUND_ERROR = "STUDY_CARD_NOT_FOUND"
+	INVALID_TIMEZONE_ERROR     = "INVALID_TIMEZONE"
+	FAIL_GET_STUDY_CARDS_ERROR = "FAIL_GET_STUDY_CARDS_ERROR"
+	FAIL_REVIEW_STUDY_ERROR    = "FAIL_REVIEW_STUDY_ERROR"
+
+	DAILY_CHALLENGE_NOT_FOUND_ERROR  = "DAILY_CHALLENGE_NOT_FOUND"
+	DAILY_ATTEMPT_NOT_FOUND_ERROR    = "DAILY_ATTEMPT_NOT_FOUND"
+	DAILY_RULE_ERROR                 = "DAILY_RULE"
+	FAIL_GET_DAILY_CHALLENGE_ERROR   = "FAIL_GET_DAILY_CHALLENGE_ERROR"
+	FAIL_START_DAILY_ATTEMPT_ERROR   = "FAIL_START_DAILY_ATTEMPT_ERROR"
+	FAIL_SUBMIT_DAILY_ANSWER_ERROR   = "FAIL_SUBMIT_DAILY_ANSWER_ERROR"
+	FAIL_FINISH_DAILY_ATTEMPT_ERROR  = "FAIL_FINISH_DAILY_ATTEMPT_ERROR"
+	FAIL_GET_DAILY_LEADERBOARD_ERROR = "FAIL_GET_DAILY_LEADERBOARD_ERROR"
+
+	TOURNAMENT_NOT_FOUND_ERROR     = "TOURNAMENT_NOT_FOUND"
+	NOT_TOURNAMENT_HOST_ERROR      = "NOT_TOURNAMENT_HOST"
+	PARTICIPANT_NOT_FOUND_ERROR    = "PARTICIPANT_NOT_FOUND"
+	MATCH_NOT_FOUND_ERROR          = "MATCH_NOT_FOUND"
+	TOURNAMENT_RULE_ERROR          = "TOURNAMENT_RULE"
+	FAIL_CREATE_TOURNAMENT_ERROR   = "FAIL_CREATE_TOURNAMENT_ERROR"
+	FAIL_GET_TOURNAMENT_ERROR      = "FAIL_GET_TOURNAMENT_ERROR"
+	FAIL_UPDATE_TOURNAMENT_ERROR   = "FAIL_UPDATE_TOURNAMENT_ERROR"
+	FAIL_START_TOURNAMENT_ERROR    = "FAIL_START_TOURNAMENT_ERROR"
+	FAIL_RECORD_MATCH_RESULT_ERROR = "FAIL_RECORD_MATCH_RESULT_ERROR"
+
+	NOT_ADMIN_ERROR                   = "NOT_ADMIN"
+	SEASON_NOT_FOUND_ERROR            = "SEASON_NOT_FOUND"
+	FAIL_GET_SEASONS_ERROR            = "FAIL_GET_SEASONS_ERROR"
+	FAIL_CREATE_SEASON_ERROR          = "FAIL_CREATE_SEASON_ERROR"
+	FAIL_GET_RATING_LEADERBOARD_ERROR = "FAIL_GET_RATING_LEADERBOARD_ERROR"
+	FAIL_GET_PLAYER_RATING_ERROR      = "FAIL_GET_PLAYER_RATING_ERROR"
+
+	USER_NOT_FOUND_ERROR        = "USER_NOT_FOUND"
+	FAIL_GET_PLAYER_STATS_ERROR = "FAIL_GET_PLAYER_STATS_ERROR"
+
+	INVALID_LEADERBOARD_ERROR  = "INVALID_LEADERBOARD"
+	FAIL_GET_LEADERBOARD_ERROR = "FAIL_GET_LEADERBOARD_ERROR"
 
 	VALIDATION_PASSWORD_TOO_SHORT = "VALIDATION_PASSWORD_TOO_SHORT"
 	VALIDATION_USERNAME_TOO_LONG  = "VALIDATION_USERNAME_TOO_LONG"
@@ -97,6 +251,10 @@ func ParseSqlError(err error) ErrorResponse {
 }
 
 func MapGameTemplateClientToDb(body types.GameTemplateClient) (types.GameTemplateServer, []types.TemplateRoundServer, map[string][]types.TemplateThemeServer, map[string][]types.TemplateQuestionServer, error) {
+	if err := validateFinalRounds(body.Rounds); err != nil {
+		return types.GameTemplateServer{}, nil, nil, nil, err
+	}
+
 	rounds := make([]types.TemplateRoundServer, len(body.Rounds))
 	themes := make(map[string][]types.TemplateThemeServer)
 	questions := make(map[string][]types.TemplateQuestionServer)
@@ -121,6 +279,8 @@ func MapGameTemplateClientToDb(body types.GameTemplateClient) (types.GameTemplat
 				IsSelected: round.Time.IsSelected,
 			},
 			RankSettings: rankSettings,
+			Rules:        types.RoundRules{NegativePoints: round.Rules.NegativePoints},
+			IsFinal:      round.IsFinal,
 		}
 
 		for j, theme := range round.Themes {
@@ -132,13 +292,32 @@ func MapGameTemplateClientToDb(body types.GameTemplateClient) (types.GameTemplat
 			})
 
 			for j, question := range theme.Questions {
+				questionType, err := types.ParseQuestionType(question.Type)
+				if err != nil {
+					return types.GameTemplateServer{}, nil, nil, nil, err
+				}
+
+				attachments, err := mapMediaAttachments(question.Media)
+				if err != nil {
+					return types.GameTemplateServer{}, nil, nil, nil, err
+				}
+
+				options, err := mapQuestionOptions(questionType, question.Options, true)
+				if err != nil {
+					return types.GameTemplateServer{}, nil, nil, nil, err
+				}
+
 				questions[theme.Id] = append(questions[theme.Id], types.TemplateQuestionServer{
-					ID:       question.Id,
-					ThemeID:  theme.Id,
-					Text:     question.Text,
-					Answer:   question.Answer,
-					Points:   question.Points,
-					Position: uint16(j),
+					ID:             question.Id,
+					ThemeID:        theme.Id,
+					Text:           question.Text,
+					Answer:         question.Answer,
+					Points:         question.Points,
+					Type:           questionType,
+					Media:          attachments,
+					Options:        options,
+					ShuffleOptions: question.ShuffleOptions,
+					Position:       uint16(j),
 				})
 			}
 		}
@@ -149,84 +328,15 @@ func MapGameTemplateClientToDb(body types.GameTemplateClient) (types.GameTemplat
 		Name:        body.Name,
 		Description: body.Description,
 		IsPublic:    body.IsPublic,
+		Rules:       types.DefaultGameRules().Apply(body.Rules),
 		CreatorID:   body.CreatorID,
 	}, rounds, themes, questions, nil
 }
 
-func MapGameClientToCreate(body types.GameClient) (types.GameServer, []types.RoundServer, map[string][]types.ThemeServer, map[string][]types.QuestionServer, []types.UserServer, []types.AnswerServer, error) {
-	rounds := make([]types.RoundServer, len(body.Rounds))
-	themes := make(map[string][]types.ThemeServer)
-	questions := make(map[string][]types.QuestionServer)
-	users := make([]types.UserServer, len(body.Users))
-	answers := make([]types.AnswerServer, 0)
-
-	for i, user := range body.Users {
-		users[i] = types.UserServer{
-			ID: user.ID,
-		}
-	}
-
-	for i, round := range body.Rounds {
-		rankSettings := make([]types.RankSettings, len(round.Ranks))
-		for j, rank := range round.Ranks {
-			rankSettings[j] = types.RankSettings{
-				ID:         rank.Id,
-				Label:      rank.Label,
-				IsSelected: rank.IsSelected,
-			}
-		}
-
-		rounds[i] = types.RoundServer{
-			ID:     round.Id,
-			Name:   round.Name,
-			GameID: body.ID,
-			TimeSettings: types.TimeSettings{
-				ID:         round.Time.Id,
-				Label:      round.Time.Label,
-				IsSelected: round.Time.IsSelected,
-			},
-			RankSettings: rankSettings,
-			Position:     uint16(i),
-		}
-
-		for j, theme := range round.Themes {
-			themes[round.Id] = append(themes[round.Id], types.ThemeServer{
-				ID:       theme.Id,
-				RoundID:  round.Id,
-				Name:     theme.Name,
-				Position: uint16(j),
-			})
-
-			for _, question := range theme.Questions {
-				questions[theme.Id] = append(questions[theme.Id], types.QuestionServer{
-					ID:      question.Id,
-					ThemeID: theme.Id,
-					Text:    question.Text,
-					Answer:  question.Answer,
-					Points:  question.Points,
-				})
-
-				for userID, answeredBy := range question.AnsweredBy {
-					answers = append(answers, types.AnswerServer{
-						QuestionID:   question.Id,
-						UserID:       userID,
-						IsCorrect:    answeredBy.IsCorrect,
-						TimeAnswered: answeredBy.TimeAnswered,
-					})
-				}
-			}
-		}
-	}
-
-	return types.GameServer{
-		ID:         body.ID,
-		Name:       body.Name,
-		CreatorID:  body.CreatorID,
-		TemplateID: body.TemplateID,
-	}, rounds, themes, questions, users, answers, nil
-}
-
-func MapGameClientToUpdate(body types.GameClient) (types.GameServer, []types.GameUserServer, []types.AnswerServer, error) {
+// MapGameClientToUpdate extracts the game pointers and answers from a client
+// update. The users' round scores are deliberately ignored: the server derives
+// them from answers.
+func MapGameClientToUpdate(body types.GameClient) (types.GameServer, []types.AnswerServer, error) {
 	answers := make([]types.AnswerServer, 0)
 
 	game := types.GameServer{
@@ -240,29 +350,139 @@ func MapGameClientToUpdate(body types.GameClient) (types.GameServer, []types.Gam
 		CurrentUserID:     body.CurrentUser,
 	}
 
-	users := make([]types.GameUserServer, len(body.Users))
-	for i, user := range body.Users {
-		users[i] = types.GameUserServer{
-			GameID:     body.ID,
-			UserID:     user.ID,
-			RoundScore: user.RoundScore,
-		}
-	}
-
 	for _, round := range body.Rounds {
 		for _, theme := range round.Themes {
 			for _, question := range theme.Questions {
 				for userID, answeredBy := range question.AnsweredBy {
 					answers = append(answers, types.AnswerServer{
-						QuestionID:   question.Id,
-						UserID:       userID,
-						IsCorrect:    answeredBy.IsCorrect,
-						TimeAnswered: answeredBy.TimeAnswered,
+						QuestionID:    question.Id,
+						UserID:        userID,
+						IsCorrect:     answeredBy.IsCorrect,
+						TimeAnswered:  answeredBy.TimeAnswered,
+						ContributorID: answeredBy.ContributorID,
 					})
 				}
 			}
 		}
 	}
 
-	return game, users, answers, nil
+	return game, answers, nil
+}
+
+func toTieBreakers(names []string) []scoring.TieBreaker {
+	tieBreakers := make([]scoring.TieBreaker, len(names))
+	for i, name := range names {
+		tieBreakers[i] = scoring.TieBreaker(name)
+	}
+	return tieBreakers
+}
+
+func fromTieBreakers(tieBreakers []scoring.TieBreaker) []string {
+	names := make([]string, len(tieBreakers))
+	for i, tieBreaker := range tieBreakers {
+		names[i] = string(tieBreaker)
+	}
+	return names
+}
+
+// validateFinalRounds allows at most one final round and requires it to be
+// played last.
+func validateFinalRounds(rounds []types.RoundClient) error {
+	for i, round := range rounds {
+		if round.IsFinal && i != len(rounds)-1 {
+			return fmt.Errorf("final round %q must be the last round", round.Name)
+		}
+	}
+	return nil
+}
+
+// mapMediaAttachments validates attachment references. Files must have been
+// uploaded beforehand; positions are numbered separately for each role.
+func mapMediaAttachments(attachments []types.MediaAttachmentClient) ([]types.MediaAttachmentServer, error) {
+	result := make([]types.MediaAttachmentServer, len(attachments))
+	positions := make(map[types.MediaRole]uint16)
+	for i, attachment := range attachments {
+		if !media.ValidID(attachment.MediaID) {
+			return nil, fmt.Errorf("invalid media id %q", attachment.MediaID)
+		}
+
+		role, err := types.ParseMediaRole(attachment.Role)
+		if err != nil {
+			return nil, err
+		}
+
+		result[i] = types.MediaAttachmentServer{
+			MediaID:  attachment.MediaID,
+			Role:     role,
+			Position: positions[role],
+		}
+		positions[role]++
+	}
+	return result, nil
+}
+
+const (
+	minQuestionOptions = 2
+	maxQuestionOptions = 6
+)
+
+// mapQuestionOptions validates the options of a multiple-choice question;
+// other question types must not have any. keepIDs reuses valid client ids,
+// otherwise every option gets a fresh one.
+func mapQuestionOptions(questionType types.QuestionType, options []types.QuestionOptionClient, keepIDs bool) ([]types.QuestionOptionServer, error) {
+	if questionType != types.QuestionMultipleChoice {
+		if len(options) > 0 {
+			return nil, fmt.Errorf("only multiple-choice questions can have options")
+		}
+		return nil, nil
+	}
+
+	if len(options) < minQuestionOptions || len(options) > maxQuestionOptions {
+		return nil, fmt.Errorf("multiple-choice questions need %d to %d options, got %d", minQuestionOptions, maxQuestionOptions, len(options))
+	}
+
+	result := make([]types.QuestionOptionServer, len(options))
+	hasCorrect := false
+	for i, option := range options {
+		if strings.TrimSpace(option.Text) == "" {
+			return nil, fmt.Errorf("option %d has no text", i+1)
+		}
+
+		id := option.ID
+		if _, err := uuid.Parse(id); !keepIDs || err != nil {
+			id = uuid.NewString()
+		}
+
+		isCorrect := option.IsCorrect != nil && *option.IsCorrect
+		hasCorrect = hasCorrect || isCorrect
+
+		result[i] = types.QuestionOptionServer{
+			ID:        id,
+			Text:      option.Text,
+			IsCorrect: isCorrect,
+			Position:  uint16(i),
+		}
+	}
+
+	if !hasCorrect {
+		return nil, fmt.Errorf("multiple-choice questions need at least one correct option")
+	}
+
+	return result, nil
+}
+
+// pageParams reads the offset and limit query parameters, answering the
+// request with an error and returning false when they are out of range.
+func pageParams(c *gin.Context, defaultLimit int, maxLimit int) (int, int, bool) {
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: "offset must be a non-negative number"})
+		return 0, 0, false
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+	if err != nil || limit < 1 || limit > maxLimit {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: fmt.Sprintf("limit must be between 1 and %d", maxLimit)})
+		return 0, 0, false
+	}
+	return offset, limit, true
 }
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"mindwarp/db"
+	"mindwarp/logger"
+	"mindwarp/types"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mergeRetries bounds how often MergeGame re-reads the game when another
+// write lands between its read and its write.
+const mergeRetries = 3
+
+// AnswerConflict is an answer the client sent that differs from the one
+// already stored for the same player and question.
+type AnswerConflict struct {
+	QuestionID string                 `json:"questionId"`
+	UserID     string                 `json:"userId"`
+	Sent       types.AnsweredByClient `json:"sent"`
+	Current    types.AnsweredByClient `json:"current"`
+}
+
+// expectedVersion returns the game version the client last read, taken from
+// the If-Match header or, failing that, the version field of the body.
+func expectedVersion(c *gin.Context, bodyVersion int) (int, bool) {
+	if header := c.GetHeader("If-Match"); header != "" {
+		header = strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+		version, err := strconv.Atoi(header)
+		return version, err == nil && version > 0
+	}
+	return bodyVersion, bodyVersion > 0
+}
+
+func setVersionHeader(c *gin.Context, version int) {
+	c.Header("ETag", fmt.Sprintf(`"%d"`, version))
+}
+
+// getCurrentGame loads a game as its players see it, for conflict responses
+// and merges.
+func (s *Server) getCurrentGame(c *gin.Context, gameID string) (*types.GameClient, error) {
+	games, err := s.Db.GetGameByFilter(c.Request.Context(), "id", gameID, "", "", "")
+	if err != nil {
+		return nil, err
+	}
+	if len(games) == 0 {
+		return nil, fmt.Errorf("game %s not found", gameID)
+	}
+	hideUnjudgedOptions(games[0], currentUserID(c))
+	hideAsyncProgress(games[0], currentUserID(c))
+	return games[0], nil
+}
+
+// versionConflict answers a stale write with the game as it is now, so the
+// client can rebase its changes without another round trip.
+func (s *Server) versionConflict(c *gin.Context, gameID string, err error) {
+	game, getErr := s.getCurrentGame(c, gameID)
+	if getErr != nil {
+		logger.Errorf("Failed to get game after version conflict: %v", getErr)
+		c.JSON(http.StatusConflict, ErrorResponse{Code: VERSION_CONFLICT_ERROR, Message: err.Error()})
+		return
+	}
+	setVersionHeader(c, game.Version)
+	c.JSON(http.StatusConflict, ErrorResponse{Code: VERSION_CONFLICT_ERROR, Message: err.Error(), Details: game})
+}
+
+// rebaseAnswers splits the answers a client sent against the current game.
+// Answers the server does not have yet, or already has as sent, are kept;
+// answers that contradict a stored one are returned as conflicts.
+func rebaseAnswers(current *types.GameClient, answers []types.AnswerServer) ([]types.AnswerServer, []AnswerConflict) {
+	stored := make(map[string]map[string]types.AnsweredByClient)
+	for _, round := range current.Rounds {
+		for _, theme := range round.Themes {
+			for _, question := range theme.Questions {
+				stored[question.Id] = question.AnsweredBy
+			}
+		}
+	}
+
+	apply := make([]types.AnswerServer, 0, len(answers))
+	conflicts := make([]AnswerConflict, 0)
+	for _, answer := range answers {
+		existing, ok := stored[answer.QuestionID][answer.UserID]
+		if ok && (existing.IsCorrect != answer.IsCorrect || existing.TimeAnswered != answer.TimeAnswered || existing.ContributorID != answer.ContributorID) {
+			conflicts = append(conflicts, AnswerConflict{
+				QuestionID: answer.QuestionID,
+				UserID:     answer.UserID,
+				Sent: types.AnsweredByClient{
+					IsCorrect:     answer.IsCorrect,
+					TimeAnswered:  answer.TimeAnswered,
+					ContributorID: answer.ContributorID,
+				},
+				Current: existing,
+			})
+			continue
+		}
+		apply = append(apply, answer)
+	}
+	return apply, conflicts
+}
+
+// MergeGame re-applies a stale update on top of the current game. Answers
+// that do not contradict stored ones are written; the game's pointers (round,
+// question, picker) are left as the server has them. Conflicting answers are
+// reported back instead of overwriting what another client wrote.
+func (s *Server) MergeGame(c *gin.Context) {
+	var gameBody types.GameClient
+	if err := c.ShouldBindJSON(&gameBody); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: INVALID_REQUEST_BODY, Message: err.Error()})
+		return
+	}
+
+	gameID := c.Param("id")
+	if !s.requireGameHost(c, gameID) {
+		return
+	}
+
+	_, answers, err := MapGameClientToUpdate(gameBody)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_MAP_GAME_CLIENT_TO_DB_ERROR, Message: err.Error()})
+		return
+	}
+
+	for range mergeRetries {
+		current, err := s.getCurrentGame(c, gameID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, ErrorResponse{Code: GAME_NOT_FOUND_ERROR, Message: err.Error()})
+			return
+		}
+
+		apply, conflicts := rebaseAnswers(current, answers)
+		version, err := s.Db.UpdateGameAndGameUsers(c.Request.Context(), types.GameServer{
+			ID:                current.ID,
+			Name:              current.Name,
+			CurrentRoundID:    current.CurrentRound,
+			CurrentQuestionID: current.CurrentQuestion,
+			CurrentUserID:     current.CurrentUser,
+			Version:           current.Version,
+		}, apply)
+		if errors.Is(err, db.ErrVersionConflict) {
+			continue
+		}
+		if errors.Is(err, db.ErrNotTeamCaptain) || errors.Is(err, db.ErrInvalidContributor) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: TEAM_RULE_ERROR, Message: err.Error()})
+			return
+		}
+		if errors.Is(err, db.ErrQuestionNotInGame) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Code: QUESTION_NOT_FOUND_ERROR, Message: err.Error()})
+			return
+		}
+		if errors.Is(err, db.ErrUserNotInGame) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: GAME_MUTATION_RULE_ERROR, Message: err.Error()})
+			return
+		}
+		if errors.Is(err, db.ErrGameNotInProgress) {
+			c.JSON(http.StatusConflict, ErrorResponse{Code: GAME_NOT_IN_PROGRESS_ERROR, Message: err.Error()})
+			return
+		}
+		if err != nil {
+			logger.Errorf("Failed to merge game update: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Code: FAIL_MERGE_GAME_ERROR, Message: err.Error()})
+			return
+		}
+
+		setVersionHeader(c, version)
+		c.JSON(http.StatusOK, gin.H{"version": version, "applied": len(apply), "conflicts": conflicts})
+		return
+	}
+
+	s.versionConflict(c, gameID, db.ErrVersionConflict)
+}
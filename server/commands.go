@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"mindwarp/db"
+	"mindwarp/logger"
+	"os"
+	"time"
+)
+
+// runCommand executes a maintenance subcommand and reports whether args named one.
+func runCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	switch args[0] {
+	case "recompute-scores":
+		recomputeScores(args[1:])
+	case "abandon-stale-games":
+		abandonStaleGames(args[1:])
+	default:
+		return false
+	}
+
+	return true
+}
+
+// recomputeScores rebuilds game_users.round_scores from answers and score
+// adjustments, either for one game or for every game whose scores drifted.
+func recomputeScores(args []string) {
+	flags := flag.NewFlagSet("recompute-scores", flag.ExitOnError)
+	gameID := flags.String("game", "", "recompute a single game instead of scanning all games")
+	dryRun := flags.Bool("dry-run", false, "only report drifted games, do not write")
+	flags.Parse(args)
+
+	database := db.CreateDB()
+	defer database.Close()
+
+	ctx := context.Background()
+	if *gameID != "" {
+		if err := database.RecomputeGameScores(ctx, *gameID); err != nil {
+			logger.Errorf("Failed to recompute scores for game %s: %v", *gameID, err)
+			os.Exit(1)
+		}
+		logger.Infof("Recomputed scores for game %s", *gameID)
+		return
+	}
+
+	drifted, err := database.RepairGameScores(ctx, *dryRun)
+	if err != nil {
+		logger.Errorf("Failed to repair scores: %v", err)
+		os.Exit(1)
+	}
+
+	if *dryRun {
+		logger.Infof("%d game(s) have drifted scores: %v", len(drifted), drifted)
+		return
+	}
+	logger.Infof("Repaired scores for %d game(s): %v", len(drifted), drifted)
+}
+
+// abandonStaleGames runs the abandoned-game sweep once, for use from cron
+// when the API server is not the one sweeping.
+func abandonStaleGames(args []string) {
+	flags := flag.NewFlagSet("abandon-stale-games", flag.ExitOnError)
+	days := flags.Int("days", 14, "abandon unfinished games idle for this many days")
+	flags.Parse(args)
+
+	database := db.CreateDB()
+	defer database.Close()
+
+	gameIDs, err := database.AbandonStaleGames(context.Background(), time.Duration(*days)*24*time.Hour)
+	if err != nil {
+		logger.Errorf("Failed to abandon stale games: %v", err)
+		os.Exit(1)
+	}
+	logger.Infof("Abandoned %d game(s): %v", len(gameIDs), gameIDs)
+}
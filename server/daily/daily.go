@@ -0,0 +1,105 @@
+// Package daily picks the questions of the shared daily challenge.
+package daily
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// Size is how many questions a daily challenge has.
+	Size = 10
+	// SecondsPerQuestion sets the time limit of an attempt: a player gets
+	// this long per question for the whole set.
+	SecondsPerQuestion = 30
+)
+
+// Candidate is a public template question that may be picked.
+type Candidate struct {
+	ID     string
+	Theme  string
+	Points int
+}
+
+// Day returns the UTC calendar day of t as YYYY-MM-DD.
+func Day(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// Seed derives the random seed of a day, so every server picks the same set
+// for it.
+func Seed(day string) uint64 {
+	t, err := time.Parse(time.DateOnly, day)
+	if err != nil {
+		return 0
+	}
+	y, m, d := t.Date()
+	return uint64(y*10000 + int(m)*100 + d)
+}
+
+// NewRand returns the random source used to pick the set of a day.
+func NewRand(day string) *rand.Rand {
+	seed := Seed(day)
+	return rand.New(rand.NewPCG(seed, seed))
+}
+
+// Pick chooses up to n candidates with rng, balanced across themes and point
+// values: themes take turns in a random order, and each theme gives up the
+// question whose point value has been picked least so far. The result is
+// ordered by points. The same candidates and seed always give the same set,
+// whatever order the candidates come in.
+func Pick(candidates []Candidate, n int, rng *rand.Rand) []Candidate {
+	candidates = slices.Clone(candidates)
+	slices.SortFunc(candidates, func(a, b Candidate) int { return strings.Compare(a.ID, b.ID) })
+
+	byTheme := make(map[string][]Candidate)
+	for _, candidate := range candidates {
+		theme := strings.ToLower(strings.TrimSpace(candidate.Theme))
+		byTheme[theme] = append(byTheme[theme], candidate)
+	}
+
+	themes := make([]string, 0, len(byTheme))
+	for theme := range byTheme {
+		themes = append(themes, theme)
+	}
+	slices.Sort(themes)
+	rng.Shuffle(len(themes), func(i, j int) { themes[i], themes[j] = themes[j], themes[i] })
+	for _, theme := range themes {
+		questions := byTheme[theme]
+		rng.Shuffle(len(questions), func(i, j int) { questions[i], questions[j] = questions[j], questions[i] })
+	}
+
+	picked := make([]Candidate, 0, n)
+	pointsUsed := make(map[int]int)
+	for len(picked) < n {
+		progressed := false
+		for _, theme := range themes {
+			if len(picked) == n {
+				break
+			}
+			questions := byTheme[theme]
+			if len(questions) == 0 {
+				continue
+			}
+
+			best := 0
+			for i, question := range questions {
+				if pointsUsed[question.Points] < pointsUsed[questions[best].Points] {
+					best = i
+				}
+			}
+			picked = append(picked, questions[best])
+			pointsUsed[questions[best].Points]++
+			byTheme[theme] = slices.Delete(questions, best, best+1)
+			progressed = true
+		}
+		if !progressed {
+			break
+		}
+	}
+
+	slices.SortStableFunc(picked, func(a, b Candidate) int { return a.Points - b.Points })
+	return picked
+}
@@ -0,0 +1,119 @@
+package daily
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+// candidates returns a pool with one question per theme and point value, in
+// a fixed order.
+func candidates(themes int, points []int) []Candidate {
+	pool := make([]Candidate, 0, themes*len(points))
+	for t := range themes {
+		for _, p := range points {
+			pool = append(pool, Candidate{
+				ID:     fmt.Sprintf("q-%02d-%03d", t, p),
+				Theme:  fmt.Sprintf("Theme %d", t),
+				Points: p,
+			})
+		}
+	}
+	return pool
+}
+
+func ids(picked []Candidate) []string {
+	ids := make([]string, len(picked))
+	for i, candidate := range picked {
+		ids[i] = candidate.ID
+	}
+	return ids
+}
+
+func TestDayUsesUTC(t *testing.T) {
+	// Late evening in New York is already the next day in UTC
+	clock := time.Date(2026, 10, 19, 21, 30, 0, 0, time.FixedZone("EDT", -4*60*60))
+	if got := Day(clock); got != "2026-10-20" {
+		t.Fatalf("Day() = %q, want %q", got, "2026-10-20")
+	}
+}
+
+func TestPickSameDayIgnoresCandidateOrder(t *testing.T) {
+	day := Day(time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC))
+	pool := candidates(8, []int{100, 200, 300, 400, 500})
+	want := ids(Pick(pool, Size, NewRand(day)))
+
+	shuffler := rand.New(rand.NewPCG(1, 2))
+	for range 10 {
+		shuffled := slices.Clone(pool)
+		shuffler.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+		if got := ids(Pick(shuffled, Size, NewRand(day))); !slices.Equal(got, want) {
+			t.Fatalf("Pick() on reordered candidates = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestPickDiffersBetweenDays(t *testing.T) {
+	pool := candidates(8, []int{100, 200, 300, 400, 500})
+	clock := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+
+	seen := make(map[string]string)
+	for range 7 {
+		day := Day(clock)
+		picked := ids(Pick(pool, Size, NewRand(day)))
+		slices.Sort(picked)
+		set := fmt.Sprint(picked)
+		if other, ok := seen[set]; ok {
+			t.Fatalf("days %s and %s picked the same set %s", other, day, set)
+		}
+		seen[set] = day
+		clock = clock.AddDate(0, 0, 1)
+	}
+}
+
+func TestPickSpreadsThemesAndPoints(t *testing.T) {
+	points := []int{100, 200, 300, 400, 500}
+	pool := candidates(8, points)
+	day := Day(time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC))
+
+	picked := Pick(pool, Size, NewRand(day))
+	if len(picked) != Size {
+		t.Fatalf("Pick() returned %d questions, want %d", len(picked), Size)
+	}
+
+	themes := make(map[string]int)
+	values := make(map[int]int)
+	for _, candidate := range picked {
+		themes[candidate.Theme]++
+		values[candidate.Points]++
+	}
+	// 10 questions over 8 themes: every theme once, two of them twice
+	if len(themes) != 8 {
+		t.Errorf("Pick() used %d themes, want 8", len(themes))
+	}
+	for theme, count := range themes {
+		if count > 2 {
+			t.Errorf("theme %q picked %d times, want at most 2", theme, count)
+		}
+	}
+	// 10 questions over 5 point values: each value exactly twice
+	for _, p := range points {
+		if values[p] != 2 {
+			t.Errorf("%d points picked %d times, want 2", p, values[p])
+		}
+	}
+	if !slices.IsSortedFunc(picked, func(a, b Candidate) int { return a.Points - b.Points }) {
+		t.Errorf("Pick() = %v, want it ordered by points", ids(picked))
+	}
+}
+
+func TestPickWithFewCandidates(t *testing.T) {
+	pool := candidates(1, []int{100, 200, 300})
+	picked := Pick(pool, Size, NewRand("2026-10-19"))
+	if len(picked) != len(pool) {
+		t.Fatalf("Pick() returned %d questions, want all %d", len(picked), len(pool))
+	}
+}
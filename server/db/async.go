@@ -0,0 +1,557 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mindwarp/logger"
+	"mindwarp/scoring"
+	"mindwarp/types"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrAsyncGame         = fmt.Errorf("%w: async games are played through their own endpoints", ErrGameNotInProgress)
+	ErrNotAsyncGame      = errors.New("game is not an async game")
+	ErrDeadlinePassed    = errors.New("the game's deadline has passed")
+	ErrQuestionNotOpened = errors.New("question must be opened before it can be answered")
+	ErrGameNotFinished   = errors.New("game has not finished yet")
+)
+
+// asyncAnswerGrace is how many seconds past the timer an answer still counts,
+// to make up for the time it spends on the network.
+const asyncAnswerGrace = 2
+
+// asyncQuestionsSQL selects the questions every player of an async game has
+// to answer. Final rounds need wagers and are not played asynchronously.
+const asyncQuestionsSQL = `
+	SELECT q.id
+	FROM questions q
+	JOIN themes t ON t.id = q.theme_id
+	JOIN rounds r ON r.id = t.round_id
+	WHERE r.game_id = $1 AND NOT r.is_final
+`
+
+type asyncGame struct {
+	status          types.GameStatus
+	deadline        time.Time
+	questionSeconds int
+	deadlinePassed  bool
+}
+
+const asyncGameColumns = "status, mode, deadline, question_seconds, deadline <= now()"
+
+// lockAsyncGame locks an async game for the rest of the transaction, so
+// answers are serialized with the check for whether everyone is done, and
+// records the activity so the sweeper leaves the game alone.
+func lockAsyncGame(ctx context.Context, q querier, gameID string) (asyncGame, error) {
+	return scanAsyncGame(q.QueryRow(ctx, "UPDATE games SET last_activity_at = now() WHERE id = $1 RETURNING "+asyncGameColumns, gameID))
+}
+
+func getAsyncGame(ctx context.Context, q querier, gameID string) (asyncGame, error) {
+	return scanAsyncGame(q.QueryRow(ctx, "SELECT "+asyncGameColumns+" FROM games WHERE id = $1", gameID))
+}
+
+func scanAsyncGame(row pgx.Row) (asyncGame, error) {
+	var (
+		game            asyncGame
+		mode            types.GameMode
+		deadline        *time.Time
+		questionSeconds *int
+		deadlinePassed  *bool
+	)
+	err := row.Scan(&game.status, &mode, &deadline, &questionSeconds, &deadlinePassed)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return game, ErrGameNotFound
+	}
+	if err != nil {
+		return game, fmt.Errorf("failed to get game: %w", err)
+	}
+	if mode != types.GameModeAsync || deadline == nil || questionSeconds == nil {
+		return game, ErrNotAsyncGame
+	}
+
+	game.deadline = *deadline
+	game.questionSeconds = *questionSeconds
+	game.deadlinePassed = *deadlinePassed
+	return game, nil
+}
+
+// requireAsyncPlay checks that a player may open or answer a question of an
+// async game right now.
+func requireAsyncPlay(ctx context.Context, q querier, gameID string, questionID string, userID string) (asyncGame, error) {
+	game, err := lockAsyncGame(ctx, q, gameID)
+	if err != nil {
+		return game, err
+	}
+	if game.status != types.GameInProgress {
+		return game, ErrGameNotInProgress
+	}
+	if game.deadlinePassed {
+		return game, ErrDeadlinePassed
+	}
+
+	question, err := getGameQuestion(ctx, q, gameID, questionID)
+	if err != nil {
+		return game, err
+	}
+	if question.isFinal {
+		return game, ErrWrongQuestionType
+	}
+
+	ok, err := isGameUser(ctx, q, gameID, userID)
+	if err != nil {
+		return game, err
+	}
+	if !ok {
+		return game, ErrUserNotInGame
+	}
+	return game, nil
+}
+
+// OpenAsyncQuestion shows a question of an async game to a player and starts
+// their timer on it. Opening the same question again returns it with the
+// timer that is already running.
+func (db *DB) OpenAsyncQuestion(ctx context.Context, gameID string, questionID string, userID string) (types.AsyncQuestionClient, error) {
+	result := types.AsyncQuestionClient{QuestionID: questionID}
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	game, err := requireAsyncPlay(ctx, tx, gameID, questionID, userID)
+	if err != nil {
+		return result, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO question_opens (question_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (question_id, user_id) DO NOTHING
+	`, questionID, userID)
+	if err != nil {
+		return result, fmt.Errorf("failed to open question: %w", err)
+	}
+
+	var (
+		openedAt time.Time
+		answered bool
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT qo.opened_at, q.text, q.points, q.question_type,
+			EXISTS (SELECT 1 FROM answers a WHERE a.question_id = q.id AND a.user_id = qo.user_id)
+		FROM question_opens qo
+		JOIN questions q ON q.id = qo.question_id
+		WHERE qo.question_id = $1 AND qo.user_id = $2
+	`, questionID, userID).Scan(&openedAt, &result.Text, &result.Points, &result.Type, &answered)
+	if err != nil {
+		return result, fmt.Errorf("failed to get opened question: %w", err)
+	}
+	if answered {
+		return result, ErrAlreadyAnswered
+	}
+
+	options, err := getQuestionOptions(ctx, tx, questionOptionsTable, []string{questionID})
+	if err != nil {
+		return result, err
+	}
+	for _, option := range options[questionID] {
+		option.IsCorrect = nil
+		result.Options = append(result.Options, option)
+	}
+
+	attachments, err := getQuestionMedia(ctx, tx, questionMediaTable, []string{questionID})
+	if err != nil {
+		return result, err
+	}
+	for _, attachment := range attachments[questionID] {
+		if attachment.Role == string(types.MediaRoleQuestion) {
+			result.Media = append(result.Media, attachment)
+		}
+	}
+
+	result.OpenedAt = openedAt.UnixMilli()
+	result.ExpiresAt = openedAt.Add(time.Duration(game.questionSeconds) * time.Second).UnixMilli()
+
+	if err := tx.Commit(ctx); err != nil {
+		return result, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// SubmitAsyncAnswer judges a player's answer to a question they opened.
+// Multiple-choice questions are graded by the selected options and any other
+// question by comparing response with the stored answer. Answers that arrive
+// after the timer are kept but score nothing. The game finishes as soon as
+// the last player answers their last question.
+func (db *DB) SubmitAsyncAnswer(ctx context.Context, gameID string, questionID string, userID string, response string, optionIDs []string) (types.AsyncAnswerClient, error) {
+	result := types.AsyncAnswerClient{QuestionID: questionID}
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	game, err := requireAsyncPlay(ctx, tx, gameID, questionID, userID)
+	if err != nil {
+		return result, err
+	}
+
+	var (
+		elapsed      float64
+		questionType types.QuestionType
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT EXTRACT(EPOCH FROM now() - qo.opened_at)::float8, q.answer, q.question_type
+		FROM question_opens qo
+		JOIN questions q ON q.id = qo.question_id
+		WHERE qo.question_id = $1 AND qo.user_id = $2
+	`, questionID, userID).Scan(&elapsed, &result.Answer, &questionType)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return result, ErrQuestionNotOpened
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to get opened question: %w", err)
+	}
+
+	result.TimeAnswered = min(int(elapsed), game.questionSeconds)
+	result.TimedOut = int(elapsed) > game.questionSeconds+asyncAnswerGrace
+
+	var selectedIDs []string
+	if questionType == types.QuestionMultipleChoice {
+		options, err := getQuestionOptions(ctx, tx, questionOptionsTable, []string{questionID})
+		if err != nil {
+			return result, err
+		}
+		result.IsCorrect, selectedIDs, err = judgeChoice(options[questionID], optionIDs)
+		if err != nil {
+			return result, err
+		}
+	} else {
+		result.IsCorrect = matchesAnswer(response, result.Answer)
+	}
+	if result.TimedOut {
+		result.IsCorrect = false
+	}
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO answers (question_id, user_id, is_correct, time_answered, selected_options, response, timed_out)
+		VALUES ($1, $2, $3, $4, $5::uuid[], $6, $7)
+		ON CONFLICT (question_id, user_id) DO NOTHING
+	`, questionID, userID, result.IsCorrect, result.TimeAnswered, selectedIDs, response, result.TimedOut)
+	if err != nil {
+		return result, fmt.Errorf("failed to store answer: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return result, ErrAlreadyAnswered
+	}
+
+	if _, err := storeGameScores(ctx, tx, gameID); err != nil {
+		return result, fmt.Errorf("failed to recompute scores: %w", err)
+	}
+
+	in, err := loadScoringInput(ctx, tx, gameID)
+	if err != nil {
+		return result, err
+	}
+	result.Points = scoring.AnswerEffects(in, questionID)[userID]
+
+	if err := markFinishedPlayers(ctx, tx, gameID); err != nil {
+		return result, err
+	}
+	result.GameFinished, err = finishAsyncGameIfDone(ctx, tx, gameID)
+	if err != nil {
+		return result, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return result, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// closeExpiredOpens records a timed-out answer for every question of the
+// game that was opened and left unanswered past its timer.
+func closeExpiredOpens(ctx context.Context, q querier, gameID string) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO answers (question_id, user_id, is_correct, time_answered, timed_out)
+		SELECT qo.question_id, qo.user_id, false, g.question_seconds, true
+		FROM question_opens qo
+		JOIN questions q ON q.id = qo.question_id
+		JOIN themes t ON t.id = q.theme_id
+		JOIN rounds r ON r.id = t.round_id
+		JOIN games g ON g.id = r.game_id
+		WHERE g.id = $1
+			AND qo.opened_at + make_interval(secs => g.question_seconds + $2) < now()
+		ON CONFLICT (question_id, user_id) DO NOTHING
+	`, gameID, asyncAnswerGrace)
+	if err != nil {
+		return fmt.Errorf("failed to close expired questions: %w", err)
+	}
+	return nil
+}
+
+// markFinishedPlayers stamps every player who has answered all questions of
+// an async game.
+func markFinishedPlayers(ctx context.Context, q querier, gameID string) error {
+	_, err := q.Exec(ctx, `
+		UPDATE game_users gu SET finished_at = now()
+		WHERE gu.game_id = $1
+			AND gu.finished_at IS NULL
+			AND NOT EXISTS (
+				SELECT 1 FROM (`+asyncQuestionsSQL+`) aq
+				WHERE NOT EXISTS (SELECT 1 FROM answers a WHERE a.question_id = aq.id AND a.user_id = gu.user_id)
+			)
+	`, gameID)
+	if err != nil {
+		return fmt.Errorf("failed to mark finished players: %w", err)
+	}
+	return nil
+}
+
+// finishAsyncGameIfDone finishes an async game once every player is done or
+// the deadline has passed, and reports whether it did.
+func finishAsyncGameIfDone(ctx context.Context, tx pgx.Tx, gameID string) (bool, error) {
+	var done bool
+	err := tx.QueryRow(ctx, `
+		SELECT g.deadline <= now()
+			OR NOT EXISTS (SELECT 1 FROM game_users gu WHERE gu.game_id = g.id AND gu.finished_at IS NULL)
+		FROM games g
+		WHERE g.id = $1
+	`, gameID).Scan(&done)
+	if err != nil {
+		return false, fmt.Errorf("failed to check whether the game is done: %w", err)
+	}
+	if !done {
+		return false, nil
+	}
+
+	if _, _, err := finishGame(ctx, tx, gameID, ""); err != nil {
+		return false, fmt.Errorf("failed to finish async game: %w", err)
+	}
+	return true, nil
+}
+
+// SweepAsyncGames times out questions whose timer ran out unanswered and
+// finishes the async games that are done, returning their ids. Each game is
+// handled in its own transaction so one failure does not hold up the rest.
+func (db *DB) SweepAsyncGames(ctx context.Context) ([]string, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT g.id::text
+		FROM games g
+		WHERE g.mode = 'async' AND g.status = 'in_progress'
+			AND (
+				g.deadline <= now()
+				OR EXISTS (
+					SELECT 1
+					FROM question_opens qo
+					JOIN questions q ON q.id = qo.question_id
+					JOIN themes t ON t.id = q.theme_id
+					JOIN rounds r ON r.id = t.round_id
+					WHERE r.game_id = g.id
+						AND qo.opened_at + make_interval(secs => g.question_seconds + $1) < now()
+						AND NOT EXISTS (SELECT 1 FROM answers a WHERE a.question_id = qo.question_id AND a.user_id = qo.user_id)
+				)
+			)
+	`, asyncAnswerGrace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query async games: %w", err)
+	}
+	gameIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan async games: %w", err)
+	}
+
+	finished := make([]string, 0)
+	for _, gameID := range gameIDs {
+		done, err := db.sweepAsyncGame(ctx, gameID)
+		if err != nil {
+			logger.Errorf("Failed to sweep async game %s: %v", gameID, err)
+			continue
+		}
+		if done {
+			finished = append(finished, gameID)
+		}
+	}
+	return finished, nil
+}
+
+func (db *DB) sweepAsyncGame(ctx context.Context, gameID string) (bool, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	game, err := lockAsyncGame(ctx, tx, gameID)
+	if err != nil {
+		return false, err
+	}
+	if game.status != types.GameInProgress {
+		return false, nil
+	}
+
+	if err := closeExpiredOpens(ctx, tx, gameID); err != nil {
+		return false, err
+	}
+	if err := markFinishedPlayers(ctx, tx, gameID); err != nil {
+		return false, err
+	}
+	done, err := finishAsyncGameIfDone(ctx, tx, gameID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return done, nil
+}
+
+// GetAsyncProgress tells how far every player of an async game has got,
+// without showing any answers.
+func (db *DB) GetAsyncProgress(ctx context.Context, gameID string) (types.AsyncProgressClient, error) {
+	progress := types.AsyncProgressClient{
+		GameID:  gameID,
+		Players: make([]types.AsyncPlayerProgressClient, 0),
+	}
+
+	game, err := getAsyncGame(ctx, db.pool, gameID)
+	if err != nil {
+		return progress, err
+	}
+	progress.Status = string(game.status)
+	progress.Deadline = game.deadline.UnixMilli()
+	progress.QuestionSeconds = game.questionSeconds
+
+	if err := db.pool.QueryRow(ctx, "SELECT COUNT(*) FROM ("+asyncQuestionsSQL+") aq", gameID).Scan(&progress.Questions); err != nil {
+		return progress, fmt.Errorf("failed to count questions: %w", err)
+	}
+
+	rows, err := db.pool.Query(ctx, `
+		SELECT gu.user_id::text, u.name, gu.finished_at,
+			(SELECT COUNT(*) FROM answers a WHERE a.user_id = gu.user_id AND a.question_id IN (`+asyncQuestionsSQL+`))
+		FROM game_users gu
+		JOIN users u ON u.id = gu.user_id
+		WHERE gu.game_id = $1
+		ORDER BY u.name
+	`, gameID)
+	if err != nil {
+		return progress, fmt.Errorf("failed to query player progress: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			player     types.AsyncPlayerProgressClient
+			finishedAt *time.Time
+		)
+		if err := rows.Scan(&player.UserID, &player.Name, &finishedAt, &player.Answered); err != nil {
+			return progress, fmt.Errorf("failed to scan player progress: %w", err)
+		}
+		if finishedAt != nil {
+			player.FinishedAt = finishedAt.UnixMilli()
+		}
+		progress.Players = append(progress.Players, player)
+	}
+
+	if err := rows.Err(); err != nil {
+		return progress, fmt.Errorf("error iterating player progress rows: %w", err)
+	}
+
+	return progress, nil
+}
+
+// GetAsyncComparison lines up every player's answers to each question of a
+// finished async game, in board order.
+func (db *DB) GetAsyncComparison(ctx context.Context, gameID string) ([]types.AsyncComparisonClient, error) {
+	game, err := getAsyncGame(ctx, db.pool, gameID)
+	if err != nil {
+		return nil, err
+	}
+	if game.status != types.GameFinished {
+		return nil, ErrGameNotFinished
+	}
+
+	in, err := loadScoringInput(ctx, db.pool, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.pool.Query(ctx, `
+		SELECT q.id::text, q.text, q.answer, q.points,
+			COALESCE(a.user_id::text, ''), COALESCE(u.name, ''), COALESCE(a.response, ''),
+			COALESCE(a.is_correct, false), COALESCE(a.timed_out, false), COALESCE(a.time_answered, 0)
+		FROM questions q
+		JOIN themes t ON t.id = q.theme_id
+		JOIN rounds r ON r.id = t.round_id
+		LEFT JOIN answers a ON a.question_id = q.id
+		LEFT JOIN users u ON u.id = a.user_id
+		WHERE r.game_id = $1 AND NOT r.is_final
+		ORDER BY r.position, t.position, q.points, q.id, a.time_answered, u.name
+	`, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query answers: %w", err)
+	}
+	defer rows.Close()
+
+	comparison := make([]types.AsyncComparisonClient, 0)
+	effects := make(map[string]map[string]int)
+	for rows.Next() {
+		var (
+			question types.AsyncComparisonClient
+			answer   types.AsyncPlayerAnswerClient
+		)
+		err := rows.Scan(&question.QuestionID, &question.Text, &question.Answer, &question.Points,
+			&answer.UserID, &answer.Name, &answer.Response, &answer.IsCorrect, &answer.TimedOut, &answer.TimeAnswered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan answer: %w", err)
+		}
+
+		if len(comparison) == 0 || comparison[len(comparison)-1].QuestionID != question.QuestionID {
+			question.Answers = make([]types.AsyncPlayerAnswerClient, 0)
+			comparison = append(comparison, question)
+			effects[question.QuestionID] = scoring.AnswerEffects(in, question.QuestionID)
+		}
+		if answer.UserID == "" {
+			continue
+		}
+
+		answer.Points = effects[question.QuestionID][answer.UserID]
+		last := &comparison[len(comparison)-1]
+		last.Answers = append(last.Answers, answer)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating answers rows: %w", err)
+	}
+
+	return comparison, nil
+}
+
+// matchesAnswer compares a typed answer with the expected one, ignoring
+// case, punctuation, extra spaces and a leading article.
+func matchesAnswer(response string, answer string) bool {
+	normalized := normalizeAnswer(response)
+	return normalized != "" && normalized == normalizeAnswer(answer)
+}
+
+func normalizeAnswer(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) > 1 {
+		switch words[0] {
+		case "the", "a", "an":
+			words = words[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,265 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mindwarp/scoring"
+	"mindwarp/types"
+	"time"
+)
+
+var (
+	ErrQuestionResolved    = errors.New("question has already been resolved")
+	ErrQuestionNotAssigned = errors.New("question must be handed out before it can be answered")
+	ErrNotAssignee         = errors.New("only the player the question was given to may answer it")
+)
+
+// RecordAttempt adds the next attempt at a question. The question opens to
+// the other players after a miss and is resolved once someone gets it right,
+// every player (or team) has tried, or the rules do not allow steals.
+func (db *DB) RecordAttempt(ctx context.Context, attempt types.AttemptServer) (types.QuestionAttemptsClient, error) {
+	var result types.QuestionAttemptsClient
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	question, err := getGameQuestion(ctx, tx, attempt.GameID, attempt.QuestionID)
+	if err != nil {
+		return result, err
+	}
+	if err := requireGameInProgress(ctx, tx, attempt.GameID); err != nil {
+		return result, err
+	}
+	if question.isFinal || question.questionType == types.QuestionMultipleChoice {
+		return result, ErrWrongQuestionType
+	}
+
+	// Serialize attempts on the same question
+	var resolvedAt *time.Time
+	err = tx.QueryRow(ctx, "SELECT resolved_at FROM questions WHERE id = $1 FOR UPDATE", attempt.QuestionID).Scan(&resolvedAt)
+	if err != nil {
+		return result, fmt.Errorf("failed to lock question: %w", err)
+	}
+	if resolvedAt != nil {
+		return result, ErrQuestionResolved
+	}
+
+	ok, err := isGameUser(ctx, tx, attempt.GameID, attempt.UserID)
+	if err != nil {
+		return result, err
+	}
+	if !ok {
+		return result, ErrUserNotInGame
+	}
+
+	var assigneeID *string
+	if question.questionType == types.QuestionCatInBag || question.questionType == types.QuestionAuction {
+		if !question.played {
+			return result, ErrQuestionNotAssigned
+		}
+		err := tx.QueryRow(ctx, "SELECT assignee_id::text FROM question_plays WHERE question_id = $1", attempt.QuestionID).Scan(&assigneeID)
+		if err != nil {
+			return result, fmt.Errorf("failed to get question play: %w", err)
+		}
+		if *assigneeID != attempt.UserID {
+			return result, ErrNotAssignee
+		}
+	}
+
+	if err := checkTeamAnswer(ctx, tx, attempt.GameID, attempt.UserID, attempt.ContributorID); err != nil {
+		return result, err
+	}
+
+	contributorID := interface{}(nil)
+	if attempt.ContributorID != "" {
+		contributorID = attempt.ContributorID
+	}
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO answers (question_id, user_id, is_correct, time_answered, contributor_id, attempt)
+		SELECT $1, $2, $3, $4, $5, COALESCE(MAX(attempt), 0) + 1
+		FROM answers WHERE question_id = $1
+		ON CONFLICT (question_id, user_id) DO NOTHING
+	`, attempt.QuestionID, attempt.UserID, attempt.IsCorrect, attempt.TimeAnswered, contributorID)
+	if err != nil {
+		return result, fmt.Errorf("failed to record attempt: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return result, ErrAlreadyAnswered
+	}
+
+	var rulesJSON []byte
+	if err := tx.QueryRow(ctx, "SELECT rules FROM games WHERE id = $1", attempt.GameID).Scan(&rulesJSON); err != nil {
+		return result, fmt.Errorf("failed to get game rules: %w", err)
+	}
+	rules := unmarshalGameRules(attempt.GameID, rulesJSON)
+
+	// Team members answer through their captain, so each team tries once
+	var attempts, sides int
+	err = tx.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM answers WHERE question_id = $1),
+			(SELECT COUNT(DISTINCT COALESCE(team_id::text, user_id::text)) FROM game_users WHERE game_id = $2)
+	`, attempt.QuestionID, attempt.GameID).Scan(&attempts, &sides)
+	if err != nil {
+		return result, fmt.Errorf("failed to count attempts: %w", err)
+	}
+
+	if attempt.IsCorrect || !rules.AllowSteal || assigneeID != nil || attempts >= sides {
+		if _, err := tx.Exec(ctx, "UPDATE questions SET resolved_at = now() WHERE id = $1", attempt.QuestionID); err != nil {
+			return result, fmt.Errorf("failed to resolve question: %w", err)
+		}
+	}
+
+	if _, err := storeGameScores(ctx, tx, attempt.GameID); err != nil {
+		return result, fmt.Errorf("failed to recompute scores: %w", err)
+	}
+
+	result, err = getQuestionAttempts(ctx, tx, attempt.GameID, attempt.QuestionID)
+	if err != nil {
+		return result, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return result, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// ResolveQuestion closes a question early, for example when the remaining
+// players pass.
+func (db *DB) ResolveQuestion(ctx context.Context, gameID string, questionID string) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := getGameQuestion(ctx, tx, gameID, questionID); err != nil {
+		return err
+	}
+	if err := requireGameInProgress(ctx, tx, gameID); err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, "UPDATE questions SET resolved_at = now() WHERE id = $1 AND resolved_at IS NULL", questionID)
+	if err != nil {
+		return fmt.Errorf("failed to resolve question: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrQuestionResolved
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetQuestionAttempts lists the attempts at a question. Async games keep
+// their answers hidden until they finish, so they have none to show before.
+// Multiple-choice attempts are hidden from viewerID the same way the
+// question's options are until they have been judged on it.
+func (db *DB) GetQuestionAttempts(ctx context.Context, gameID string, questionID string, viewerID string) (types.QuestionAttemptsClient, error) {
+	question, err := getGameQuestion(ctx, db.pool, gameID, questionID)
+	if err != nil {
+		return types.QuestionAttemptsClient{}, err
+	}
+	if game, err := getAsyncGame(ctx, db.pool, gameID); err == nil && game.status != types.GameFinished {
+		return types.QuestionAttemptsClient{}, ErrAsyncGame
+	}
+
+	result, err := getQuestionAttempts(ctx, db.pool, gameID, questionID)
+	if err != nil {
+		return result, err
+	}
+	if question.questionType != types.QuestionMultipleChoice || question.creatorID == viewerID {
+		return result, nil
+	}
+
+	judged, err := choiceJudged(ctx, db.pool, gameID, questionID, viewerID)
+	if err != nil {
+		return result, err
+	}
+	if !judged {
+		result.Attempts = make([]types.AttemptClient, 0)
+	}
+	return result, nil
+}
+
+// choiceJudged reports whether a multiple-choice question may be shown in
+// full to viewerID: the game is finished, they have answered it, or every
+// player has.
+func choiceJudged(ctx context.Context, q querier, gameID string, questionID string, viewerID string) (bool, error) {
+	var judged bool
+	err := q.QueryRow(ctx, `
+		SELECT g.status = 'finished'
+			OR EXISTS (SELECT 1 FROM answers WHERE question_id = $2 AND user_id = $3)
+			OR (SELECT COUNT(*) FROM answers WHERE question_id = $2) >= (SELECT COUNT(*) FROM game_users WHERE game_id = $1)
+		FROM games g
+		WHERE g.id = $1
+	`, gameID, questionID, viewerID).Scan(&judged)
+	if err != nil {
+		return false, fmt.Errorf("failed to check question is judged: %w", err)
+	}
+	return judged, nil
+}
+
+func getQuestionAttempts(ctx context.Context, q querier, gameID string, questionID string) (types.QuestionAttemptsClient, error) {
+	result := types.QuestionAttemptsClient{
+		QuestionID: questionID,
+		Attempts:   make([]types.AttemptClient, 0),
+	}
+
+	var resolvedAt *time.Time
+	if err := q.QueryRow(ctx, "SELECT resolved_at FROM questions WHERE id = $1", questionID).Scan(&resolvedAt); err != nil {
+		return result, fmt.Errorf("failed to get question: %w", err)
+	}
+	if resolvedAt != nil {
+		result.Resolved = true
+		result.ResolvedAt = resolvedAt.UnixMilli()
+	}
+
+	in, err := loadScoringInput(ctx, q, gameID)
+	if err != nil {
+		return result, err
+	}
+	effects := scoring.AnswerEffects(in, questionID)
+
+	rows, err := q.Query(ctx, `
+		SELECT
+			COALESCE(a.attempt, 0), a.user_id, u.name, a.is_correct, a.time_answered,
+			COALESCE(a.contributor_id::text, ''), a.created_at
+		FROM answers a
+		JOIN users u ON u.id = a.user_id
+		WHERE a.question_id = $1
+		ORDER BY a.attempt NULLS LAST, a.created_at, a.id
+	`, questionID)
+	if err != nil {
+		return result, fmt.Errorf("failed to query attempts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			attempt   types.AttemptClient
+			createdAt time.Time
+		)
+		if err := rows.Scan(&attempt.Attempt, &attempt.UserID, &attempt.Name, &attempt.IsCorrect, &attempt.TimeAnswered, &attempt.ContributorID, &createdAt); err != nil {
+			return result, fmt.Errorf("failed to scan attempt: %w", err)
+		}
+		attempt.Points = effects[attempt.UserID]
+		attempt.CreatedAt = createdAt.UnixMilli()
+		result.Attempts = append(result.Attempts, attempt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return result, fmt.Errorf("error iterating attempts rows: %w", err)
+	}
+
+	return result, nil
+}
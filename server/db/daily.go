@@ -0,0 +1,571 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mindwarp/daily"
+	"mindwarp/types"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrDailyChallengeNotFound = errors.New("there is no daily challenge for that day")
+	ErrNoDailyQuestions       = errors.New("there are no public questions to build a daily challenge from")
+	ErrDailyAlreadyPlayed     = errors.New("today's challenge has already been played")
+	ErrDailyAttemptNotFound   = errors.New("daily challenge has not been started")
+	ErrDailyAttemptFinished   = errors.New("daily challenge attempt is already finished")
+	ErrDailyTimeUp            = errors.New("the time for this attempt has run out")
+	ErrQuestionNotInChallenge = errors.New("question is not part of this daily challenge")
+)
+
+// dailyAnswerGrace is how many seconds late an answer may arrive and still
+// count, to allow for network latency.
+const dailyAnswerGrace = 2
+
+// dailyCandidatesSQL lists the questions a daily challenge may be built from:
+// those of public templates, outside final rounds, that have an answer.
+const dailyCandidatesSQL = `
+	SELECT tq.id, tt.name, tq.points
+	FROM template_questions tq
+	JOIN template_themes tt ON tt.id = tq.theme_id
+	JOIN template_rounds tr ON tr.id = tt.round_id
+	JOIN game_templates gt ON gt.id = tr.game_template_id
+	WHERE gt.is_public AND NOT tr.is_final AND tq.text <> '' AND tq.answer <> ''
+`
+
+type dailyAttempt struct {
+	startedAt        time.Time
+	finishedAt       *time.Time
+	timeLimitSeconds int
+}
+
+func (a dailyAttempt) expiresAt() time.Time {
+	return a.startedAt.Add(time.Duration(a.timeLimitSeconds) * time.Second)
+}
+
+// timeUp tells whether the attempt can no longer take answers at now.
+func (a dailyAttempt) timeUp(now time.Time) bool {
+	return now.After(a.expiresAt().Add(dailyAnswerGrace * time.Second))
+}
+
+// EnsureDailyChallenge fixes the question set of day (YYYY-MM-DD) if it has
+// not been picked yet. The set depends only on the day and the public
+// questions at the time, so concurrent callers agree on it.
+func (db *DB) EnsureDailyChallenge(ctx context.Context, day string) error {
+	var exists bool
+	if err := db.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM daily_challenges WHERE day = $1::date)", day).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check daily challenge: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	rows, err := db.pool.Query(ctx, dailyCandidatesSQL)
+	if err != nil {
+		return fmt.Errorf("failed to query daily challenge candidates: %w", err)
+	}
+	candidates, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (daily.Candidate, error) {
+		var candidate daily.Candidate
+		err := row.Scan(&candidate.ID, &candidate.Theme, &candidate.Points)
+		return candidate, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to collect daily challenge candidates: %w", err)
+	}
+
+	picked := daily.Pick(candidates, daily.Size, daily.NewRand(day))
+	if len(picked) == 0 {
+		return ErrNoDailyQuestions
+	}
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO daily_challenges (day, seed, time_limit_seconds)
+		VALUES ($1::date, $2, $3)
+		ON CONFLICT (day) DO NOTHING
+	`, day, int64(daily.Seed(day)), len(picked)*daily.SecondsPerQuestion)
+	if err != nil {
+		return fmt.Errorf("failed to create daily challenge: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	for i, candidate := range picked {
+		batch.Queue("INSERT INTO daily_challenge_questions (day, question_id, position) VALUES ($1::date, $2, $3)", day, candidate.ID, i)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to store daily challenge questions: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetDailyChallenge describes the challenge of day, with userID's attempt at
+// it if they have started one.
+func (db *DB) GetDailyChallenge(ctx context.Context, day string, userID string, now time.Time) (types.DailyChallengeClient, error) {
+	challenge := types.DailyChallengeClient{Day: day}
+	err := db.pool.QueryRow(ctx, `
+		SELECT dc.time_limit_seconds, (SELECT COUNT(*) FROM daily_challenge_questions dq WHERE dq.day = dc.day)
+		FROM daily_challenges dc
+		WHERE dc.day = $1::date
+	`, day).Scan(&challenge.TimeLimitSeconds, &challenge.QuestionCount)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return challenge, ErrDailyChallengeNotFound
+	}
+	if err != nil {
+		return challenge, fmt.Errorf("failed to get daily challenge: %w", err)
+	}
+
+	attempt, err := db.GetDailyAttempt(ctx, day, userID, now)
+	if errors.Is(err, ErrDailyAttemptNotFound) {
+		return challenge, nil
+	}
+	if err != nil {
+		return challenge, err
+	}
+	challenge.Attempt = &attempt
+	return challenge, nil
+}
+
+func lockDailyAttempt(ctx context.Context, q querier, day string, userID string) (dailyAttempt, error) {
+	var attempt dailyAttempt
+	err := q.QueryRow(ctx, `
+		SELECT da.started_at, da.finished_at, dc.time_limit_seconds
+		FROM daily_attempts da
+		JOIN daily_challenges dc ON dc.day = da.day
+		WHERE da.day = $1::date AND da.user_id = $2
+		FOR UPDATE OF da
+	`, day, userID).Scan(&attempt.startedAt, &attempt.finishedAt, &attempt.timeLimitSeconds)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return attempt, ErrDailyAttemptNotFound
+	}
+	if err != nil {
+		return attempt, fmt.Errorf("failed to lock daily attempt: %w", err)
+	}
+	return attempt, nil
+}
+
+// finishDailyAttempt closes an attempt at finishedAt, or when its time ran
+// out if that was earlier, and totals its score.
+func finishDailyAttempt(ctx context.Context, q querier, day string, userID string, attempt dailyAttempt, finishedAt time.Time) error {
+	if expiresAt := attempt.expiresAt(); finishedAt.After(expiresAt) {
+		finishedAt = expiresAt
+	}
+
+	_, err := q.Exec(ctx, `
+		UPDATE daily_attempts da
+		SET finished_at = $3, elapsed_ms = $4,
+			score = s.score, correct = s.correct
+		FROM (
+			SELECT COALESCE(SUM(tq.points) FILTER (WHERE a.is_correct), 0) AS score,
+				COUNT(*) FILTER (WHERE a.is_correct) AS correct
+			FROM daily_answers a
+			JOIN template_questions tq ON tq.id = a.question_id
+			WHERE a.day = $1::date AND a.user_id = $2
+		) s
+		WHERE da.day = $1::date AND da.user_id = $2
+	`, day, userID, finishedAt, finishedAt.Sub(attempt.startedAt).Milliseconds())
+	if err != nil {
+		return fmt.Errorf("failed to finish daily attempt: %w", err)
+	}
+	return nil
+}
+
+// StartDailyAttempt starts userID's one attempt at the challenge of day and
+// starts its clock. Starting again while the attempt runs returns it as it
+// is.
+func (db *DB) StartDailyAttempt(ctx context.Context, day string, userID string, now time.Time) (types.DailyAttemptClient, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return types.DailyAttemptClient{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO daily_attempts (day, user_id, started_at)
+		SELECT day, $2, $3 FROM daily_challenges WHERE day = $1::date
+		ON CONFLICT (day, user_id) DO NOTHING
+	`, day, userID, now)
+	if err != nil {
+		return types.DailyAttemptClient{}, fmt.Errorf("failed to start daily attempt: %w", err)
+	}
+
+	attempt, err := lockDailyAttempt(ctx, tx, day, userID)
+	if errors.Is(err, ErrDailyAttemptNotFound) {
+		return types.DailyAttemptClient{}, ErrDailyChallengeNotFound
+	}
+	if err != nil {
+		return types.DailyAttemptClient{}, err
+	}
+	if tag.RowsAffected() == 0 && (attempt.finishedAt != nil || attempt.timeUp(now)) {
+		return types.DailyAttemptClient{}, ErrDailyAlreadyPlayed
+	}
+
+	result, err := getDailyAttempt(ctx, tx, day, userID, attempt)
+	if err != nil {
+		return result, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return result, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// GetDailyAttempt returns userID's attempt at the challenge of day. An
+// attempt whose time ran out is finished first.
+func (db *DB) GetDailyAttempt(ctx context.Context, day string, userID string, now time.Time) (types.DailyAttemptClient, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return types.DailyAttemptClient{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	attempt, err := lockDailyAttempt(ctx, tx, day, userID)
+	if err != nil {
+		return types.DailyAttemptClient{}, err
+	}
+	if attempt.finishedAt == nil && attempt.timeUp(now) {
+		if err := finishDailyAttempt(ctx, tx, day, userID, attempt, now); err != nil {
+			return types.DailyAttemptClient{}, err
+		}
+		if attempt, err = lockDailyAttempt(ctx, tx, day, userID); err != nil {
+			return types.DailyAttemptClient{}, err
+		}
+	}
+
+	result, err := getDailyAttempt(ctx, tx, day, userID, attempt)
+	if err != nil {
+		return result, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return result, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// getDailyAttempt builds an attempt with its questions. Answers and correct
+// options stay hidden until the attempt finishes.
+func getDailyAttempt(ctx context.Context, q querier, day string, userID string, attempt dailyAttempt) (types.DailyAttemptClient, error) {
+	result := types.DailyAttemptClient{
+		Day:       day,
+		StartedAt: attempt.startedAt.UnixMilli(),
+		ExpiresAt: attempt.expiresAt().UnixMilli(),
+		Questions: make([]types.DailyQuestionClient, 0),
+	}
+	finished := attempt.finishedAt != nil
+
+	var elapsedMs *int
+	err := q.QueryRow(ctx, "SELECT score, correct, elapsed_ms FROM daily_attempts WHERE day = $1::date AND user_id = $2", day, userID).
+		Scan(&result.Score, &result.Correct, &elapsedMs)
+	if err != nil {
+		return result, fmt.Errorf("failed to get daily attempt: %w", err)
+	}
+	if finished {
+		result.FinishedAt = attempt.finishedAt.UnixMilli()
+		result.ElapsedMs = *elapsedMs
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT tq.id, tt.name, tq.text, tq.answer, tq.points, tq.question_type, a.is_correct
+		FROM daily_challenge_questions dq
+		JOIN template_questions tq ON tq.id = dq.question_id
+		JOIN template_themes tt ON tt.id = tq.theme_id
+		LEFT JOIN daily_answers a ON a.day = dq.day AND a.question_id = dq.question_id AND a.user_id = $2
+		WHERE dq.day = $1::date
+		ORDER BY dq.position
+	`, day, userID)
+	if err != nil {
+		return result, fmt.Errorf("failed to query daily challenge questions: %w", err)
+	}
+	defer rows.Close()
+
+	questionIDs := make([]string, 0)
+	for rows.Next() {
+		var (
+			question types.DailyQuestionClient
+			answer   string
+		)
+		if err := rows.Scan(&question.QuestionID, &question.Theme, &question.Text, &answer, &question.Points, &question.Type, &question.IsCorrect); err != nil {
+			return result, fmt.Errorf("failed to scan daily challenge question: %w", err)
+		}
+		question.Answered = question.IsCorrect != nil
+		if finished {
+			question.Answer = answer
+		}
+		result.Questions = append(result.Questions, question)
+		questionIDs = append(questionIDs, question.QuestionID)
+	}
+	if err := rows.Err(); err != nil {
+		return result, fmt.Errorf("error iterating daily challenge question rows: %w", err)
+	}
+
+	options, err := getQuestionOptions(ctx, q, templateQuestionOptionsTable, questionIDs)
+	if err != nil {
+		return result, err
+	}
+	attachments, err := getQuestionMedia(ctx, q, templateQuestionMediaTable, questionIDs)
+	if err != nil {
+		return result, err
+	}
+
+	for i := range result.Questions {
+		question := &result.Questions[i]
+		for _, option := range options[question.QuestionID] {
+			if !finished {
+				option.IsCorrect = nil
+			}
+			question.Options = append(question.Options, option)
+		}
+		for _, attachment := range attachments[question.QuestionID] {
+			if finished || attachment.Role == string(types.MediaRoleQuestion) {
+				question.Media = append(question.Media, attachment)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// SubmitDailyAnswer judges userID's answer to a question of their running
+// attempt. The attempt finishes once every question is answered.
+func (db *DB) SubmitDailyAnswer(ctx context.Context, day string, userID string, questionID string, response string, optionIDs []string, now time.Time) (types.DailyAnswerClient, error) {
+	result := types.DailyAnswerClient{QuestionID: questionID}
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	attempt, err := lockDailyAttempt(ctx, tx, day, userID)
+	if err != nil {
+		return result, err
+	}
+	if attempt.finishedAt != nil {
+		return result, ErrDailyAttemptFinished
+	}
+	if attempt.timeUp(now) {
+		if err := finishDailyAttempt(ctx, tx, day, userID, attempt, now); err != nil {
+			return result, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return result, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return result, ErrDailyTimeUp
+	}
+
+	var (
+		answer       string
+		questionType types.QuestionType
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT tq.answer, tq.points, tq.question_type
+		FROM daily_challenge_questions dq
+		JOIN template_questions tq ON tq.id = dq.question_id
+		WHERE dq.day = $1::date AND dq.question_id = $2
+	`, day, questionID).Scan(&answer, &result.Points, &questionType)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return result, ErrQuestionNotInChallenge
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to get daily challenge question: %w", err)
+	}
+
+	var selectedIDs []string
+	if questionType == types.QuestionMultipleChoice {
+		options, err := getQuestionOptions(ctx, tx, templateQuestionOptionsTable, []string{questionID})
+		if err != nil {
+			return result, err
+		}
+		result.IsCorrect, selectedIDs, err = judgeChoice(options[questionID], optionIDs)
+		if err != nil {
+			return result, err
+		}
+	} else {
+		result.IsCorrect = matchesAnswer(response, answer)
+	}
+	if !result.IsCorrect {
+		result.Points = 0
+	}
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO daily_answers (day, user_id, question_id, response, selected_options, is_correct, answered_at)
+		VALUES ($1::date, $2, $3, $4, $5::uuid[], $6, $7)
+		ON CONFLICT (day, user_id, question_id) DO NOTHING
+	`, day, userID, questionID, response, selectedIDs, result.IsCorrect, now)
+	if err != nil {
+		return result, fmt.Errorf("failed to store daily answer: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return result, ErrAlreadyAnswered
+	}
+
+	var remaining int
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM daily_challenge_questions dq
+		WHERE dq.day = $1::date AND NOT EXISTS (
+			SELECT 1 FROM daily_answers a WHERE a.day = dq.day AND a.question_id = dq.question_id AND a.user_id = $2
+		)
+	`, day, userID).Scan(&remaining)
+	if err != nil {
+		return result, fmt.Errorf("failed to count unanswered questions: %w", err)
+	}
+	if remaining == 0 {
+		if err := finishDailyAttempt(ctx, tx, day, userID, attempt, now); err != nil {
+			return result, err
+		}
+		result.AttemptFinished = true
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return result, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// FinishDailyAttempt ends userID's running attempt early; unanswered
+// questions score nothing.
+func (db *DB) FinishDailyAttempt(ctx context.Context, day string, userID string, now time.Time) (types.DailyAttemptClient, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return types.DailyAttemptClient{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	attempt, err := lockDailyAttempt(ctx, tx, day, userID)
+	if err != nil {
+		return types.DailyAttemptClient{}, err
+	}
+	if attempt.finishedAt != nil {
+		return types.DailyAttemptClient{}, ErrDailyAttemptFinished
+	}
+	if err := finishDailyAttempt(ctx, tx, day, userID, attempt, now); err != nil {
+		return types.DailyAttemptClient{}, err
+	}
+	if attempt, err = lockDailyAttempt(ctx, tx, day, userID); err != nil {
+		return types.DailyAttemptClient{}, err
+	}
+
+	result, err := getDailyAttempt(ctx, tx, day, userID, attempt)
+	if err != nil {
+		return result, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return result, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// FinishExpiredDailyAttempts closes every attempt whose time ran out, so
+// abandoned attempts reach the leaderboard.
+func (db *DB) FinishExpiredDailyAttempts(ctx context.Context, now time.Time) (int, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT da.day::text, da.user_id::text
+		FROM daily_attempts da
+		JOIN daily_challenges dc ON dc.day = da.day
+		WHERE da.finished_at IS NULL
+			AND da.started_at + make_interval(secs => dc.time_limit_seconds + $2) < $1
+	`, now, dailyAnswerGrace)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query expired daily attempts: %w", err)
+	}
+	type attemptKey struct{ day, userID string }
+	keys, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (attemptKey, error) {
+		var key attemptKey
+		err := row.Scan(&key.day, &key.userID)
+		return key, err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to collect expired daily attempts: %w", err)
+	}
+
+	finished := 0
+	for _, key := range keys {
+		if _, err := db.GetDailyAttempt(ctx, key.day, key.userID, now); err != nil {
+			return finished, err
+		}
+		finished++
+	}
+	return finished, nil
+}
+
+// dailyLeaderboardSQL ranks the finished attempts of a day; callers append
+// the filter on the ranked rows.
+const dailyLeaderboardSQL = `
+	WITH ranked AS (
+		SELECT RANK() OVER (ORDER BY da.score DESC, da.elapsed_ms) AS rank,
+			da.user_id::text AS user_id, u.name, da.score, da.correct, da.elapsed_ms,
+			da.finished_at
+		FROM daily_attempts da
+		JOIN users u ON u.id = da.user_id
+		WHERE da.day = $1::date AND da.finished_at IS NOT NULL
+	)
+	SELECT rank, user_id, name, score, correct, elapsed_ms FROM ranked
+`
+
+func scanDailyLeaderboardEntry(row pgx.Row) (types.DailyLeaderboardEntryClient, error) {
+	var entry types.DailyLeaderboardEntryClient
+	err := row.Scan(&entry.Rank, &entry.UserID, &entry.Name, &entry.Score, &entry.Correct, &entry.ElapsedMs)
+	return entry, err
+}
+
+// GetDailyLeaderboard returns a page of the finished attempts of day, best
+// score first and quickest first among equal scores, with userID's entry.
+func (db *DB) GetDailyLeaderboard(ctx context.Context, day string, userID string, offset int, limit int) (types.DailyLeaderboardClient, error) {
+	board := types.DailyLeaderboardClient{Day: day, Entries: make([]types.DailyLeaderboardEntryClient, 0)}
+
+	var exists bool
+	err := db.pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM daily_challenges WHERE day = $1::date),
+			(SELECT COUNT(*) FROM daily_attempts WHERE day = $1::date AND finished_at IS NOT NULL)
+	`, day).Scan(&exists, &board.Total)
+	if err != nil {
+		return board, fmt.Errorf("failed to count daily attempts: %w", err)
+	}
+	if !exists {
+		return board, ErrDailyChallengeNotFound
+	}
+
+	rows, err := db.pool.Query(ctx, dailyLeaderboardSQL+" ORDER BY rank, finished_at, user_id OFFSET $2 LIMIT $3", day, offset, limit)
+	if err != nil {
+		return board, fmt.Errorf("failed to query daily leaderboard: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, err := scanDailyLeaderboardEntry(rows)
+		if err != nil {
+			return board, fmt.Errorf("failed to scan daily leaderboard entry: %w", err)
+		}
+		board.Entries = append(board.Entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return board, fmt.Errorf("error iterating daily leaderboard rows: %w", err)
+	}
+
+	me, err := scanDailyLeaderboardEntry(db.pool.QueryRow(ctx, dailyLeaderboardSQL+" WHERE user_id = $2", day, userID))
+	if err == nil {
+		board.Me = &me
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return board, fmt.Errorf("failed to get own daily leaderboard entry: %w", err)
+	}
+
+	return board, nil
+}
@@ -6,6 +6,8 @@ import (
 	"mindwarp/logger"
 	"os"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -13,6 +15,14 @@ type DB struct {
 	pool *pgxpool.Pool
 }
 
+// querier is satisfied by both the pool and a transaction, so helpers can
+// run either standalone or as part of a larger unit of work.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 func CreateDB() *DB {
 	db := &DB{}
 	db.Initialize()
@@ -0,0 +1,229 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mindwarp/types"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateEmailInvite invites someone to a game by email. The caller mails the
+// signed link; if that fails the invite should be deleted again.
+func (db *DB) CreateEmailInvite(ctx context.Context, gameID string, email string, invitedBy string) (types.EmailInviteServer, error) {
+	invite := types.EmailInviteServer{
+		GameID:    gameID,
+		Email:     email,
+		InvitedBy: invitedBy,
+		Status:    string(types.InvitePending),
+	}
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return invite, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var status types.GameStatus
+	err = tx.QueryRow(ctx, "SELECT status FROM games WHERE id = $1 FOR UPDATE", gameID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return invite, ErrGameNotFound
+	}
+	if err != nil {
+		return invite, fmt.Errorf("failed to get game: %w", err)
+	}
+	if status == types.GameFinished || status == types.GameAbandoned {
+		return invite, ErrGameClosed
+	}
+
+	// A lapsed invite must not block the new one
+	_, err = tx.Exec(ctx, `
+		UPDATE email_invites SET status = 'expired', updated_at = now()
+		WHERE game_id = $1 AND lower(email) = lower($2) AND status = 'pending' AND expires_at <= now()
+	`, gameID, email)
+	if err != nil {
+		return invite, fmt.Errorf("failed to expire old email invite: %w", err)
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO email_invites (game_id, email, invited_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (game_id, lower(email)) WHERE status = 'pending' DO NOTHING
+		RETURNING id, expires_at, created_at
+	`, gameID, email, invitedBy).Scan(&invite.ID, &invite.ExpiresAt, &invite.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return invite, ErrInviteExists
+	}
+	if err != nil {
+		return invite, fmt.Errorf("failed to create email invite: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return invite, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return invite, nil
+}
+
+func (db *DB) DeleteEmailInvite(ctx context.Context, inviteID string) error {
+	if _, err := db.pool.Exec(ctx, "DELETE FROM email_invites WHERE id = $1", inviteID); err != nil {
+		return fmt.Errorf("failed to delete email invite: %w", err)
+	}
+	return nil
+}
+
+// RevokeEmailInvite withdraws a pending email invite of the game, so its link
+// stops working.
+func (db *DB) RevokeEmailInvite(ctx context.Context, gameID string, inviteID string) error {
+	var status types.InviteStatus
+	err := db.pool.QueryRow(ctx, "SELECT status FROM email_invites WHERE id = $1 AND game_id = $2", inviteID, gameID).Scan(&status)
+	if err != nil {
+		return ErrInviteNotFound
+	}
+
+	tag, err := db.pool.Exec(ctx, `
+		UPDATE email_invites SET status = 'revoked', updated_at = now()
+		WHERE id = $1 AND status = 'pending'
+	`, inviteID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke email invite: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInviteNotPending
+	}
+	return nil
+}
+
+// ClaimEmailInvite attaches an email invite to the account that followed its
+// link and returns the game it is for. The account gets a regular pending
+// invite, so the game shows up in its invite list; if it already plays in the
+// game or has an open invite, that is kept instead.
+func (db *DB) ClaimEmailInvite(ctx context.Context, inviteID string, userID string) (string, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		gameID     string
+		invitedBy  *string
+		status     types.InviteStatus
+		lapsed     bool
+		gameStatus types.GameStatus
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT ei.game_id::text, ei.invited_by::text, ei.status, ei.expires_at <= now(), g.status
+		FROM email_invites ei
+		JOIN games g ON g.id = ei.game_id
+		WHERE ei.id = $1
+		FOR UPDATE OF ei
+	`, inviteID).Scan(&gameID, &invitedBy, &status, &lapsed, &gameStatus)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrInviteNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get email invite: %w", err)
+	}
+
+	switch {
+	case status == types.InviteRevoked:
+		return "", ErrInviteRevoked
+	case status == types.InviteExpired, status == types.InvitePending && lapsed:
+		return "", ErrInviteExpired
+	case status != types.InvitePending:
+		return "", ErrInviteNotPending
+	case gameStatus == types.GameFinished || gameStatus == types.GameAbandoned:
+		return "", ErrGameClosed
+	}
+
+	var gameInviteID *string
+	err = tx.QueryRow(ctx, `
+		SELECT id::text FROM game_invites
+		WHERE game_id = $1 AND user_id = $2 AND status IN ('pending', 'accepted')
+	`, gameID, userID).Scan(&gameInviteID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("failed to get game invite: %w", err)
+	}
+
+	if gameInviteID == nil {
+		ok, err := isGameUser(ctx, tx, gameID, userID)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			err = tx.QueryRow(ctx, `
+				INSERT INTO game_invites (game_id, user_id, invited_by)
+				VALUES ($1, $2, $3)
+				RETURNING id::text
+			`, gameID, userID, invitedBy).Scan(&gameInviteID)
+			if err != nil {
+				return "", fmt.Errorf("failed to create game invite: %w", err)
+			}
+
+			notification := types.NotificationServer{
+				UserID: userID,
+				Type:   types.NotificationInviteReceived,
+				GameID: gameID,
+			}
+			if invitedBy != nil {
+				notification.ActorID = *invitedBy
+			}
+			if err := notify(ctx, tx, notification); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE email_invites
+		SET status = 'accepted', claimed_by = $2, game_invite_id = $3, responded_at = now(), updated_at = now()
+		WHERE id = $1
+	`, inviteID, userID, gameInviteID)
+	if err != nil {
+		return "", fmt.Errorf("failed to claim email invite: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return gameID, nil
+}
+
+// GetEmailInvites lists every email invite of a game, newest first.
+func (db *DB) GetEmailInvites(ctx context.Context, gameID string) ([]types.EmailInviteClient, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT
+			ei.id, ei.game_id, ei.email,
+			CASE WHEN ei.status = 'pending' AND ei.expires_at <= now() THEN 'expired' ELSE ei.status::text END,
+			COALESCE(ei.invited_by::text, ''), COALESCE(ei.claimed_by::text, ''),
+			ei.expires_at, ei.responded_at, ei.created_at
+		FROM email_invites ei
+		WHERE ei.game_id = $1
+		ORDER BY ei.created_at DESC, ei.id
+	`, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query email invites: %w", err)
+	}
+	defer rows.Close()
+
+	invites := make([]types.EmailInviteClient, 0)
+	for rows.Next() {
+		var invite types.EmailInviteClient
+		err := rows.Scan(
+			&invite.ID, &invite.GameID, &invite.Email, &invite.Status,
+			&invite.InvitedBy, &invite.ClaimedBy,
+			&invite.ExpiresAt, &invite.RespondedAt, &invite.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan email invite: %w", err)
+		}
+		invites = append(invites, invite)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating email invites rows: %w", err)
+	}
+
+	return invites, nil
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mindwarp/types"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// lockGameVersion locks the game row for the rest of the transaction and
+// rejects the change if the caller read an older version. A zero version
+// skips the check.
+func lockGameVersion(ctx context.Context, q querier, gameID string, version int) error {
+	var current int
+	err := q.QueryRow(ctx, "SELECT version FROM games WHERE id = $1 FOR UPDATE", gameID).Scan(&current)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrGameNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock game: %w", err)
+	}
+	if version != 0 && version != current {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// recordGameEvent stores a change at the game's current version, so it has
+// to run after the change bumped the version.
+func recordGameEvent(ctx context.Context, q querier, mutation types.GameMutation, eventType types.GameEventType, payload map[string]any) (types.GameEventServer, error) {
+	event := types.GameEventServer{
+		GameID:  mutation.GameID,
+		Type:    eventType,
+		ActorID: mutation.ActorID,
+		Payload: payload,
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return event, fmt.Errorf("failed to marshal event payload: %w", err)
+	}
+
+	actorID := interface{}(nil)
+	if mutation.ActorID != "" {
+		actorID = mutation.ActorID
+	}
+
+	err = q.QueryRow(ctx, `
+		INSERT INTO game_events (game_id, version, type, actor_id, payload)
+		SELECT id, version, $2, $3, $4 FROM games WHERE id = $1
+		RETURNING id, version, created_at
+	`, mutation.GameID, string(eventType), actorID, payloadJSON).Scan(&event.ID, &event.Version, &event.CreatedAt)
+	if err != nil {
+		return event, fmt.Errorf("failed to record game event: %w", err)
+	}
+	return event, nil
+}
+
+// GetGameEvents returns the changes made after the given version, oldest
+// first.
+func (db *DB) GetGameEvents(ctx context.Context, gameID string, since int) ([]types.GameEventServer, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT id, game_id, version, type, COALESCE(actor_id::text, ''), payload, created_at
+		FROM game_events
+		WHERE game_id = $1 AND version > $2
+		ORDER BY version, created_at
+	`, gameID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query game events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]types.GameEventServer, 0)
+	for rows.Next() {
+		var (
+			event       types.GameEventServer
+			eventType   string
+			payloadJSON []byte
+		)
+		if err := rows.Scan(&event.ID, &event.GameID, &event.Version, &eventType, &event.ActorID, &payloadJSON, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan game event: %w", err)
+		}
+		if err := json.Unmarshal(payloadJSON, &event.Payload); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal event payload: %w", err)
+		}
+		event.Type = types.GameEventType(eventType)
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating game events rows: %w", err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,297 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mindwarp/scoring"
+	"mindwarp/types"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrNotFinalRound          = errors.New("round is not a final round of this game")
+	ErrQuestionNotInRound     = errors.New("question does not belong to this round")
+	ErrFinalNotOpen           = errors.New("final round is not open for wagers")
+	ErrFinalDeadlinePassed    = errors.New("final round deadline has passed")
+	ErrFinalDeadlineNotPassed = errors.New("final round deadline has not passed yet")
+	ErrFinalAlreadyRevealed   = errors.New("final round reveal has already started")
+	ErrWagerTooHigh           = errors.New("wager exceeds current score")
+	ErrWagerNotFound          = errors.New("no wager placed for this player")
+)
+
+type finalRound struct {
+	questionID   *string
+	deadline     *time.Time
+	beforeCutoff bool
+}
+
+// getFinalRound loads the final round's state, comparing the deadline against
+// the database clock so every server instance agrees on it.
+func getFinalRound(ctx context.Context, q querier, gameID string, roundID string) (finalRound, error) {
+	var (
+		round   finalRound
+		isFinal bool
+	)
+	err := q.QueryRow(ctx, `
+		SELECT is_final, final_question_id::text, final_deadline, COALESCE(final_deadline > now(), false)
+		FROM rounds
+		WHERE id = $1 AND game_id = $2
+	`, roundID, gameID).Scan(&isFinal, &round.questionID, &round.deadline, &round.beforeCutoff)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return round, ErrNotFinalRound
+	}
+	if err != nil {
+		return round, fmt.Errorf("failed to get final round: %w", err)
+	}
+	if !isFinal {
+		return round, ErrNotFinalRound
+	}
+	return round, nil
+}
+
+// finalMaxWager is the player's score from every round except the final one.
+// Players at or below zero may only wager nothing.
+func finalMaxWager(ctx context.Context, q querier, gameID string, roundID string, userID string) (int, error) {
+	in, err := loadScoringInput(ctx, q, gameID)
+	if err != nil {
+		return 0, err
+	}
+
+	return max(scoring.Compute(in).TotalExcept(userID, roundID), 0), nil
+}
+
+// OpenFinalRound starts the wager phase on the chosen question of the final
+// round. Wagers and answers are accepted until the deadline.
+func (db *DB) OpenFinalRound(ctx context.Context, gameID string, roundID string, questionID string, duration time.Duration) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := getFinalRound(ctx, tx, gameID, roundID); err != nil {
+		return err
+	}
+	if err := requireGameInProgress(ctx, tx, gameID); err != nil {
+		return err
+	}
+
+	var inRound, revealed bool
+	err = tx.QueryRow(ctx, `
+		SELECT
+			EXISTS (SELECT 1 FROM questions q JOIN themes t ON t.id = q.theme_id WHERE q.id = $1 AND t.round_id = $2),
+			EXISTS (SELECT 1 FROM final_wagers WHERE round_id = $2 AND revealed_at IS NOT NULL)
+	`, questionID, roundID).Scan(&inRound, &revealed)
+	if err != nil {
+		return fmt.Errorf("failed to validate final question: %w", err)
+	}
+	if !inRound {
+		return ErrQuestionNotInRound
+	}
+	if revealed {
+		return ErrFinalAlreadyRevealed
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE rounds SET final_question_id = $1, final_deadline = now() + make_interval(secs => $2) WHERE id = $3
+	`, questionID, duration.Seconds(), roundID)
+	if err != nil {
+		return fmt.Errorf("failed to open final round: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// PlaceFinalWager stores or replaces a player's secret wager.
+func (db *DB) PlaceFinalWager(ctx context.Context, gameID string, roundID string, userID string, wager int) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	round, err := getFinalRound(ctx, tx, gameID, roundID)
+	if err != nil {
+		return err
+	}
+	if round.deadline == nil {
+		return ErrFinalNotOpen
+	}
+	if !round.beforeCutoff {
+		return ErrFinalDeadlinePassed
+	}
+	if err := requireGameInProgress(ctx, tx, gameID); err != nil {
+		return err
+	}
+
+	var isPlayer bool
+	err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM game_users WHERE game_id = $1 AND user_id = $2)", gameID, userID).Scan(&isPlayer)
+	if err != nil {
+		return fmt.Errorf("failed to check game user: %w", err)
+	}
+	if !isPlayer {
+		return ErrUserNotInGame
+	}
+
+	maxWager, err := finalMaxWager(ctx, tx, gameID, roundID, userID)
+	if err != nil {
+		return err
+	}
+	if wager > maxWager {
+		return ErrWagerTooHigh
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO final_wagers (round_id, user_id, wager)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (round_id, user_id)
+		DO UPDATE SET wager = EXCLUDED.wager, updated_at = now()
+	`, roundID, userID, wager)
+	if err != nil {
+		return fmt.Errorf("failed to place wager: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// SubmitFinalAnswer stores a player's written answer. A wager must exist.
+func (db *DB) SubmitFinalAnswer(ctx context.Context, gameID string, roundID string, userID string, answer string) error {
+	round, err := getFinalRound(ctx, db.pool, gameID, roundID)
+	if err != nil {
+		return err
+	}
+	if round.deadline == nil {
+		return ErrFinalNotOpen
+	}
+	if !round.beforeCutoff {
+		return ErrFinalDeadlinePassed
+	}
+	if err := requireGameInProgress(ctx, db.pool, gameID); err != nil {
+		return err
+	}
+
+	tag, err := db.pool.Exec(ctx, `
+		UPDATE final_wagers SET answer = $1, updated_at = now()
+		WHERE round_id = $2 AND user_id = $3
+	`, answer, roundID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to submit answer: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWagerNotFound
+	}
+	return nil
+}
+
+// RevealFinalWager judges one player's final answer once the deadline has
+// passed. The wager is then applied to the player's score.
+func (db *DB) RevealFinalWager(ctx context.Context, gameID string, roundID string, userID string, isCorrect bool) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	round, err := getFinalRound(ctx, tx, gameID, roundID)
+	if err != nil {
+		return err
+	}
+	if round.deadline == nil {
+		return ErrFinalNotOpen
+	}
+	if round.beforeCutoff {
+		return ErrFinalDeadlineNotPassed
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE final_wagers
+		SET is_correct = $1, revealed_at = COALESCE(revealed_at, now()), updated_at = now()
+		WHERE round_id = $2 AND user_id = $3
+	`, isCorrect, roundID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to reveal wager: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWagerNotFound
+	}
+
+	if _, err := storeGameScores(ctx, tx, gameID); err != nil {
+		return fmt.Errorf("failed to recompute scores: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetFinalRound returns the final round as seen by viewerID. Players always
+// see their own entry; other entries stay hidden until revealed, except that
+// the host sees everything once the deadline has passed so they can judge.
+func (db *DB) GetFinalRound(ctx context.Context, gameID string, roundID string, viewerID string, isHost bool) (types.FinalRoundClient, error) {
+	result := types.FinalRoundClient{RoundID: roundID, Wagers: []types.FinalWagerClient{}}
+
+	round, err := getFinalRound(ctx, db.pool, gameID, roundID)
+	if err != nil {
+		return result, err
+	}
+
+	if round.questionID != nil {
+		result.QuestionID = *round.questionID
+	}
+	if round.deadline != nil {
+		result.Deadline = round.deadline.UnixMilli()
+	}
+	result.IsOpen = round.beforeCutoff
+
+	result.MaxWager, err = finalMaxWager(ctx, db.pool, gameID, roundID, viewerID)
+	if err != nil {
+		return result, err
+	}
+
+	rows, err := db.pool.Query(ctx, `
+		SELECT user_id, wager, answer, is_correct, revealed_at IS NOT NULL
+		FROM final_wagers
+		WHERE round_id = $1
+		ORDER BY created_at
+	`, roundID)
+	if err != nil {
+		return result, fmt.Errorf("failed to query final wagers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			entry     types.FinalWagerClient
+			wager     int
+			answer    *string
+			isCorrect *bool
+		)
+		if err := rows.Scan(&entry.UserID, &wager, &answer, &isCorrect, &entry.IsRevealed); err != nil {
+			return result, fmt.Errorf("failed to scan final wager: %w", err)
+		}
+
+		entry.HasAnswer = answer != nil
+		if entry.UserID == viewerID || entry.IsRevealed || (isHost && !round.beforeCutoff) {
+			entry.Wager = &wager
+			entry.Answer = answer
+			entry.IsCorrect = isCorrect
+		}
+		result.Wagers = append(result.Wagers, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return result, fmt.Errorf("error iterating final wagers rows: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,297 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"mindwarp/types"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrTemplateNotFound      = errors.New("game template not found")
+	ErrInvalidRoundSelection = errors.New("selected rounds do not belong to the template")
+)
+
+// CreateGameFromTemplate copies a template's rounds, themes and questions into
+// a new game in one transaction, so the game always matches the template it
+// names. Every copied row gets a fresh id. The template must be public or
+// belong to the game's creator.
+func (db *DB) CreateGameFromTemplate(ctx context.Context, game types.GameServer, options types.TemplateCopyOptions, users []types.UserServer) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := createGameFromTemplate(ctx, tx, game, options, users); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// createGameFromTemplate does the work of CreateGameFromTemplate inside tx.
+func createGameFromTemplate(ctx context.Context, tx pgx.Tx, game types.GameServer, options types.TemplateCopyOptions, users []types.UserServer) error {
+	var visible bool
+	err := tx.QueryRow(ctx, `
+		SELECT is_public OR creator_id = $2
+		FROM game_templates
+		WHERE id = $1
+		FOR SHARE
+	`, game.TemplateID, game.CreatorID).Scan(&visible)
+	if err != nil || !visible {
+		return ErrTemplateNotFound
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, name, time_settings, rank_settings, rules, is_final
+		FROM template_rounds
+		WHERE game_template_id = $1
+		ORDER BY position
+	`, game.TemplateID)
+	if err != nil {
+		return fmt.Errorf("failed to query template rounds: %w", err)
+	}
+
+	var (
+		rounds   []types.RoundServer
+		roundIDs = make(map[string]string)
+	)
+	for rows.Next() {
+		var (
+			templateRoundID string
+			round           types.RoundServer
+			timeJSON        []byte
+			rankJSON        []byte
+			rulesJSON       []byte
+		)
+		if err := rows.Scan(&templateRoundID, &round.Name, &timeJSON, &rankJSON, &rulesJSON, &round.IsFinal); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan template round: %w", err)
+		}
+		if len(options.RoundIDs) > 0 && !slices.Contains(options.RoundIDs, templateRoundID) {
+			continue
+		}
+
+		round.ID = uuid.NewString()
+		round.GameID = game.ID
+		round.TimeSettings, round.RankSettings = unmarshalRoundSettings(templateRoundID, timeJSON, rankJSON)
+		round.Rules = unmarshalRoundRules(templateRoundID, rulesJSON)
+		round.Position = uint16(len(rounds))
+		roundIDs[templateRoundID] = round.ID
+		rounds = append(rounds, round)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating template rounds rows: %w", err)
+	}
+
+	for _, roundID := range options.RoundIDs {
+		if _, ok := roundIDs[roundID]; !ok {
+			return ErrInvalidRoundSelection
+		}
+	}
+
+	themes, themeIDs, err := copyTemplateThemes(ctx, tx, roundIDs, options.ShuffleThemes)
+	if err != nil {
+		return err
+	}
+
+	questions, err := copyTemplateQuestions(ctx, tx, themeIDs)
+	if err != nil {
+		return err
+	}
+
+	if err := insertGameTree(ctx, tx, game, rounds, themes, questions, users, nil); err != nil {
+		return err
+	}
+
+	if _, err := storeGameScores(ctx, tx, game.ID); err != nil {
+		return fmt.Errorf("failed to compute scores: %w", err)
+	}
+	return nil
+}
+
+// copyTemplateThemes copies the themes of the selected rounds, keyed by the
+// new round id, and returns the mapping from template theme ids to new ones.
+func copyTemplateThemes(ctx context.Context, q querier, roundIDs map[string]string, shuffle bool) (map[string][]types.ThemeServer, map[string]string, error) {
+	templateRoundIDs := make([]string, 0, len(roundIDs))
+	for templateRoundID := range roundIDs {
+		templateRoundIDs = append(templateRoundIDs, templateRoundID)
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT id, round_id, name
+		FROM template_themes
+		WHERE round_id = ANY($1)
+		ORDER BY position
+	`, templateRoundIDs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query template themes: %w", err)
+	}
+	defer rows.Close()
+
+	themes := make(map[string][]types.ThemeServer)
+	themeIDs := make(map[string]string)
+	for rows.Next() {
+		var templateThemeID, templateRoundID, name string
+		if err := rows.Scan(&templateThemeID, &templateRoundID, &name); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan template theme: %w", err)
+		}
+
+		roundID := roundIDs[templateRoundID]
+		theme := types.ThemeServer{
+			ID:      uuid.NewString(),
+			RoundID: roundID,
+			Name:    name,
+		}
+		themeIDs[templateThemeID] = theme.ID
+		themes[roundID] = append(themes[roundID], theme)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating template themes rows: %w", err)
+	}
+
+	for roundID, roundThemes := range themes {
+		if shuffle {
+			rand.Shuffle(len(roundThemes), func(i, j int) {
+				roundThemes[i], roundThemes[j] = roundThemes[j], roundThemes[i]
+			})
+		}
+		for i := range roundThemes {
+			roundThemes[i].Position = uint16(i)
+		}
+		themes[roundID] = roundThemes
+	}
+
+	return themes, themeIDs, nil
+}
+
+// copyTemplateQuestions copies the questions of the copied themes, keyed by
+// the new theme id, together with their media and options. Options are
+// shuffled once for the game when the template question asks for it.
+func copyTemplateQuestions(ctx context.Context, q querier, themeIDs map[string]string) (map[string][]types.QuestionServer, error) {
+	templateThemeIDs := make([]string, 0, len(themeIDs))
+	for templateThemeID := range themeIDs {
+		templateThemeIDs = append(templateThemeIDs, templateThemeID)
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT id, theme_id, text, answer, points, question_type, shuffle_options
+		FROM template_questions
+		WHERE theme_id = ANY($1)
+		ORDER BY position
+	`, templateThemeIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query template questions: %w", err)
+	}
+
+	var (
+		copied              []*types.QuestionServer
+		questionIDs         = make(map[string]*types.QuestionServer)
+		shuffleOptions      = make(map[string]bool)
+		templateQuestionIDs []string
+	)
+	for rows.Next() {
+		var (
+			templateQuestionID, templateThemeID string
+			question                            types.QuestionServer
+			points                              int
+			shuffle                             bool
+		)
+		if err := rows.Scan(&templateQuestionID, &templateThemeID, &question.Text, &question.Answer, &points, &question.Type, &shuffle); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan template question: %w", err)
+		}
+		question.ID = uuid.NewString()
+		question.ThemeID = themeIDs[templateThemeID]
+		question.Points = uint16(points)
+
+		copied = append(copied, &question)
+		questionIDs[templateQuestionID] = &question
+		shuffleOptions[templateQuestionID] = shuffle
+		templateQuestionIDs = append(templateQuestionIDs, templateQuestionID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating template questions rows: %w", err)
+	}
+
+	rows, err = q.Query(ctx, `
+		SELECT question_id, media_id, role, position
+		FROM template_question_media
+		WHERE question_id = ANY($1)
+		ORDER BY position
+	`, templateQuestionIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query template question media: %w", err)
+	}
+	for rows.Next() {
+		var (
+			templateQuestionID string
+			attachment         types.MediaAttachmentServer
+		)
+		if err := rows.Scan(&templateQuestionID, &attachment.MediaID, &attachment.Role, &attachment.Position); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan template question media: %w", err)
+		}
+		question := questionIDs[templateQuestionID]
+		question.Media = append(question.Media, attachment)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating template question media rows: %w", err)
+	}
+
+	rows, err = q.Query(ctx, `
+		SELECT question_id, text, is_correct
+		FROM template_question_options
+		WHERE question_id = ANY($1)
+		ORDER BY position
+	`, templateQuestionIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query template question options: %w", err)
+	}
+	for rows.Next() {
+		var (
+			templateQuestionID string
+			option             types.QuestionOptionServer
+		)
+		if err := rows.Scan(&templateQuestionID, &option.Text, &option.IsCorrect); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan template question option: %w", err)
+		}
+		option.ID = uuid.NewString()
+		question := questionIDs[templateQuestionID]
+		question.Options = append(question.Options, option)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating template question options rows: %w", err)
+	}
+
+	questions := make(map[string][]types.QuestionServer)
+	for templateQuestionID, question := range questionIDs {
+		if shuffleOptions[templateQuestionID] {
+			rand.Shuffle(len(question.Options), func(i, j int) {
+				question.Options[i], question.Options[j] = question.Options[j], question.Options[i]
+			})
+		}
+		for i := range question.Options {
+			question.Options[i].Position = uint16(i)
+		}
+	}
+	for _, question := range copied {
+		questions[question.ThemeID] = append(questions[question.ThemeID], *question)
+	}
+
+	return questions, nil
+}
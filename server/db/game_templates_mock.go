@@ -285,8 +285,8 @@ func Generate30GameTemplates(creatorID string) []types.GameTemplateClient {
 						points := rankOptions[q%len(rankOptions)].Points
 						questions = append(questions, types.QuestionClient{
 							Id:         questionID,
-							Text:       themeName + " Question " + string(q+1+48),
-							Answer:     "Answer " + string(q+1+48),
+							Text:       themeName + " Question " + string(rune(q+1+48)),
+							Answer:     "Answer " + string(rune(q+1+48)),
 							Points:     points,
 							AnsweredBy: map[string]types.AnsweredByClient{},
 						})
@@ -300,7 +300,7 @@ func Generate30GameTemplates(creatorID string) []types.GameTemplateClient {
 			}
 			rounds = append(rounds, types.RoundClient{
 				Id:    roundID,
-				Name:  "Round " + string(r+1+48),
+				Name:  "Round " + string(rune(r+1+48)),
 				Ranks: ranks,
 				Time: types.RoundTimeClient{
 					Id:         timeOpt.Seconds,
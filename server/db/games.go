@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"mindwarp/logger"
+	"mindwarp/scoring"
 	"mindwarp/types"
 	"sort"
 
@@ -12,8 +13,35 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
+// insertGame stores the game row. A game with a schedule waits in the
+// scheduled status; any other starts in the lobby. Games without a mode are
+// live.
 func insertGame(ctx context.Context, tx pgx.Tx, game types.GameServer) error {
-	_, err := tx.Exec(ctx, "INSERT INTO games (id, name, creator_id, template_id) VALUES ($1, $2, $3, $4)", game.ID, game.Name, game.CreatorID, game.TemplateID)
+	status := types.GameLobby
+	var (
+		scheduledAt     interface{}
+		timezone        interface{}
+		reminderOffsets = []int{}
+		mode            = types.GameModeLive
+		deadline        interface{}
+		questionSeconds interface{}
+	)
+	if game.Schedule != nil {
+		status = types.GameScheduled
+		scheduledAt = game.Schedule.StartsAt
+		timezone = game.Schedule.Timezone
+		reminderOffsets = game.Schedule.ReminderOffsets
+	}
+	if game.Mode == types.GameModeAsync && game.Async != nil {
+		mode = types.GameModeAsync
+		deadline = game.Async.Deadline
+		questionSeconds = game.Async.QuestionSeconds
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO games (id, name, creator_id, template_id, tie_breakers, rules, min_players, status, scheduled_at, timezone, reminder_offsets, mode, deadline, question_seconds, casual)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	`, game.ID, game.Name, game.CreatorID, game.TemplateID, game.TieBreakers, game.Rules, game.MinPlayers, string(status), scheduledAt, timezone, reminderOffsets, string(mode), deadline, questionSeconds, game.Casual)
 	if err != nil {
 		return err
 	}
@@ -21,7 +49,7 @@ func insertGame(ctx context.Context, tx pgx.Tx, game types.GameServer) error {
 }
 
 func insertGameRound(ctx context.Context, tx pgx.Tx, round types.RoundServer) error {
-	_, err := tx.Exec(ctx, "INSERT INTO rounds (id, game_id, name, time_settings, rank_settings, position) VALUES ($1, $2, $3, $4, $5, $6)", round.ID, round.GameID, round.Name, round.TimeSettings, round.RankSettings, round.Position)
+	_, err := tx.Exec(ctx, "INSERT INTO rounds (id, game_id, name, time_settings, rank_settings, rules, is_final, position) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)", round.ID, round.GameID, round.Name, round.TimeSettings, round.RankSettings, round.Rules, round.IsFinal, round.Position)
 	if err != nil {
 		return err
 	}
@@ -38,13 +66,14 @@ func getGameByFilter(filterType string, offset string, limit string, query strin
 			LIMIT %s
 		)
 		SELECT
-			g.id, g.name, g.is_finished, g.creator_id, g.template_id,
+			g.id, g.name, g.is_finished, g.status, g.min_players, g.version, g.creator_id, g.template_id,
 			g.current_round_id, g.current_question_id, g.current_user_id,
-			g.finish_date,
+			g.finish_date, g.tie_breakers, g.rules, g.scheduled_at, g.timezone, g.reminder_offsets,
+			g.mode, g.deadline, g.question_seconds, g.casual,
 			w.id as winner_id, w.name as winner_name, g.created_at,
-			r.id, r.name, r.time_settings, r.rank_settings, r.position,
+			r.id, r.name, r.time_settings, r.rank_settings, r.rules, r.is_final, r.position,
 			t.id, t.name, t.position,
-			q.id, q.text, q.answer, q.points
+			q.id, q.text, q.answer, q.points, q.question_type, q.resolved_at
 		FROM
 			ordered_games g
 		LEFT JOIN
@@ -88,7 +117,7 @@ func getGameByFilter(filterType string, offset string, limit string, query strin
 		LEFT JOIN
 			questions q ON t.id = q.theme_id
 		WHERE
-			g.is_finished = false
+			g.status IN ('scheduled', 'lobby', 'in_progress', 'paused')
 		`
 	case "user_finished":
 		filters = `
@@ -101,7 +130,7 @@ func getGameByFilter(filterType string, offset string, limit string, query strin
 		LEFT JOIN
 			questions q ON t.id = q.theme_id
 		WHERE
-			g.is_finished = true
+			g.status IN ('finished', 'abandoned')
 		`
 	case "public_unfinished":
 		filters = `
@@ -112,7 +141,7 @@ func getGameByFilter(filterType string, offset string, limit string, query strin
 		LEFT JOIN
 			questions q ON t.id = q.theme_id
 		WHERE
-			g.is_public = true AND g.is_finished = false
+			g.is_public = true AND g.status IN ('scheduled', 'lobby', 'in_progress', 'paused')
 		`
 	case "public_finished":
 		filters = `
@@ -123,7 +152,7 @@ func getGameByFilter(filterType string, offset string, limit string, query strin
 		LEFT JOIN
 			questions q ON t.id = q.theme_id
 		WHERE
-			g.is_public = true AND g.is_finished = true
+			g.is_public = true AND g.status IN ('finished', 'abandoned')
 		`
 	case "all":
 		filters = "1=1"
@@ -148,7 +177,7 @@ func getGameByFilter(filterType string, offset string, limit string, query strin
 func (db *DB) GetUsersByGameId(ctx context.Context, id string) ([]types.GameUserClient, error) {
 	query := `
 		SELECT
-			u.id, u.name, u.is_admin, gu.round_scores
+			u.id, u.name, u.is_admin, gu.round_scores, COALESCE(gu.team_id::text, '')
 		FROM
 			game_users gu
 		LEFT JOIN
@@ -165,7 +194,7 @@ func (db *DB) GetUsersByGameId(ctx context.Context, id string) ([]types.GameUser
 	var users []types.GameUserClient
 	for rows.Next() {
 		var user types.GameUserClient
-		err := rows.Scan(&user.ID, &user.Name, &user.IsAdmin, &user.RoundScore)
+		err := rows.Scan(&user.ID, &user.Name, &user.IsAdmin, &user.RoundScore, &user.TeamID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
@@ -175,13 +204,9 @@ func (db *DB) GetUsersByGameId(ctx context.Context, id string) ([]types.GameUser
 	return users, nil
 }
 
-func (db *DB) CreateGame(ctx context.Context, game types.GameServer, rounds []types.RoundServer, themes map[string][]types.ThemeServer, questions map[string][]types.QuestionServer, users []types.UserServer, answers []types.AnswerServer) error {
-	tx, err := db.pool.Begin(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback(ctx)
-
+// insertGameTree inserts a game with its rounds, themes, questions, invites
+// and answers.
+func insertGameTree(ctx context.Context, tx pgx.Tx, game types.GameServer, rounds []types.RoundServer, themes map[string][]types.ThemeServer, questions map[string][]types.QuestionServer, users []types.UserServer, answers []types.AnswerServer) error {
 	// 1. Insert template (single insert)
 	if err := insertGame(ctx, tx, game); err != nil {
 		return fmt.Errorf("failed to insert game: %w", err)
@@ -201,6 +226,8 @@ func (db *DB) CreateGame(ctx context.Context, game types.GameServer, rounds []ty
 	opCounts := struct {
 		themes    int
 		questions int
+		media     int
+		options   int
 		users     int
 		answers   int
 	}{}
@@ -236,22 +263,25 @@ func (db *DB) CreateGame(ctx context.Context, game types.GameServer, rounds []ty
 
 			batch.Queue(
 				`INSERT INTO questions 
-				(id, theme_id, text, answer, points) 
-				VALUES ($1, $2, $3, $4, $5)`,
+				(id, theme_id, text, answer, points, question_type) 
+				VALUES ($1, $2, $3, $4, $5, $6)`,
 				question.ID,
 				question.ThemeID,
 				question.Text,
 				question.Answer,
 				question.Points,
+				question.Type,
 			)
 			opCounts.questions++
+			opCounts.options += queueQuestionOptions(batch, questionOptionsTable, question.ID, question.Options, false)
+			opCounts.media += queueQuestionMedia(batch, questionMediaTable, question.ID, question.Media, false)
 		}
 	}
 
 	// Queue users
 	for _, user := range users {
 		batch.Queue(
-			`INSERT INTO game_invites (game_id, user_id, status) VALUES ($1, $2, CASE WHEN $3 = $4 THEN 'accepted'::invite_status ELSE 'pending'::invite_status END)`,
+			`INSERT INTO game_invites (game_id, user_id, invited_by, status) VALUES ($1, $2, $4, CASE WHEN $3 = $4 THEN 'accepted'::invite_status ELSE 'pending'::invite_status END)`,
 			game.ID,
 			user.ID,
 			user.ID,
@@ -267,6 +297,20 @@ func (db *DB) CreateGame(ctx context.Context, game types.GameServer, rounds []ty
 			opCounts.users++
 		}
 
+		if user.ID != game.CreatorID {
+			args, err := notificationArgs(types.NotificationServer{
+				UserID:  user.ID,
+				Type:    types.NotificationInviteReceived,
+				GameID:  game.ID,
+				ActorID: game.CreatorID,
+			})
+			if err != nil {
+				return err
+			}
+			batch.Queue(insertNotificationSQL, args...)
+			opCounts.users++
+		}
+
 		opCounts.users++
 	}
 
@@ -293,10 +337,10 @@ func (db *DB) CreateGame(ctx context.Context, game types.GameServer, rounds []ty
 			}
 		}
 
-		// Process questions results
-		for i := 0; i < opCounts.questions; i++ {
+		// Process questions and their media results
+		for i := 0; i < opCounts.questions+opCounts.media+opCounts.options; i++ {
 			if _, err := batchResults.Exec(); err != nil {
-				return fmt.Errorf("failed to insert question %d: %w", i+1, err)
+				return fmt.Errorf("failed to insert question or media %d: %w", i+1, err)
 			}
 		}
 
@@ -312,11 +356,6 @@ func (db *DB) CreateGame(ctx context.Context, game types.GameServer, rounds []ty
 		}
 	}
 
-	// Commit the transaction
-	if err = tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
 	return nil
 }
 
@@ -330,7 +369,11 @@ func (db *DB) GetAnswersByQuestionIds(ctx context.Context, questionIds []string)
 			question_id,
 			user_id,
 			is_correct,
-			time_answered
+			time_answered,
+			selected_options::text[],
+			COALESCE(contributor_id::text, ''),
+			COALESCE(attempt, 0),
+			timed_out
 		FROM
 			answers
 		WHERE
@@ -346,13 +389,17 @@ func (db *DB) GetAnswersByQuestionIds(ctx context.Context, questionIds []string)
 	answers := make(map[string]map[string]types.AnsweredByClient)
 	for rows.Next() {
 		var (
-			questionID   string
-			userID       string
-			isCorrect    bool
-			timeAnswered uint16
+			questionID      string
+			userID          string
+			isCorrect       bool
+			timeAnswered    uint16
+			selectedOptions []string
+			contributorID   string
+			attempt         int
+			timedOut        bool
 		)
 
-		err := rows.Scan(&questionID, &userID, &isCorrect, &timeAnswered)
+		err := rows.Scan(&questionID, &userID, &isCorrect, &timeAnswered, &selectedOptions, &contributorID, &attempt, &timedOut)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan answer: %w", err)
 		}
@@ -362,8 +409,12 @@ func (db *DB) GetAnswersByQuestionIds(ctx context.Context, questionIds []string)
 		}
 
 		answers[questionID][userID] = types.AnsweredByClient{
-			IsCorrect:    isCorrect,
-			TimeAnswered: timeAnswered,
+			IsCorrect:       isCorrect,
+			TimeAnswered:    timeAnswered,
+			SelectedOptions: selectedOptions,
+			ContributorID:   contributorID,
+			Attempt:         attempt,
+			TimedOut:        timedOut,
 		}
 	}
 
@@ -381,28 +432,44 @@ func (db *DB) GetGameByFilter(ctx context.Context, filter string, filterValue st
 		gameCreatorID         pgtype.UUID
 		gameTemplateID        pgtype.UUID
 		gameIsFinished        pgtype.Bool
+		gameStatus            pgtype.Text
+		gameMinPlayers        pgtype.Int4
+		gameVersion           pgtype.Int4
 		gameCurrentRoundID    pgtype.UUID
 		gameCurrentQuestionID pgtype.UUID
 		gameCurrentUserID     pgtype.UUID
 		gameFinishDate        pgtype.Timestamp
+		gameTieBreakers       []string
+		gameRulesJSON         []byte
+		gameScheduledAt       pgtype.Timestamptz
+		gameTimezone          pgtype.Text
+		gameReminderOffsets   []int
+		gameMode              pgtype.Text
+		gameDeadline          pgtype.Timestamptz
+		gameQuestionSeconds   pgtype.Int4
+		gameCasual            pgtype.Bool
 		gameWinnerName        pgtype.Text
 		gameWinnerID          pgtype.UUID
 		gameCreatedAt         pgtype.Timestamp
 
-		roundID       pgtype.UUID
-		roundName     pgtype.Text
-		roundTimeJSON []byte
-		roundRankJSON []byte
-		roundPosition pgtype.Int4
+		roundID        pgtype.UUID
+		roundName      pgtype.Text
+		roundTimeJSON  []byte
+		roundRankJSON  []byte
+		roundRulesJSON []byte
+		roundIsFinal   pgtype.Bool
+		roundPosition  pgtype.Int4
 
 		themeID       pgtype.UUID
 		themeName     pgtype.Text
 		themePosition pgtype.Int4
 
-		questionID     pgtype.UUID
-		questionText   pgtype.Text
-		questionAnswer pgtype.Text
-		questionPoints pgtype.Int4
+		questionID       pgtype.UUID
+		questionText     pgtype.Text
+		questionAnswer   pgtype.Text
+		questionPoints   pgtype.Int4
+		questionType     pgtype.Text
+		questionResolved pgtype.Timestamptz
 	)
 	pgQuery := getGameByFilter(filter, offset, limit, query)
 
@@ -425,12 +492,13 @@ func (db *DB) GetGameByFilter(ctx context.Context, filter string, filterValue st
 
 	for rows.Next() {
 		err := rows.Scan(
-			&gameID, &gameName, &gameIsFinished, &gameCreatorID, &gameTemplateID,
+			&gameID, &gameName, &gameIsFinished, &gameStatus, &gameMinPlayers, &gameVersion, &gameCreatorID, &gameTemplateID,
 			&gameCurrentRoundID, &gameCurrentQuestionID, &gameCurrentUserID,
-			&gameFinishDate, &gameWinnerID, &gameWinnerName, &gameCreatedAt,
-			&roundID, &roundName, &roundTimeJSON, &roundRankJSON, &roundPosition,
+			&gameFinishDate, &gameTieBreakers, &gameRulesJSON, &gameScheduledAt, &gameTimezone, &gameReminderOffsets,
+			&gameMode, &gameDeadline, &gameQuestionSeconds, &gameCasual, &gameWinnerID, &gameWinnerName, &gameCreatedAt,
+			&roundID, &roundName, &roundTimeJSON, &roundRankJSON, &roundRulesJSON, &roundIsFinal, &roundPosition,
 			&themeID, &themeName, &themePosition,
-			&questionID, &questionText, &questionAnswer, &questionPoints,
+			&questionID, &questionText, &questionAnswer, &questionPoints, &questionType, &questionResolved,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan game template row: %w", err)
@@ -449,6 +517,11 @@ func (db *DB) GetGameByFilter(ctx context.Context, filter string, filterValue st
 				ID:              gameIDStr,
 				Name:            gameName.String,
 				IsFinished:      gameIsFinished.Bool,
+				Status:          gameStatus.String,
+				Mode:            gameMode.String,
+				Casual:          gameCasual.Bool,
+				MinPlayers:      int(gameMinPlayers.Int),
+				Version:         int(gameVersion.Int),
 				CurrentRound:    uuidToString(gameCurrentRoundID),
 				CurrentQuestion: uuidToString(gameCurrentQuestionID),
 				CurrentUser:     uuidToString(gameCurrentUserID),
@@ -456,12 +529,27 @@ func (db *DB) GetGameByFilter(ctx context.Context, filter string, filterValue st
 					ID:   uuidToString(gameWinnerID),
 					Name: gameWinnerName.String,
 				},
-				Rounds:     []types.RoundClient{},
-				CreatorID:  uuidToString(gameCreatorID),
-				TemplateID: uuidToString(gameTemplateID),
-				CreatedAt:  gameCreatedAt.Time.UnixMilli(),
+				Rounds:      []types.RoundClient{},
+				CreatorID:   uuidToString(gameCreatorID),
+				TemplateID:  uuidToString(gameTemplateID),
+				TieBreakers: gameTieBreakers,
+				Rules:       unmarshalGameRules(gameIDStr, gameRulesJSON).Client(),
+				CreatedAt:   gameCreatedAt.Time.UnixMilli(),
 			}
 
+			if gameScheduledAt.Status == pgtype.Present {
+				game.Schedule = &types.GameScheduleClient{
+					StartsAt:        gameScheduledAt.Time.UnixMilli(),
+					Timezone:        gameTimezone.String,
+					ReminderOffsets: gameReminderOffsets,
+				}
+			}
+			if gameDeadline.Status == pgtype.Present {
+				game.Async = &types.AsyncSettingsClient{
+					Deadline:        gameDeadline.Time.UnixMilli(),
+					QuestionSeconds: int(gameQuestionSeconds.Int),
+				}
+			}
 			if gameFinishDate.Status == pgtype.Present {
 				game.FinishDate = gameFinishDate.Time.UnixMilli()
 			}
@@ -495,6 +583,8 @@ func (db *DB) GetGameByFilter(ctx context.Context, filter string, filterValue st
 				Name:         roundName.String,
 				TimeSettings: timeSetting,
 				RankSettings: rankSetting,
+				Rules:        unmarshalRoundRules(roundIDStr, roundRulesJSON),
+				IsFinal:      roundIsFinal.Bool,
 			}
 			roundsMap[gameIDStr][roundIDStr] = round
 
@@ -518,6 +608,10 @@ func (db *DB) GetGameByFilter(ctx context.Context, filter string, filterValue st
 						Text:    questionText.String,
 						Answer:  questionAnswer.String,
 						Points:  uint16(questionPoints.Int),
+						Type:    types.QuestionType(questionType.String),
+					}
+					if questionResolved.Status == pgtype.Present {
+						question.ResolvedAt = &questionResolved.Time
 					}
 					questionsMap[gameIDStr][questionIDStr] = question
 					questionIds = append(questionIds, questionIDStr)
@@ -540,6 +634,21 @@ func (db *DB) GetGameByFilter(ctx context.Context, filter string, filterValue st
 		return nil, fmt.Errorf("failed to get answers: %w", err)
 	}
 
+	plays, err := db.GetQuestionPlaysByQuestionIds(ctx, questionIds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get question plays: %w", err)
+	}
+
+	attachments, err := getQuestionMedia(ctx, db.pool, questionMediaTable, questionIds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get question media: %w", err)
+	}
+
+	options, err := getQuestionOptions(ctx, db.pool, questionOptionsTable, questionIds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get question options: %w", err)
+	}
+
 	// Process each game in the original order
 	for _, game := range orderedGames {
 		gameID := game.ID
@@ -556,6 +665,11 @@ func (db *DB) GetGameByFilter(ctx context.Context, filter string, filterValue st
 								Text:       question.Text,
 								Answer:     question.Answer,
 								Points:     question.Points,
+								Type:       string(question.Type),
+								Play:       plays[question.ID],
+								Media:      attachments[question.ID],
+								Options:    options[question.ID],
+								Resolved:   question.ResolvedAt != nil,
 								AnsweredBy: make(map[string]types.AnsweredByClient),
 							}
 
@@ -603,11 +717,13 @@ func (db *DB) GetGameByFilter(ctx context.Context, filter string, filterValue st
 			}
 
 			roundClient := types.RoundClient{
-				Id:     round.ID,
-				Name:   round.Name,
-				Ranks:  roundRanks,
-				Time:   timeClient,
-				Themes: roundThemes,
+				Id:      round.ID,
+				Name:    round.Name,
+				Ranks:   roundRanks,
+				Time:    timeClient,
+				Rules:   types.RoundRulesClient{NegativePoints: round.Rules.NegativePoints},
+				IsFinal: round.IsFinal,
+				Themes:  roundThemes,
 			}
 
 			game.Rounds = append(game.Rounds, roundClient)
@@ -619,6 +735,14 @@ func (db *DB) GetGameByFilter(ctx context.Context, filter string, filterValue st
 		}
 		game.Users = users
 
+		teams, err := db.GetTeamsByGameId(ctx, game.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get teams by game id: %w", err)
+		}
+		if len(teams) > 0 {
+			game.Teams = teams
+		}
+
 		games = append(games, game)
 	}
 
@@ -628,14 +752,14 @@ func (db *DB) GetGameByFilter(ctx context.Context, filter string, filterValue st
 func (db *DB) GetGameInvitesByUserId(ctx context.Context, userID string) ([]types.GameInviteClient, error) {
 	query := `
 		SELECT
-			gi.id, gi.game_id, gi.user_id, gi.status, gi.created_at, gi.updated_at,
+			gi.id, gi.game_id, gi.user_id, gi.status, gi.created_at, gi.updated_at, gi.expires_at,
 			g.name as game_name, u.name as game_creator_name
 		FROM
 			game_invites gi
 		JOIN games g ON g.id = gi.game_id
 		JOIN users u ON u.id = g.creator_id
 		WHERE
-			gi.user_id = $1 AND gi.status = 'pending'
+			gi.user_id = $1 AND gi.status = 'pending' AND gi.expires_at > now()
 		ORDER BY gi.created_at DESC
 	`
 
@@ -648,7 +772,7 @@ func (db *DB) GetGameInvitesByUserId(ctx context.Context, userID string) ([]type
 	var gameInvites []types.GameInviteClient
 	for rows.Next() {
 		var gameInvite types.GameInviteClient
-		err := rows.Scan(&gameInvite.ID, &gameInvite.GameID, &gameInvite.UserID, &gameInvite.Status, &gameInvite.CreatedAt, &gameInvite.UpdatedAt, &gameInvite.GameName, &gameInvite.GameCreatorName)
+		err := rows.Scan(&gameInvite.ID, &gameInvite.GameID, &gameInvite.UserID, &gameInvite.Status, &gameInvite.CreatedAt, &gameInvite.UpdatedAt, &gameInvite.ExpiresAt, &gameInvite.GameName, &gameInvite.GameCreatorName)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan game invite: %w", err)
 		}
@@ -670,7 +794,7 @@ func (db *DB) GetPendingGameUsersByGameId(ctx context.Context, gameID string) ([
 			game_invites gi
 		JOIN users u ON u.id = gi.user_id
 		WHERE
-			gi.game_id = $1 AND gi.status = 'pending'
+			gi.game_id = $1 AND gi.status = 'pending' AND gi.expires_at > now()
 	`
 
 	rows, err := db.pool.Query(ctx, query, gameID)
@@ -696,53 +820,47 @@ func (db *DB) GetPendingGameUsersByGameId(ctx context.Context, gameID string) ([
 	return pendingGameUsers, nil
 }
 
+// RemoveUserFromGame takes a player out of a game and revokes their invite,
+// so they can be invited again later.
 func (db *DB) RemoveUserFromGame(ctx context.Context, gameID string, userID string) error {
-	_, err := db.pool.Exec(ctx, "DELETE FROM game_users WHERE game_id = $1 AND user_id = $2", gameID, userID)
+	tx, err := db.pool.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to remove user from game: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	return nil
-}
+	defer tx.Rollback(ctx)
 
-func (db *DB) AddUserToGame(ctx context.Context, gameID string, userID string) error {
-	_, err := db.pool.Exec(ctx, "INSERT INTO game_users (game_id, user_id) VALUES ($1, $2)", gameID, userID)
-	if err != nil {
+	if _, err := tx.Exec(ctx, "DELETE FROM game_users WHERE game_id = $1 AND user_id = $2", gameID, userID); err != nil {
 		return fmt.Errorf("failed to remove user from game: %w", err)
 	}
-	return nil
-}
 
-func (db *DB) SendGameInvite(ctx context.Context, gameID string, userID string) error {
-	_, err := db.pool.Exec(ctx, "INSERT INTO game_invites (game_id, user_id) VALUES ($1, $2)", gameID, userID)
+	_, err = tx.Exec(ctx, `
+		UPDATE game_invites SET status = 'revoked', updated_at = now()
+		WHERE game_id = $1 AND user_id = $2 AND status IN ('pending', 'accepted')
+	`, gameID, userID)
 	if err != nil {
-		return fmt.Errorf("failed to send game invite: %w", err)
+		return fmt.Errorf("failed to revoke game invite: %w", err)
 	}
-	return nil
-}
 
-func (db *DB) AcceptGameInvite(ctx context.Context, inviteID string, gameID string, userID string) error {
-	_, err := db.pool.Exec(ctx, "UPDATE game_invites SET status = 'accepted' WHERE id = $1", inviteID)
-	if err != nil {
-		return fmt.Errorf("failed to accept game invite: %w", err)
-	}
-
-	err = db.AddUserToGame(ctx, gameID, userID)
-	if err != nil {
-		return fmt.Errorf("failed to add user to game: %w", err)
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
-
 	return nil
 }
 
 func (db *DB) GetUnconfirmedUsersByGameId(ctx context.Context, gameID string) ([]types.UnconfirmedUserClient, error) {
 	query := `
-		SELECT
-			u.id, u.name, gi.status
-		FROM
-			game_invites gi
-		JOIN users u ON u.id = gi.user_id
-		WHERE
-			gi.game_id = $1 AND gi.status != 'accepted'
+		SELECT id, name, status
+		FROM (
+			SELECT DISTINCT ON (gi.user_id)
+				u.id, u.name, ` + inviteStatusColumn + ` AS status
+			FROM
+				game_invites gi
+			JOIN users u ON u.id = gi.user_id
+			WHERE
+				gi.game_id = $1
+			ORDER BY gi.user_id, gi.created_at DESC
+		) latest
+		WHERE status NOT IN ('accepted', 'revoked')
 	`
 
 	rows, err := db.pool.Query(ctx, query, gameID)
@@ -768,29 +886,98 @@ func (db *DB) GetUnconfirmedUsersByGameId(ctx context.Context, gameID string) ([
 	return unconfirmedUsers, nil
 }
 
-func (db *DB) DeclineGameInvite(ctx context.Context, inviteID string) error {
-	_, err := db.pool.Exec(ctx, "UPDATE game_invites SET status = 'declined' WHERE id = $1", inviteID)
+func (db *DB) DeleteGame(ctx context.Context, gameID string) error {
+	_, err := db.pool.Exec(ctx, "DELETE FROM games WHERE id = $1", gameID)
 	if err != nil {
-		return fmt.Errorf("failed to decline game invite: %w", err)
+		return fmt.Errorf("failed to delete game: %w", err)
 	}
 	return nil
 }
 
-func (db *DB) DeleteGame(ctx context.Context, gameID string) error {
-	_, err := db.pool.Exec(ctx, "DELETE FROM games WHERE id = $1", gameID)
+// nextPicker applies the game's next-picker rule to a client update. The
+// client's choice is only taken when the host picks every question or nobody
+// has picked yet; otherwise the picker moves on once the current question is
+// closed and stays put while it is being played. Players take turns in the
+// order they joined the game.
+func nextPicker(ctx context.Context, tx pgx.Tx, game types.GameServer) (string, error) {
+	var (
+		storedQuestionID *string
+		storedPickerID   *string
+		rulesJSON        []byte
+	)
+	err := tx.QueryRow(ctx, "SELECT current_question_id::text, current_user_id::text, rules FROM games WHERE id = $1 FOR UPDATE", game.ID).Scan(&storedQuestionID, &storedPickerID, &rulesJSON)
 	if err != nil {
-		return fmt.Errorf("failed to delete game: %w", err)
+		return "", fmt.Errorf("failed to get game turn: %w", err)
 	}
-	return nil
+	rules := unmarshalGameRules(game.ID, rulesJSON)
+
+	if rules.NextPicker == types.PickerHost || storedPickerID == nil {
+		return game.CurrentUserID, nil
+	}
+	if storedQuestionID == nil || *storedQuestionID == game.CurrentQuestionID {
+		return *storedPickerID, nil
+	}
+
+	rows, err := tx.Query(ctx, "SELECT user_id FROM game_users WHERE game_id = $1 ORDER BY created_at, user_id", game.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to query game users: %w", err)
+	}
+	players, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return "", fmt.Errorf("failed to scan game users: %w", err)
+	}
+
+	rows, err = tx.Query(ctx, "SELECT user_id, is_correct FROM answers WHERE question_id = $1 ORDER BY attempt NULLS LAST, created_at, id", *storedQuestionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to query answers: %w", err)
+	}
+	answers, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (scoring.Answer, error) {
+		answer := scoring.Answer{QuestionID: *storedQuestionID}
+		err := row.Scan(&answer.UserID, &answer.IsCorrect)
+		return answer, err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to scan answers: %w", err)
+	}
+
+	return scoring.NextPicker(rules, players, *storedPickerID, answers), nil
 }
 
-func (db *DB) UpdateGameAndGameUsers(ctx context.Context, game types.GameServer, users []types.GameUserServer, answers []types.AnswerServer) error {
+// UpdateGameAndGameUsers stores the game pointers and answers sent by the
+// client. Scores are not taken from the client; they are recomputed from the
+// stored answers once the answers are written. game.Version must be the
+// version the client last read; otherwise nothing is written and the current
+// version is returned with ErrVersionConflict. On success the new version is
+// returned.
+func (db *DB) UpdateGameAndGameUsers(ctx context.Context, game types.GameServer, answers []types.AnswerServer) (int, error) {
 	tx, err := db.pool.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
+	var (
+		status  types.GameStatus
+		mode    types.GameMode
+		version int
+	)
+	err = tx.QueryRow(ctx, "SELECT status, mode, version FROM games WHERE id = $1 FOR UPDATE", game.ID).Scan(&status, &mode, &version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get game: %w", err)
+	}
+	if version != game.Version {
+		return version, ErrVersionConflict
+	}
+
+	// Answers are only taken while the game is being played; the lobby may
+	// still be renamed and finished games corrected.
+	if status == types.GameAbandoned || (len(answers) > 0 && (status == types.GameLobby || status == types.GamePaused)) {
+		return 0, ErrGameNotInProgress
+	}
+	if len(answers) > 0 && mode == types.GameModeAsync {
+		return 0, ErrAsyncGame
+	}
+
 	currentRoundID := interface{}(nil)
 	if game.CurrentRoundID != "" {
 		currentRoundID = game.CurrentRoundID
@@ -801,49 +988,105 @@ func (db *DB) UpdateGameAndGameUsers(ctx context.Context, game types.GameServer,
 		currentQuestionID = game.CurrentQuestionID
 	}
 
-	currentUserID := interface{}(nil)
-	if game.CurrentUserID != "" {
-		currentUserID = game.CurrentUserID
-	}
-
-	_, err = tx.Exec(ctx, "UPDATE games SET name = $1, current_round_id = $2, current_question_id = $3, current_user_id = $4 WHERE id = $5", game.Name, currentRoundID, currentQuestionID, currentUserID, game.ID)
-	if err != nil {
-		return fmt.Errorf("failed to update game: %w", err)
-	}
+	// Multiple-choice answers are graded by SubmitChoiceAnswer and are never
+	// taken from the client.
+	for _, answer := range answers {
+		if _, err := getGameQuestion(ctx, tx, game.ID, answer.QuestionID); err != nil {
+			return 0, err
+		}
+		ok, err := isGameUser(ctx, tx, game.ID, answer.UserID)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			return 0, ErrUserNotInGame
+		}
 
-	for _, user := range users {
-		_, err = tx.Exec(ctx, "UPDATE game_users SET round_scores = $1 WHERE game_id = $2 AND user_id = $3", user.RoundScore, game.ID, user.UserID)
+		// New answers must respect the team rules; answers already stored may
+		// still be corrected even if the team's captain has changed since.
+		var exists bool
+		err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM answers WHERE question_id = $1 AND user_id = $2)", answer.QuestionID, answer.UserID).Scan(&exists)
 		if err != nil {
-			return fmt.Errorf("failed to update game user: %w", err)
+			return 0, fmt.Errorf("failed to check answer: %w", err)
+		}
+		if !exists {
+			if err := checkTeamAnswer(ctx, tx, game.ID, answer.UserID, answer.ContributorID); err != nil {
+				return 0, err
+			}
+		}
+
+		contributorID := interface{}(nil)
+		if answer.ContributorID != "" {
+			contributorID = answer.ContributorID
 		}
-	}
 
-	for _, answer := range answers {
 		_, err = tx.Exec(ctx, `
-			INSERT INTO answers (question_id, user_id, is_correct, time_answered) 
-			VALUES ($1, $2, $3, $4)
+			INSERT INTO answers (question_id, user_id, is_correct, time_answered, contributor_id) 
+			SELECT $1, $2, $3, $4, $5
+			WHERE NOT EXISTS (SELECT 1 FROM questions WHERE id = $1 AND question_type = 'multiple_choice')
 			ON CONFLICT (question_id, user_id) 
 			DO UPDATE SET 
 				is_correct = EXCLUDED.is_correct,
-				time_answered = EXCLUDED.time_answered
-		`, answer.QuestionID, answer.UserID, answer.IsCorrect, answer.TimeAnswered)
+				time_answered = EXCLUDED.time_answered,
+				contributor_id = EXCLUDED.contributor_id
+		`, answer.QuestionID, answer.UserID, answer.IsCorrect, answer.TimeAnswered, contributorID)
 		if err != nil {
-			return fmt.Errorf("failed to upsert answer: %w", err)
+			return 0, fmt.Errorf("failed to upsert answer: %w", err)
 		}
 	}
 
+	var previousPickerID *string
+	if err := tx.QueryRow(ctx, "SELECT current_user_id::text FROM games WHERE id = $1", game.ID).Scan(&previousPickerID); err != nil {
+		return 0, fmt.Errorf("failed to get current picker: %w", err)
+	}
+
+	pickerID, err := nextPicker(ctx, tx, game)
+	if err != nil {
+		return 0, err
+	}
+	if err := notifyTurn(ctx, tx, game.ID, previousPickerID, pickerID); err != nil {
+		return 0, err
+	}
+
+	currentUserID := interface{}(nil)
+	if pickerID != "" {
+		currentUserID = pickerID
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE games SET name = $1, current_round_id = $2, current_question_id = $3, current_user_id = $4, last_activity_at = now() WHERE id = $5", game.Name, currentRoundID, currentQuestionID, currentUserID, game.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update game: %w", err)
+	}
+
+	if _, err := storeGameScores(ctx, tx, game.ID); err != nil {
+		return 0, fmt.Errorf("failed to recompute scores: %w", err)
+	}
+
+	if err := tx.QueryRow(ctx, "SELECT version FROM games WHERE id = $1", game.ID).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to get game version: %w", err)
+	}
+
 	err = tx.Commit(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
 	}
-	return nil
+	return version, nil
 }
 
-func (db *DB) FinishGame(ctx context.Context, gameID string, winningUserID string) error {
-	logger.Info("Finishing game", "gameID", gameID, "winningUserID", winningUserID)
-	_, err := db.pool.Exec(ctx, "UPDATE games SET is_finished = true, winner_id = $1, finish_date = NOW() WHERE id = $2", winningUserID, gameID)
+func (db *DB) GetGameCreatorID(ctx context.Context, gameID string) (string, error) {
+	var creatorID string
+	err := db.pool.QueryRow(ctx, "SELECT creator_id FROM games WHERE id = $1", gameID).Scan(&creatorID)
 	if err != nil {
-		return fmt.Errorf("failed to finish game: %w", err)
+		return "", fmt.Errorf("failed to get game creator: %w", err)
 	}
-	return nil
+	return creatorID, nil
+}
+
+func (db *DB) GetGameName(ctx context.Context, gameID string) (string, error) {
+	var name string
+	err := db.pool.QueryRow(ctx, "SELECT name FROM games WHERE id = $1", gameID).Scan(&name)
+	if err != nil {
+		return "", fmt.Errorf("failed to get game name: %w", err)
+	}
+	return name, nil
 }
@@ -0,0 +1,287 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mindwarp/types"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrInviteNotFound   = errors.New("game invite not found")
+	ErrInviteExpired    = errors.New("game invite has expired")
+	ErrInviteRevoked    = errors.New("game invite was revoked")
+	ErrInviteNotPending = errors.New("game invite has already been answered")
+	ErrInviteExists     = errors.New("user already has an open invite to this game")
+	ErrAlreadyInGame    = errors.New("user already plays in this game")
+	ErrGameClosed       = errors.New("game no longer takes new players")
+)
+
+// inviteStatusColumn reports pending invites past their expiry as expired
+// before the sweeper has marked them.
+const inviteStatusColumn = `CASE WHEN gi.status = 'pending' AND gi.expires_at <= now() THEN 'expired' ELSE gi.status::text END`
+
+// SendGameInvite invites a player to a game. A player may be invited again
+// after declining or after an earlier invite expired or was revoked.
+func (db *DB) SendGameInvite(ctx context.Context, gameID string, userID string, invitedBy string) (string, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var status types.GameStatus
+	err = tx.QueryRow(ctx, "SELECT status FROM games WHERE id = $1 FOR UPDATE", gameID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrGameNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get game: %w", err)
+	}
+	if status == types.GameFinished || status == types.GameAbandoned {
+		return "", ErrGameClosed
+	}
+
+	ok, err := isGameUser(ctx, tx, gameID, userID)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return "", ErrAlreadyInGame
+	}
+
+	// A lapsed invite must not block the new one
+	_, err = tx.Exec(ctx, `
+		UPDATE game_invites SET status = 'expired', updated_at = now()
+		WHERE game_id = $1 AND user_id = $2 AND status = 'pending' AND expires_at <= now()
+	`, gameID, userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to expire old invite: %w", err)
+	}
+
+	var inviteID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO game_invites (game_id, user_id, invited_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (game_id, user_id) WHERE status IN ('pending', 'accepted') DO NOTHING
+		RETURNING id
+	`, gameID, userID, invitedBy).Scan(&inviteID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrInviteExists
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to send game invite: %w", err)
+	}
+
+	err = notify(ctx, tx, types.NotificationServer{
+		UserID:  userID,
+		Type:    types.NotificationInviteReceived,
+		GameID:  gameID,
+		ActorID: invitedBy,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return inviteID, nil
+}
+
+// lockOpenInvite locks one of the user's invites and checks it can still be
+// answered. Invites of other users are reported as not found.
+func lockOpenInvite(ctx context.Context, tx pgx.Tx, inviteID string, userID string) (string, error) {
+	var (
+		gameID     string
+		ownerID    string
+		status     types.InviteStatus
+		lapsed     bool
+		gameStatus types.GameStatus
+	)
+	err := tx.QueryRow(ctx, `
+		SELECT gi.game_id::text, gi.user_id::text, gi.status, gi.expires_at <= now(), g.status
+		FROM game_invites gi
+		JOIN games g ON g.id = gi.game_id
+		WHERE gi.id = $1
+		FOR UPDATE OF gi
+	`, inviteID).Scan(&gameID, &ownerID, &status, &lapsed, &gameStatus)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrInviteNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get invite: %w", err)
+	}
+	if ownerID != userID {
+		return "", ErrInviteNotFound
+	}
+
+	switch {
+	case status == types.InviteRevoked:
+		return "", ErrInviteRevoked
+	case status == types.InviteExpired, status == types.InvitePending && lapsed:
+		return "", ErrInviteExpired
+	case status != types.InvitePending:
+		return "", ErrInviteNotPending
+	case gameStatus == types.GameFinished || gameStatus == types.GameAbandoned:
+		return "", ErrGameClosed
+	}
+	return gameID, nil
+}
+
+// AcceptGameInvite joins the invited user to the game. Only the invitee may
+// accept, and only while the invite is pending and not expired.
+func (db *DB) AcceptGameInvite(ctx context.Context, inviteID string, userID string) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	gameID, err := lockOpenInvite(ctx, tx, inviteID, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE game_invites SET status = 'accepted', responded_at = now(), updated_at = now() WHERE id = $1", inviteID)
+	if err != nil {
+		return fmt.Errorf("failed to accept game invite: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, "INSERT INTO game_users (game_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", gameID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to add user to game: %w", err)
+	}
+	if err := touchGame(ctx, tx, gameID); err != nil {
+		return err
+	}
+
+	var creatorID string
+	if err := tx.QueryRow(ctx, "SELECT creator_id::text FROM games WHERE id = $1", gameID).Scan(&creatorID); err != nil {
+		return fmt.Errorf("failed to get game creator: %w", err)
+	}
+	if creatorID != userID {
+		err := notify(ctx, tx, types.NotificationServer{
+			UserID:  creatorID,
+			Type:    types.NotificationInviteAccepted,
+			GameID:  gameID,
+			ActorID: userID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) DeclineGameInvite(ctx context.Context, inviteID string, userID string) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := lockOpenInvite(ctx, tx, inviteID, userID); err != nil && !errors.Is(err, ErrGameClosed) {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE game_invites SET status = 'declined', responded_at = now(), updated_at = now() WHERE id = $1", inviteID)
+	if err != nil {
+		return fmt.Errorf("failed to decline game invite: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// RevokeGameInvite withdraws a pending invite of the game.
+func (db *DB) RevokeGameInvite(ctx context.Context, gameID string, inviteID string) error {
+	var status types.InviteStatus
+	err := db.pool.QueryRow(ctx, "SELECT status FROM game_invites WHERE id = $1 AND game_id = $2", inviteID, gameID).Scan(&status)
+	if err != nil {
+		return ErrInviteNotFound
+	}
+
+	tag, err := db.pool.Exec(ctx, `
+		UPDATE game_invites SET status = 'revoked', updated_at = now()
+		WHERE id = $1 AND status = 'pending'
+	`, inviteID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke game invite: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInviteNotPending
+	}
+	return nil
+}
+
+// GetGameInviteHistory lists every invite of a game, newest first, including
+// the ones that were declined, expired or revoked.
+func (db *DB) GetGameInviteHistory(ctx context.Context, gameID string) ([]types.GameInviteClient, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT
+			gi.id, gi.game_id, gi.user_id, u.name, `+inviteStatusColumn+`,
+			COALESCE(gi.invited_by::text, ''), gi.created_at, gi.updated_at, gi.expires_at, gi.responded_at,
+			g.name, COALESCE(c.name, '')
+		FROM game_invites gi
+		JOIN users u ON u.id = gi.user_id
+		JOIN games g ON g.id = gi.game_id
+		LEFT JOIN users c ON c.id = g.creator_id
+		WHERE gi.game_id = $1
+		ORDER BY gi.created_at DESC, gi.id
+	`, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query game invite history: %w", err)
+	}
+	defer rows.Close()
+
+	invites := make([]types.GameInviteClient, 0)
+	for rows.Next() {
+		var invite types.GameInviteClient
+		err := rows.Scan(
+			&invite.ID, &invite.GameID, &invite.UserID, &invite.UserName, &invite.Status,
+			&invite.InvitedBy, &invite.CreatedAt, &invite.UpdatedAt, &invite.ExpiresAt, &invite.RespondedAt,
+			&invite.GameName, &invite.GameCreatorName,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan game invite: %w", err)
+		}
+		invites = append(invites, invite)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating game invites rows: %w", err)
+	}
+
+	return invites, nil
+}
+
+// ExpireGameInvites marks pending invites, by account or by email, past
+// their expiry as expired and returns how many were.
+func (db *DB) ExpireGameInvites(ctx context.Context) (int64, error) {
+	tag, err := db.pool.Exec(ctx, `
+		UPDATE game_invites SET status = 'expired', updated_at = now()
+		WHERE status = 'pending' AND expires_at <= now()
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire game invites: %w", err)
+	}
+	expired := tag.RowsAffected()
+
+	tag, err = db.pool.Exec(ctx, `
+		UPDATE email_invites SET status = 'expired', updated_at = now()
+		WHERE status = 'pending' AND expires_at <= now()
+	`)
+	if err != nil {
+		return expired, fmt.Errorf("failed to expire email invites: %w", err)
+	}
+	return expired + tag.RowsAffected(), nil
+}
@@ -0,0 +1,263 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mindwarp/types"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrUnknownLeaderboard = errors.New("unknown leaderboard")
+
+// leaderboardMinGames keeps players with a lucky game or two off the
+// leaderboards that rank averages.
+const leaderboardMinGames = 3
+
+// gameResultsSQL is the CTE of the players of game $1 with the place they
+// finished in, their team's in team games, and the points they scored
+// themselves.
+const gameResultsSQL = `
+	results AS (
+		SELECT gu.user_id, COALESCE(gs.place, gts.place) AS place,
+			COALESCE((SELECT SUM(value::int) FROM jsonb_each_text(gu.round_scores)), 0) AS score
+		FROM game_users gu
+		LEFT JOIN game_standings gs ON gs.game_id = gu.game_id AND gs.user_id = gu.user_id
+		LEFT JOIN game_team_standings gts ON gts.game_id = gu.game_id AND gts.team_id = gu.team_id
+		WHERE gu.game_id = $1
+	)
+`
+
+// recordLeaderboards adds a finished game to the leaderboard totals of its
+// ranked players for all time and the month and week it finished in, and
+// keeps their best scores on its template.
+func recordLeaderboards(ctx context.Context, tx pgx.Tx, gameID string) error {
+	_, err := tx.Exec(ctx, `
+		WITH `+gameResultsSQL+`,
+		answered AS (
+			SELECT a.user_id, COUNT(*) AS answered, COUNT(*) FILTER (WHERE a.is_correct) AS correct
+			FROM answers a
+			JOIN questions q ON q.id = a.question_id
+			JOIN themes t ON t.id = q.theme_id
+			JOIN rounds r ON r.id = t.round_id
+			WHERE r.game_id = $1 AND a.is_correct IS NOT NULL
+			GROUP BY a.user_id
+		),
+		shares AS (
+			SELECT r.user_id, r.place,
+				COALESCE(GREATEST(r.score, 0)::float8 / NULLIF(SUM(GREATEST(r.score, 0)) OVER (), 0), 0) AS share,
+				COALESCE(a.answered, 0) AS answered, COALESCE(a.correct, 0) AS correct
+			FROM results r
+			LEFT JOIN answered a ON a.user_id = r.user_id
+			WHERE r.place IS NOT NULL
+		)
+		INSERT INTO leaderboard_stats (period, period_start, user_id, games, wins, score_share_sum, answered, correct)
+		SELECT p.period, p.period_start, s.user_id, 1, (s.place = 1)::int, s.share, s.answered, s.correct
+		FROM shares s
+		JOIN games g ON g.id = $1
+		CROSS JOIN LATERAL (VALUES
+			('all_time'::leaderboard_period, DATE '1970-01-01'),
+			('monthly'::leaderboard_period, date_trunc('month', g.finish_date AT TIME ZONE 'UTC')::date),
+			('weekly'::leaderboard_period, date_trunc('week', g.finish_date AT TIME ZONE 'UTC')::date)
+		) AS p(period, period_start)
+		ON CONFLICT (period, period_start, user_id) DO UPDATE SET
+			games = leaderboard_stats.games + EXCLUDED.games,
+			wins = leaderboard_stats.wins + EXCLUDED.wins,
+			score_share_sum = leaderboard_stats.score_share_sum + EXCLUDED.score_share_sum,
+			answered = leaderboard_stats.answered + EXCLUDED.answered,
+			correct = leaderboard_stats.correct + EXCLUDED.correct
+	`, gameID)
+	if err != nil {
+		return fmt.Errorf("failed to update leaderboard stats: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		WITH `+gameResultsSQL+`
+		INSERT INTO template_best_scores (template_id, user_id, score, game_id, achieved_at)
+		SELECT g.template_id, r.user_id, r.score, g.id, g.finish_date
+		FROM results r
+		JOIN games g ON g.id = $1
+		WHERE g.template_id IS NOT NULL
+		ON CONFLICT (template_id, user_id) DO UPDATE SET
+			score = EXCLUDED.score,
+			game_id = EXCLUDED.game_id,
+			achieved_at = EXCLUDED.achieved_at
+		WHERE EXCLUDED.score > template_best_scores.score
+	`, gameID)
+	if err != nil {
+		return fmt.Errorf("failed to update template best scores: %w", err)
+	}
+
+	return nil
+}
+
+// leaderboardPeriodStart returns the first day of the period containing
+// day, matching the UTC months and ISO weeks the totals are kept in.
+func leaderboardPeriodStart(period types.LeaderboardPeriod, day time.Time) time.Time {
+	day = day.UTC()
+	switch period {
+	case types.PeriodMonthly:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case types.PeriodWeekly:
+		sinceMonday := (int(day.Weekday()) + 6) % 7
+		return time.Date(day.Year(), day.Month(), day.Day()-sinceMonday, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Unix(0, 0).UTC()
+	}
+}
+
+// leaderboardSQL ranks the players of a period, $1 and $2, by metric;
+// callers append the filter on the ranked rows.
+func leaderboardSQL(metric types.LeaderboardMetric) (string, error) {
+	var order, filter string
+	switch metric {
+	case types.MetricWins:
+		order = "wins DESC"
+	case types.MetricScoreShare:
+		order = "score_share DESC"
+		filter = fmt.Sprintf("AND ls.games >= %d", leaderboardMinGames)
+	case types.MetricAccuracy:
+		order = "accuracy DESC"
+		filter = fmt.Sprintf("AND ls.games >= %d AND ls.answered > 0", leaderboardMinGames)
+	default:
+		return "", ErrUnknownLeaderboard
+	}
+
+	return `
+		WITH entries AS (
+			SELECT ls.user_id::text AS user_id, u.name, ls.games, ls.wins,
+				ls.score_share_sum / ls.games AS score_share,
+				COALESCE(ls.correct::float8 / NULLIF(ls.answered, 0), 0) AS accuracy
+			FROM leaderboard_stats ls
+			JOIN users u ON u.id = ls.user_id
+			WHERE ls.period = $1 AND ls.period_start = $2 AND ls.games > 0 ` + filter + `
+		),
+		ranked AS (
+			SELECT RANK() OVER (ORDER BY ` + order + `) AS rank, * FROM entries
+		)
+		SELECT rank, user_id, name, games, wins, score_share, accuracy FROM ranked
+	`, nil
+}
+
+func scanLeaderboardEntry(row pgx.Row) (types.LeaderboardEntryClient, error) {
+	var entry types.LeaderboardEntryClient
+	err := row.Scan(&entry.Rank, &entry.UserID, &entry.Name, &entry.Games, &entry.Wins, &entry.ScoreShare, &entry.Accuracy)
+	return entry, err
+}
+
+// GetLeaderboard returns a page of the players of the period containing day
+// ranked by metric, with userID's entry. Average metrics only rank players
+// with a few games in the period.
+func (db *DB) GetLeaderboard(ctx context.Context, period types.LeaderboardPeriod, metric types.LeaderboardMetric, day time.Time, userID string, offset int, limit int) (types.LeaderboardClient, error) {
+	board := types.LeaderboardClient{Period: string(period), Metric: string(metric), Entries: make([]types.LeaderboardEntryClient, 0)}
+
+	query, err := leaderboardSQL(metric)
+	if err != nil {
+		return board, err
+	}
+	start := leaderboardPeriodStart(period, day)
+	if period != types.PeriodAllTime {
+		board.PeriodStart = start.Format(time.DateOnly)
+	}
+
+	if err := db.pool.QueryRow(ctx, "SELECT COUNT(*) FROM ("+query+") board", string(period), start).Scan(&board.Total); err != nil {
+		return board, fmt.Errorf("failed to count leaderboard: %w", err)
+	}
+
+	rows, err := db.pool.Query(ctx, query+" ORDER BY rank, name, user_id OFFSET $3 LIMIT $4", string(period), start, offset, limit)
+	if err != nil {
+		return board, fmt.Errorf("failed to query leaderboard: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, err := scanLeaderboardEntry(rows)
+		if err != nil {
+			return board, fmt.Errorf("failed to scan leaderboard entry: %w", err)
+		}
+		board.Entries = append(board.Entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return board, fmt.Errorf("error iterating leaderboard rows: %w", err)
+	}
+
+	me, err := scanLeaderboardEntry(db.pool.QueryRow(ctx, query+" WHERE user_id = $3", string(period), start, userID))
+	if err == nil {
+		board.Me = &me
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return board, fmt.Errorf("failed to get own leaderboard entry: %w", err)
+	}
+
+	return board, nil
+}
+
+// templateLeaderboardSQL ranks the best scores on template $1; callers
+// append the filter on the ranked rows.
+const templateLeaderboardSQL = `
+	WITH ranked AS (
+		SELECT RANK() OVER (ORDER BY tbs.score DESC) AS rank,
+			tbs.user_id::text AS user_id, u.name, tbs.score,
+			COALESCE(tbs.game_id::text, '') AS game_id, tbs.achieved_at
+		FROM template_best_scores tbs
+		JOIN users u ON u.id = tbs.user_id
+		WHERE tbs.template_id = $1
+	)
+	SELECT rank, user_id, name, score, game_id, achieved_at FROM ranked
+`
+
+func scanTemplateScore(row pgx.Row) (types.TemplateScoreClient, error) {
+	var (
+		score      types.TemplateScoreClient
+		achievedAt time.Time
+	)
+	err := row.Scan(&score.Rank, &score.UserID, &score.Name, &score.Score, &score.GameID, &achievedAt)
+	score.AchievedAt = achievedAt.UnixMilli()
+	return score, err
+}
+
+// GetTemplateLeaderboard returns a page of the best scores ever made on a
+// template, highest first and earliest first among equal scores, with
+// userID's own.
+func (db *DB) GetTemplateLeaderboard(ctx context.Context, templateID string, userID string, offset int, limit int) (types.TemplateLeaderboardClient, error) {
+	board := types.TemplateLeaderboardClient{TemplateID: templateID, Entries: make([]types.TemplateScoreClient, 0)}
+
+	err := db.pool.QueryRow(ctx, `
+		SELECT name, (SELECT COUNT(*) FROM template_best_scores WHERE template_id = $1)
+		FROM game_templates
+		WHERE id = $1
+	`, templateID).Scan(&board.TemplateName, &board.Total)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return board, ErrTemplateNotFound
+	}
+	if err != nil {
+		return board, fmt.Errorf("failed to get template: %w", err)
+	}
+
+	rows, err := db.pool.Query(ctx, templateLeaderboardSQL+" ORDER BY rank, achieved_at, user_id OFFSET $2 LIMIT $3", templateID, offset, limit)
+	if err != nil {
+		return board, fmt.Errorf("failed to query template leaderboard: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		score, err := scanTemplateScore(rows)
+		if err != nil {
+			return board, fmt.Errorf("failed to scan template leaderboard entry: %w", err)
+		}
+		board.Entries = append(board.Entries, score)
+	}
+	if err := rows.Err(); err != nil {
+		return board, fmt.Errorf("error iterating template leaderboard rows: %w", err)
+	}
+
+	me, err := scanTemplateScore(db.pool.QueryRow(ctx, templateLeaderboardSQL+" WHERE user_id = $2", templateID, userID))
+	if err == nil {
+		board.Me = &me
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return board, fmt.Errorf("failed to get own template leaderboard entry: %w", err)
+	}
+
+	return board, nil
+}
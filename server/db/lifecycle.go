@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mindwarp/types"
+	"time"
+)
+
+var (
+	ErrInvalidTransition = errors.New("game cannot move to that status from its current one")
+	ErrNotEnoughPlayers  = errors.New("not enough players have accepted to start the game")
+	ErrGameNotInProgress = errors.New("game is not in progress")
+	ErrVersionConflict   = errors.New("game was changed by someone else")
+)
+
+// requireGameInProgress rejects live gameplay on games that are still in the
+// lobby, paused, finished or abandoned, and on async games, which are played
+// through their own endpoints. Otherwise it records the activity so the
+// sweeper leaves the game alone.
+func requireGameInProgress(ctx context.Context, q querier, gameID string) error {
+	var (
+		status types.GameStatus
+		mode   types.GameMode
+	)
+	err := q.QueryRow(ctx, "UPDATE games SET last_activity_at = now() WHERE id = $1 RETURNING status, mode", gameID).Scan(&status, &mode)
+	if err != nil {
+		return fmt.Errorf("failed to get game status: %w", err)
+	}
+	if status != types.GameInProgress {
+		return ErrGameNotInProgress
+	}
+	if mode == types.GameModeAsync {
+		return ErrAsyncGame
+	}
+	return nil
+}
+
+// touchGame records activity on a game so the sweeper leaves it alone, and
+// bumps its version.
+func touchGame(ctx context.Context, q querier, gameID string) error {
+	if _, err := q.Exec(ctx, "UPDATE games SET last_activity_at = now(), version = version + 1 WHERE id = $1", gameID); err != nil {
+		return fmt.Errorf("failed to touch game: %w", err)
+	}
+	return nil
+}
+
+// SetGameStatus moves a game to next if its current status allows it. A game
+// leaves the lobby only once min_players players have accepted. Finishing
+// goes through FinishGame, which also ranks the players.
+func (db *DB) SetGameStatus(ctx context.Context, gameID string, next types.GameStatus) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		status     types.GameStatus
+		minPlayers int
+		players    int
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT g.status, g.min_players, (SELECT COUNT(*) FROM game_users gu WHERE gu.game_id = g.id)
+		FROM games g
+		WHERE g.id = $1
+		FOR UPDATE
+	`, gameID).Scan(&status, &minPlayers, &players)
+	if err != nil {
+		return fmt.Errorf("failed to get game: %w", err)
+	}
+
+	if next == types.GameFinished || !status.CanBecome(next) {
+		return ErrInvalidTransition
+	}
+	if status == types.GameLobby && next == types.GameInProgress && players < minPlayers {
+		return ErrNotEnoughPlayers
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE games SET status = $1, last_activity_at = now(), version = version + 1 WHERE id = $2", string(next), gameID)
+	if err != nil {
+		return fmt.Errorf("failed to update game status: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// AbandonStaleGames marks every unfinished game without activity for longer
+// than idle as abandoned and returns their ids.
+func (db *DB) AbandonStaleGames(ctx context.Context, idle time.Duration) ([]string, error) {
+	rows, err := db.pool.Query(ctx, `
+		UPDATE games SET status = 'abandoned'
+		WHERE status IN ('lobby', 'in_progress', 'paused')
+			AND last_activity_at < now() - make_interval(secs => $1)
+		RETURNING id::text
+	`, idle.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to abandon stale games: %w", err)
+	}
+	defer rows.Close()
+
+	gameIDs := make([]string, 0)
+	for rows.Next() {
+		var gameID string
+		if err := rows.Scan(&gameID); err != nil {
+			return nil, fmt.Errorf("failed to scan game id: %w", err)
+		}
+		gameIDs = append(gameIDs, gameID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating abandoned games rows: %w", err)
+	}
+
+	return gameIDs, nil
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mindwarp/types"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrMediaNotFound = errors.New("media not found")
+
+const (
+	templateQuestionMediaTable = "template_question_media"
+	questionMediaTable         = "question_media"
+)
+
+// MediaURL is the path the API serves a stored file from.
+func MediaURL(id string) string {
+	return "/media/" + id
+}
+
+// SaveMedia records an uploaded file. Files are content-addressed, so saving
+// one that already exists keeps the original row.
+func (db *DB) SaveMedia(ctx context.Context, media types.MediaServer) error {
+	_, err := db.pool.Exec(ctx, `
+		INSERT INTO media (id, mime_type, kind, size, width, height, thumbnail_id, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (id) DO NOTHING
+	`, media.ID, media.MimeType, media.Kind, media.Size, media.Width, media.Height, media.ThumbnailID, media.UploadedBy)
+	if err != nil {
+		return fmt.Errorf("failed to save media: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) GetMediaByID(ctx context.Context, id string) (types.MediaServer, error) {
+	var (
+		media      types.MediaServer
+		uploadedBy *string
+	)
+	err := db.pool.QueryRow(ctx, `
+		SELECT id, mime_type, kind, size, width, height, thumbnail_id, uploaded_by::text, created_at
+		FROM media
+		WHERE id = $1
+	`, id).Scan(&media.ID, &media.MimeType, &media.Kind, &media.Size, &media.Width, &media.Height, &media.ThumbnailID, &uploadedBy, &media.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return media, ErrMediaNotFound
+	}
+	if err != nil {
+		return media, fmt.Errorf("failed to get media: %w", err)
+	}
+	if uploadedBy != nil {
+		media.UploadedBy = *uploadedBy
+	}
+	return media, nil
+}
+
+// queueQuestionMedia queues the attachment rows of one question and returns
+// how many statements were queued. With replace set, the question's existing
+// attachments are removed first.
+func queueQuestionMedia(batch *pgx.Batch, table string, questionID string, attachments []types.MediaAttachmentServer, replace bool) int {
+	queued := 0
+	if replace {
+		batch.Queue(fmt.Sprintf("DELETE FROM %s WHERE question_id = $1", table), questionID)
+		queued++
+	}
+
+	for _, attachment := range attachments {
+		batch.Queue(
+			fmt.Sprintf("INSERT INTO %s (question_id, media_id, role, position) VALUES ($1, $2, $3, $4)", table),
+			questionID,
+			attachment.MediaID,
+			attachment.Role,
+			attachment.Position,
+		)
+		queued++
+	}
+	return queued
+}
+
+// getQuestionMedia loads the attachments of the given questions, ordered by
+// role and position.
+func getQuestionMedia(ctx context.Context, q querier, table string, questionIds []string) (map[string][]types.MediaAttachmentClient, error) {
+	attachments := make(map[string][]types.MediaAttachmentClient)
+	if len(questionIds) == 0 {
+		return attachments, nil
+	}
+
+	rows, err := q.Query(ctx, fmt.Sprintf(`
+		SELECT qm.question_id, m.id, qm.role, m.mime_type, m.kind, m.width, m.height, m.thumbnail_id
+		FROM %s qm
+		JOIN media m ON m.id = qm.media_id
+		WHERE qm.question_id = ANY($1)
+		ORDER BY qm.role DESC, qm.position
+	`, table), questionIds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query question media: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			questionID  string
+			attachment  types.MediaAttachmentClient
+			thumbnailID *string
+		)
+		err := rows.Scan(&questionID, &attachment.MediaID, &attachment.Role, &attachment.MimeType, &attachment.Kind, &attachment.Width, &attachment.Height, &thumbnailID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan question media: %w", err)
+		}
+
+		attachment.URL = MediaURL(attachment.MediaID)
+		if thumbnailID != nil {
+			attachment.ThumbnailURL = MediaURL(*thumbnailID)
+		}
+		attachments[questionID] = append(attachments[questionID], attachment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating question media rows: %w", err)
+	}
+
+	return attachments, nil
+}
@@ -0,0 +1,283 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mindwarp/types"
+)
+
+var (
+	ErrGameNotFound   = errors.New("game not found")
+	ErrAnswerNotFound = errors.New("answer not found")
+)
+
+// SetCurrentQuestion opens a question of the game's board. The round follows
+// the question, and the picker moves on under the game's rules as it would
+// through UpdateGameAndGameUsers.
+func (db *DB) SetCurrentQuestion(ctx context.Context, mutation types.GameMutation, questionID string) (types.GameEventServer, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return types.GameEventServer{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockGameVersion(ctx, tx, mutation.GameID, mutation.Version); err != nil {
+		return types.GameEventServer{}, err
+	}
+	question, err := getGameQuestion(ctx, tx, mutation.GameID, questionID)
+	if err != nil {
+		return types.GameEventServer{}, err
+	}
+	if err := requireGameInProgress(ctx, tx, mutation.GameID); err != nil {
+		return types.GameEventServer{}, err
+	}
+
+	var roundID string
+	err = tx.QueryRow(ctx, "SELECT t.round_id::text FROM questions q JOIN themes t ON t.id = q.theme_id WHERE q.id = $1", questionID).Scan(&roundID)
+	if err != nil {
+		return types.GameEventServer{}, fmt.Errorf("failed to get question round: %w", err)
+	}
+
+	game := types.GameServer{ID: mutation.GameID, CurrentQuestionID: questionID}
+	if question.pickerID != nil {
+		game.CurrentUserID = *question.pickerID
+	}
+	pickerID, err := nextPicker(ctx, tx, game)
+	if err != nil {
+		return types.GameEventServer{}, err
+	}
+
+	currentUserID := interface{}(nil)
+	if pickerID != "" {
+		currentUserID = pickerID
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE games
+		SET current_round_id = $1, current_question_id = $2, current_user_id = $3, version = version + 1
+		WHERE id = $4
+	`, roundID, questionID, currentUserID, mutation.GameID)
+	if err != nil {
+		return types.GameEventServer{}, fmt.Errorf("failed to set current question: %w", err)
+	}
+	if err := notifyTurn(ctx, tx, mutation.GameID, question.pickerID, pickerID); err != nil {
+		return types.GameEventServer{}, err
+	}
+
+	event, err := recordGameEvent(ctx, tx, mutation, types.EventQuestionSelected, map[string]any{
+		"roundId":    roundID,
+		"questionId": questionID,
+		"pickerId":   pickerID,
+	})
+	if err != nil {
+		return event, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return event, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return event, nil
+}
+
+// RecordAnswer stores or corrects one player's answer to a question and
+// refreshes the scores. Final-round and multiple-choice questions have their
+// own flows and are rejected here.
+func (db *DB) RecordAnswer(ctx context.Context, mutation types.GameMutation, answer types.AnswerServer) (types.GameEventServer, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return types.GameEventServer{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockGameVersion(ctx, tx, mutation.GameID, mutation.Version); err != nil {
+		return types.GameEventServer{}, err
+	}
+	question, err := getGameQuestion(ctx, tx, mutation.GameID, answer.QuestionID)
+	if err != nil {
+		return types.GameEventServer{}, err
+	}
+	if question.isFinal || question.questionType == types.QuestionMultipleChoice {
+		return types.GameEventServer{}, ErrWrongQuestionType
+	}
+	if err := requireGameInProgress(ctx, tx, mutation.GameID); err != nil {
+		return types.GameEventServer{}, err
+	}
+
+	ok, err := isGameUser(ctx, tx, mutation.GameID, answer.UserID)
+	if err != nil {
+		return types.GameEventServer{}, err
+	}
+	if !ok {
+		return types.GameEventServer{}, ErrUserNotInGame
+	}
+	if err := checkTeamAnswer(ctx, tx, mutation.GameID, answer.UserID, answer.ContributorID); err != nil {
+		return types.GameEventServer{}, err
+	}
+
+	contributorID := interface{}(nil)
+	if answer.ContributorID != "" {
+		contributorID = answer.ContributorID
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO answers (question_id, user_id, is_correct, time_answered, contributor_id)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (question_id, user_id) DO UPDATE SET
+			is_correct = EXCLUDED.is_correct,
+			time_answered = EXCLUDED.time_answered,
+			contributor_id = EXCLUDED.contributor_id
+	`, answer.QuestionID, answer.UserID, answer.IsCorrect, answer.TimeAnswered, contributorID)
+	if err != nil {
+		return types.GameEventServer{}, fmt.Errorf("failed to upsert answer: %w", err)
+	}
+
+	if _, err := storeGameScores(ctx, tx, mutation.GameID); err != nil {
+		return types.GameEventServer{}, fmt.Errorf("failed to recompute scores: %w", err)
+	}
+
+	event, err := recordGameEvent(ctx, tx, mutation, types.EventAnswerRecorded, map[string]any{
+		"questionId":    answer.QuestionID,
+		"userId":        answer.UserID,
+		"isCorrect":     answer.IsCorrect,
+		"timeAnswered":  answer.TimeAnswered,
+		"contributorId": answer.ContributorID,
+	})
+	if err != nil {
+		return event, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return event, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return event, nil
+}
+
+// ClearAnswer removes one player's answer to a question and refreshes the
+// scores.
+func (db *DB) ClearAnswer(ctx context.Context, mutation types.GameMutation, questionID string, userID string) (types.GameEventServer, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return types.GameEventServer{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockGameVersion(ctx, tx, mutation.GameID, mutation.Version); err != nil {
+		return types.GameEventServer{}, err
+	}
+	question, err := getGameQuestion(ctx, tx, mutation.GameID, questionID)
+	if err != nil {
+		return types.GameEventServer{}, err
+	}
+	if question.isFinal || question.questionType == types.QuestionMultipleChoice {
+		return types.GameEventServer{}, ErrWrongQuestionType
+	}
+	if err := requireGameInProgress(ctx, tx, mutation.GameID); err != nil {
+		return types.GameEventServer{}, err
+	}
+
+	tag, err := tx.Exec(ctx, "DELETE FROM answers WHERE question_id = $1 AND user_id = $2", questionID, userID)
+	if err != nil {
+		return types.GameEventServer{}, fmt.Errorf("failed to delete answer: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return types.GameEventServer{}, ErrAnswerNotFound
+	}
+
+	if _, err := storeGameScores(ctx, tx, mutation.GameID); err != nil {
+		return types.GameEventServer{}, fmt.Errorf("failed to recompute scores: %w", err)
+	}
+
+	event, err := recordGameEvent(ctx, tx, mutation, types.EventAnswerCleared, map[string]any{
+		"questionId": questionID,
+		"userId":     userID,
+	})
+	if err != nil {
+		return event, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return event, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return event, nil
+}
+
+// SetCurrentPicker hands the board to a player, overriding the game's
+// next-picker rule.
+func (db *DB) SetCurrentPicker(ctx context.Context, mutation types.GameMutation, userID string) (types.GameEventServer, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return types.GameEventServer{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockGameVersion(ctx, tx, mutation.GameID, mutation.Version); err != nil {
+		return types.GameEventServer{}, err
+	}
+	ok, err := isGameUser(ctx, tx, mutation.GameID, userID)
+	if err != nil {
+		return types.GameEventServer{}, err
+	}
+	if !ok {
+		return types.GameEventServer{}, ErrUserNotInGame
+	}
+	if err := requireGameInProgress(ctx, tx, mutation.GameID); err != nil {
+		return types.GameEventServer{}, err
+	}
+
+	var previousPickerID *string
+	if err := tx.QueryRow(ctx, "SELECT current_user_id::text FROM games WHERE id = $1", mutation.GameID).Scan(&previousPickerID); err != nil {
+		return types.GameEventServer{}, fmt.Errorf("failed to get current picker: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE games SET current_user_id = $1, version = version + 1 WHERE id = $2", userID, mutation.GameID)
+	if err != nil {
+		return types.GameEventServer{}, fmt.Errorf("failed to set current picker: %w", err)
+	}
+	if err := notifyTurn(ctx, tx, mutation.GameID, previousPickerID, userID); err != nil {
+		return types.GameEventServer{}, err
+	}
+
+	event, err := recordGameEvent(ctx, tx, mutation, types.EventPickerSet, map[string]any{
+		"userId": userID,
+	})
+	if err != nil {
+		return event, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return event, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return event, nil
+}
+
+// RenameGame changes the game's name. Unlike the board changes it is allowed
+// in the lobby and after the game has finished.
+func (db *DB) RenameGame(ctx context.Context, mutation types.GameMutation, name string) (types.GameEventServer, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return types.GameEventServer{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockGameVersion(ctx, tx, mutation.GameID, mutation.Version); err != nil {
+		return types.GameEventServer{}, err
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE games SET name = $1, last_activity_at = now(), version = version + 1 WHERE id = $2", name, mutation.GameID)
+	if err != nil {
+		return types.GameEventServer{}, fmt.Errorf("failed to rename game: %w", err)
+	}
+
+	event, err := recordGameEvent(ctx, tx, mutation, types.EventGameRenamed, map[string]any{
+		"name": name,
+	})
+	if err != nil {
+		return event, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return event, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return event, nil
+}
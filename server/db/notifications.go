@@ -0,0 +1,206 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mindwarp/logger"
+	"mindwarp/types"
+	"time"
+)
+
+// NotificationChannel is the Postgres channel new notification ids are sent
+// on. NOTIFY is transactional, so listeners only hear about committed rows.
+const NotificationChannel = "notifications"
+
+const insertNotificationSQL = `
+	WITH inserted AS (
+		INSERT INTO notifications (user_id, type, game_id, template_id, actor_id, payload)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	)
+	SELECT pg_notify('` + NotificationChannel + `', id::text) FROM inserted
+`
+
+// notify adds a notification to a user's inbox as part of the caller's
+// transaction.
+func notify(ctx context.Context, q querier, n types.NotificationServer) error {
+	args, err := notificationArgs(n)
+	if err != nil {
+		return err
+	}
+	if _, err := q.Exec(ctx, insertNotificationSQL, args...); err != nil {
+		return fmt.Errorf("failed to insert notification: %w", err)
+	}
+	return nil
+}
+
+func notificationArgs(n types.NotificationServer) ([]any, error) {
+	payload := n.Payload
+	if payload == nil {
+		payload = map[string]any{}
+	}
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal notification payload: %w", err)
+	}
+	return []any{n.UserID, string(n.Type), nullableID(n.GameID), nullableID(n.TemplateID), nullableID(n.ActorID), payloadJSON}, nil
+}
+
+// notifyTurn tells the picker it is their turn when the board passed to
+// someone new.
+func notifyTurn(ctx context.Context, q querier, gameID string, previousPickerID *string, pickerID string) error {
+	if pickerID == "" || (previousPickerID != nil && *previousPickerID == pickerID) {
+		return nil
+	}
+	return notify(ctx, q, types.NotificationServer{
+		UserID: pickerID,
+		Type:   types.NotificationYourTurn,
+		GameID: gameID,
+	})
+}
+
+// notifyGamePlayers notifies every player of a game except the actor.
+func notifyGamePlayers(ctx context.Context, q querier, gameID string, notifType types.NotificationType, actorID string) error {
+	_, err := q.Exec(ctx, `
+		WITH inserted AS (
+			INSERT INTO notifications (user_id, type, game_id, actor_id)
+			SELECT gu.user_id, $2, gu.game_id, $3::uuid
+			FROM game_users gu
+			WHERE gu.game_id = $1 AND gu.user_id IS DISTINCT FROM $3::uuid
+			RETURNING id
+		)
+		SELECT pg_notify('`+NotificationChannel+`', id::text) FROM inserted
+	`, gameID, string(notifType), nullableID(actorID))
+	if err != nil {
+		return fmt.Errorf("failed to notify game players: %w", err)
+	}
+	return nil
+}
+
+func nullableID(id string) any {
+	if id == "" {
+		return nil
+	}
+	return id
+}
+
+const selectNotificationSQL = `
+	SELECT
+		n.id, n.user_id, n.type, COALESCE(n.game_id::text, ''), COALESCE(n.template_id::text, ''),
+		COALESCE(n.actor_id::text, ''), COALESCE(a.name, ''), COALESCE(g.name, ''),
+		n.payload, n.read_at, n.created_at
+	FROM notifications n
+	LEFT JOIN users a ON a.id = n.actor_id
+	LEFT JOIN games g ON g.id = n.game_id
+`
+
+func scanNotification(row interface{ Scan(...any) error }) (types.NotificationServer, error) {
+	var (
+		n           types.NotificationServer
+		notifType   string
+		payloadJSON []byte
+	)
+	err := row.Scan(&n.ID, &n.UserID, &notifType, &n.GameID, &n.TemplateID, &n.ActorID, &n.ActorName, &n.GameName, &payloadJSON, &n.ReadAt, &n.CreatedAt)
+	if err != nil {
+		return n, err
+	}
+	n.Type = types.NotificationType(notifType)
+	if err := json.Unmarshal(payloadJSON, &n.Payload); err != nil {
+		return n, fmt.Errorf("failed to unmarshal notification payload: %w", err)
+	}
+	return n, nil
+}
+
+func (db *DB) GetNotification(ctx context.Context, notificationID string) (types.NotificationServer, error) {
+	n, err := scanNotification(db.pool.QueryRow(ctx, selectNotificationSQL+" WHERE n.id = $1", notificationID))
+	if err != nil {
+		return n, fmt.Errorf("failed to get notification: %w", err)
+	}
+	return n, nil
+}
+
+// GetNotifications returns a page of a user's inbox, newest first, together
+// with the unread count and the total size of the (filtered) inbox.
+func (db *DB) GetNotifications(ctx context.Context, userID string, unreadOnly bool, offset int, limit int) (types.NotificationPageClient, error) {
+	page := types.NotificationPageClient{Notifications: make([]types.NotificationClient, 0)}
+
+	err := db.pool.QueryRow(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE read_at IS NULL),
+			COUNT(*) FILTER (WHERE NOT $2 OR read_at IS NULL)
+		FROM notifications
+		WHERE user_id = $1
+	`, userID, unreadOnly).Scan(&page.UnreadCount, &page.Total)
+	if err != nil {
+		return page, fmt.Errorf("failed to count notifications: %w", err)
+	}
+
+	rows, err := db.pool.Query(ctx, selectNotificationSQL+`
+		WHERE n.user_id = $1 AND (NOT $2 OR n.read_at IS NULL)
+		ORDER BY n.created_at DESC, n.id
+		OFFSET $3 LIMIT $4
+	`, userID, unreadOnly, offset, limit)
+	if err != nil {
+		return page, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return page, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		page.Notifications = append(page.Notifications, n.Client())
+	}
+
+	if err := rows.Err(); err != nil {
+		return page, fmt.Errorf("error iterating notifications rows: %w", err)
+	}
+
+	return page, nil
+}
+
+// MarkNotificationsRead marks the given notifications of a user as read, or
+// all of them when ids is empty, and returns how many changed.
+func (db *DB) MarkNotificationsRead(ctx context.Context, userID string, ids []string) (int64, error) {
+	tag, err := db.pool.Exec(ctx, `
+		UPDATE notifications SET read_at = now()
+		WHERE user_id = $1 AND read_at IS NULL AND (COALESCE(cardinality($2::uuid[]), 0) = 0 OR id = ANY($2::uuid[]))
+	`, userID, ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ListenNotifications calls handle for every notification committed from now
+// on, until ctx is done or the connection fails.
+func (db *DB) ListenNotifications(ctx context.Context, handle func(types.NotificationServer)) error {
+	conn, err := db.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+NotificationChannel); err != nil {
+		return fmt.Errorf("failed to listen for notifications: %w", err)
+	}
+
+	for {
+		event, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to wait for notification: %w", err)
+		}
+
+		// Looked up on a separate connection; this one is busy listening
+		lookupCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		n, err := db.GetNotification(lookupCtx, event.Payload)
+		cancel()
+		if err != nil {
+			logger.Errorf("Failed to load notification %s: %v", event.Payload, err)
+			continue
+		}
+		handle(n)
+	}
+}
@@ -0,0 +1,480 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"mindwarp/types"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrPracticeNotFound    = errors.New("practice session not found")
+	ErrPracticeFinished    = errors.New("practice session is already finished")
+	ErrNoPracticeQuestions = errors.New("there are no questions to practice")
+	ErrNotCurrentQuestion  = errors.New("questions must be played in order")
+	ErrAnswerNotRevealed   = errors.New("reveal the answer before grading it")
+	ErrReviewItemNotFound  = errors.New("question is not on the review list")
+)
+
+const maxPracticeQuestions = 200
+
+// selectPracticeSessionSQL reads a session with its progress; callers append
+// the WHERE clause.
+const selectPracticeSessionSQL = `
+	SELECT
+		ps.id, COALESCE(ps.template_id::text, ''), COALESCE(gt.name, ''), ps.status,
+		(SELECT COUNT(*) FROM practice_questions pq WHERE pq.session_id = ps.id),
+		(SELECT COUNT(*) FROM practice_questions pq WHERE pq.session_id = ps.id AND pq.graded_at IS NOT NULL),
+		(SELECT COUNT(*) FROM practice_questions pq WHERE pq.session_id = ps.id AND pq.is_correct),
+		ps.created_at, ps.finished_at
+	FROM practice_sessions ps
+	LEFT JOIN game_templates gt ON gt.id = ps.template_id
+`
+
+func scanPracticeSession(row pgx.Row) (types.PracticeSessionClient, error) {
+	var (
+		session    types.PracticeSessionClient
+		createdAt  time.Time
+		finishedAt *time.Time
+	)
+	err := row.Scan(&session.ID, &session.TemplateID, &session.TemplateName, &session.Status,
+		&session.Total, &session.Graded, &session.Correct, &createdAt, &finishedAt)
+	if err != nil {
+		return session, err
+	}
+	session.CreatedAt = createdAt.UnixMilli()
+	if finishedAt != nil {
+		session.FinishedAt = finishedAt.UnixMilli()
+	}
+	return session, nil
+}
+
+func getPracticeSession(ctx context.Context, q querier, sessionID string, userID string) (types.PracticeSessionClient, error) {
+	session, err := scanPracticeSession(q.QueryRow(ctx, selectPracticeSessionSQL+" WHERE ps.id = $1 AND ps.user_id = $2", sessionID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return session, ErrPracticeNotFound
+	}
+	if err != nil {
+		return session, fmt.Errorf("failed to get practice session: %w", err)
+	}
+	return session, nil
+}
+
+// lockActivePractice locks a session of userID that is still being played.
+func lockActivePractice(ctx context.Context, q querier, sessionID string, userID string) error {
+	var status types.PracticeStatus
+	err := q.QueryRow(ctx, "SELECT status FROM practice_sessions WHERE id = $1 AND user_id = $2 FOR UPDATE", sessionID, userID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrPracticeNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock practice session: %w", err)
+	}
+	if status != types.PracticeActive {
+		return ErrPracticeFinished
+	}
+	return nil
+}
+
+// CreatePracticeSession starts a solo practice session on a public or own
+// template, or on the player's review list. Practice never creates a game.
+func (db *DB) CreatePracticeSession(ctx context.Context, options types.PracticeOptions) (types.PracticeSessionClient, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return types.PracticeSessionClient{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var rows pgx.Rows
+	if options.FromReview {
+		rows, err = tx.Query(ctx, `
+			SELECT pr.question_id
+			FROM practice_reviews pr
+			JOIN template_questions tq ON tq.id = pr.question_id
+			JOIN template_themes tt ON tt.id = tq.theme_id
+			JOIN template_rounds tr ON tr.id = tt.round_id
+			JOIN game_templates gt ON gt.id = tr.game_template_id
+			WHERE pr.user_id = $1 AND (gt.is_public OR gt.creator_id = $1)
+			ORDER BY pr.last_missed_at, pr.question_id
+		`, options.UserID)
+	} else {
+		var visible bool
+		err = tx.QueryRow(ctx, "SELECT is_public OR creator_id = $2 FROM game_templates WHERE id = $1", options.TemplateID, options.UserID).Scan(&visible)
+		if err != nil || !visible {
+			return types.PracticeSessionClient{}, ErrTemplateNotFound
+		}
+
+		rows, err = tx.Query(ctx, `
+			SELECT tq.id
+			FROM template_questions tq
+			JOIN template_themes tt ON tt.id = tq.theme_id
+			JOIN template_rounds tr ON tr.id = tt.round_id
+			WHERE tr.game_template_id = $1
+				AND (COALESCE(cardinality($2::uuid[]), 0) = 0 OR tr.id = ANY($2::uuid[]))
+			ORDER BY tr.position, tt.position, tq.position, tq.points
+		`, options.TemplateID, options.RoundIDs)
+	}
+	if err != nil {
+		return types.PracticeSessionClient{}, fmt.Errorf("failed to query practice questions: %w", err)
+	}
+	questionIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return types.PracticeSessionClient{}, fmt.Errorf("failed to scan practice questions: %w", err)
+	}
+	if len(questionIDs) == 0 {
+		return types.PracticeSessionClient{}, ErrNoPracticeQuestions
+	}
+
+	if options.Shuffle {
+		rand.Shuffle(len(questionIDs), func(i, j int) {
+			questionIDs[i], questionIDs[j] = questionIDs[j], questionIDs[i]
+		})
+	}
+	limit := options.Limit
+	if limit <= 0 || limit > maxPracticeQuestions {
+		limit = maxPracticeQuestions
+	}
+	questionIDs = questionIDs[:min(limit, len(questionIDs))]
+
+	templateID := interface{}(nil)
+	if !options.FromReview {
+		templateID = options.TemplateID
+	}
+
+	var sessionID string
+	err = tx.QueryRow(ctx, "INSERT INTO practice_sessions (user_id, template_id) VALUES ($1, $2) RETURNING id::text", options.UserID, templateID).Scan(&sessionID)
+	if err != nil {
+		return types.PracticeSessionClient{}, fmt.Errorf("failed to create practice session: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO practice_questions (session_id, question_id, position)
+		SELECT $1::uuid, question_id, position - 1
+		FROM unnest($2::uuid[]) WITH ORDINALITY AS q(question_id, position)
+	`, sessionID, questionIDs)
+	if err != nil {
+		return types.PracticeSessionClient{}, fmt.Errorf("failed to add practice questions: %w", err)
+	}
+
+	session, err := getPracticeSession(ctx, tx, sessionID, options.UserID)
+	if err != nil {
+		return session, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return session, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return session, nil
+}
+
+func (db *DB) GetPracticeSession(ctx context.Context, sessionID string, userID string) (types.PracticeSessionClient, error) {
+	return getPracticeSession(ctx, db.pool, sessionID, userID)
+}
+
+// GetPracticeSessions lists a player's practice sessions, newest first.
+func (db *DB) GetPracticeSessions(ctx context.Context, userID string, offset int, limit int) ([]types.PracticeSessionClient, error) {
+	rows, err := db.pool.Query(ctx, selectPracticeSessionSQL+`
+		WHERE ps.user_id = $1
+		ORDER BY ps.created_at DESC
+		OFFSET $2 LIMIT $3
+	`, userID, offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query practice sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]types.PracticeSessionClient, 0)
+	for rows.Next() {
+		session, err := scanPracticeSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan practice session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating practice sessions rows: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// getPracticeQuestion loads one question of a session. The answer, answer
+// media and correct options are only filled in once it has been revealed.
+func getPracticeQuestion(ctx context.Context, q querier, sessionID string, questionID string) (types.PracticeQuestionClient, error) {
+	var (
+		question   types.PracticeQuestionClient
+		revealedAt *time.Time
+		answer     string
+	)
+	err := q.QueryRow(ctx, `
+		SELECT pq.question_id, pq.position, tr.name, tt.name, tq.text, tq.points, tq.question_type, tq.answer, pq.revealed_at
+		FROM practice_questions pq
+		JOIN template_questions tq ON tq.id = pq.question_id
+		JOIN template_themes tt ON tt.id = tq.theme_id
+		JOIN template_rounds tr ON tr.id = tt.round_id
+		WHERE pq.session_id = $1 AND pq.question_id = $2
+	`, sessionID, questionID).Scan(&question.QuestionID, &question.Position, &question.Round, &question.Theme,
+		&question.Text, &question.Points, &question.Type, &answer, &revealedAt)
+	if err != nil {
+		return question, fmt.Errorf("failed to get practice question: %w", err)
+	}
+	question.Revealed = revealedAt != nil
+
+	options, err := getQuestionOptions(ctx, q, templateQuestionOptionsTable, []string{questionID})
+	if err != nil {
+		return question, err
+	}
+	attachments, err := getQuestionMedia(ctx, q, templateQuestionMediaTable, []string{questionID})
+	if err != nil {
+		return question, err
+	}
+
+	if question.Revealed {
+		question.Answer = answer
+		question.Options = options[questionID]
+		question.Media = attachments[questionID]
+		return question, nil
+	}
+
+	for _, option := range options[questionID] {
+		option.IsCorrect = nil
+		question.Options = append(question.Options, option)
+	}
+	for _, attachment := range attachments[questionID] {
+		if attachment.Role == string(types.MediaRoleQuestion) {
+			question.Media = append(question.Media, attachment)
+		}
+	}
+	return question, nil
+}
+
+// currentPracticeQuestion returns the id of the first question of a session
+// that has not been graded, or "" when every question has.
+func currentPracticeQuestion(ctx context.Context, q querier, sessionID string) (string, error) {
+	var questionID string
+	err := q.QueryRow(ctx, `
+		SELECT question_id::text FROM practice_questions
+		WHERE session_id = $1 AND graded_at IS NULL
+		ORDER BY position
+		LIMIT 1
+	`, sessionID).Scan(&questionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get current practice question: %w", err)
+	}
+	return questionID, nil
+}
+
+// NextPracticeQuestion serves the question the player is on. It returns nil
+// once every question has been graded.
+func (db *DB) NextPracticeQuestion(ctx context.Context, sessionID string, userID string) (*types.PracticeQuestionClient, error) {
+	session, err := getPracticeSession(ctx, db.pool, sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != string(types.PracticeActive) {
+		return nil, nil
+	}
+
+	questionID, err := currentPracticeQuestion(ctx, db.pool, sessionID)
+	if err != nil || questionID == "" {
+		return nil, err
+	}
+
+	question, err := getPracticeQuestion(ctx, db.pool, sessionID, questionID)
+	if err != nil {
+		return nil, err
+	}
+	return &question, nil
+}
+
+// RevealPracticeQuestion shows the answer to the question the player is on.
+func (db *DB) RevealPracticeQuestion(ctx context.Context, sessionID string, questionID string, userID string) (types.PracticeQuestionClient, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return types.PracticeQuestionClient{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockActivePractice(ctx, tx, sessionID, userID); err != nil {
+		return types.PracticeQuestionClient{}, err
+	}
+	current, err := currentPracticeQuestion(ctx, tx, sessionID)
+	if err != nil {
+		return types.PracticeQuestionClient{}, err
+	}
+	if current != questionID {
+		return types.PracticeQuestionClient{}, ErrNotCurrentQuestion
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE practice_questions SET revealed_at = COALESCE(revealed_at, now())
+		WHERE session_id = $1 AND question_id = $2
+	`, sessionID, questionID)
+	if err != nil {
+		return types.PracticeQuestionClient{}, fmt.Errorf("failed to reveal practice question: %w", err)
+	}
+
+	question, err := getPracticeQuestion(ctx, tx, sessionID, questionID)
+	if err != nil {
+		return question, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return question, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return question, nil
+}
+
+// GradePracticeQuestion records the player's own verdict on the question
+// they are on. A miss puts the question on their review list and a correct
+// answer takes it off. Grading the last question finishes the session.
+func (db *DB) GradePracticeQuestion(ctx context.Context, sessionID string, questionID string, userID string, isCorrect bool) (types.PracticeSessionClient, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return types.PracticeSessionClient{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockActivePractice(ctx, tx, sessionID, userID); err != nil {
+		return types.PracticeSessionClient{}, err
+	}
+	current, err := currentPracticeQuestion(ctx, tx, sessionID)
+	if err != nil {
+		return types.PracticeSessionClient{}, err
+	}
+	if current != questionID {
+		return types.PracticeSessionClient{}, ErrNotCurrentQuestion
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE practice_questions SET is_correct = $3, graded_at = now()
+		WHERE session_id = $1 AND question_id = $2 AND revealed_at IS NOT NULL
+	`, sessionID, questionID, isCorrect)
+	if err != nil {
+		return types.PracticeSessionClient{}, fmt.Errorf("failed to grade practice question: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return types.PracticeSessionClient{}, ErrAnswerNotRevealed
+	}
+
+	if isCorrect {
+		_, err = tx.Exec(ctx, "DELETE FROM practice_reviews WHERE user_id = $1 AND question_id = $2", userID, questionID)
+	} else {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO practice_reviews (user_id, question_id)
+			VALUES ($1, $2)
+			ON CONFLICT (user_id, question_id) DO UPDATE
+			SET misses = practice_reviews.misses + 1, last_missed_at = now()
+		`, userID, questionID)
+	}
+	if err != nil {
+		return types.PracticeSessionClient{}, fmt.Errorf("failed to update review list: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE practice_sessions SET status = 'finished', finished_at = now()
+		WHERE id = $1 AND NOT EXISTS (
+			SELECT 1 FROM practice_questions WHERE session_id = $1 AND graded_at IS NULL
+		)
+	`, sessionID)
+	if err != nil {
+		return types.PracticeSessionClient{}, fmt.Errorf("failed to finish practice session: %w", err)
+	}
+
+	session, err := getPracticeSession(ctx, tx, sessionID, userID)
+	if err != nil {
+		return session, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return session, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return session, nil
+}
+
+// FinishPracticeSession ends a session early. Questions left ungraded do not
+// count either way.
+func (db *DB) FinishPracticeSession(ctx context.Context, sessionID string, userID string) (types.PracticeSessionClient, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return types.PracticeSessionClient{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockActivePractice(ctx, tx, sessionID, userID); err != nil {
+		return types.PracticeSessionClient{}, err
+	}
+	if _, err := tx.Exec(ctx, "UPDATE practice_sessions SET status = 'finished', finished_at = now() WHERE id = $1", sessionID); err != nil {
+		return types.PracticeSessionClient{}, fmt.Errorf("failed to finish practice session: %w", err)
+	}
+
+	session, err := getPracticeSession(ctx, tx, sessionID, userID)
+	if err != nil {
+		return session, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return session, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return session, nil
+}
+
+// GetReviewList returns the questions a player has missed in practice and
+// not got right since, most missed first.
+func (db *DB) GetReviewList(ctx context.Context, userID string, offset int, limit int) ([]types.ReviewItemClient, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT pr.question_id, gt.id, gt.name, tt.name, tq.text, tq.answer, tq.points, pr.misses, pr.last_missed_at
+		FROM practice_reviews pr
+		JOIN template_questions tq ON tq.id = pr.question_id
+		JOIN template_themes tt ON tt.id = tq.theme_id
+		JOIN template_rounds tr ON tr.id = tt.round_id
+		JOIN game_templates gt ON gt.id = tr.game_template_id
+		WHERE pr.user_id = $1 AND (gt.is_public OR gt.creator_id = $1)
+		ORDER BY pr.misses DESC, pr.last_missed_at DESC, pr.question_id
+		OFFSET $2 LIMIT $3
+	`, userID, offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query review list: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]types.ReviewItemClient, 0)
+	for rows.Next() {
+		var (
+			item         types.ReviewItemClient
+			lastMissedAt time.Time
+		)
+		err := rows.Scan(&item.QuestionID, &item.TemplateID, &item.TemplateName, &item.Theme, &item.Text, &item.Answer, &item.Points, &item.Misses, &lastMissedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review item: %w", err)
+		}
+		item.LastMissedAt = lastMissedAt.UnixMilli()
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating review list rows: %w", err)
+	}
+
+	return items, nil
+}
+
+// DismissReviewItem takes a question off a player's review list.
+func (db *DB) DismissReviewItem(ctx context.Context, userID string, questionID string) error {
+	tag, err := db.pool.Exec(ctx, "DELETE FROM practice_reviews WHERE user_id = $1 AND question_id = $2", userID, questionID)
+	if err != nil {
+		return fmt.Errorf("failed to dismiss review item: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrReviewItemNotFound
+	}
+	return nil
+}
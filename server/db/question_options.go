@@ -0,0 +1,175 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mindwarp/types"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrNotMultipleChoice = errors.New("question is not a multiple-choice question")
+	ErrInvalidOption     = errors.New("selected option does not belong to this question")
+	ErrAlreadyAnswered   = errors.New("question has already been answered")
+)
+
+const (
+	templateQuestionOptionsTable = "template_question_options"
+	questionOptionsTable         = "question_options"
+)
+
+// queueQuestionOptions queues the option rows of one question and returns how
+// many statements were queued. With replace set, the question's existing
+// options are removed first.
+func queueQuestionOptions(batch *pgx.Batch, table string, questionID string, options []types.QuestionOptionServer, replace bool) int {
+	queued := 0
+	if replace {
+		batch.Queue(fmt.Sprintf("DELETE FROM %s WHERE question_id = $1", table), questionID)
+		queued++
+	}
+
+	for _, option := range options {
+		batch.Queue(
+			fmt.Sprintf("INSERT INTO %s (id, question_id, text, is_correct, position) VALUES ($1, $2, $3, $4, $5)", table),
+			option.ID,
+			questionID,
+			option.Text,
+			option.IsCorrect,
+			option.Position,
+		)
+		queued++
+	}
+	return queued
+}
+
+// getQuestionOptions loads the options of the given questions in display
+// order. Correctness is always filled in; callers hide it where needed.
+func getQuestionOptions(ctx context.Context, q querier, table string, questionIds []string) (map[string][]types.QuestionOptionClient, error) {
+	options := make(map[string][]types.QuestionOptionClient)
+	if len(questionIds) == 0 {
+		return options, nil
+	}
+
+	rows, err := q.Query(ctx, fmt.Sprintf(`
+		SELECT question_id, id, text, is_correct
+		FROM %s
+		WHERE question_id = ANY($1)
+		ORDER BY position
+	`, table), questionIds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query question options: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			questionID string
+			option     types.QuestionOptionClient
+			isCorrect  bool
+		)
+		if err := rows.Scan(&questionID, &option.ID, &option.Text, &isCorrect); err != nil {
+			return nil, fmt.Errorf("failed to scan question option: %w", err)
+		}
+		option.IsCorrect = &isCorrect
+		options[questionID] = append(options[questionID], option)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating question options rows: %w", err)
+	}
+
+	return options, nil
+}
+
+// judgeChoice grades a selection of options. It is correct when exactly the
+// correct options are selected. The selected ids come back in display order.
+func judgeChoice(options []types.QuestionOptionClient, optionIDs []string) (bool, []string, error) {
+	selected := make(map[string]bool, len(optionIDs))
+	for _, optionID := range optionIDs {
+		if !slices.ContainsFunc(options, func(o types.QuestionOptionClient) bool { return o.ID == optionID }) {
+			return false, nil, ErrInvalidOption
+		}
+		selected[optionID] = true
+	}
+
+	isCorrect := len(selected) > 0
+	for _, option := range options {
+		if *option.IsCorrect != selected[option.ID] {
+			isCorrect = false
+		}
+	}
+
+	selectedIDs := make([]string, 0, len(selected))
+	for _, option := range options {
+		if selected[option.ID] {
+			selectedIDs = append(selectedIDs, option.ID)
+		}
+	}
+	return isCorrect, selectedIDs, nil
+}
+
+// SubmitChoiceAnswer grades a player's multiple-choice answer. It is correct
+// when exactly the correct options are selected. Each player answers once.
+func (db *DB) SubmitChoiceAnswer(ctx context.Context, gameID string, questionID string, userID string, optionIDs []string, timeAnswered uint16) (bool, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	question, err := getGameQuestion(ctx, tx, gameID, questionID)
+	if err != nil {
+		return false, err
+	}
+	if err := requireGameInProgress(ctx, tx, gameID); err != nil {
+		return false, err
+	}
+	if question.questionType != types.QuestionMultipleChoice {
+		return false, ErrNotMultipleChoice
+	}
+
+	ok, err := isGameUser(ctx, tx, gameID, userID)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, ErrUserNotInGame
+	}
+	if err := checkTeamAnswer(ctx, tx, gameID, userID, ""); err != nil {
+		return false, err
+	}
+
+	options, err := getQuestionOptions(ctx, tx, questionOptionsTable, []string{questionID})
+	if err != nil {
+		return false, err
+	}
+
+	isCorrect, selectedIDs, err := judgeChoice(options[questionID], optionIDs)
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO answers (question_id, user_id, is_correct, time_answered, selected_options)
+		VALUES ($1, $2, $3, $4, $5::uuid[])
+		ON CONFLICT (question_id, user_id) DO NOTHING
+	`, questionID, userID, isCorrect, timeAnswered, selectedIDs)
+	if err != nil {
+		return false, fmt.Errorf("failed to store answer: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, ErrAlreadyAnswered
+	}
+
+	if _, err := storeGameScores(ctx, tx, gameID); err != nil {
+		return false, fmt.Errorf("failed to recompute scores: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return isCorrect, nil
+}
@@ -0,0 +1,355 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"mindwarp/rating"
+	"mindwarp/scoring"
+	"mindwarp/types"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrSeasonNotFound = errors.New("season not found")
+
+// season is a stored rating season.
+type season struct {
+	types.SeasonServer
+	startsAt time.Time
+	endsAt   *time.Time
+}
+
+func (s season) client() types.SeasonClient {
+	client := types.SeasonClient{
+		ID:           s.ID,
+		Name:         s.Name,
+		CarryOver:    string(s.CarryOver),
+		SoftenFactor: s.SoftenFactor,
+		StartsAt:     s.startsAt.UnixMilli(),
+	}
+	if s.endsAt != nil {
+		client.EndsAt = s.endsAt.UnixMilli()
+	}
+	return client
+}
+
+const selectSeasonSQL = "SELECT id::text, name, carry_over, soften_factor, starts_at, ends_at FROM seasons"
+
+func scanSeason(row pgx.Row) (season, error) {
+	var s season
+	err := row.Scan(&s.ID, &s.Name, &s.CarryOver, &s.SoftenFactor, &s.startsAt, &s.endsAt)
+	return s, err
+}
+
+// getSeason loads a season, the current one when seasonID is empty.
+func getSeason(ctx context.Context, q querier, seasonID string) (season, error) {
+	row := q.QueryRow(ctx, selectSeasonSQL+" WHERE ends_at IS NULL")
+	if seasonID != "" {
+		row = q.QueryRow(ctx, selectSeasonSQL+" WHERE id = $1", seasonID)
+	}
+
+	s, err := scanSeason(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s, ErrSeasonNotFound
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to get season: %w", err)
+	}
+	return s, nil
+}
+
+// seasonStartRating is the rating userID enters s with: their latest rating
+// from an earlier season, softened, or the default.
+func seasonStartRating(ctx context.Context, q querier, s season, userID string) (float64, error) {
+	if s.CarryOver != types.SeasonSoften {
+		return rating.Default, nil
+	}
+
+	var last float64
+	err := q.QueryRow(ctx, `
+		SELECT pr.rating
+		FROM player_ratings pr
+		JOIN seasons s ON s.id = pr.season_id
+		WHERE pr.user_id = $1 AND s.starts_at < $2
+		ORDER BY s.starts_at DESC
+		LIMIT 1
+	`, userID, s.startsAt).Scan(&last)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return rating.Default, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get previous rating: %w", err)
+	}
+
+	return rating.Soften(last, s.SoftenFactor), nil
+}
+
+// rateGame moves the current season's ratings of a finished game's players
+// by their places in standings and records the change. In team games the ids
+// in standings are teams and every member takes their team's place; players
+// outside a team are not rated. Games with fewer than two rated players, or
+// finished while no season is open, leave ratings alone.
+func rateGame(ctx context.Context, tx pgx.Tx, gameID string, standings []scoring.Standing, isTeamGame bool) error {
+	placeOf := make(map[string]int, len(standings))
+	for _, standing := range standings {
+		placeOf[standing.UserID] = standing.Place
+	}
+	if isTeamGame {
+		teamOf, err := getTeamMembership(ctx, tx, gameID)
+		if err != nil {
+			return err
+		}
+		teamPlaceOf := placeOf
+		placeOf = make(map[string]int, len(teamOf))
+		for userID, teamID := range teamOf {
+			if place, ok := teamPlaceOf[teamID]; ok {
+				placeOf[userID] = place
+			}
+		}
+	}
+	if len(placeOf) < 2 {
+		return nil
+	}
+
+	s, err := getSeason(ctx, tx, "")
+	if errors.Is(err, ErrSeasonNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	userIDs := slices.Sorted(maps.Keys(placeOf))
+	rows, err := tx.Query(ctx, `
+		SELECT user_id::text, rating, games
+		FROM player_ratings
+		WHERE season_id = $1 AND user_id = ANY($2::uuid[])
+		FOR UPDATE
+	`, s.ID, userIDs)
+	if err != nil {
+		return fmt.Errorf("failed to query ratings: %w", err)
+	}
+	current, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (rating.Player, error) {
+		var player rating.Player
+		err := row.Scan(&player.ID, &player.Rating, &player.Games)
+		return player, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan ratings: %w", err)
+	}
+
+	players := make([]rating.Player, len(userIDs))
+	for i, userID := range userIDs {
+		j := slices.IndexFunc(current, func(player rating.Player) bool { return player.ID == userID })
+		if j >= 0 {
+			players[i] = current[j]
+		} else {
+			start, err := seasonStartRating(ctx, tx, s, userID)
+			if err != nil {
+				return err
+			}
+			players[i] = rating.Player{ID: userID, Rating: start}
+		}
+		players[i].Place = placeOf[userID]
+	}
+
+	ratings := rating.Rate(players)
+
+	batch := &pgx.Batch{}
+	for i, player := range players {
+		wins := 0
+		if player.Place == 1 {
+			wins = 1
+		}
+		batch.Queue(`
+			INSERT INTO player_ratings (season_id, user_id, rating, peak, games, wins)
+			VALUES ($1, $2, $3, $4, 1, $5)
+			ON CONFLICT (season_id, user_id) DO UPDATE SET
+				rating = EXCLUDED.rating,
+				peak = GREATEST(player_ratings.peak, EXCLUDED.peak),
+				games = player_ratings.games + 1,
+				wins = player_ratings.wins + EXCLUDED.wins,
+				updated_at = now()
+		`, s.ID, player.ID, ratings[i], math.Max(player.Rating, ratings[i]), wins)
+		batch.Queue(`
+			INSERT INTO rating_history (game_id, user_id, season_id, place, rating_before, rating_after)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, gameID, player.ID, s.ID, player.Place, player.Rating, ratings[i])
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to store ratings: %w", err)
+	}
+
+	return nil
+}
+
+// GetSeasons lists every season, the current one first.
+func (db *DB) GetSeasons(ctx context.Context) ([]types.SeasonClient, error) {
+	rows, err := db.pool.Query(ctx, selectSeasonSQL+" ORDER BY starts_at DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query seasons: %w", err)
+	}
+	seasons, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.SeasonClient, error) {
+		s, err := scanSeason(row)
+		return s.client(), err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan seasons: %w", err)
+	}
+
+	return seasons, nil
+}
+
+// CreateSeason ends the current season and starts s in its place.
+func (db *DB) CreateSeason(ctx context.Context, s types.SeasonServer) (types.SeasonClient, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return types.SeasonClient{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "UPDATE seasons SET ends_at = now() WHERE ends_at IS NULL"); err != nil {
+		return types.SeasonClient{}, fmt.Errorf("failed to end season: %w", err)
+	}
+
+	created, err := scanSeason(tx.QueryRow(ctx, `
+		INSERT INTO seasons (id, name, carry_over, soften_factor)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id::text, name, carry_over, soften_factor, starts_at, ends_at
+	`, s.ID, s.Name, string(s.CarryOver), s.SoftenFactor))
+	if err != nil {
+		return types.SeasonClient{}, fmt.Errorf("failed to create season: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return types.SeasonClient{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return created.client(), nil
+}
+
+// ratingLeaderboardSQL ranks the ratings of a season; callers append the
+// filter on the ranked rows.
+const ratingLeaderboardSQL = `
+	WITH ranked AS (
+		SELECT RANK() OVER (ORDER BY pr.rating DESC) AS rank,
+			pr.user_id::text AS user_id, u.name, round(pr.rating)::int AS rating,
+			round(pr.peak)::int AS peak, pr.games, pr.wins
+		FROM player_ratings pr
+		JOIN users u ON u.id = pr.user_id
+		WHERE pr.season_id = $1
+	)
+	SELECT rank, user_id, name, rating, peak, games, wins FROM ranked
+`
+
+func scanRatingEntry(row pgx.Row) (types.RatingEntryClient, error) {
+	var entry types.RatingEntryClient
+	err := row.Scan(&entry.Rank, &entry.UserID, &entry.Name, &entry.Rating, &entry.Peak, &entry.Games, &entry.Wins)
+	return entry, err
+}
+
+// getRatingEntry returns userID's entry in a season, or nil if they have
+// no rating in it.
+func getRatingEntry(ctx context.Context, q querier, seasonID string, userID string) (*types.RatingEntryClient, error) {
+	entry, err := scanRatingEntry(q.QueryRow(ctx, ratingLeaderboardSQL+" WHERE user_id = $2", seasonID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rating: %w", err)
+	}
+	return &entry, nil
+}
+
+// GetRatingLeaderboard returns a page of a season's ratings, the current
+// season when seasonID is empty, highest first, with userID's entry.
+func (db *DB) GetRatingLeaderboard(ctx context.Context, seasonID string, userID string, offset int, limit int) (types.RatingLeaderboardClient, error) {
+	board := types.RatingLeaderboardClient{Entries: make([]types.RatingEntryClient, 0)}
+
+	s, err := getSeason(ctx, db.pool, seasonID)
+	if err != nil {
+		return board, err
+	}
+	board.Season = s.client()
+
+	if err := db.pool.QueryRow(ctx, "SELECT COUNT(*) FROM player_ratings WHERE season_id = $1", s.ID).Scan(&board.Total); err != nil {
+		return board, fmt.Errorf("failed to count ratings: %w", err)
+	}
+
+	rows, err := db.pool.Query(ctx, ratingLeaderboardSQL+" ORDER BY rank, name, user_id OFFSET $2 LIMIT $3", s.ID, offset, limit)
+	if err != nil {
+		return board, fmt.Errorf("failed to query rating leaderboard: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, err := scanRatingEntry(rows)
+		if err != nil {
+			return board, fmt.Errorf("failed to scan rating leaderboard entry: %w", err)
+		}
+		board.Entries = append(board.Entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return board, fmt.Errorf("error iterating rating leaderboard rows: %w", err)
+	}
+
+	board.Me, err = getRatingEntry(ctx, db.pool, s.ID, userID)
+	if err != nil {
+		return board, err
+	}
+
+	return board, nil
+}
+
+// GetPlayerRating returns userID's rating in a season, the current season
+// when seasonID is empty, and a page of their rated games in it.
+func (db *DB) GetPlayerRating(ctx context.Context, seasonID string, userID string, offset int, limit int) (types.PlayerRatingClient, error) {
+	player := types.PlayerRatingClient{History: make([]types.RatingChangeClient, 0)}
+
+	s, err := getSeason(ctx, db.pool, seasonID)
+	if err != nil {
+		return player, err
+	}
+	player.Season = s.client()
+
+	player.Rating, err = getRatingEntry(ctx, db.pool, s.ID, userID)
+	if err != nil {
+		return player, err
+	}
+
+	rows, err := db.pool.Query(ctx, `
+		SELECT rh.game_id::text, g.name, rh.place, round(rh.rating_before)::int, round(rh.rating_after)::int,
+			round(rh.rating_after - rh.rating_before)::int, rh.created_at
+		FROM rating_history rh
+		JOIN games g ON g.id = rh.game_id
+		WHERE rh.user_id = $1 AND rh.season_id = $2
+		ORDER BY rh.created_at DESC, rh.game_id
+		OFFSET $3 LIMIT $4
+	`, userID, s.ID, offset, limit)
+	if err != nil {
+		return player, fmt.Errorf("failed to query rating history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			change    types.RatingChangeClient
+			createdAt time.Time
+		)
+		if err := rows.Scan(&change.GameID, &change.GameName, &change.Place, &change.Before, &change.After, &change.Change, &createdAt); err != nil {
+			return player, fmt.Errorf("failed to scan rating change: %w", err)
+		}
+		change.CreatedAt = createdAt.UnixMilli()
+		player.History = append(player.History, change)
+	}
+	if err := rows.Err(); err != nil {
+		return player, fmt.Errorf("error iterating rating history rows: %w", err)
+	}
+
+	return player, nil
+}
@@ -0,0 +1,248 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"mindwarp/types"
+	"time"
+)
+
+// SetGameSchedule schedules, reschedules or, with a nil schedule, unschedules
+// a game that has not started yet. Unscheduling opens the lobby right away.
+// Reminders already sent go out again for the new time.
+func (db *DB) SetGameSchedule(ctx context.Context, gameID string, schedule *types.GameSchedule) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var status types.GameStatus
+	if err := tx.QueryRow(ctx, "SELECT status FROM games WHERE id = $1 FOR UPDATE", gameID).Scan(&status); err != nil {
+		return ErrGameNotFound
+	}
+	if status != types.GameScheduled && status != types.GameLobby {
+		return ErrInvalidTransition
+	}
+
+	if schedule == nil {
+		_, err = tx.Exec(ctx, `
+			UPDATE games
+			SET status = 'lobby', scheduled_at = NULL, timezone = NULL, reminder_offsets = '{}',
+				last_activity_at = now(), version = version + 1
+			WHERE id = $1
+		`, gameID)
+	} else {
+		_, err = tx.Exec(ctx, `
+			UPDATE games
+			SET status = 'scheduled', scheduled_at = $2, timezone = $3, reminder_offsets = $4,
+				last_activity_at = now(), version = version + 1
+			WHERE id = $1
+		`, gameID, schedule.StartsAt, schedule.Timezone, schedule.ReminderOffsets)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update game schedule: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM game_reminders WHERE game_id = $1", gameID); err != nil {
+		return fmt.Errorf("failed to reset game reminders: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// notifyGameAudience notifies the players of a game and everyone with an
+// open invite to it.
+func notifyGameAudience(ctx context.Context, q querier, gameID string, notifType types.NotificationType, payload map[string]any) error {
+	rows, err := q.Query(ctx, `
+		SELECT user_id::text FROM game_users WHERE game_id = $1
+		UNION
+		SELECT user_id::text FROM game_invites
+		WHERE game_id = $1 AND status = 'pending' AND expires_at > now()
+	`, gameID)
+	if err != nil {
+		return fmt.Errorf("failed to query game audience: %w", err)
+	}
+
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan game audience: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating game audience rows: %w", err)
+	}
+
+	for _, userID := range userIDs {
+		err := notify(ctx, q, types.NotificationServer{
+			UserID:  userID,
+			Type:    notifType,
+			GameID:  gameID,
+			Payload: payload,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SendDueReminders reminds the audience of every scheduled game whose
+// reminder time has come and returns how many games were reminded. When
+// several reminders of a game are due at once only the latest one is sent.
+func (db *DB) SendDueReminders(ctx context.Context) (int, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		WITH due AS (
+			INSERT INTO game_reminders (game_id, offset_minutes)
+			SELECT g.id, o.offset_minutes
+			FROM games g, unnest(g.reminder_offsets) AS o(offset_minutes)
+			WHERE g.status = 'scheduled'
+				AND g.scheduled_at > now()
+				AND g.scheduled_at - make_interval(mins => o.offset_minutes) <= now()
+			ON CONFLICT DO NOTHING
+			RETURNING game_id, offset_minutes
+		)
+		SELECT due.game_id::text, MIN(due.offset_minutes), g.scheduled_at
+		FROM due
+		JOIN games g ON g.id = due.game_id
+		GROUP BY due.game_id, g.scheduled_at
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record due reminders: %w", err)
+	}
+
+	type reminder struct {
+		gameID   string
+		offset   int
+		startsAt time.Time
+	}
+	var reminders []reminder
+	for rows.Next() {
+		var r reminder
+		if err := rows.Scan(&r.gameID, &r.offset, &r.startsAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan due reminder: %w", err)
+		}
+		reminders = append(reminders, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating due reminders rows: %w", err)
+	}
+
+	for _, r := range reminders {
+		err := notifyGameAudience(ctx, tx, r.gameID, types.NotificationGameReminder, map[string]any{
+			"startsAt":      r.startsAt.UnixMilli(),
+			"minutesBefore": r.offset,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(reminders), nil
+}
+
+// OpenScheduledGames moves scheduled games whose start time has come to the
+// lobby, tells their audience and returns their ids.
+func (db *DB) OpenScheduledGames(ctx context.Context) ([]string, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		UPDATE games SET status = 'lobby', last_activity_at = now(), version = version + 1
+		WHERE status = 'scheduled' AND scheduled_at <= now()
+		RETURNING id::text
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open scheduled games: %w", err)
+	}
+
+	gameIDs := make([]string, 0)
+	for rows.Next() {
+		var gameID string
+		if err := rows.Scan(&gameID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan game id: %w", err)
+		}
+		gameIDs = append(gameIDs, gameID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating opened games rows: %w", err)
+	}
+
+	for _, gameID := range gameIDs {
+		if err := notifyGameAudience(ctx, tx, gameID, types.NotificationLobbyOpen, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return gameIDs, nil
+}
+
+// GetScheduledGames returns the scheduled games a user hosts, plays in or is
+// invited to, soonest first. With a gameID only that game is returned.
+// Abandoned games are left out.
+func (db *DB) GetScheduledGames(ctx context.Context, userID string, gameID string) ([]types.ScheduledGameServer, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT g.id::text, g.name, COALESCE(h.name, ''), g.status, g.scheduled_at, COALESCE(g.timezone, 'UTC'), g.reminder_offsets
+		FROM games g
+		LEFT JOIN users h ON h.id = g.creator_id
+		WHERE g.scheduled_at IS NOT NULL
+			AND g.status != 'abandoned'
+			AND ($2 = '' OR g.id::text = $2)
+			AND (
+				g.creator_id = $1
+				OR EXISTS (SELECT 1 FROM game_users gu WHERE gu.game_id = g.id AND gu.user_id = $1)
+				OR EXISTS (
+					SELECT 1 FROM game_invites gi
+					WHERE gi.game_id = g.id AND gi.user_id = $1 AND gi.status = 'pending' AND gi.expires_at > now()
+				)
+			)
+		ORDER BY g.scheduled_at
+	`, userID, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scheduled games: %w", err)
+	}
+	defer rows.Close()
+
+	games := make([]types.ScheduledGameServer, 0)
+	for rows.Next() {
+		var game types.ScheduledGameServer
+		err := rows.Scan(&game.ID, &game.Name, &game.HostName, &game.Status, &game.Schedule.StartsAt, &game.Schedule.Timezone, &game.Schedule.ReminderOffsets)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled game: %w", err)
+		}
+		games = append(games, game)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating scheduled games rows: %w", err)
+	}
+
+	return games, nil
+}
@@ -0,0 +1,399 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mindwarp/logger"
+	"mindwarp/scoring"
+	"mindwarp/types"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrUserNotInGame  = errors.New("user is not a participant of this game")
+	ErrRoundNotInGame = errors.New("round does not belong to this game")
+)
+
+func loadScoringInput(ctx context.Context, q querier, gameID string) (scoring.Input, error) {
+	var in scoring.Input
+
+	var (
+		gameRulesJSON []byte
+		mode          types.GameMode
+	)
+	if err := q.QueryRow(ctx, "SELECT rules, mode FROM games WHERE id = $1", gameID).Scan(&gameRulesJSON, &mode); err != nil {
+		return in, fmt.Errorf("failed to get game rules: %w", err)
+	}
+	in.Rules = unmarshalGameRules(gameID, gameRulesJSON)
+	in.Independent = mode == types.GameModeAsync
+
+	rows, err := q.Query(ctx, `SELECT id, rules, is_final FROM rounds WHERE game_id = $1`, gameID)
+	if err != nil {
+		return in, fmt.Errorf("failed to query rounds: %w", err)
+	}
+	for rows.Next() {
+		var (
+			roundID   string
+			rulesJSON []byte
+			isFinal   bool
+		)
+		if err := rows.Scan(&roundID, &rulesJSON, &isFinal); err != nil {
+			rows.Close()
+			return in, fmt.Errorf("failed to scan round: %w", err)
+		}
+		rules := unmarshalRoundRules(roundID, rulesJSON)
+		in.Rounds = append(in.Rounds, scoring.Round{ID: roundID, NegativePoints: rules.HasNegativePoints(in.Rules), IsFinal: isFinal})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return in, fmt.Errorf("error iterating rounds rows: %w", err)
+	}
+
+	rows, err = q.Query(ctx, `
+		SELECT q.id, t.round_id, q.points, q.question_type
+		FROM questions q
+		JOIN themes t ON t.id = q.theme_id
+		JOIN rounds r ON r.id = t.round_id
+		WHERE r.game_id = $1
+	`, gameID)
+	if err != nil {
+		return in, fmt.Errorf("failed to query questions: %w", err)
+	}
+	for rows.Next() {
+		var question scoring.Question
+		if err := rows.Scan(&question.ID, &question.RoundID, &question.Points, &question.Type); err != nil {
+			rows.Close()
+			return in, fmt.Errorf("failed to scan question: %w", err)
+		}
+		in.Questions = append(in.Questions, question)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return in, fmt.Errorf("error iterating questions rows: %w", err)
+	}
+
+	rows, err = q.Query(ctx, `
+		SELECT a.question_id, a.user_id, COALESCE(a.contributor_id::text, ''), a.is_correct, a.time_answered, a.timed_out
+		FROM answers a
+		JOIN questions q ON q.id = a.question_id
+		JOIN themes t ON t.id = q.theme_id
+		JOIN rounds r ON r.id = t.round_id
+		WHERE r.game_id = $1
+		ORDER BY a.attempt NULLS LAST, a.created_at, a.id
+	`, gameID)
+	if err != nil {
+		return in, fmt.Errorf("failed to query answers: %w", err)
+	}
+	for rows.Next() {
+		var answer scoring.Answer
+		if err := rows.Scan(&answer.QuestionID, &answer.UserID, &answer.ContributorID, &answer.IsCorrect, &answer.TimeAnswered, &answer.TimedOut); err != nil {
+			rows.Close()
+			return in, fmt.Errorf("failed to scan answer: %w", err)
+		}
+		in.Answers = append(in.Answers, answer)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return in, fmt.Errorf("error iterating answers rows: %w", err)
+	}
+
+	rows, err = q.Query(ctx, `SELECT user_id, round_id, points FROM score_adjustments WHERE game_id = $1`, gameID)
+	if err != nil {
+		return in, fmt.Errorf("failed to query score adjustments: %w", err)
+	}
+	for rows.Next() {
+		var adjustment scoring.Adjustment
+		if err := rows.Scan(&adjustment.UserID, &adjustment.RoundID, &adjustment.Points); err != nil {
+			rows.Close()
+			return in, fmt.Errorf("failed to scan score adjustment: %w", err)
+		}
+		in.Adjustments = append(in.Adjustments, adjustment)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return in, fmt.Errorf("error iterating score adjustments rows: %w", err)
+	}
+
+	rows, err = q.Query(ctx, `
+		SELECT fw.user_id, fw.round_id, fw.wager, fw.is_correct
+		FROM final_wagers fw
+		JOIN rounds r ON r.id = fw.round_id
+		WHERE r.game_id = $1 AND fw.revealed_at IS NOT NULL AND fw.is_correct IS NOT NULL
+	`, gameID)
+	if err != nil {
+		return in, fmt.Errorf("failed to query final wagers: %w", err)
+	}
+	for rows.Next() {
+		var wager scoring.Wager
+		if err := rows.Scan(&wager.UserID, &wager.RoundID, &wager.Amount, &wager.IsCorrect); err != nil {
+			rows.Close()
+			return in, fmt.Errorf("failed to scan final wager: %w", err)
+		}
+		in.Wagers = append(in.Wagers, wager)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return in, fmt.Errorf("error iterating final wagers rows: %w", err)
+	}
+
+	rows, err = q.Query(ctx, `
+		SELECT qp.question_id, qp.assignee_id, qp.stake
+		FROM question_plays qp
+		JOIN questions q ON q.id = qp.question_id
+		JOIN themes t ON t.id = q.theme_id
+		JOIN rounds r ON r.id = t.round_id
+		WHERE r.game_id = $1
+	`, gameID)
+	if err != nil {
+		return in, fmt.Errorf("failed to query question plays: %w", err)
+	}
+	for rows.Next() {
+		var play scoring.Play
+		if err := rows.Scan(&play.QuestionID, &play.AssigneeID, &play.Stake); err != nil {
+			rows.Close()
+			return in, fmt.Errorf("failed to scan question play: %w", err)
+		}
+		in.Plays = append(in.Plays, play)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return in, fmt.Errorf("error iterating question plays rows: %w", err)
+	}
+
+	return in, nil
+}
+
+func getStoredScores(ctx context.Context, q querier, gameID string) (scoring.Scores, error) {
+	rows, err := q.Query(ctx, `SELECT user_id, round_scores FROM game_users WHERE game_id = $1`, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query game users: %w", err)
+	}
+	defer rows.Close()
+
+	scores := make(scoring.Scores)
+	for rows.Next() {
+		var (
+			userID      string
+			roundScores map[string]int16
+		)
+		if err := rows.Scan(&userID, &roundScores); err != nil {
+			return nil, fmt.Errorf("failed to scan game user: %w", err)
+		}
+		scores[userID] = roundScores
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating game users rows: %w", err)
+	}
+
+	return scores, nil
+}
+
+// storeGameScores recomputes every participant's round scores from answers
+// and adjustments and writes them to game_users.round_scores. Every change to
+// a game goes through here, so it also bumps the game's version.
+func storeGameScores(ctx context.Context, q querier, gameID string) (scoring.Scores, error) {
+	in, err := loadScoringInput(ctx, q, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := scoring.Compute(in)
+
+	stored, err := getStoredScores(ctx, q, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	for userID := range stored {
+		roundScores := scores[userID]
+		if roundScores == nil {
+			roundScores = map[string]int16{}
+		}
+
+		_, err := q.Exec(ctx, "UPDATE game_users SET round_scores = $1 WHERE game_id = $2 AND user_id = $3", roundScores, gameID, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update scores for user %s: %w", userID, err)
+		}
+	}
+
+	if err := storeTeamScores(ctx, q, gameID, scores); err != nil {
+		return nil, err
+	}
+
+	if _, err := q.Exec(ctx, "UPDATE games SET version = version + 1 WHERE id = $1", gameID); err != nil {
+		return nil, fmt.Errorf("failed to bump game version: %w", err)
+	}
+
+	return scores, nil
+}
+
+// RecomputeGameScores rebuilds the stored scores of a single game.
+func (db *DB) RecomputeGameScores(ctx context.Context, gameID string) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := storeGameScores(ctx, tx, gameID); err != nil {
+		return fmt.Errorf("failed to recompute scores: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// RepairGameScores finds games whose stored round_scores have drifted from
+// the computed values and rewrites them. With dryRun set nothing is written.
+// It returns the ids of the drifted games.
+func (db *DB) RepairGameScores(ctx context.Context, dryRun bool) ([]string, error) {
+	rows, err := db.pool.Query(ctx, "SELECT id FROM games ORDER BY created_at")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query games: %w", err)
+	}
+	gameIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan games: %w", err)
+	}
+
+	drifted := make([]string, 0)
+	for _, gameID := range gameIDs {
+		in, err := loadScoringInput(ctx, db.pool, gameID)
+		if err != nil {
+			return drifted, fmt.Errorf("game %s: %w", gameID, err)
+		}
+
+		stored, err := getStoredScores(ctx, db.pool, gameID)
+		if err != nil {
+			return drifted, fmt.Errorf("game %s: %w", gameID, err)
+		}
+
+		// Answers of users who have since left the game are not stored anywhere,
+		// so only compare the current participants.
+		computed := scoring.Compute(in)
+		for userID := range computed {
+			if _, ok := stored[userID]; !ok {
+				delete(computed, userID)
+			}
+		}
+
+		if scoring.Equal(stored, computed) {
+			continue
+		}
+
+		logger.Infof("Scores drifted for game %s", gameID)
+		drifted = append(drifted, gameID)
+		if dryRun {
+			continue
+		}
+
+		if err := db.RecomputeGameScores(ctx, gameID); err != nil {
+			return drifted, fmt.Errorf("game %s: %w", gameID, err)
+		}
+	}
+
+	return drifted, nil
+}
+
+// AddScoreAdjustment records a manual host adjustment and refreshes the
+// game's scores in the same transaction. version is the game version the
+// host last read; zero skips the check.
+func (db *DB) AddScoreAdjustment(ctx context.Context, adjustment types.ScoreAdjustmentServer, version int) (types.GameEventServer, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return types.GameEventServer{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockGameVersion(ctx, tx, adjustment.GameID, version); err != nil {
+		return types.GameEventServer{}, err
+	}
+
+	var isPlayer, hasRound bool
+	err = tx.QueryRow(ctx, `
+		SELECT
+			EXISTS (SELECT 1 FROM game_users WHERE game_id = $1 AND user_id = $2),
+			EXISTS (SELECT 1 FROM rounds WHERE game_id = $1 AND id = $3)
+	`, adjustment.GameID, adjustment.UserID, adjustment.RoundID).Scan(&isPlayer, &hasRound)
+	if err != nil {
+		return types.GameEventServer{}, fmt.Errorf("failed to validate score adjustment: %w", err)
+	}
+	if !isPlayer {
+		return types.GameEventServer{}, ErrUserNotInGame
+	}
+	if !hasRound {
+		return types.GameEventServer{}, ErrRoundNotInGame
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO score_adjustments (game_id, user_id, round_id, points, reason, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, adjustment.GameID, adjustment.UserID, adjustment.RoundID, adjustment.Points, adjustment.Reason, adjustment.CreatedBy)
+	if err != nil {
+		return types.GameEventServer{}, fmt.Errorf("failed to insert score adjustment: %w", err)
+	}
+
+	if _, err := storeGameScores(ctx, tx, adjustment.GameID); err != nil {
+		return types.GameEventServer{}, fmt.Errorf("failed to recompute scores: %w", err)
+	}
+
+	mutation := types.GameMutation{GameID: adjustment.GameID, ActorID: adjustment.CreatedBy}
+	event, err := recordGameEvent(ctx, tx, mutation, types.EventScoreAdjusted, map[string]any{
+		"userId":  adjustment.UserID,
+		"roundId": adjustment.RoundID,
+		"points":  adjustment.Points,
+		"reason":  adjustment.Reason,
+	})
+	if err != nil {
+		return event, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return event, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return event, nil
+}
+
+func (db *DB) GetScoreAdjustmentsByGameId(ctx context.Context, gameID string) ([]types.ScoreAdjustmentClient, error) {
+	query := `
+		SELECT
+			sa.id, sa.user_id, sa.round_id, sa.points, sa.reason,
+			COALESCE(sa.created_by::text, ''), COALESCE(u.name, ''), sa.created_at
+		FROM
+			score_adjustments sa
+		LEFT JOIN users u ON u.id = sa.created_by
+		WHERE
+			sa.game_id = $1
+		ORDER BY sa.created_at
+	`
+
+	rows, err := db.pool.Query(ctx, query, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query score adjustments: %w", err)
+	}
+	defer rows.Close()
+
+	adjustments := make([]types.ScoreAdjustmentClient, 0)
+	for rows.Next() {
+		var adjustment types.ScoreAdjustmentClient
+		var createdAt time.Time
+		err := rows.Scan(&adjustment.ID, &adjustment.UserID, &adjustment.RoundID, &adjustment.Points, &adjustment.Reason, &adjustment.CreatedBy, &adjustment.CreatedByName, &createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan score adjustment: %w", err)
+		}
+		adjustment.CreatedAt = createdAt.UnixMilli()
+		adjustments = append(adjustments, adjustment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating score adjustments rows: %w", err)
+	}
+
+	return adjustments, nil
+}
@@ -0,0 +1,330 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mindwarp/scoring"
+	"mindwarp/types"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrQuestionNotInGame     = errors.New("question does not belong to this game")
+	ErrWrongQuestionType     = errors.New("question type does not support this action")
+	ErrQuestionAlreadyPlayed = errors.New("question has already been assigned")
+	ErrNotPicker             = errors.New("only the current picker or the host can hand out this question")
+	ErrInvalidAssignee       = errors.New("the question must be given to another player of this game")
+	ErrBidTooLow             = errors.New("bid must be at least the question's points and above the current highest bid")
+	ErrBidTooHigh            = errors.New("bid exceeds current score")
+	ErrNoBids                = errors.New("auction has no bids")
+)
+
+type gameQuestion struct {
+	questionType types.QuestionType
+	points       int
+	creatorID    string
+	pickerID     *string
+	played       bool
+	isFinal      bool
+}
+
+// getGameQuestion loads a question together with the game state needed to
+// play it, making sure it belongs to gameID.
+func getGameQuestion(ctx context.Context, q querier, gameID string, questionID string) (gameQuestion, error) {
+	var question gameQuestion
+	err := q.QueryRow(ctx, `
+		SELECT
+			q.question_type, q.points, g.creator_id, g.current_user_id::text,
+			EXISTS (SELECT 1 FROM question_plays qp WHERE qp.question_id = q.id),
+			r.is_final
+		FROM questions q
+		JOIN themes t ON t.id = q.theme_id
+		JOIN rounds r ON r.id = t.round_id
+		JOIN games g ON g.id = r.game_id
+		WHERE q.id = $1 AND g.id = $2
+	`, questionID, gameID).Scan(&question.questionType, &question.points, &question.creatorID, &question.pickerID, &question.played, &question.isFinal)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return question, ErrQuestionNotInGame
+	}
+	if err != nil {
+		return question, fmt.Errorf("failed to get question: %w", err)
+	}
+	return question, nil
+}
+
+// lockAuction locks an auction question of gameID, so that bids and the
+// close that settles them run one at a time, and loads it once the lock is
+// held.
+func lockAuction(ctx context.Context, q querier, gameID string, questionID string) (gameQuestion, error) {
+	var id string
+	err := q.QueryRow(ctx, `
+		SELECT q.id::text
+		FROM questions q
+		JOIN themes t ON t.id = q.theme_id
+		JOIN rounds r ON r.id = t.round_id
+		WHERE q.id = $1 AND r.game_id = $2
+		FOR UPDATE OF q
+	`, questionID, gameID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return gameQuestion{}, ErrQuestionNotInGame
+	}
+	if err != nil {
+		return gameQuestion{}, fmt.Errorf("failed to lock auction: %w", err)
+	}
+
+	question, err := getGameQuestion(ctx, q, gameID, questionID)
+	if err != nil {
+		return question, err
+	}
+	if question.questionType != types.QuestionAuction {
+		return question, ErrWrongQuestionType
+	}
+	if question.played {
+		return question, ErrQuestionAlreadyPlayed
+	}
+	return question, nil
+}
+
+func isGameUser(ctx context.Context, q querier, gameID string, userID string) (bool, error) {
+	var exists bool
+	err := q.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM game_users WHERE game_id = $1 AND user_id = $2)", gameID, userID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check game user: %w", err)
+	}
+	return exists, nil
+}
+
+// AssignCatInBag hands a cat-in-the-bag question from the picker to another
+// player, who then has to answer it.
+func (db *DB) AssignCatInBag(ctx context.Context, gameID string, questionID string, pickerID string, assigneeID string) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	question, err := getGameQuestion(ctx, tx, gameID, questionID)
+	if err != nil {
+		return err
+	}
+	if err := requireGameInProgress(ctx, tx, gameID); err != nil {
+		return err
+	}
+	if question.questionType != types.QuestionCatInBag {
+		return ErrWrongQuestionType
+	}
+	if question.played {
+		return ErrQuestionAlreadyPlayed
+	}
+
+	isPicker := question.pickerID != nil && *question.pickerID == pickerID
+	if !isPicker && question.creatorID != pickerID {
+		return ErrNotPicker
+	}
+
+	if assigneeID == pickerID {
+		return ErrInvalidAssignee
+	}
+	ok, err := isGameUser(ctx, tx, gameID, assigneeID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidAssignee
+	}
+
+	_, err = tx.Exec(ctx, "INSERT INTO question_plays (question_id, picker_id, assignee_id) VALUES ($1, $2, $3)", questionID, pickerID, assigneeID)
+	if err != nil {
+		return fmt.Errorf("failed to assign question: %w", err)
+	}
+
+	if _, err := storeGameScores(ctx, tx, gameID); err != nil {
+		return fmt.Errorf("failed to recompute scores: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// PlaceAuctionBid records a player's bid. Bids start at the question's points,
+// must beat the current highest bid and may not exceed the player's score
+// (a player can always bid the question's own value).
+func (db *DB) PlaceAuctionBid(ctx context.Context, gameID string, questionID string, userID string, amount int) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	question, err := lockAuction(ctx, tx, gameID, questionID)
+	if err != nil {
+		return err
+	}
+	if err := requireGameInProgress(ctx, tx, gameID); err != nil {
+		return err
+	}
+
+	ok, err := isGameUser(ctx, tx, gameID, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUserNotInGame
+	}
+
+	var highest int
+	err = tx.QueryRow(ctx, "SELECT COALESCE(MAX(amount), 0) FROM auction_bids WHERE question_id = $1", questionID).Scan(&highest)
+	if err != nil {
+		return fmt.Errorf("failed to get highest bid: %w", err)
+	}
+	if amount < question.points || amount <= highest {
+		return ErrBidTooLow
+	}
+
+	in, err := loadScoringInput(ctx, tx, gameID)
+	if err != nil {
+		return err
+	}
+	if amount > max(scoring.Compute(in).Total(userID), question.points) {
+		return ErrBidTooHigh
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO auction_bids (question_id, user_id, amount)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (question_id, user_id)
+		DO UPDATE SET amount = EXCLUDED.amount, created_at = now()
+	`, questionID, userID, amount)
+	if err != nil {
+		return fmt.Errorf("failed to place bid: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// CloseAuction gives the question to the highest bidder, who plays for the
+// amount they bid. Earlier bids win ties.
+func (db *DB) CloseAuction(ctx context.Context, gameID string, questionID string) (types.QuestionPlayClient, error) {
+	var play types.QuestionPlayClient
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return play, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := lockAuction(ctx, tx, gameID, questionID); err != nil {
+		return play, err
+	}
+	if err := requireGameInProgress(ctx, tx, gameID); err != nil {
+		return play, err
+	}
+
+	var stake int
+	err = tx.QueryRow(ctx, `
+		SELECT user_id, amount FROM auction_bids
+		WHERE question_id = $1
+		ORDER BY amount DESC, created_at
+		LIMIT 1
+	`, questionID).Scan(&play.AssigneeID, &stake)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return play, ErrNoBids
+	}
+	if err != nil {
+		return play, fmt.Errorf("failed to get highest bid: %w", err)
+	}
+	play.Stake = &stake
+
+	_, err = tx.Exec(ctx, "INSERT INTO question_plays (question_id, assignee_id, stake) VALUES ($1, $2, $3)", questionID, play.AssigneeID, stake)
+	if err != nil {
+		return play, fmt.Errorf("failed to close auction: %w", err)
+	}
+
+	if _, err := storeGameScores(ctx, tx, gameID); err != nil {
+		return play, fmt.Errorf("failed to recompute scores: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return play, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return play, nil
+}
+
+func (db *DB) GetAuctionBids(ctx context.Context, gameID string, questionID string) ([]types.AuctionBidClient, error) {
+	if _, err := getGameQuestion(ctx, db.pool, gameID, questionID); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.pool.Query(ctx, `
+		SELECT ab.user_id, u.name, ab.amount, ab.created_at
+		FROM auction_bids ab
+		JOIN users u ON u.id = ab.user_id
+		WHERE ab.question_id = $1
+		ORDER BY ab.amount DESC, ab.created_at
+	`, questionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query auction bids: %w", err)
+	}
+	defer rows.Close()
+
+	bids := make([]types.AuctionBidClient, 0)
+	for rows.Next() {
+		var (
+			bid       types.AuctionBidClient
+			createdAt time.Time
+		)
+		if err := rows.Scan(&bid.UserID, &bid.Name, &bid.Amount, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan auction bid: %w", err)
+		}
+		bid.CreatedAt = createdAt.UnixMilli()
+		bids = append(bids, bid)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating auction bids rows: %w", err)
+	}
+
+	return bids, nil
+}
+
+func (db *DB) GetQuestionPlaysByQuestionIds(ctx context.Context, questionIds []string) (map[string]*types.QuestionPlayClient, error) {
+	plays := make(map[string]*types.QuestionPlayClient)
+	if len(questionIds) == 0 {
+		return plays, nil
+	}
+
+	rows, err := db.pool.Query(ctx, `
+		SELECT question_id, COALESCE(picker_id::text, ''), assignee_id, stake
+		FROM question_plays
+		WHERE question_id = ANY($1)
+	`, questionIds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query question plays: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			questionID string
+			play       types.QuestionPlayClient
+		)
+		if err := rows.Scan(&questionID, &play.PickerID, &play.AssigneeID, &play.Stake); err != nil {
+			return nil, fmt.Errorf("failed to scan question play: %w", err)
+		}
+		plays[questionID] = &play
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating question plays rows: %w", err)
+	}
+
+	return plays, nil
+}
@@ -0,0 +1,296 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mindwarp/logger"
+	"mindwarp/scoring"
+	"mindwarp/types"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrGameAlreadyFinished = errors.New("game is already finished")
+
+// loadPlayerResults combines the freshly computed scores with the per-player
+// answer statistics used by the tie-breakers.
+func loadPlayerResults(ctx context.Context, q querier, gameID string, scores scoring.Scores) ([]scoring.PlayerResult, error) {
+	rows, err := q.Query(ctx, `
+		SELECT
+			gu.user_id,
+			COUNT(a.id) FILTER (WHERE a.is_correct),
+			AVG(a.time_answered)::float8
+		FROM
+			game_users gu
+		LEFT JOIN answers a ON a.user_id = gu.user_id AND a.question_id IN (
+			SELECT q.id
+			FROM questions q
+			JOIN themes t ON t.id = q.theme_id
+			JOIN rounds r ON r.id = t.round_id
+			WHERE r.game_id = $1
+		)
+		WHERE
+			gu.game_id = $1
+		GROUP BY gu.user_id
+	`, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query player results: %w", err)
+	}
+	defer rows.Close()
+
+	results := make([]scoring.PlayerResult, 0)
+	for rows.Next() {
+		var result scoring.PlayerResult
+		if err := rows.Scan(&result.UserID, &result.CorrectAnswers, &result.AvgTimeAnswered); err != nil {
+			return nil, fmt.Errorf("failed to scan player result: %w", err)
+		}
+		result.Score = scores.Total(result.UserID)
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating player results rows: %w", err)
+	}
+
+	return results, nil
+}
+
+// FinishGame computes the final standings from the server-side scores, stores
+// the full ranking and marks the game finished. When the game breaks ties with
+// a sudden-death question and first place is shared, suddenDeathWinner must
+// name the player who won it; otherwise scoring.ErrSuddenDeathRequired is
+// returned together with the tied players. Games with teams rank the teams
+// instead, and the ids above are team ids.
+func (db *DB) FinishGame(ctx context.Context, gameID string, suddenDeathWinner string) ([]scoring.Standing, []string, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	standings, tied, err := finishGame(ctx, tx, gameID, suddenDeathWinner)
+	if err != nil {
+		return nil, tied, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return standings, nil, nil
+}
+
+// finishGame ranks and finishes a game inside tx. Async games have nobody to
+// play a sudden-death question, so they skip that tie-breaker and may end
+// with a shared first place. The game counts towards the leaderboards and,
+// unless it is casual, moves its players' ratings by their places; a game
+// played for a tournament match moves its bracket on.
+func finishGame(ctx context.Context, tx pgx.Tx, gameID string, suddenDeathWinner string) ([]scoring.Standing, []string, error) {
+	var (
+		status      types.GameStatus
+		mode        types.GameMode
+		tieBreakers []string
+		casual      bool
+	)
+	err := tx.QueryRow(ctx, "SELECT status, mode, tie_breakers, casual FROM games WHERE id = $1 FOR UPDATE", gameID).Scan(&status, &mode, &tieBreakers, &casual)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get game: %w", err)
+	}
+	if status == types.GameFinished {
+		return nil, nil, ErrGameAlreadyFinished
+	}
+	if !status.CanBecome(types.GameFinished) {
+		return nil, nil, ErrInvalidTransition
+	}
+
+	scores, err := storeGameScores(ctx, tx, gameID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compute scores: %w", err)
+	}
+
+	isTeamGame, err := gameHasTeams(ctx, tx, gameID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var results []scoring.PlayerResult
+	if isTeamGame {
+		results, err = loadTeamResults(ctx, tx, gameID, scores)
+	} else {
+		results, err = loadPlayerResults(ctx, tx, gameID, scores)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rules := make([]scoring.TieBreaker, 0, len(tieBreakers))
+	for _, tieBreaker := range tieBreakers {
+		if mode == types.GameModeAsync && scoring.TieBreaker(tieBreaker) == scoring.TieBreakSuddenDeath {
+			continue
+		}
+		rules = append(rules, scoring.TieBreaker(tieBreaker))
+	}
+
+	standings, tied, err := scoring.Rank(results, rules, suddenDeathWinner)
+	if err != nil {
+		return nil, tied, err
+	}
+
+	insertStanding := `
+		INSERT INTO game_standings (game_id, user_id, place, score, correct_answers, avg_time_answered, won_sudden_death)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	if isTeamGame {
+		insertStanding = `
+			INSERT INTO game_team_standings (game_id, team_id, place, score, correct_answers, avg_time_answered, won_sudden_death)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`
+	}
+
+	batch := &pgx.Batch{}
+	for _, standing := range standings {
+		batch.Queue(insertStanding, gameID, standing.UserID, standing.Place, standing.Score, standing.CorrectAnswers, standing.AvgTimeAnswered, standing.WonSuddenDeath)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return nil, nil, fmt.Errorf("failed to insert standings: %w", err)
+	}
+
+	winnerID, winnerTeamID := interface{}(nil), interface{}(nil)
+	if winner := scoring.Winner(standings); winner != "" {
+		if isTeamGame {
+			winnerTeamID = winner
+		} else {
+			winnerID = winner
+		}
+	}
+
+	logger.Infof("Finishing game %s, winner %v, winning team %v", gameID, winnerID, winnerTeamID)
+	_, err = tx.Exec(ctx, "UPDATE games SET is_finished = true, status = 'finished', winner_id = $1, winner_team_id = $2, finish_date = NOW(), last_activity_at = NOW() WHERE id = $3", winnerID, winnerTeamID, gameID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to finish game: %w", err)
+	}
+
+	var creatorID string
+	if err := tx.QueryRow(ctx, "SELECT creator_id::text FROM games WHERE id = $1", gameID).Scan(&creatorID); err != nil {
+		return nil, nil, fmt.Errorf("failed to get game creator: %w", err)
+	}
+	// Async games usually finish on their own, so the host is told as well
+	actorID := creatorID
+	if mode == types.GameModeAsync {
+		actorID = ""
+	}
+	if err := notifyGamePlayers(ctx, tx, gameID, types.NotificationGameFinished, actorID); err != nil {
+		return nil, nil, err
+	}
+
+	if err := recordLeaderboards(ctx, tx, gameID); err != nil {
+		return nil, nil, err
+	}
+
+	if !casual {
+		if err := rateGame(ctx, tx, gameID, standings, isTeamGame); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := advanceTournament(ctx, tx, gameID, scoring.Winner(standings)); err != nil {
+		return nil, nil, err
+	}
+
+	return standings, nil, nil
+}
+
+func (db *DB) GetGameResults(ctx context.Context, gameID string) (types.GameResultsClient, error) {
+	results := types.GameResultsClient{GameID: gameID}
+
+	var (
+		finishDate *time.Time
+		winnerID   *string
+		winnerName *string
+	)
+	err := db.pool.QueryRow(ctx, `
+		SELECT g.is_finished, g.finish_date, g.tie_breakers, w.id::text, w.name
+		FROM games g
+		LEFT JOIN users w ON w.id = g.winner_id
+		WHERE g.id = $1
+	`, gameID).Scan(&results.IsFinished, &finishDate, &results.TieBreakers, &winnerID, &winnerName)
+	if err != nil {
+		return results, fmt.Errorf("failed to get game: %w", err)
+	}
+
+	if finishDate != nil {
+		results.FinishDate = finishDate.UnixMilli()
+	}
+	if winnerID != nil {
+		results.Winner = types.UserClient{ID: *winnerID, Name: *winnerName}
+	}
+
+	rows, err := db.pool.Query(ctx, `
+		SELECT gs.user_id, u.name, gs.place, gs.score, gs.correct_answers, gs.avg_time_answered, gs.won_sudden_death
+		FROM game_standings gs
+		JOIN users u ON u.id = gs.user_id
+		WHERE gs.game_id = $1
+		ORDER BY gs.place, gs.won_sudden_death DESC, u.name
+	`, gameID)
+	if err != nil {
+		return results, fmt.Errorf("failed to query standings: %w", err)
+	}
+	defer rows.Close()
+
+	results.Standings = make([]types.StandingClient, 0)
+	results.Podium = make([]types.StandingClient, 0)
+	for rows.Next() {
+		var standing types.StandingClient
+		err := rows.Scan(&standing.UserID, &standing.Name, &standing.Place, &standing.Score, &standing.CorrectAnswers, &standing.AvgTimeAnswered, &standing.WonSuddenDeath)
+		if err != nil {
+			return results, fmt.Errorf("failed to scan standing: %w", err)
+		}
+
+		results.Standings = append(results.Standings, standing)
+		if standing.Place <= 3 {
+			results.Podium = append(results.Podium, standing)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return results, fmt.Errorf("error iterating standings rows: %w", err)
+	}
+	rows.Close()
+
+	rows, err = db.pool.Query(ctx, `
+		SELECT gs.team_id, t.name, gs.place, gs.score, gs.correct_answers, gs.avg_time_answered, gs.won_sudden_death, g.winner_team_id IS NOT DISTINCT FROM gs.team_id
+		FROM game_team_standings gs
+		JOIN game_teams t ON t.id = gs.team_id
+		JOIN games g ON g.id = gs.game_id
+		WHERE gs.game_id = $1
+		ORDER BY gs.place, gs.won_sudden_death DESC, t.name
+	`, gameID)
+	if err != nil {
+		return results, fmt.Errorf("failed to query team standings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			standing types.TeamStandingClient
+			isWinner bool
+		)
+		err := rows.Scan(&standing.TeamID, &standing.Name, &standing.Place, &standing.Score, &standing.CorrectAnswers, &standing.AvgTimeAnswered, &standing.WonSuddenDeath, &isWinner)
+		if err != nil {
+			return results, fmt.Errorf("failed to scan team standing: %w", err)
+		}
+
+		results.TeamStandings = append(results.TeamStandings, standing)
+		if isWinner {
+			results.WinnerTeam = &standing
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return results, fmt.Errorf("error iterating team standings rows: %w", err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,202 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mindwarp/types"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrUserNotFound = errors.New("user not found")
+
+const (
+	statsThemeLimit    = 10
+	statsTemplateLimit = 5
+	statsOpponentLimit = 10
+)
+
+// playedGamesSQL is the CTE of the finished games of user $1 with the place
+// they finished in: their own, or their team's in team games.
+const playedGamesSQL = `
+	played AS (
+		SELECT gu.game_id, g.template_id, gu.team_id, COALESCE(gs.place, gts.place) AS place
+		FROM game_users gu
+		JOIN games g ON g.id = gu.game_id
+		LEFT JOIN game_standings gs ON gs.game_id = gu.game_id AND gs.user_id = gu.user_id
+		LEFT JOIN game_team_standings gts ON gts.game_id = gu.game_id AND gts.team_id = gu.team_id
+		WHERE gu.user_id = $1 AND g.is_finished
+	)
+`
+
+// answeredSQL is the CTE of user $1's judged answers in the played games.
+const answeredSQL = `
+	answered AS (
+		SELECT a.id, a.is_correct, a.time_answered, a.created_at, t.name AS theme
+		FROM answers a
+		JOIN questions q ON q.id = a.question_id
+		JOIN themes t ON t.id = q.theme_id
+		JOIN rounds r ON r.id = t.round_id
+		JOIN played p ON p.game_id = r.game_id
+		WHERE a.user_id = $1 AND a.is_correct IS NOT NULL
+	)
+`
+
+func ratio(part int, whole int) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(part) / float64(whole)
+}
+
+// GetPlayerStats sums up userID's finished games: results, accuracy and
+// answer times, scoring by round position, the templates they play most and
+// their record against the opponents they meet most. The longest correct
+// streak runs over their answers in the order they were given.
+func (db *DB) GetPlayerStats(ctx context.Context, userID string) (types.PlayerStatsClient, error) {
+	stats := types.PlayerStatsClient{
+		UserID:             userID,
+		Themes:             make([]types.ThemeStatsClient, 0),
+		Rounds:             make([]types.RoundPositionStatsClient, 0),
+		FavouriteTemplates: make([]types.TemplateStatsClient, 0),
+		HeadToHead:         make([]types.HeadToHeadClient, 0),
+	}
+
+	err := db.pool.QueryRow(ctx, "SELECT name FROM users WHERE id = $1", userID).Scan(&stats.Name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return stats, ErrUserNotFound
+	}
+	if err != nil {
+		return stats, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	err = db.pool.QueryRow(ctx, `
+		WITH `+playedGamesSQL+`, `+answeredSQL+`,
+		streaks AS (
+			SELECT is_correct,
+				ROW_NUMBER() OVER (ORDER BY created_at, id)
+					- ROW_NUMBER() OVER (PARTITION BY is_correct ORDER BY created_at, id) AS streak
+			FROM answered
+		)
+		SELECT
+			(SELECT COUNT(*) FROM played),
+			(SELECT COUNT(*) FROM played WHERE place = 1),
+			(SELECT COUNT(*) FROM answered),
+			(SELECT COUNT(*) FROM answered WHERE is_correct),
+			(SELECT AVG(time_answered)::float8 FROM answered),
+			(SELECT percentile_cont(0.5) WITHIN GROUP (ORDER BY time_answered) FROM answered),
+			(SELECT COALESCE(MAX(streak_length), 0) FROM (
+				SELECT COUNT(*) AS streak_length FROM streaks WHERE is_correct GROUP BY streak
+			) s)
+	`, userID).Scan(
+		&stats.GamesPlayed, &stats.GamesWon, &stats.Answered, &stats.Correct,
+		&stats.AvgTimeAnswered, &stats.MedianTimeAnswered, &stats.LongestCorrectStreak,
+	)
+	if err != nil {
+		return stats, fmt.Errorf("failed to get player totals: %w", err)
+	}
+	stats.WinRate = ratio(stats.GamesWon, stats.GamesPlayed)
+	stats.Accuracy = ratio(stats.Correct, stats.Answered)
+
+	rows, err := db.pool.Query(ctx, `
+		WITH `+playedGamesSQL+`, `+answeredSQL+`
+		SELECT MIN(theme), COUNT(*), COUNT(*) FILTER (WHERE is_correct)
+		FROM answered
+		GROUP BY lower(theme)
+		ORDER BY COUNT(*) DESC, MIN(theme)
+		LIMIT $2
+	`, userID, statsThemeLimit)
+	if err != nil {
+		return stats, fmt.Errorf("failed to query theme stats: %w", err)
+	}
+	stats.Themes, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.ThemeStatsClient, error) {
+		var theme types.ThemeStatsClient
+		err := row.Scan(&theme.Theme, &theme.Answered, &theme.Correct)
+		theme.Accuracy = ratio(theme.Correct, theme.Answered)
+		return theme, err
+	})
+	if err != nil {
+		return stats, fmt.Errorf("failed to scan theme stats: %w", err)
+	}
+
+	rows, err = db.pool.Query(ctx, `
+		WITH `+playedGamesSQL+`
+		SELECT r.position, COUNT(*), SUM(rs.value::int), AVG(rs.value::int)::float8
+		FROM played p
+		JOIN game_users gu ON gu.game_id = p.game_id AND gu.user_id = $1
+		CROSS JOIN LATERAL jsonb_each_text(gu.round_scores) rs
+		JOIN rounds r ON r.id::text = rs.key AND r.game_id = p.game_id
+		GROUP BY r.position
+		ORDER BY r.position
+	`, userID)
+	if err != nil {
+		return stats, fmt.Errorf("failed to query round stats: %w", err)
+	}
+	stats.Rounds, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.RoundPositionStatsClient, error) {
+		var round types.RoundPositionStatsClient
+		err := row.Scan(&round.Position, &round.Games, &round.Points, &round.AvgPoints)
+		return round, err
+	})
+	if err != nil {
+		return stats, fmt.Errorf("failed to scan round stats: %w", err)
+	}
+
+	rows, err = db.pool.Query(ctx, `
+		WITH `+playedGamesSQL+`
+		SELECT gt.id::text, gt.name, COUNT(*), COUNT(*) FILTER (WHERE p.place = 1)
+		FROM played p
+		JOIN game_templates gt ON gt.id = p.template_id
+		GROUP BY gt.id, gt.name
+		ORDER BY COUNT(*) DESC, COUNT(*) FILTER (WHERE p.place = 1) DESC, gt.name
+		LIMIT $2
+	`, userID, statsTemplateLimit)
+	if err != nil {
+		return stats, fmt.Errorf("failed to query template stats: %w", err)
+	}
+	stats.FavouriteTemplates, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.TemplateStatsClient, error) {
+		var template types.TemplateStatsClient
+		err := row.Scan(&template.TemplateID, &template.Name, &template.Played, &template.Won)
+		return template, err
+	})
+	if err != nil {
+		return stats, fmt.Errorf("failed to scan template stats: %w", err)
+	}
+
+	// Teammates play with the player, not against them
+	rows, err = db.pool.Query(ctx, `
+		WITH `+playedGamesSQL+`,
+		opponents AS (
+			SELECT gu.user_id, p.place AS own_place, COALESCE(gs.place, gts.place) AS place
+			FROM played p
+			JOIN game_users gu ON gu.game_id = p.game_id AND gu.user_id <> $1
+				AND (p.team_id IS NULL OR gu.team_id IS DISTINCT FROM p.team_id)
+			LEFT JOIN game_standings gs ON gs.game_id = gu.game_id AND gs.user_id = gu.user_id
+			LEFT JOIN game_team_standings gts ON gts.game_id = gu.game_id AND gts.team_id = gu.team_id
+			WHERE p.place IS NOT NULL
+		)
+		SELECT o.user_id::text, u.name, COUNT(*),
+			COUNT(*) FILTER (WHERE o.own_place < o.place),
+			COUNT(*) FILTER (WHERE o.own_place > o.place),
+			COUNT(*) FILTER (WHERE o.own_place = o.place)
+		FROM opponents o
+		JOIN users u ON u.id = o.user_id
+		WHERE o.place IS NOT NULL
+		GROUP BY o.user_id, u.name
+		ORDER BY COUNT(*) DESC, u.name
+		LIMIT $2
+	`, userID, statsOpponentLimit)
+	if err != nil {
+		return stats, fmt.Errorf("failed to query head-to-head records: %w", err)
+	}
+	stats.HeadToHead, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.HeadToHeadClient, error) {
+		var record types.HeadToHeadClient
+		err := row.Scan(&record.UserID, &record.Name, &record.Games, &record.Wins, &record.Losses, &record.Draws)
+		return record, err
+	})
+	if err != nil {
+		return stats, fmt.Errorf("failed to scan head-to-head records: %w", err)
+	}
+
+	return stats, nil
+}
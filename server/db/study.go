@@ -0,0 +1,188 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mindwarp/srs"
+	"mindwarp/types"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrStudyCardNotFound = errors.New("study card not found")
+
+// syncStudyCardsSQL adds a card for every question userID got wrong in a game
+// or graded wrong in practice. A miss newer than the card's last one puts a
+// card back at the start of its schedule and makes it due again.
+const syncStudyCardsSQL = `
+	WITH game_misses AS (
+		SELECT a.question_id, MAX(a.created_at) AS missed_at
+		FROM answers a
+		WHERE a.user_id = $1 AND a.is_correct = false
+		GROUP BY a.question_id
+	)
+	INSERT INTO study_cards (user_id, question_id, due_at, last_missed_at)
+	SELECT $1, question_id, missed_at, missed_at FROM game_misses
+	ON CONFLICT (user_id, question_id) WHERE question_id IS NOT NULL DO UPDATE
+	SET repetitions = 0, interval_days = 0, lapses = study_cards.lapses + 1,
+		due_at = LEAST(study_cards.due_at, EXCLUDED.due_at), last_missed_at = EXCLUDED.last_missed_at
+	WHERE EXCLUDED.last_missed_at > study_cards.last_missed_at
+`
+
+const syncPracticeCardsSQL = `
+	WITH practice_misses AS (
+		SELECT pq.question_id, MAX(pq.graded_at) AS missed_at
+		FROM practice_questions pq
+		JOIN practice_sessions ps ON ps.id = pq.session_id
+		WHERE ps.user_id = $1 AND pq.is_correct = false
+		GROUP BY pq.question_id
+	)
+	INSERT INTO study_cards (user_id, template_question_id, due_at, last_missed_at)
+	SELECT $1, question_id, missed_at, missed_at FROM practice_misses
+	ON CONFLICT (user_id, template_question_id) WHERE template_question_id IS NOT NULL DO UPDATE
+	SET repetitions = 0, interval_days = 0, lapses = study_cards.lapses + 1,
+		due_at = LEAST(study_cards.due_at, EXCLUDED.due_at), last_missed_at = EXCLUDED.last_missed_at
+	WHERE EXCLUDED.last_missed_at > study_cards.last_missed_at
+`
+
+// selectStudyCardSQL reads a card with its question; callers append the
+// WHERE clause.
+const selectStudyCardSQL = `
+	SELECT
+		sc.id, COALESCE(sc.question_id, sc.template_question_id)::text,
+		CASE WHEN sc.question_id IS NOT NULL THEN 'game' ELSE 'practice' END,
+		COALESCE(t.name, tt.name, ''), COALESCE(q.text, tq.text, ''), COALESCE(q.answer, tq.answer, ''),
+		COALESCE(q.points, tq.points, 0),
+		sc.ease, sc.interval_days, sc.repetitions, sc.lapses, sc.due_at, sc.last_reviewed_at
+	FROM study_cards sc
+	LEFT JOIN questions q ON q.id = sc.question_id
+	LEFT JOIN themes t ON t.id = q.theme_id
+	LEFT JOIN template_questions tq ON tq.id = sc.template_question_id
+	LEFT JOIN template_themes tt ON tt.id = tq.theme_id
+`
+
+func scanStudyCard(row pgx.Row) (types.StudyCardClient, error) {
+	var (
+		card           types.StudyCardClient
+		dueAt          time.Time
+		lastReviewedAt *time.Time
+	)
+	err := row.Scan(&card.ID, &card.QuestionID, &card.Source, &card.Theme, &card.Text, &card.Answer, &card.Points,
+		&card.Ease, &card.IntervalDays, &card.Repetitions, &card.Lapses, &dueAt, &lastReviewedAt)
+	if err != nil {
+		return card, err
+	}
+	card.DueAt = dueAt.UnixMilli()
+	if lastReviewedAt != nil {
+		card.LastReviewedAt = lastReviewedAt.UnixMilli()
+	}
+	return card, nil
+}
+
+// syncStudyCards brings userID's schedule up to date with their misses.
+// Cards are synced when the schedule is read rather than when an answer is
+// judged, so answers corrected by the host before then never become cards.
+func syncStudyCards(ctx context.Context, q querier, userID string) error {
+	if _, err := q.Exec(ctx, syncStudyCardsSQL, userID); err != nil {
+		return fmt.Errorf("failed to sync game study cards: %w", err)
+	}
+	if _, err := q.Exec(ctx, syncPracticeCardsSQL, userID); err != nil {
+		return fmt.Errorf("failed to sync practice study cards: %w", err)
+	}
+	return nil
+}
+
+// GetDueStudyCards returns the cards of userID due before dueBefore, the most
+// overdue first, with how many are due and how many cards there are in all.
+func (db *DB) GetDueStudyCards(ctx context.Context, userID string, dueBefore time.Time, offset int, limit int) (types.StudyQueueClient, error) {
+	queue := types.StudyQueueClient{Cards: make([]types.StudyCardClient, 0)}
+
+	if err := syncStudyCards(ctx, db.pool, userID); err != nil {
+		return queue, err
+	}
+
+	err := db.pool.QueryRow(ctx, `
+		SELECT COUNT(*) FILTER (WHERE due_at < $2), COUNT(*)
+		FROM study_cards
+		WHERE user_id = $1
+	`, userID, dueBefore).Scan(&queue.DueCount, &queue.Total)
+	if err != nil {
+		return queue, fmt.Errorf("failed to count study cards: %w", err)
+	}
+
+	rows, err := db.pool.Query(ctx, selectStudyCardSQL+`
+		WHERE sc.user_id = $1 AND sc.due_at < $2
+		ORDER BY sc.due_at, sc.id
+		OFFSET $3 LIMIT $4
+	`, userID, dueBefore, offset, limit)
+	if err != nil {
+		return queue, fmt.Errorf("failed to query due study cards: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		card, err := scanStudyCard(rows)
+		if err != nil {
+			return queue, fmt.Errorf("failed to scan study card: %w", err)
+		}
+		queue.Cards = append(queue.Cards, card)
+	}
+
+	if err := rows.Err(); err != nil {
+		return queue, fmt.Errorf("error iterating study card rows: %w", err)
+	}
+
+	return queue, nil
+}
+
+// ReviewStudyCard records how well userID recalled a card, graded 0 to 5, and
+// moves it along its SM-2 schedule.
+func (db *DB) ReviewStudyCard(ctx context.Context, cardID string, userID string, quality int, now time.Time) (types.StudyCardClient, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return types.StudyCardClient{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var card srs.Card
+	err = tx.QueryRow(ctx, `
+		SELECT ease, interval_days, repetitions, due_at
+		FROM study_cards
+		WHERE id = $1 AND user_id = $2
+		FOR UPDATE
+	`, cardID, userID).Scan(&card.Ease, &card.IntervalDays, &card.Repetitions, &card.Due)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return types.StudyCardClient{}, ErrStudyCardNotFound
+	}
+	if err != nil {
+		return types.StudyCardClient{}, fmt.Errorf("failed to lock study card: %w", err)
+	}
+
+	card, err = srs.Review(card, quality, now)
+	if err != nil {
+		return types.StudyCardClient{}, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE study_cards
+		SET ease = $2, interval_days = $3, repetitions = $4, due_at = $5, last_reviewed_at = $6,
+			lapses = lapses + CASE WHEN $7 THEN 1 ELSE 0 END
+		WHERE id = $1
+	`, cardID, card.Ease, card.IntervalDays, card.Repetitions, card.Due, now, quality < srs.PassQuality)
+	if err != nil {
+		return types.StudyCardClient{}, fmt.Errorf("failed to update study card: %w", err)
+	}
+
+	result, err := scanStudyCard(tx.QueryRow(ctx, selectStudyCardSQL+" WHERE sc.id = $1", cardID))
+	if err != nil {
+		return types.StudyCardClient{}, fmt.Errorf("failed to get study card: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return types.StudyCardClient{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,418 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mindwarp/scoring"
+	"mindwarp/types"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrTeamNotFound       = errors.New("team not found in this game")
+	ErrTeamNameTaken      = errors.New("a team with this name already exists in this game")
+	ErrCaptainNotInTeam   = errors.New("the captain must be a member of the team")
+	ErrNotTeamCaptain     = errors.New("only the team captain can answer for the team")
+	ErrInvalidContributor = errors.New("the contributor must be a member of the answering team")
+)
+
+// getTeamMembership maps every player of the game that is in a team to the
+// team's id.
+func getTeamMembership(ctx context.Context, q querier, gameID string) (map[string]string, error) {
+	rows, err := q.Query(ctx, "SELECT user_id, team_id FROM game_users WHERE game_id = $1 AND team_id IS NOT NULL", gameID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query team members: %w", err)
+	}
+	defer rows.Close()
+
+	teamOf := make(map[string]string)
+	for rows.Next() {
+		var userID, teamID string
+		if err := rows.Scan(&userID, &teamID); err != nil {
+			return nil, fmt.Errorf("failed to scan team member: %w", err)
+		}
+		teamOf[userID] = teamID
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating team members rows: %w", err)
+	}
+
+	return teamOf, nil
+}
+
+func gameHasTeams(ctx context.Context, q querier, gameID string) (bool, error) {
+	var exists bool
+	err := q.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM game_teams WHERE game_id = $1)", gameID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check game teams: %w", err)
+	}
+	return exists, nil
+}
+
+// storeTeamScores refreshes game_teams.round_scores from the players' scores.
+func storeTeamScores(ctx context.Context, q querier, gameID string, scores scoring.Scores) error {
+	teamOf, err := getTeamMembership(ctx, q, gameID)
+	if err != nil {
+		return err
+	}
+	teamScores := scoring.TeamScores(scores, teamOf)
+
+	rows, err := q.Query(ctx, "SELECT id FROM game_teams WHERE game_id = $1", gameID)
+	if err != nil {
+		return fmt.Errorf("failed to query teams: %w", err)
+	}
+	teamIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("failed to scan teams: %w", err)
+	}
+
+	for _, teamID := range teamIDs {
+		roundScores := teamScores[teamID]
+		if roundScores == nil {
+			roundScores = map[string]int16{}
+		}
+
+		_, err := q.Exec(ctx, "UPDATE game_teams SET round_scores = $1 WHERE id = $2", roundScores, teamID)
+		if err != nil {
+			return fmt.Errorf("failed to update scores for team %s: %w", teamID, err)
+		}
+	}
+
+	return nil
+}
+
+// checkTeamAnswer enforces that in a team only the captain answers, and that
+// a credited contributor is the captain's teammate. Players outside any team
+// answer for themselves.
+func checkTeamAnswer(ctx context.Context, q querier, gameID string, userID string, contributorID string) error {
+	var teamID, captainID *string
+	err := q.QueryRow(ctx, `
+		SELECT gu.team_id::text, t.captain_id::text
+		FROM game_users gu
+		LEFT JOIN game_teams t ON t.id = gu.team_id
+		WHERE gu.game_id = $1 AND gu.user_id = $2
+	`, gameID, userID).Scan(&teamID, &captainID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to get team of user: %w", err)
+	}
+
+	if teamID == nil {
+		if contributorID != "" && contributorID != userID {
+			return ErrInvalidContributor
+		}
+		return nil
+	}
+
+	if captainID == nil || *captainID != userID {
+		return ErrNotTeamCaptain
+	}
+
+	if contributorID != "" && contributorID != userID {
+		var sameTeam bool
+		err := q.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM game_users WHERE game_id = $1 AND user_id = $2 AND team_id = $3)", gameID, contributorID, *teamID).Scan(&sameTeam)
+		if err != nil {
+			return fmt.Errorf("failed to check contributor: %w", err)
+		}
+		if !sameTeam {
+			return ErrInvalidContributor
+		}
+	}
+
+	return nil
+}
+
+func getGameTeam(ctx context.Context, q querier, gameID string, teamID string) (*string, error) {
+	var captainID *string
+	err := q.QueryRow(ctx, "SELECT captain_id::text FROM game_teams WHERE id = $1 AND game_id = $2", teamID, gameID).Scan(&captainID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTeamNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team: %w", err)
+	}
+	return captainID, nil
+}
+
+func teamNameError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrTeamNameTaken
+	}
+	return err
+}
+
+func (db *DB) CreateTeam(ctx context.Context, gameID string, name string) (string, error) {
+	var teamID string
+	err := db.pool.QueryRow(ctx, "INSERT INTO game_teams (game_id, name) VALUES ($1, $2) RETURNING id", gameID, name).Scan(&teamID)
+	if err != nil {
+		return "", fmt.Errorf("failed to create team: %w", teamNameError(err))
+	}
+	return teamID, nil
+}
+
+// UpdateTeam renames a team and sets its captain, who must be a member.
+func (db *DB) UpdateTeam(ctx context.Context, gameID string, teamID string, name string, captainID string) error {
+	if _, err := getGameTeam(ctx, db.pool, gameID, teamID); err != nil {
+		return err
+	}
+
+	if captainID != "" {
+		var isMember bool
+		err := db.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM game_users WHERE game_id = $1 AND user_id = $2 AND team_id = $3)", gameID, captainID, teamID).Scan(&isMember)
+		if err != nil {
+			return fmt.Errorf("failed to check captain: %w", err)
+		}
+		if !isMember {
+			return ErrCaptainNotInTeam
+		}
+	}
+
+	_, err := db.pool.Exec(ctx, `
+		UPDATE game_teams
+		SET name = COALESCE(NULLIF($1, ''), name), captain_id = COALESCE(NULLIF($2, '')::uuid, captain_id)
+		WHERE id = $3
+	`, name, captainID, teamID)
+	if err != nil {
+		return fmt.Errorf("failed to update team: %w", teamNameError(err))
+	}
+	return nil
+}
+
+func (db *DB) DeleteTeam(ctx context.Context, gameID string, teamID string) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, "DELETE FROM game_teams WHERE id = $1 AND game_id = $2", teamID, gameID)
+	if err != nil {
+		return fmt.Errorf("failed to delete team: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTeamNotFound
+	}
+
+	if _, err := storeGameScores(ctx, tx, gameID); err != nil {
+		return fmt.Errorf("failed to recompute scores: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// AssignTeamMember moves a player into a team. The first member of a team
+// becomes its captain; a captain leaving their old team leaves it without one.
+func (db *DB) AssignTeamMember(ctx context.Context, gameID string, teamID string, userID string) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	captainID, err := getGameTeam(ctx, tx, gameID, teamID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE game_teams SET captain_id = NULL WHERE game_id = $1 AND captain_id = $2 AND id <> $3", gameID, userID, teamID)
+	if err != nil {
+		return fmt.Errorf("failed to clear previous captaincy: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, "UPDATE game_users SET team_id = $1 WHERE game_id = $2 AND user_id = $3", teamID, gameID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to assign team member: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotInGame
+	}
+
+	if captainID == nil {
+		if _, err := tx.Exec(ctx, "UPDATE game_teams SET captain_id = $1 WHERE id = $2", userID, teamID); err != nil {
+			return fmt.Errorf("failed to set captain: %w", err)
+		}
+	}
+
+	if _, err := storeGameScores(ctx, tx, gameID); err != nil {
+		return fmt.Errorf("failed to recompute scores: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) RemoveTeamMember(ctx context.Context, gameID string, teamID string, userID string) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := getGameTeam(ctx, tx, gameID, teamID); err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, "UPDATE game_users SET team_id = NULL WHERE game_id = $1 AND user_id = $2 AND team_id = $3", gameID, userID, teamID)
+	if err != nil {
+		return fmt.Errorf("failed to remove team member: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotInGame
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE game_teams SET captain_id = NULL WHERE id = $1 AND captain_id = $2", teamID, userID); err != nil {
+		return fmt.Errorf("failed to clear captaincy: %w", err)
+	}
+
+	if _, err := storeGameScores(ctx, tx, gameID); err != nil {
+		return fmt.Errorf("failed to recompute scores: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetTeamsByGameId returns the teams with their scores and, per member, the
+// correct answers and points credited to them.
+func (db *DB) GetTeamsByGameId(ctx context.Context, gameID string) ([]types.TeamClient, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT id, name, COALESCE(captain_id::text, ''), round_scores
+		FROM game_teams
+		WHERE game_id = $1
+		ORDER BY created_at, name
+	`, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query teams: %w", err)
+	}
+	defer rows.Close()
+
+	teams := make([]types.TeamClient, 0)
+	index := make(map[string]int)
+	for rows.Next() {
+		team := types.TeamClient{Members: []types.TeamMemberClient{}}
+		if err := rows.Scan(&team.ID, &team.Name, &team.CaptainID, &team.RoundScore); err != nil {
+			return nil, fmt.Errorf("failed to scan team: %w", err)
+		}
+		for _, points := range team.RoundScore {
+			team.Score += int(points)
+		}
+		index[team.ID] = len(teams)
+		teams = append(teams, team)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating teams rows: %w", err)
+	}
+
+	if len(teams) == 0 {
+		return teams, nil
+	}
+
+	in, err := loadScoringInput(ctx, db.pool, gameID)
+	if err != nil {
+		return nil, err
+	}
+	contributions := scoring.Contributions(in)
+
+	rows, err = db.pool.Query(ctx, `
+		SELECT
+			gu.team_id::text, u.id, u.name,
+			(
+				SELECT COUNT(*)
+				FROM answers a
+				JOIN questions q ON q.id = a.question_id
+				JOIN themes t ON t.id = q.theme_id
+				JOIN rounds r ON r.id = t.round_id
+				WHERE r.game_id = gu.game_id AND a.is_correct AND COALESCE(a.contributor_id, a.user_id) = u.id
+			)
+		FROM game_users gu
+		JOIN users u ON u.id = gu.user_id
+		WHERE gu.game_id = $1 AND gu.team_id IS NOT NULL
+		ORDER BY u.name
+	`, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query team members: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			teamID string
+			member types.TeamMemberClient
+		)
+		if err := rows.Scan(&teamID, &member.ID, &member.Name, &member.CorrectAnswers); err != nil {
+			return nil, fmt.Errorf("failed to scan team member: %w", err)
+		}
+
+		team := &teams[index[teamID]]
+		member.IsCaptain = team.CaptainID == member.ID
+		member.Points = contributions.Total(member.ID)
+		team.Members = append(team.Members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating team members rows: %w", err)
+	}
+
+	return teams, nil
+}
+
+// loadTeamResults builds the ranking input of a team game: one entry per
+// team, keyed by the team's id, with the answer statistics of its members.
+func loadTeamResults(ctx context.Context, q querier, gameID string, scores scoring.Scores) ([]scoring.PlayerResult, error) {
+	teamOf, err := getTeamMembership(ctx, q, gameID)
+	if err != nil {
+		return nil, err
+	}
+	teamScores := scoring.TeamScores(scores, teamOf)
+
+	rows, err := q.Query(ctx, `
+		SELECT
+			gt.id,
+			COUNT(a.id) FILTER (WHERE a.is_correct),
+			AVG(a.time_answered)::float8
+		FROM
+			game_teams gt
+		LEFT JOIN game_users gu ON gu.team_id = gt.id
+		LEFT JOIN answers a ON a.user_id = gu.user_id AND a.question_id IN (
+			SELECT q.id
+			FROM questions q
+			JOIN themes t ON t.id = q.theme_id
+			JOIN rounds r ON r.id = t.round_id
+			WHERE r.game_id = $1
+		)
+		WHERE
+			gt.game_id = $1
+		GROUP BY gt.id
+	`, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query team results: %w", err)
+	}
+	defer rows.Close()
+
+	results := make([]scoring.PlayerResult, 0)
+	for rows.Next() {
+		var result scoring.PlayerResult
+		if err := rows.Scan(&result.UserID, &result.CorrectAnswers, &result.AvgTimeAnswered); err != nil {
+			return nil, fmt.Errorf("failed to scan team result: %w", err)
+		}
+		result.Score = teamScores.Total(result.UserID)
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating team results rows: %w", err)
+	}
+
+	return results, nil
+}
@@ -145,28 +145,33 @@ func (db *DB) GetFullGameTemplateById(ctx context.Context, id string) (*types.Ga
 		gameDescription pgtype.Text
 		gameIsPublic    pgtype.Bool
 		gameCreatorID   pgtype.UUID
+		gameRulesJSON   []byte
 		roundID         pgtype.UUID
 		roundName       pgtype.Text
 		roundTimeJSON   []byte // Scan JSONB as []byte
 		roundRankJSON   []byte // Scan JSONB as []byte
+		roundRulesJSON  []byte // Scan JSONB as []byte
+		roundIsFinal    pgtype.Bool
 		roundPosition   pgtype.Int4
 
 		themeID       pgtype.UUID
 		themeName     pgtype.Text
 		themePosition pgtype.Int4
 
-		questionID     pgtype.UUID
-		questionText   pgtype.Text
-		questionAnswer pgtype.Text
-		questionPoints pgtype.Int4
+		questionID      pgtype.UUID
+		questionText    pgtype.Text
+		questionAnswer  pgtype.Text
+		questionPoints  pgtype.Int4
+		questionType    pgtype.Text
+		questionShuffle pgtype.Bool
 	)
 
 	query := `
 		SELECT
-			gt.id, gt.name, gt.description, gt.is_public, gt.creator_id,
-			tr.id, tr.name, tr.time_settings, tr.rank_settings, tr.position,
+			gt.id, gt.name, gt.description, gt.is_public, gt.creator_id, gt.rules,
+			tr.id, tr.name, tr.time_settings, tr.rank_settings, tr.rules, tr.is_final, tr.position,
 			tt.id, tt.name, tt.position,
-			tq.id, tq.text, tq.answer, tq.points
+			tq.id, tq.text, tq.answer, tq.points, tq.question_type, tq.shuffle_options
 		FROM
 			game_templates gt
 		LEFT JOIN
@@ -192,14 +197,15 @@ func (db *DB) GetFullGameTemplateById(ctx context.Context, id string) (*types.Ga
 	roundsMap := make(map[string]*types.RoundServer)
 	themesMap := make(map[string]*types.ThemeServer)
 	questionsMap := make(map[string]*types.QuestionServer)
+	shuffleOptions := make(map[string]bool)
 	count := 0
 	for rows.Next() {
 		count++
 		err := rows.Scan(
-			&gameID, &gameName, &gameDescription, &gameIsPublic, &gameCreatorID,
-			&roundID, &roundName, &roundTimeJSON, &roundRankJSON, &roundPosition,
+			&gameID, &gameName, &gameDescription, &gameIsPublic, &gameCreatorID, &gameRulesJSON,
+			&roundID, &roundName, &roundTimeJSON, &roundRankJSON, &roundRulesJSON, &roundIsFinal, &roundPosition,
 			&themeID, &themeName, &themePosition,
-			&questionID, &questionText, &questionAnswer, &questionPoints,
+			&questionID, &questionText, &questionAnswer, &questionPoints, &questionType, &questionShuffle,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan game template row: %w", err)
@@ -221,6 +227,7 @@ func (db *DB) GetFullGameTemplateById(ctx context.Context, id string) (*types.Ga
 				Description: gameDescription.String,
 				IsPublic:    gameIsPublic.Bool,
 				CreatorID:   gameCreatorIDStr,
+				Rules:       unmarshalGameRules(gameIDStr, gameRulesJSON).Client(),
 				Rounds:      []types.RoundClient{}, // Initialize slice
 			}
 		}
@@ -247,6 +254,8 @@ func (db *DB) GetFullGameTemplateById(ctx context.Context, id string) (*types.Ga
 				Name:         roundName.String,
 				TimeSettings: timeSetting,
 				RankSettings: rankSetting,
+				Rules:        unmarshalRoundRules(roundIDStr, roundRulesJSON),
+				IsFinal:      roundIsFinal.Bool,
 			}
 			roundsMap[roundIDStr] = round
 
@@ -271,14 +280,31 @@ func (db *DB) GetFullGameTemplateById(ctx context.Context, id string) (*types.Ga
 						Text:    questionText.String,
 						Answer:  questionAnswer.String,
 						Points:  uint16(questionPoints.Int),
+						Type:    types.QuestionType(questionType.String),
 					}
 
 					questionsMap[questionIDStr] = question
+					shuffleOptions[questionIDStr] = questionShuffle.Bool
 				}
 			}
 		}
 	}
 
+	questionIds := make([]string, 0, len(questionsMap))
+	for questionID := range questionsMap {
+		questionIds = append(questionIds, questionID)
+	}
+
+	attachments, err := getQuestionMedia(ctx, db.pool, templateQuestionMediaTable, questionIds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get question media: %w", err)
+	}
+
+	options, err := getQuestionOptions(ctx, db.pool, templateQuestionOptionsTable, questionIds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get question options: %w", err)
+	}
+
 	// Convert maps to slices and organize the data
 	for _, round := range roundsMap {
 		// Get themes for this round
@@ -291,10 +317,14 @@ func (db *DB) GetFullGameTemplateById(ctx context.Context, id string) (*types.Ga
 				for _, question := range questionsMap {
 					if question.ThemeID == theme.ID {
 						themeQuestions = append(themeQuestions, types.QuestionClient{
-							Id:     question.ID,
-							Text:   question.Text,
-							Answer: question.Answer,
-							Points: question.Points,
+							Id:             question.ID,
+							Text:           question.Text,
+							Answer:         question.Answer,
+							Points:         question.Points,
+							Type:           string(question.Type),
+							Media:          attachments[question.ID],
+							Options:        options[question.ID],
+							ShuffleOptions: shuffleOptions[question.ID],
 						})
 					}
 				}
@@ -336,11 +366,13 @@ func (db *DB) GetFullGameTemplateById(ctx context.Context, id string) (*types.Ga
 
 		// Create round client
 		roundClient := types.RoundClient{
-			Id:     round.ID,
-			Name:   round.Name,
-			Ranks:  roundRanks,
-			Time:   timeClient,
-			Themes: roundThemes,
+			Id:      round.ID,
+			Name:    round.Name,
+			Ranks:   roundRanks,
+			Time:    timeClient,
+			Rules:   types.RoundRulesClient{NegativePoints: round.Rules.NegativePoints},
+			IsFinal: round.IsFinal,
+			Themes:  roundThemes,
 		}
 
 		gameTemplate.Rounds = append(gameTemplate.Rounds, roundClient)
@@ -357,6 +389,17 @@ func (db *DB) GetFullGameTemplateById(ctx context.Context, id string) (*types.Ga
 	return gameTemplate, nil
 }
 
+// GetGameTemplateRules returns the house rules games created from the
+// template start with.
+func (db *DB) GetGameTemplateRules(ctx context.Context, id string) (types.GameRules, error) {
+	var rulesJSON []byte
+	err := db.pool.QueryRow(ctx, "SELECT rules FROM game_templates WHERE id = $1", id).Scan(&rulesJSON)
+	if err != nil {
+		return types.GameRules{}, fmt.Errorf("failed to get game template rules: %w", err)
+	}
+	return unmarshalGameRules(id, rulesJSON), nil
+}
+
 func (db *DB) CreateGameTemplate(ctx context.Context, gameTemplate types.GameTemplateServer) error {
 	logger.Infof("Creating game template: %v", gameTemplate)
 	_, err := db.pool.Exec(ctx, "INSERT INTO game_templates (id, name, description, creator_id, is_public) VALUES ($1, $2, $3, $4, $5)", gameTemplate.ID, gameTemplate.Name, gameTemplate.Description, gameTemplate.CreatorID, gameTemplate.IsPublic)
@@ -367,7 +410,7 @@ func (db *DB) CreateGameTemplate(ctx context.Context, gameTemplate types.GameTem
 }
 
 func (db *DB) CreateRound(ctx context.Context, round types.TemplateRoundServer) error {
-	_, err := db.pool.Exec(ctx, "INSERT INTO template_rounds (id, game_template_id, name, time_settings, rank_settings, position) VALUES ($1, $2, $3, $4, $5, $6)", round.ID, round.GameTemplateID, round.Name, round.TimeSettings, round.RankSettings, round.Position)
+	_, err := db.pool.Exec(ctx, "INSERT INTO template_rounds (id, game_template_id, name, time_settings, rank_settings, rules, is_final, position) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)", round.ID, round.GameTemplateID, round.Name, round.TimeSettings, round.RankSettings, round.Rules, round.IsFinal, round.Position)
 	if err != nil {
 		return err
 	}
@@ -375,7 +418,7 @@ func (db *DB) CreateRound(ctx context.Context, round types.TemplateRoundServer)
 }
 
 func insertTemplate(ctx context.Context, tx pgx.Tx, template types.GameTemplateServer) error {
-	_, err := tx.Exec(ctx, "INSERT INTO game_templates (id, name, description, creator_id, is_public) VALUES ($1, $2, $3, $4, $5)", template.ID, template.Name, template.Description, template.CreatorID, template.IsPublic)
+	_, err := tx.Exec(ctx, "INSERT INTO game_templates (id, name, description, creator_id, is_public, rules) VALUES ($1, $2, $3, $4, $5, $6)", template.ID, template.Name, template.Description, template.CreatorID, template.IsPublic, template.Rules)
 	if err != nil {
 		return err
 	}
@@ -383,7 +426,7 @@ func insertTemplate(ctx context.Context, tx pgx.Tx, template types.GameTemplateS
 }
 
 func insertRound(ctx context.Context, tx pgx.Tx, round types.TemplateRoundServer) error {
-	_, err := tx.Exec(ctx, "INSERT INTO template_rounds (id, game_template_id, name, time_settings, rank_settings, position) VALUES ($1, $2, $3, $4, $5, $6)", round.ID, round.GameTemplateID, round.Name, round.TimeSettings, round.RankSettings, round.Position)
+	_, err := tx.Exec(ctx, "INSERT INTO template_rounds (id, game_template_id, name, time_settings, rank_settings, rules, is_final, position) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)", round.ID, round.GameTemplateID, round.Name, round.TimeSettings, round.RankSettings, round.Rules, round.IsFinal, round.Position)
 	if err != nil {
 		return err
 	}
@@ -391,7 +434,7 @@ func insertRound(ctx context.Context, tx pgx.Tx, round types.TemplateRoundServer
 }
 
 func updateTemplate(ctx context.Context, tx pgx.Tx, template types.GameTemplateServer) error {
-	_, err := tx.Exec(ctx, "UPDATE game_templates SET name = $1, description = $2, creator_id = $3, is_public = $4 WHERE id = $5", template.Name, template.Description, template.CreatorID, template.IsPublic, template.ID)
+	_, err := tx.Exec(ctx, "UPDATE game_templates SET name = $1, description = $2, creator_id = $3, is_public = $4, rules = $5 WHERE id = $6", template.Name, template.Description, template.CreatorID, template.IsPublic, template.Rules, template.ID)
 	if err != nil {
 		return err
 	}
@@ -400,12 +443,12 @@ func updateTemplate(ctx context.Context, tx pgx.Tx, template types.GameTemplateS
 
 func updateRound(ctx context.Context, tx pgx.Tx, round types.TemplateRoundServer) error {
 	_, err := tx.Exec(ctx, `
-		INSERT INTO template_rounds (id, game_template_id, name, time_settings, rank_settings, position)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO template_rounds (id, game_template_id, name, time_settings, rank_settings, rules, is_final, position)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		ON CONFLICT (id) DO UPDATE 
-		SET name = $3, time_settings = $4, rank_settings = $5, position = $6
+		SET name = $3, time_settings = $4, rank_settings = $5, rules = $6, is_final = $7, position = $8
 		WHERE template_rounds.id = $1`,
-		round.ID, round.GameTemplateID, round.Name, round.TimeSettings, round.RankSettings, round.Position)
+		round.ID, round.GameTemplateID, round.Name, round.TimeSettings, round.RankSettings, round.Rules, round.IsFinal, round.Position)
 	if err != nil {
 		return err
 	}
@@ -439,6 +482,8 @@ func (db *DB) CreateCompleteGameTemplate(ctx context.Context, template types.Gam
 	opCounts := struct {
 		themes    int
 		questions int
+		media     int
+		options   int
 	}{}
 
 	// Queue themes
@@ -472,16 +517,20 @@ func (db *DB) CreateCompleteGameTemplate(ctx context.Context, template types.Gam
 
 			batch.Queue(
 				`INSERT INTO template_questions 
-				(id, theme_id, text, answer, points, position) 
-				VALUES ($1, $2, $3, $4, $5, $6)`,
+				(id, theme_id, text, answer, points, question_type, shuffle_options, position) 
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
 				question.ID,
 				question.ThemeID,
 				question.Text,
 				question.Answer,
 				question.Points,
+				question.Type,
+				question.ShuffleOptions,
 				question.Position,
 			)
 			opCounts.questions++
+			opCounts.media += queueQuestionMedia(batch, templateQuestionMediaTable, question.ID, question.Media, false)
+			opCounts.options += queueQuestionOptions(batch, templateQuestionOptionsTable, question.ID, question.Options, false)
 		}
 	}
 
@@ -496,10 +545,10 @@ func (db *DB) CreateCompleteGameTemplate(ctx context.Context, template types.Gam
 			}
 		}
 
-		// Process questions results
-		for i := 0; i < opCounts.questions; i++ {
+		// Process questions and their media results
+		for i := 0; i < opCounts.questions+opCounts.media+opCounts.options; i++ {
 			if _, err := batchResults.Exec(); err != nil {
-				return fmt.Errorf("failed to insert question %d: %w", i+1, err)
+				return fmt.Errorf("failed to insert question or media %d: %w", i+1, err)
 			}
 		}
 
@@ -542,6 +591,8 @@ func (db *DB) UpdateGameTemplate(ctx context.Context, template types.GameTemplat
 	opCounts := struct {
 		themes    int
 		questions int
+		media     int
+		options   int
 	}{}
 
 	// Queue themes
@@ -575,18 +626,22 @@ func (db *DB) UpdateGameTemplate(ctx context.Context, template types.GameTemplat
 			}
 
 			batch.Queue(
-				`INSERT INTO template_questions (id, theme_id, text, answer, points, position)
-				VALUES ($1, $2, $3, $4, $5, $6)
+				`INSERT INTO template_questions (id, theme_id, text, answer, points, question_type, shuffle_options, position)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 				ON CONFLICT (id) DO UPDATE 
-				SET text = $3, answer = $4, points = $5, position = $6`,
+				SET text = $3, answer = $4, points = $5, question_type = $6, shuffle_options = $7, position = $8`,
 				question.ID,
 				question.ThemeID,
 				question.Text,
 				question.Answer,
 				question.Points,
+				question.Type,
+				question.ShuffleOptions,
 				question.Position,
 			)
 			opCounts.questions++
+			opCounts.media += queueQuestionMedia(batch, templateQuestionMediaTable, question.ID, question.Media, true)
+			opCounts.options += queueQuestionOptions(batch, templateQuestionOptionsTable, question.ID, question.Options, true)
 		}
 	}
 
@@ -601,10 +656,10 @@ func (db *DB) UpdateGameTemplate(ctx context.Context, template types.GameTemplat
 			}
 		}
 
-		// Process questions results
-		for i := 0; i < opCounts.questions; i++ {
+		// Process questions and their media results
+		for i := 0; i < opCounts.questions+opCounts.media+opCounts.options; i++ {
 			if _, err := batchResults.Exec(); err != nil {
-				return fmt.Errorf("failed to insert question %d: %w", i+1, err)
+				return fmt.Errorf("failed to insert question or media %d: %w", i+1, err)
 			}
 		}
 
@@ -0,0 +1,954 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"mindwarp/logger"
+	"mindwarp/tournament"
+	"mindwarp/types"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrTournamentNotFound   = errors.New("tournament not found")
+	ErrRegistrationClosed   = errors.New("the tournament has already started")
+	ErrTournamentNotRunning = errors.New("the tournament is not in progress")
+	ErrUnknownParticipant   = errors.New("participants must be registered users")
+	ErrParticipantNotFound  = errors.New("user is not registered for this tournament")
+	ErrInvalidSeeding       = errors.New("seeding must list each participant at most once")
+	ErrMatchNotFound        = errors.New("tournament match not found")
+	ErrMatchNotReady        = errors.New("match is not waiting for a result")
+	ErrWinnerNotInMatch     = errors.New("winner must be one of the match's players")
+)
+
+// tournamentRow is what advancing a bracket needs to know of a tournament.
+type tournamentRow struct {
+	id          string
+	name        string
+	creatorID   string
+	templateID  *string
+	roundIDs    []string
+	tieBreakers []string
+	rules       types.GameRules
+	format      tournament.Format
+	status      types.TournamentStatus
+}
+
+func lockTournament(ctx context.Context, q querier, tournamentID string) (tournamentRow, error) {
+	t := tournamentRow{id: tournamentID}
+	var roundIDs []string
+	err := q.QueryRow(ctx, `
+		SELECT name, creator_id::text, template_id::text, round_ids::text[], tie_breakers, rules, format, status
+		FROM tournaments
+		WHERE id = $1
+		FOR UPDATE
+	`, tournamentID).Scan(&t.name, &t.creatorID, &t.templateID, &roundIDs, &t.tieBreakers, &t.rules, &t.format, &t.status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return t, ErrTournamentNotFound
+	}
+	if err != nil {
+		return t, fmt.Errorf("failed to lock tournament: %w", err)
+	}
+	t.roundIDs = roundIDs
+	return t, nil
+}
+
+// tournamentMatch is a bracket match as stored. Slots are 0 and 1 here and
+// 1 and 2 in the table.
+type tournamentMatch struct {
+	id          string
+	bracket     tournament.Bracket
+	round       int
+	position    int
+	status      types.TournamentMatchStatus
+	players     [2]*string
+	decided     [2]bool
+	gameID      *string
+	winnerTo    *string
+	winnerSlot  *int
+	loserTo     *string
+	loserSlot   *int
+	conditional bool
+}
+
+func lockTournamentMatch(ctx context.Context, q querier, tournamentID string, matchID string) (tournamentMatch, error) {
+	m := tournamentMatch{id: matchID}
+	err := q.QueryRow(ctx, `
+		SELECT bracket, round, position, status, player1_id::text, player2_id::text, slot1_decided, slot2_decided,
+			game_id::text, winner_to::text, winner_slot, loser_to::text, loser_slot, conditional
+		FROM tournament_matches
+		WHERE id = $1 AND tournament_id = $2
+		FOR UPDATE
+	`, matchID, tournamentID).Scan(&m.bracket, &m.round, &m.position, &m.status, &m.players[0], &m.players[1],
+		&m.decided[0], &m.decided[1], &m.gameID, &m.winnerTo, &m.winnerSlot, &m.loserTo, &m.loserSlot, &m.conditional)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return m, ErrMatchNotFound
+	}
+	if err != nil {
+		return m, fmt.Errorf("failed to lock tournament match: %w", err)
+	}
+	return m, nil
+}
+
+// matchLabel names a match for its game, e.g. "Winners round 2, match 1".
+func matchLabel(m tournamentMatch) string {
+	switch m.bracket {
+	case tournament.BracketFinal:
+		if m.conditional {
+			return "Grand final reset"
+		}
+		return "Grand final"
+	case tournament.BracketWinners:
+		return fmt.Sprintf("Winners round %d, match %d", m.round, m.position+1)
+	case tournament.BracketLosers:
+		return fmt.Sprintf("Losers round %d, match %d", m.round, m.position+1)
+	default:
+		return fmt.Sprintf("Round %d, match %d", m.round, m.position+1)
+	}
+}
+
+// CreateTournament opens registration for a tournament played on a public
+// or own template.
+func (db *DB) CreateTournament(ctx context.Context, t types.TournamentServer) error {
+	var visible bool
+	err := db.pool.QueryRow(ctx, "SELECT is_public OR creator_id = $2 FROM game_templates WHERE id = $1", t.TemplateID, t.CreatorID).Scan(&visible)
+	if err != nil || !visible {
+		return ErrTemplateNotFound
+	}
+
+	roundIDs := t.RoundIDs
+	if roundIDs == nil {
+		roundIDs = []string{}
+	}
+	_, err = db.pool.Exec(ctx, `
+		INSERT INTO tournaments (id, name, creator_id, template_id, round_ids, tie_breakers, rules, format)
+		VALUES ($1, $2, $3, $4, $5::uuid[], $6, $7, $8)
+	`, t.ID, t.Name, t.CreatorID, t.TemplateID, roundIDs, t.TieBreakers, t.Rules, t.Format)
+	if err != nil {
+		return fmt.Errorf("failed to create tournament: %w", err)
+	}
+	return nil
+}
+
+// GetTournamentCreator returns who organizes a tournament.
+func (db *DB) GetTournamentCreator(ctx context.Context, tournamentID string) (string, error) {
+	var creatorID string
+	err := db.pool.QueryRow(ctx, "SELECT creator_id::text FROM tournaments WHERE id = $1", tournamentID).Scan(&creatorID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrTournamentNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get tournament: %w", err)
+	}
+	return creatorID, nil
+}
+
+// GetTournaments lists the tournaments userID organizes or plays in, newest
+// first.
+func (db *DB) GetTournaments(ctx context.Context, userID string, offset int, limit int) ([]types.TournamentSummaryClient, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT t.id, t.name, t.format, t.status, t.creator_id,
+			(SELECT COUNT(*) FROM tournament_participants tp WHERE tp.tournament_id = t.id),
+			t.created_at
+		FROM tournaments t
+		WHERE t.creator_id = $1
+			OR EXISTS (SELECT 1 FROM tournament_participants tp WHERE tp.tournament_id = t.id AND tp.user_id = $1)
+		ORDER BY t.created_at DESC, t.id
+		OFFSET $2 LIMIT $3
+	`, userID, offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tournaments: %w", err)
+	}
+	defer rows.Close()
+
+	tournaments := make([]types.TournamentSummaryClient, 0)
+	for rows.Next() {
+		var (
+			t         types.TournamentSummaryClient
+			createdAt time.Time
+		)
+		if err := rows.Scan(&t.ID, &t.Name, &t.Format, &t.Status, &t.CreatorID, &t.Participants, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan tournament: %w", err)
+		}
+		t.CreatedAt = createdAt.UnixMilli()
+		tournaments = append(tournaments, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tournament rows: %w", err)
+	}
+
+	return tournaments, nil
+}
+
+// GetTournament returns a tournament with its participants in seed order and
+// its matches in bracket order.
+func (db *DB) GetTournament(ctx context.Context, tournamentID string) (types.TournamentClient, error) {
+	t := types.TournamentClient{
+		ID:           tournamentID,
+		Participants: make([]types.TournamentParticipantClient, 0),
+		Matches:      make([]types.TournamentMatchClient, 0),
+	}
+
+	var (
+		templateID, templateName, winnerID, winnerName *string
+		createdAt                                      time.Time
+		startedAt, finishedAt                          *time.Time
+	)
+	err := db.pool.QueryRow(ctx, `
+		SELECT t.name, t.format, t.status, t.creator_id::text, c.name, t.template_id::text, gt.name,
+			t.winner_id::text, w.name, t.created_at, t.started_at, t.finished_at
+		FROM tournaments t
+		JOIN users c ON c.id = t.creator_id
+		LEFT JOIN game_templates gt ON gt.id = t.template_id
+		LEFT JOIN users w ON w.id = t.winner_id
+		WHERE t.id = $1
+	`, tournamentID).Scan(&t.Name, &t.Format, &t.Status, &t.Creator.ID, &t.Creator.Name, &templateID, &templateName,
+		&winnerID, &winnerName, &createdAt, &startedAt, &finishedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return t, ErrTournamentNotFound
+	}
+	if err != nil {
+		return t, fmt.Errorf("failed to get tournament: %w", err)
+	}
+	if templateID != nil {
+		t.TemplateID, t.TemplateName = *templateID, *templateName
+	}
+	if winnerID != nil {
+		t.Winner = &types.UserClient{ID: *winnerID, Name: *winnerName}
+	}
+	t.CreatedAt = createdAt.UnixMilli()
+	if startedAt != nil {
+		t.StartedAt = startedAt.UnixMilli()
+	}
+	if finishedAt != nil {
+		t.FinishedAt = finishedAt.UnixMilli()
+	}
+
+	rows, err := db.pool.Query(ctx, `
+		SELECT tp.user_id::text, u.name, COALESCE(tp.seed, 0)
+		FROM tournament_participants tp
+		JOIN users u ON u.id = tp.user_id
+		WHERE tp.tournament_id = $1
+		ORDER BY tp.seed NULLS LAST, tp.registered_at, tp.user_id
+	`, tournamentID)
+	if err != nil {
+		return t, fmt.Errorf("failed to query tournament participants: %w", err)
+	}
+	t.Participants, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.TournamentParticipantClient, error) {
+		var p types.TournamentParticipantClient
+		err := row.Scan(&p.UserID, &p.Name, &p.Seed)
+		return p, err
+	})
+	if err != nil {
+		return t, fmt.Errorf("failed to collect tournament participants: %w", err)
+	}
+
+	rows, err = db.pool.Query(ctx, `
+		SELECT m.id, m.bracket, m.round, m.position, m.status,
+			m.player1_id::text, p1.name, m.player2_id::text, p2.name, s1.score, s2.score,
+			COALESCE(m.game_id::text, ''), COALESCE(m.winner_id::text, ''), m.is_draw
+		FROM tournament_matches m
+		LEFT JOIN users p1 ON p1.id = m.player1_id
+		LEFT JOIN users p2 ON p2.id = m.player2_id
+		LEFT JOIN game_standings s1 ON s1.game_id = m.game_id AND s1.user_id = m.player1_id
+		LEFT JOIN game_standings s2 ON s2.game_id = m.game_id AND s2.user_id = m.player2_id
+		WHERE m.tournament_id = $1
+		ORDER BY CASE m.bracket WHEN 'winners' THEN 0 WHEN 'losers' THEN 1 WHEN 'final' THEN 2 ELSE 3 END,
+			m.round, m.position
+	`, tournamentID)
+	if err != nil {
+		return t, fmt.Errorf("failed to query tournament matches: %w", err)
+	}
+	t.Matches, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.TournamentMatchClient, error) {
+		var (
+			m                      types.TournamentMatchClient
+			player1ID, player1Name *string
+			player2ID, player2Name *string
+		)
+		err := row.Scan(&m.ID, &m.Bracket, &m.Round, &m.Position, &m.Status, &player1ID, &player1Name, &player2ID, &player2Name,
+			&m.Score1, &m.Score2, &m.GameID, &m.WinnerID, &m.IsDraw)
+		if player1ID != nil {
+			m.Player1 = &types.UserClient{ID: *player1ID, Name: *player1Name}
+		}
+		if player2ID != nil {
+			m.Player2 = &types.UserClient{ID: *player2ID, Name: *player2Name}
+		}
+		return m, err
+	})
+	if err != nil {
+		return t, fmt.Errorf("failed to collect tournament matches: %w", err)
+	}
+
+	return t, nil
+}
+
+// AddTournamentParticipants registers users for a tournament that has not
+// started. Users already registered are left as they are.
+func (db *DB) AddTournamentParticipants(ctx context.Context, tournamentID string, userIDs []string) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	t, err := lockTournament(ctx, tx, tournamentID)
+	if err != nil {
+		return err
+	}
+	if t.status != types.TournamentRegistration {
+		return ErrRegistrationClosed
+	}
+
+	var known int
+	if err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM users WHERE id = ANY($1::uuid[])", userIDs).Scan(&known); err != nil {
+		return fmt.Errorf("failed to check participants: %w", err)
+	}
+	if known != len(userIDs) {
+		return ErrUnknownParticipant
+	}
+
+	var registered int
+	err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM tournament_participants WHERE tournament_id = $1 AND NOT (user_id = ANY($2::uuid[]))", tournamentID, userIDs).Scan(&registered)
+	if err != nil {
+		return fmt.Errorf("failed to count participants: %w", err)
+	}
+	if registered+len(userIDs) > tournament.MaxPlayers {
+		return tournament.ErrTooManyPlayers
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO tournament_participants (tournament_id, user_id)
+		SELECT $1, unnest($2::uuid[])
+		ON CONFLICT (tournament_id, user_id) DO NOTHING
+	`, tournamentID, userIDs)
+	if err != nil {
+		return fmt.Errorf("failed to add participants: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// RemoveTournamentParticipant withdraws a user before the tournament starts.
+func (db *DB) RemoveTournamentParticipant(ctx context.Context, tournamentID string, userID string) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	t, err := lockTournament(ctx, tx, tournamentID)
+	if err != nil {
+		return err
+	}
+	if t.status != types.TournamentRegistration {
+		return ErrRegistrationClosed
+	}
+
+	tag, err := tx.Exec(ctx, "DELETE FROM tournament_participants WHERE tournament_id = $1 AND user_id = $2", tournamentID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove participant: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrParticipantNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// orderedParticipants lists the participants of a tournament by seed, then
+// by registration.
+func orderedParticipants(ctx context.Context, q querier, tournamentID string) ([]string, error) {
+	rows, err := q.Query(ctx, `
+		SELECT user_id::text
+		FROM tournament_participants
+		WHERE tournament_id = $1
+		ORDER BY seed NULLS LAST, registered_at, user_id
+	`, tournamentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query participants: %w", err)
+	}
+	participants, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect participants: %w", err)
+	}
+	return participants, nil
+}
+
+func storeSeeds(ctx context.Context, q querier, tournamentID string, participants []string) error {
+	_, err := q.Exec(ctx, `
+		UPDATE tournament_participants tp
+		SET seed = s.seed
+		FROM unnest($2::uuid[]) WITH ORDINALITY AS s(user_id, seed)
+		WHERE tp.tournament_id = $1 AND tp.user_id = s.user_id
+	`, tournamentID, participants)
+	if err != nil {
+		return fmt.Errorf("failed to store seeds: %w", err)
+	}
+	return nil
+}
+
+// SeedTournament seeds the participants of a tournament that has not
+// started: those listed in userIDs first, in that order, then the rest by
+// their current seed and registration. With shuffle the order is random.
+func (db *DB) SeedTournament(ctx context.Context, tournamentID string, userIDs []string, shuffle bool) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	t, err := lockTournament(ctx, tx, tournamentID)
+	if err != nil {
+		return err
+	}
+	if t.status != types.TournamentRegistration {
+		return ErrRegistrationClosed
+	}
+
+	participants, err := orderedParticipants(ctx, tx, tournamentID)
+	if err != nil {
+		return err
+	}
+
+	seeded := make([]string, 0, len(participants))
+	for _, userID := range userIDs {
+		if !slices.Contains(participants, userID) || slices.Contains(seeded, userID) {
+			return ErrInvalidSeeding
+		}
+		seeded = append(seeded, userID)
+	}
+	for _, userID := range participants {
+		if !slices.Contains(seeded, userID) {
+			seeded = append(seeded, userID)
+		}
+	}
+	if shuffle {
+		rand.Shuffle(len(seeded), func(i, j int) { seeded[i], seeded[j] = seeded[j], seeded[i] })
+	}
+
+	if err := storeSeeds(ctx, tx, tournamentID, seeded); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// StartTournament closes registration, seeds anyone left unseeded, lays out
+// the bracket and creates the games of the matches that can be played
+// straight away. Byes are settled at once.
+func (db *DB) StartTournament(ctx context.Context, tournamentID string) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	t, err := lockTournament(ctx, tx, tournamentID)
+	if err != nil {
+		return err
+	}
+	if t.status != types.TournamentRegistration {
+		return ErrRegistrationClosed
+	}
+
+	participants, err := orderedParticipants(ctx, tx, tournamentID)
+	if err != nil {
+		return err
+	}
+	matches, err := tournament.Generate(t.format, len(participants))
+	if err != nil {
+		return err
+	}
+	if err := storeSeeds(ctx, tx, tournamentID, participants); err != nil {
+		return err
+	}
+
+	ids := make([]string, len(matches))
+	for i := range matches {
+		ids[i] = uuid.NewString()
+	}
+
+	batch := &pgx.Batch{}
+	for i, match := range matches {
+		var (
+			players              [2]any
+			winnerTo, winnerSlot any
+			loserTo, loserSlot   any
+		)
+		for slot, seed := range match.Seeds {
+			if seed > 0 {
+				players[slot] = participants[seed-1]
+			}
+		}
+		if match.WinnerTo != nil {
+			winnerTo, winnerSlot = ids[match.WinnerTo.Match], match.WinnerTo.Slot+1
+		}
+		if match.LoserTo != nil {
+			loserTo, loserSlot = ids[match.LoserTo.Match], match.LoserTo.Slot+1
+		}
+		batch.Queue(`
+			INSERT INTO tournament_matches (id, tournament_id, bracket, round, position, stage, player1_id, player2_id,
+				slot1_decided, slot2_decided, winner_to, winner_slot, loser_to, loser_slot, conditional)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		`, ids[i], tournamentID, string(match.Bracket), match.Round, match.Position, match.Stage, players[0], players[1],
+			!match.Fed[0], !match.Fed[1], winnerTo, winnerSlot, loserTo, loserSlot, match.Conditional)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to store tournament matches: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE tournaments SET status = 'in_progress', started_at = now() WHERE id = $1", tournamentID)
+	if err != nil {
+		return fmt.Errorf("failed to start tournament: %w", err)
+	}
+	t.status = types.TournamentInProgress
+
+	for i, match := range matches {
+		if !match.Fed[0] && !match.Fed[1] {
+			if err := resolveTournamentMatch(ctx, tx, t, ids[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// resolveTournamentMatch acts on a pending match once both of its slots are
+// decided: two players get a game, a lone player advances on a bye and an
+// empty match passes its byes on.
+func resolveTournamentMatch(ctx context.Context, tx pgx.Tx, t tournamentRow, matchID string) error {
+	m, err := lockTournamentMatch(ctx, tx, t.id, matchID)
+	if err != nil {
+		return err
+	}
+	if m.status != types.MatchPending || !m.decided[0] || !m.decided[1] {
+		return nil
+	}
+
+	switch {
+	case m.players[0] != nil && m.players[1] != nil:
+		return startTournamentMatch(ctx, tx, t, m)
+	case m.players[0] != nil:
+		return finishTournamentMatch(ctx, tx, t, m, m.players[0], false)
+	case m.players[1] != nil:
+		return finishTournamentMatch(ctx, tx, t, m, m.players[1], false)
+	default:
+		return finishTournamentMatch(ctx, tx, t, m, nil, false)
+	}
+}
+
+// startTournamentMatch creates the game of a match between two players,
+// hosted by the organizer. Without a template to copy the match waits for
+// the organizer to record its result.
+func startTournamentMatch(ctx context.Context, tx pgx.Tx, t tournamentRow, m tournamentMatch) error {
+	var gameID any
+	if t.templateID != nil {
+		game := types.GameServer{
+			ID:          uuid.NewString(),
+			Name:        fmt.Sprintf("%s: %s", t.name, matchLabel(m)),
+			CreatorID:   t.creatorID,
+			TemplateID:  *t.templateID,
+			TieBreakers: t.tieBreakers,
+			Rules:       t.rules,
+			MinPlayers:  2,
+		}
+		err := createGameFromTemplate(ctx, tx, game, types.TemplateCopyOptions{RoundIDs: t.roundIDs}, nil)
+		if errors.Is(err, ErrTemplateNotFound) || errors.Is(err, ErrInvalidRoundSelection) {
+			logger.Errorf("Tournament %s match %s has no game: %v", t.id, m.id, err)
+		} else if err != nil {
+			return err
+		} else {
+			gameID = game.ID
+			batch := &pgx.Batch{}
+			for _, playerID := range m.players {
+				batch.Queue("INSERT INTO game_users (game_id, user_id) VALUES ($1, $2)", game.ID, *playerID)
+				batch.Queue("INSERT INTO game_invites (game_id, user_id, invited_by, status) VALUES ($1, $2, $3, 'accepted')", game.ID, *playerID, t.creatorID)
+			}
+			if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+				return fmt.Errorf("failed to add match players: %w", err)
+			}
+		}
+	}
+
+	_, err := tx.Exec(ctx, "UPDATE tournament_matches SET status = 'ready', game_id = $2 WHERE id = $1", m.id, gameID)
+	if err != nil {
+		return fmt.Errorf("failed to start tournament match: %w", err)
+	}
+
+	for _, playerID := range m.players {
+		n := types.NotificationServer{
+			UserID:  *playerID,
+			Type:    types.NotificationMatchReady,
+			ActorID: t.creatorID,
+			Payload: map[string]any{"tournamentId": t.id, "matchId": m.id},
+		}
+		if id, ok := gameID.(string); ok {
+			n.GameID = id
+		}
+		if err := notify(ctx, tx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finishTournamentMatch records the result of a match and routes its players
+// onward. A nil winner with draw unset means the match had no players at
+// all. When the tournament has nothing left to play it finishes.
+func finishTournamentMatch(ctx context.Context, tx pgx.Tx, t tournamentRow, m tournamentMatch, winnerID *string, draw bool) error {
+	var loserID *string
+	if winnerID != nil && !draw {
+		for _, playerID := range m.players {
+			if playerID != nil && *playerID != *winnerID {
+				loserID = playerID
+			}
+		}
+	}
+	if draw {
+		winnerID = nil
+	}
+
+	_, err := tx.Exec(ctx, `
+		UPDATE tournament_matches
+		SET status = 'finished', winner_id = $2, loser_id = $3, is_draw = $4, finished_at = now()
+		WHERE id = $1
+	`, m.id, winnerID, loserID, draw)
+	if err != nil {
+		return fmt.Errorf("failed to finish tournament match: %w", err)
+	}
+
+	if m.winnerTo != nil {
+		// A grand-final reset is only needed when the winners' champion, who
+		// comes in through the first slot, lost the first grand final.
+		var target tournamentMatch
+		if target, err = lockTournamentMatch(ctx, tx, t.id, *m.winnerTo); err != nil {
+			return err
+		}
+		if target.conditional && winnerID != nil && m.players[0] != nil && *winnerID == *m.players[0] {
+			if _, err := tx.Exec(ctx, "UPDATE tournament_matches SET status = 'skipped' WHERE id = $1", target.id); err != nil {
+				return fmt.Errorf("failed to skip tournament match: %w", err)
+			}
+		} else {
+			if err := fillTournamentSlot(ctx, tx, t, *m.winnerTo, *m.winnerSlot, winnerID); err != nil {
+				return err
+			}
+			if m.loserTo != nil {
+				if err := fillTournamentSlot(ctx, tx, t, *m.loserTo, *m.loserSlot, loserID); err != nil {
+					return err
+				}
+			}
+		}
+	} else if m.loserTo != nil {
+		if err := fillTournamentSlot(ctx, tx, t, *m.loserTo, *m.loserSlot, loserID); err != nil {
+			return err
+		}
+	}
+
+	return finishTournamentIfDone(ctx, tx, t)
+}
+
+// fillTournamentSlot puts a player, or a bye when playerID is nil, into a
+// slot of a later match and resolves it if it is now complete.
+func fillTournamentSlot(ctx context.Context, tx pgx.Tx, t tournamentRow, matchID string, slot int, playerID *string) error {
+	_, err := tx.Exec(ctx, fmt.Sprintf(`
+		UPDATE tournament_matches
+		SET player%[1]d_id = $2, slot%[1]d_decided = true
+		WHERE id = $1 AND status = 'pending'
+	`, slot), matchID, playerID)
+	if err != nil {
+		return fmt.Errorf("failed to fill tournament slot: %w", err)
+	}
+	return resolveTournamentMatch(ctx, tx, t, matchID)
+}
+
+// finishTournamentIfDone finishes a tournament once every match is settled.
+// The winner is the one player placed first, if there is one.
+func finishTournamentIfDone(ctx context.Context, tx pgx.Tx, t tournamentRow) error {
+	var open bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM tournament_matches WHERE tournament_id = $1 AND status IN ('pending', 'ready'))
+	`, t.id).Scan(&open)
+	if err != nil {
+		return fmt.Errorf("failed to check tournament matches: %w", err)
+	}
+	if open {
+		return nil
+	}
+
+	standings, err := tournamentStandings(ctx, tx, t.id, t.format, true)
+	if err != nil {
+		return err
+	}
+	var winnerID any
+	if len(standings) > 0 && standings[0].Place == 1 && (len(standings) == 1 || standings[1].Place != 1) {
+		winnerID = standings[0].PlayerID
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE tournaments SET status = 'finished', winner_id = $2, finished_at = now() WHERE id = $1", t.id, winnerID)
+	if err != nil {
+		return fmt.Errorf("failed to finish tournament: %w", err)
+	}
+	logger.Infof("Finished tournament %s, winner %v", t.id, winnerID)
+	return nil
+}
+
+// advanceTournament feeds the result of a finished game into the tournament
+// match it was played for, if any. A game without a single winner is a draw
+// in a round robin; in an elimination bracket the better seed goes through.
+func advanceTournament(ctx context.Context, tx pgx.Tx, gameID string, gameWinnerID string) error {
+	var tournamentID, matchID string
+	err := tx.QueryRow(ctx, `
+		SELECT tournament_id::text, id::text
+		FROM tournament_matches
+		WHERE game_id = $1 AND status = 'ready'
+	`, gameID).Scan(&tournamentID, &matchID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get tournament match: %w", err)
+	}
+
+	t, err := lockTournament(ctx, tx, tournamentID)
+	if err != nil {
+		return err
+	}
+	m, err := lockTournamentMatch(ctx, tx, tournamentID, matchID)
+	if err != nil {
+		return err
+	}
+	if m.status != types.MatchReady {
+		return nil
+	}
+
+	for _, playerID := range m.players {
+		if playerID != nil && *playerID == gameWinnerID {
+			return finishTournamentMatch(ctx, tx, t, m, playerID, false)
+		}
+	}
+	if t.format == tournament.RoundRobin {
+		return finishTournamentMatch(ctx, tx, t, m, nil, true)
+	}
+
+	var betterSeed string
+	err = tx.QueryRow(ctx, `
+		SELECT user_id::text
+		FROM tournament_participants
+		WHERE tournament_id = $1 AND user_id IN ($2, $3)
+		ORDER BY seed
+		LIMIT 1
+	`, tournamentID, m.players[0], m.players[1]).Scan(&betterSeed)
+	if err != nil {
+		return fmt.Errorf("failed to get better seed: %w", err)
+	}
+	return finishTournamentMatch(ctx, tx, t, m, &betterSeed, false)
+}
+
+// RecordMatchResult settles a ready match without waiting for its game, for
+// a walkover or a game played elsewhere. A draw is only allowed in a round
+// robin.
+func (db *DB) RecordMatchResult(ctx context.Context, tournamentID string, matchID string, winnerID string) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	t, err := lockTournament(ctx, tx, tournamentID)
+	if err != nil {
+		return err
+	}
+	if t.status != types.TournamentInProgress {
+		return ErrTournamentNotRunning
+	}
+	m, err := lockTournamentMatch(ctx, tx, tournamentID, matchID)
+	if err != nil {
+		return err
+	}
+	if m.status != types.MatchReady {
+		return ErrMatchNotReady
+	}
+
+	if winnerID == "" {
+		if t.format != tournament.RoundRobin {
+			return ErrWinnerNotInMatch
+		}
+		err = finishTournamentMatch(ctx, tx, t, m, nil, true)
+	} else {
+		if !slices.ContainsFunc(m.players[:], func(playerID *string) bool { return playerID != nil && *playerID == winnerID }) {
+			return ErrWinnerNotInMatch
+		}
+		err = finishTournamentMatch(ctx, tx, t, m, &winnerID, false)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// tournamentStandings tallies the settled matches of a tournament into
+// ranked standings. Byes count as neither played nor won.
+func tournamentStandings(ctx context.Context, q querier, tournamentID string, format tournament.Format, finished bool) ([]tournament.Standing, error) {
+	rows, err := q.Query(ctx, `
+		SELECT user_id::text, COALESCE(seed, 0)
+		FROM tournament_participants
+		WHERE tournament_id = $1
+	`, tournamentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query participants: %w", err)
+	}
+	records, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (tournament.Record, error) {
+		var record tournament.Record
+		err := row.Scan(&record.PlayerID, &record.Seed)
+		return record, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect participants: %w", err)
+	}
+	index := make(map[string]int, len(records))
+	for i, record := range records {
+		index[record.PlayerID] = i
+	}
+
+	rows, err = q.Query(ctx, `
+		SELECT m.player1_id::text, m.player2_id::text, COALESCE(m.winner_id::text, ''), m.is_draw, m.stage,
+			COALESCE(s1.score, 0), COALESCE(s2.score, 0)
+		FROM tournament_matches m
+		LEFT JOIN game_standings s1 ON s1.game_id = m.game_id AND s1.user_id = m.player1_id
+		LEFT JOIN game_standings s2 ON s2.game_id = m.game_id AND s2.user_id = m.player2_id
+		WHERE m.tournament_id = $1 AND m.status = 'finished'
+			AND m.player1_id IS NOT NULL AND m.player2_id IS NOT NULL
+		ORDER BY m.stage, m.finished_at
+	`, tournamentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tournament results: %w", err)
+	}
+	defer rows.Close()
+
+	maxLosses := format.MaxLosses()
+	for rows.Next() {
+		var (
+			players  [2]string
+			winnerID string
+			isDraw   bool
+			stage    int
+			scores   [2]int
+		)
+		if err := rows.Scan(&players[0], &players[1], &winnerID, &isDraw, &stage, &scores[0], &scores[1]); err != nil {
+			return nil, fmt.Errorf("failed to scan tournament result: %w", err)
+		}
+
+		for slot, playerID := range players {
+			i, ok := index[playerID]
+			if !ok {
+				continue
+			}
+			record := &records[i]
+			record.Played++
+			record.ScoreFor += scores[slot]
+			record.ScoreAgainst += scores[1-slot]
+			switch {
+			case isDraw:
+				record.Draws++
+			case winnerID == playerID:
+				record.Wins++
+			default:
+				record.Losses++
+				if maxLosses > 0 && record.Losses == maxLosses {
+					record.KnockedOutAt = stage
+				}
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tournament result rows: %w", err)
+	}
+
+	return tournament.Standings(format, records, finished), nil
+}
+
+// GetTournamentStandings ranks the players of a tournament as it stands.
+func (db *DB) GetTournamentStandings(ctx context.Context, tournamentID string) ([]types.TournamentStandingClient, error) {
+	var (
+		format tournament.Format
+		status types.TournamentStatus
+	)
+	err := db.pool.QueryRow(ctx, "SELECT format, status FROM tournaments WHERE id = $1", tournamentID).Scan(&format, &status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTournamentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tournament: %w", err)
+	}
+
+	standings, err := tournamentStandings(ctx, db.pool, tournamentID, format, status == types.TournamentFinished)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.pool.Query(ctx, `
+		SELECT u.id::text, u.name
+		FROM tournament_participants tp
+		JOIN users u ON u.id = tp.user_id
+		WHERE tp.tournament_id = $1
+	`, tournamentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query participant names: %w", err)
+	}
+	names := make(map[string]string)
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan participant name: %w", err)
+		}
+		names[id] = name
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating participant name rows: %w", err)
+	}
+
+	result := make([]types.TournamentStandingClient, 0, len(standings))
+	for _, standing := range standings {
+		result = append(result, types.TournamentStandingClient{
+			Place:        standing.Place,
+			UserID:       standing.PlayerID,
+			Name:         names[standing.PlayerID],
+			Seed:         standing.Seed,
+			Played:       standing.Played,
+			Wins:         standing.Wins,
+			Losses:       standing.Losses,
+			Draws:        standing.Draws,
+			Points:       standing.Points,
+			ScoreFor:     standing.ScoreFor,
+			ScoreAgainst: standing.ScoreAgainst,
+			Eliminated:   standing.KnockedOutAt > 0,
+		})
+	}
+	return result, nil
+}
@@ -17,7 +17,7 @@ func (db *DB) GetUserByEmail(email string) (types.UserServer, error) {
 
 func (db *DB) GetUserByID(id string) (types.UserServer, error) {
 	var user types.UserServer
-	err := db.pool.QueryRow(context.Background(), "SELECT id, email, name FROM users WHERE id = $1", id).Scan(&user.ID, &user.Email, &user.Name)
+	err := db.pool.QueryRow(context.Background(), "SELECT id, email, name, is_admin FROM users WHERE id = $1", id).Scan(&user.ID, &user.Email, &user.Name, &user.IsAdmin)
 	if err != nil {
 		return types.UserServer{}, err
 	}
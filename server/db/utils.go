@@ -2,8 +2,10 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"mindwarp/logger"
+	"mindwarp/types"
 
 	"github.com/jackc/pgx/pgtype"
 )
@@ -17,6 +19,51 @@ func uuidToString(u pgtype.UUID) string {
 	return fmt.Sprintf("%x-%x-%x-%x-%x", buf[0:4], buf[4:6], buf[6:8], buf[8:10], buf[10:16])
 }
 
+func unmarshalRoundRules(roundID string, rulesJSON []byte) types.RoundRules {
+	var rules types.RoundRules
+	if len(rulesJSON) > 0 && string(rulesJSON) != "null" {
+		if err := json.Unmarshal(rulesJSON, &rules); err != nil {
+			logger.Errorf("Failed to unmarshal rules for round %s: %v. Using default.", roundID, err)
+			return types.RoundRules{}
+		}
+	}
+	return rules
+}
+
+// unmarshalRoundSettings reads a round's time and rank settings, falling back
+// to empty settings when they cannot be read.
+func unmarshalRoundSettings(roundID string, timeJSON []byte, rankJSON []byte) (types.TimeSettings, []types.RankSettings) {
+	var timeSetting types.TimeSettings
+	if len(timeJSON) > 0 && string(timeJSON) != "null" {
+		if err := json.Unmarshal(timeJSON, &timeSetting); err != nil {
+			logger.Errorf("Failed to unmarshal time settings for round %s: %v. Using default.", roundID, err)
+			timeSetting = types.TimeSettings{}
+		}
+	}
+
+	var rankSetting []types.RankSettings
+	if len(rankJSON) > 0 && string(rankJSON) != "null" {
+		if err := json.Unmarshal(rankJSON, &rankSetting); err != nil {
+			logger.Errorf("Failed to unmarshal rank settings for round %s: %v. Using default.", roundID, err)
+			rankSetting = []types.RankSettings{}
+		}
+	}
+	return timeSetting, rankSetting
+}
+
+// unmarshalGameRules reads stored house rules on top of the defaults, so
+// rows stored as '{}' and fields added later keep the classic behaviour.
+func unmarshalGameRules(ownerID string, rulesJSON []byte) types.GameRules {
+	rules := types.DefaultGameRules()
+	if len(rulesJSON) > 0 && string(rulesJSON) != "null" {
+		if err := json.Unmarshal(rulesJSON, &rules); err != nil {
+			logger.Errorf("Failed to unmarshal game rules for %s: %v. Using default.", ownerID, err)
+			return types.DefaultGameRules()
+		}
+	}
+	return rules
+}
+
 // CountQuery efficiently counts rows in a table using PostgreSQL's statistics
 // This is much faster than COUNT(*) for large tables
 func (db *DB) CountQuery(ctx context.Context, tableName string) (int64, error) {
@@ -16,6 +16,10 @@ func init() {
 }
 
 func main() {
+	if runCommand(os.Args[1:]) {
+		return
+	}
+
 	server := api.NewServer()
 	server.Start()
 
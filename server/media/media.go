@@ -0,0 +1,208 @@
+// Package media validates, stores and thumbnails the pictures and sounds
+// attached to questions. Files are content-addressed: the id of a file is the
+// SHA-256 of its bytes, so uploading the same file twice, or copying a
+// template into a game, never duplicates it.
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+)
+
+// Kind groups MIME types by how the client plays them.
+type Kind string
+
+const (
+	KindImage Kind = "image"
+	KindAudio Kind = "audio"
+)
+
+const (
+	MaxImageSize int64 = 10 << 20
+	MaxAudioSize int64 = 25 << 20
+	// MaxUploadSize bounds any upload before its type is known.
+	MaxUploadSize = MaxAudioSize
+	// MaxImagePixels bounds the decoded size of an image. A small file can
+	// declare huge dimensions and make decoding it exhaust memory.
+	MaxImagePixels int64 = 40_000_000
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported media type, expected a PNG, JPEG, GIF or WebP image or an MP3, WAV or OGG sound")
+	ErrTooLarge        = errors.New("media file is too large")
+	ErrEmpty           = errors.New("media file is empty")
+	ErrInvalidImage    = errors.New("image could not be read")
+	ErrTooManyPixels   = errors.New("image dimensions are too large")
+)
+
+// allowedTypes maps sniffed MIME types to their kind. The names are the ones
+// net/http.DetectContentType returns.
+var allowedTypes = map[string]Kind{
+	"image/png":       KindImage,
+	"image/jpeg":      KindImage,
+	"image/gif":       KindImage,
+	"image/webp":      KindImage,
+	"audio/mpeg":      KindAudio,
+	"audio/wave":      KindAudio,
+	"application/ogg": KindAudio,
+}
+
+// Info describes a stored file.
+type Info struct {
+	ID       string
+	MimeType string
+	Kind     Kind
+	Size     int64
+	// Width and Height are zero for audio.
+	Width  int
+	Height int
+	// Thumbnail is set for images larger than ThumbnailSize.
+	Thumbnail *Info
+}
+
+// ID returns the content address of data.
+func ID(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidID reports whether id looks like a value returned by ID. Stores use it
+// to reject keys that could escape their storage root.
+func ValidID(id string) bool {
+	if len(id) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range id {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Sniff detects the MIME type from the file contents, ignoring whatever the
+// client claimed.
+func Sniff(data []byte) (string, Kind, error) {
+	mimeType := http.DetectContentType(data)
+	kind, ok := allowedTypes[mimeType]
+	if !ok {
+		return "", "", ErrUnsupportedType
+	}
+	if mimeType == "application/ogg" {
+		mimeType = "audio/ogg"
+	}
+	return mimeType, kind, nil
+}
+
+// Ingest reads an upload, checks its type and size, and stores it along with
+// a thumbnail for large images.
+func Ingest(ctx context.Context, store MediaStore, r io.Reader) (Info, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxUploadSize+1))
+	if err != nil {
+		return Info{}, fmt.Errorf("failed to read upload: %w", err)
+	}
+	if len(data) == 0 {
+		return Info{}, ErrEmpty
+	}
+
+	mimeType, kind, err := Sniff(data)
+	if err != nil {
+		return Info{}, err
+	}
+
+	limit := MaxAudioSize
+	if kind == KindImage {
+		limit = MaxImageSize
+	}
+	if int64(len(data)) > limit {
+		return Info{}, ErrTooLarge
+	}
+
+	info := Info{
+		ID:       ID(data),
+		MimeType: mimeType,
+		Kind:     kind,
+		Size:     int64(len(data)),
+	}
+
+	if kind == KindImage {
+		config, decodable, err := imageConfig(data)
+		if err != nil {
+			return Info{}, err
+		}
+		info.Width, info.Height = config.Width, config.Height
+		if decodable && (info.Width > ThumbnailSize || info.Height > ThumbnailSize) {
+			thumbnail, err := makeThumbnail(ctx, store, data)
+			if err != nil {
+				return Info{}, err
+			}
+			info.Thumbnail = thumbnail
+		}
+	}
+
+	if err := store.Put(ctx, info.ID, bytes.NewReader(data)); err != nil {
+		return Info{}, err
+	}
+
+	return info, nil
+}
+
+// imageConfig reads an image's dimensions from its header without decoding
+// it, refusing images that cannot be read or are too large to decode safely.
+// decodable is false for WebP, which only has its header read.
+func imageConfig(data []byte) (config image.Config, decodable bool, err error) {
+	config, _, err = image.DecodeConfig(bytes.NewReader(data))
+	decodable = err == nil
+	if !decodable {
+		var ok bool
+		if config, ok = webpConfig(data); !ok {
+			return image.Config{}, false, ErrInvalidImage
+		}
+	}
+
+	if config.Width <= 0 || config.Height <= 0 {
+		return image.Config{}, false, ErrInvalidImage
+	}
+	if int64(config.Width)*int64(config.Height) > MaxImagePixels {
+		return image.Config{}, false, ErrTooManyPixels
+	}
+	return config, decodable, nil
+}
+
+func makeThumbnail(ctx context.Context, store MediaStore, data []byte) (*Info, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	var buf bytes.Buffer
+	thumbnail := Thumbnail(img, ThumbnailSize)
+	if err := encodeThumbnail(&buf, thumbnail); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+
+	bounds := thumbnail.Bounds()
+	info := &Info{
+		ID:       ID(buf.Bytes()),
+		MimeType: "image/jpeg",
+		Kind:     KindImage,
+		Size:     int64(buf.Len()),
+		Width:    bounds.Dx(),
+		Height:   bounds.Dy(),
+	}
+
+	if err := store.Put(ctx, info.ID, &buf); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
@@ -0,0 +1,113 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotFound is returned by a MediaStore when no object has the given key.
+var ErrNotFound = errors.New("media not found")
+
+// MediaStore keeps uploaded files by key. Keys are content hashes (see ID),
+// so storing the same key twice must leave a single object behind.
+type MediaStore interface {
+	// Put stores the contents of r under key. Storing an existing key is a no-op.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open returns the object stored under key, or ErrNotFound. Callers must
+	// close it. The reader also implements io.Seeker when the backend allows
+	// ranged reads, which lets audio players seek.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Exists reports whether an object is stored under key.
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+// LocalStore is a MediaStore on the local disk. Objects are sharded into
+// sub-directories by the first two characters of their key.
+type LocalStore struct {
+	dir string
+}
+
+func NewLocalStore(dir string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create media directory: %w", err)
+	}
+	return &LocalStore{dir: dir}, nil
+}
+
+func (s *LocalStore) path(key string) (string, error) {
+	if !ValidID(key) {
+		return "", fmt.Errorf("invalid media key %q", key)
+	}
+	return filepath.Join(s.dir, key[:2], key), nil
+}
+
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create media directory: %w", err)
+	}
+
+	// Write to a temporary file first so a failed upload never leaves a
+	// truncated object under its final name.
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create media file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write media file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write media file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store media file: %w", err)
+	}
+	return nil
+}
+
+func (s *LocalStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open media file: %w", err)
+	}
+	return file, nil
+}
+
+func (s *LocalStore) Exists(ctx context.Context, key string) (bool, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to stat media file: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,77 @@
+package media
+
+import (
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+)
+
+// ThumbnailSize is the longest side of a generated thumbnail, in pixels.
+const ThumbnailSize = 320
+
+// Thumbnail scales img down so that neither side exceeds maxSide, keeping the
+// aspect ratio. Each target pixel is the average of the source pixels it
+// covers, which avoids the aliasing of nearest-neighbour sampling.
+func Thumbnail(img image.Image, maxSide int) image.Image {
+	src := img.Bounds()
+	width, height := src.Dx(), src.Dy()
+	if width <= maxSide && height <= maxSide {
+		return img
+	}
+
+	if width >= height {
+		height = max(height*maxSide/width, 1)
+		width = maxSide
+	} else {
+		width = max(width*maxSide/height, 1)
+		height = maxSide
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := src.Min.Y + y*src.Dy()/height
+		y1 := max(src.Min.Y+(y+1)*src.Dy()/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := src.Min.X + x*src.Dx()/width
+			x1 := max(src.Min.X+(x+1)*src.Dx()/width, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
+
+// encodeThumbnail writes a JPEG. Transparent areas are flattened onto white,
+// since JPEG has no alpha channel.
+func encodeThumbnail(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	flat := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			white := 0xffff - a
+			flat.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r + white),
+				G: uint16(g + white),
+				B: uint16(b + white),
+				A: 0xffff,
+			})
+		}
+	}
+	return jpeg.Encode(w, flat, &jpeg.Options{Quality: 80})
+}
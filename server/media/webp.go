@@ -0,0 +1,43 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+)
+
+// webpConfig reads the dimensions of a WebP image from its header. The
+// standard library has no WebP decoder, but the size is needed to refuse
+// oversized images all the same.
+func webpConfig(data []byte) (image.Config, bool) {
+	if len(data) < 30 || !bytes.Equal(data[0:4], []byte("RIFF")) || !bytes.Equal(data[8:12], []byte("WEBP")) {
+		return image.Config{}, false
+	}
+
+	var width, height int
+	switch string(data[12:16]) {
+	case "VP8 ":
+		// Lossy: a key frame starts with a 3-byte tag and a start code
+		if !bytes.Equal(data[23:26], []byte{0x9d, 0x01, 0x2a}) {
+			return image.Config{}, false
+		}
+		width = int(binary.LittleEndian.Uint16(data[26:28]) & 0x3fff)
+		height = int(binary.LittleEndian.Uint16(data[28:30]) & 0x3fff)
+	case "VP8L":
+		// Lossless: a signature byte, then 14 bits each of width-1 and height-1
+		if data[20] != 0x2f {
+			return image.Config{}, false
+		}
+		bits := binary.LittleEndian.Uint32(data[21:25])
+		width = int(bits&0x3fff) + 1
+		height = int(bits>>14&0x3fff) + 1
+	case "VP8X":
+		// Extended: flags, then 24 bits each of canvas width-1 and height-1
+		width = int(uint32(data[24])|uint32(data[25])<<8|uint32(data[26])<<16) + 1
+		height = int(uint32(data[27])|uint32(data[28])<<8|uint32(data[29])<<16) + 1
+	default:
+		return image.Config{}, false
+	}
+
+	return image.Config{Width: width, Height: height}, true
+}
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Per-round scoring rules (e.g. whether wrong answers subtract points)
+ALTER TABLE template_rounds ADD COLUMN rules JSONB NOT NULL DEFAULT '{}'::JSONB;
+ALTER TABLE rounds ADD COLUMN rules JSONB NOT NULL DEFAULT '{}'::JSONB;
+
+-- Manual host score adjustments. Rows are never updated or deleted so they
+-- double as an audit log; game_users.round_scores is derived from answers
+-- plus these rows.
+CREATE TABLE score_adjustments (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  game_id UUID NOT NULL REFERENCES games(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  round_id UUID NOT NULL REFERENCES rounds(id) ON DELETE CASCADE,
+  points INT NOT NULL,
+  reason TEXT NOT NULL DEFAULT '',
+  created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_score_adjustments_game ON score_adjustments(game_id);
+CREATE INDEX idx_themes_round ON themes(round_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_themes_round;
+DROP INDEX IF EXISTS idx_score_adjustments_game;
+DROP TABLE IF EXISTS score_adjustments;
+
+ALTER TABLE rounds DROP COLUMN IF EXISTS rules;
+ALTER TABLE template_rounds DROP COLUMN IF EXISTS rules;
+
+-- +goose StatementEnd
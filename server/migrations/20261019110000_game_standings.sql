@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Ordered tie-breakers applied when players finish with equal scores
+ALTER TABLE games ADD COLUMN tie_breakers TEXT[] NOT NULL DEFAULT '{correct_answers,avg_time_answered}';
+
+-- Final ranking of every participant, written once when the game finishes
+CREATE TABLE game_standings (
+  game_id UUID NOT NULL REFERENCES games(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  place INT NOT NULL,
+  score INT NOT NULL,
+  correct_answers INT NOT NULL DEFAULT 0,
+  avg_time_answered DOUBLE PRECISION,
+  won_sudden_death BOOLEAN NOT NULL DEFAULT FALSE,
+  PRIMARY KEY (game_id, user_id)
+);
+
+CREATE INDEX idx_game_standings_user ON game_standings(user_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_game_standings_user;
+DROP TABLE IF EXISTS game_standings;
+ALTER TABLE games DROP COLUMN IF EXISTS tie_breakers;
+
+-- +goose StatementEnd
@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A "final" round is played with secret wagers instead of the board
+ALTER TABLE template_rounds ADD COLUMN is_final BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE rounds ADD COLUMN is_final BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Set by the host when the final question is opened for wagers
+ALTER TABLE rounds ADD COLUMN final_question_id UUID REFERENCES questions(id) ON DELETE SET NULL;
+ALTER TABLE rounds ADD COLUMN final_deadline TIMESTAMPTZ;
+
+-- Secret wagers and written answers for the final round. Hidden from other
+-- players until revealed_at is set by the host.
+CREATE TABLE final_wagers (
+  round_id UUID NOT NULL REFERENCES rounds(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  wager INT NOT NULL CHECK (wager >= 0),
+  answer TEXT,
+  is_correct BOOLEAN,
+  revealed_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (round_id, user_id)
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS final_wagers;
+
+ALTER TABLE rounds DROP COLUMN IF EXISTS final_deadline;
+ALTER TABLE rounds DROP COLUMN IF EXISTS final_question_id;
+ALTER TABLE rounds DROP COLUMN IF EXISTS is_final;
+ALTER TABLE template_rounds DROP COLUMN IF EXISTS is_final;
+
+-- +goose StatementEnd
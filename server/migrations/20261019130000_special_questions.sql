@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Svoya Igra specials: cat in the bag, auction and no-risk questions
+ALTER TABLE template_questions ADD COLUMN question_type TEXT NOT NULL DEFAULT 'standard'
+  CHECK (question_type IN ('standard', 'cat_in_bag', 'auction', 'no_risk'));
+ALTER TABLE questions ADD COLUMN question_type TEXT NOT NULL DEFAULT 'standard'
+  CHECK (question_type IN ('standard', 'cat_in_bag', 'auction', 'no_risk'));
+
+-- Who ended up answering a special question and for how much. A cat in the
+-- bag is handed to assignee_id by picker_id; an auction is won by assignee_id
+-- with a bid of stake points.
+CREATE TABLE question_plays (
+  question_id UUID PRIMARY KEY REFERENCES questions(id) ON DELETE CASCADE,
+  picker_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  assignee_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  stake INT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE auction_bids (
+  question_id UUID NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  amount INT NOT NULL CHECK (amount > 0),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (question_id, user_id)
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS auction_bids;
+DROP TABLE IF EXISTS question_plays;
+
+ALTER TABLE questions DROP COLUMN IF EXISTS question_type;
+ALTER TABLE template_questions DROP COLUMN IF EXISTS question_type;
+
+-- +goose StatementEnd
@@ -0,0 +1,47 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Uploaded files, addressed by the SHA-256 of their contents
+CREATE TABLE media (
+  id TEXT PRIMARY KEY,
+  mime_type TEXT NOT NULL,
+  kind TEXT NOT NULL CHECK (kind IN ('image', 'audio')),
+  size BIGINT NOT NULL,
+  width INT NOT NULL DEFAULT 0,
+  height INT NOT NULL DEFAULT 0,
+  thumbnail_id TEXT REFERENCES media(id),
+  uploaded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Attachments reference media rows, so a game created from a template points
+-- at the same files instead of copying them.
+CREATE TABLE template_question_media (
+  question_id UUID NOT NULL REFERENCES template_questions(id) ON DELETE CASCADE,
+  media_id TEXT NOT NULL REFERENCES media(id),
+  role TEXT NOT NULL CHECK (role IN ('question', 'answer')),
+  position SMALLINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (question_id, role, position)
+);
+
+CREATE TABLE question_media (
+  question_id UUID NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+  media_id TEXT NOT NULL REFERENCES media(id),
+  role TEXT NOT NULL CHECK (role IN ('question', 'answer')),
+  position SMALLINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (question_id, role, position)
+);
+
+CREATE INDEX idx_template_question_media_media ON template_question_media(media_id);
+CREATE INDEX idx_question_media_media ON question_media(media_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS question_media;
+DROP TABLE IF EXISTS template_question_media;
+DROP TABLE IF EXISTS media;
+
+-- +goose StatementEnd
@@ -0,0 +1,57 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE template_questions DROP CONSTRAINT template_questions_question_type_check;
+ALTER TABLE template_questions ADD CONSTRAINT template_questions_question_type_check
+  CHECK (question_type IN ('standard', 'cat_in_bag', 'auction', 'no_risk', 'multiple_choice'));
+ALTER TABLE questions DROP CONSTRAINT questions_question_type_check;
+ALTER TABLE questions ADD CONSTRAINT questions_question_type_check
+  CHECK (question_type IN ('standard', 'cat_in_bag', 'auction', 'no_risk', 'multiple_choice'));
+
+-- Shuffled once per game when the game is created from the template
+ALTER TABLE template_questions ADD COLUMN shuffle_options BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE template_question_options (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  question_id UUID NOT NULL REFERENCES template_questions(id) ON DELETE CASCADE,
+  text TEXT NOT NULL,
+  is_correct BOOLEAN NOT NULL DEFAULT false,
+  position SMALLINT NOT NULL
+);
+
+CREATE TABLE question_options (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  question_id UUID NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+  text TEXT NOT NULL,
+  is_correct BOOLEAN NOT NULL DEFAULT false,
+  position SMALLINT NOT NULL
+);
+
+CREATE INDEX idx_template_question_options_question ON template_question_options(question_id);
+CREATE INDEX idx_question_options_question ON question_options(question_id);
+
+-- The options a player picked, for auto-graded questions
+ALTER TABLE answers ADD COLUMN selected_options UUID[];
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE answers DROP COLUMN IF EXISTS selected_options;
+
+DROP TABLE IF EXISTS question_options;
+DROP TABLE IF EXISTS template_question_options;
+
+ALTER TABLE template_questions DROP COLUMN IF EXISTS shuffle_options;
+
+DELETE FROM questions WHERE question_type = 'multiple_choice';
+DELETE FROM template_questions WHERE question_type = 'multiple_choice';
+ALTER TABLE questions DROP CONSTRAINT questions_question_type_check;
+ALTER TABLE questions ADD CONSTRAINT questions_question_type_check
+  CHECK (question_type IN ('standard', 'cat_in_bag', 'auction', 'no_risk'));
+ALTER TABLE template_questions DROP CONSTRAINT template_questions_question_type_check;
+ALTER TABLE template_questions ADD CONSTRAINT template_questions_question_type_check
+  CHECK (question_type IN ('standard', 'cat_in_bag', 'auction', 'no_risk'));
+
+-- +goose StatementEnd
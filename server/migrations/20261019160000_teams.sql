@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Teams inside a game. round_scores caches the sum of the members' scores,
+-- the same shape as game_users.round_scores.
+CREATE TABLE game_teams (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  game_id UUID NOT NULL REFERENCES games(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  captain_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  round_scores JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (game_id, name)
+);
+
+ALTER TABLE game_users ADD COLUMN team_id UUID REFERENCES game_teams(id) ON DELETE SET NULL;
+
+-- The teammate credited for an answer given by the captain
+ALTER TABLE answers ADD COLUMN contributor_id UUID REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE TABLE game_team_standings (
+  game_id UUID NOT NULL REFERENCES games(id) ON DELETE CASCADE,
+  team_id UUID NOT NULL REFERENCES game_teams(id) ON DELETE CASCADE,
+  place INT NOT NULL,
+  score INT NOT NULL,
+  correct_answers INT NOT NULL DEFAULT 0,
+  avg_time_answered DOUBLE PRECISION,
+  won_sudden_death BOOLEAN NOT NULL DEFAULT false,
+  PRIMARY KEY (game_id, team_id)
+);
+
+ALTER TABLE games ADD COLUMN winner_team_id UUID REFERENCES game_teams(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_game_teams_game ON game_teams(game_id);
+CREATE INDEX idx_game_users_team ON game_users(team_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE games DROP COLUMN IF EXISTS winner_team_id;
+DROP TABLE IF EXISTS game_team_standings;
+ALTER TABLE answers DROP COLUMN IF EXISTS contributor_id;
+ALTER TABLE game_users DROP COLUMN IF EXISTS team_id;
+DROP TABLE IF EXISTS game_teams;
+
+-- +goose StatementEnd
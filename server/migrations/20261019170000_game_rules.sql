@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- House rules (types.GameRules). Templates hold the defaults a game starts
+-- from; '{}' means the classic rules.
+ALTER TABLE game_templates ADD COLUMN rules JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE games ADD COLUMN rules JSONB NOT NULL DEFAULT '{}';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE games DROP COLUMN IF EXISTS rules;
+ALTER TABLE game_templates DROP COLUMN IF EXISTS rules;
+
+-- +goose StatementEnd
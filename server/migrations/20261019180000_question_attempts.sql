@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Answers recorded through the attempts flow are numbered in the order the
+-- players tried the question. Older answers have no attempt number.
+ALTER TABLE answers ADD COLUMN attempt INT;
+ALTER TABLE answers ADD CONSTRAINT answers_question_attempt_key UNIQUE (question_id, attempt);
+
+-- Set once a player got the question right, everyone has tried or the host
+-- closed it.
+ALTER TABLE questions ADD COLUMN resolved_at TIMESTAMPTZ;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE questions DROP COLUMN IF EXISTS resolved_at;
+ALTER TABLE answers DROP CONSTRAINT IF EXISTS answers_question_attempt_key;
+ALTER TABLE answers DROP COLUMN IF EXISTS attempt;
+
+-- +goose StatementEnd
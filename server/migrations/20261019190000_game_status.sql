@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TYPE game_status AS ENUM ('lobby', 'in_progress', 'paused', 'finished', 'abandoned');
+
+-- is_finished is kept in step with status = 'finished' for older clients.
+-- last_activity_at is bumped by every change to the game and drives the
+-- abandoned-game sweeper.
+ALTER TABLE games
+  ADD COLUMN status game_status NOT NULL DEFAULT 'lobby',
+  ADD COLUMN min_players INT NOT NULL DEFAULT 2,
+  ADD COLUMN last_activity_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+-- Games created before statuses existed were already being played
+UPDATE games SET status = CASE WHEN is_finished THEN 'finished'::game_status ELSE 'in_progress'::game_status END;
+
+CREATE INDEX idx_games_status_activity ON games(status, last_activity_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_games_status_activity;
+ALTER TABLE games
+  DROP COLUMN IF EXISTS last_activity_at,
+  DROP COLUMN IF EXISTS min_players,
+  DROP COLUMN IF EXISTS status;
+DROP TYPE IF EXISTS game_status;
+
+-- +goose StatementEnd
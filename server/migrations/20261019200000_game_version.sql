@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Bumped on every change to a game or its scores. Clients send the version
+-- they last read with /games/update and get a conflict if it moved on.
+ALTER TABLE games ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE games DROP COLUMN IF EXISTS version;
+
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- One row per change made through the focused game endpoints. version is the
+-- game version the change produced, so clients can catch up from the last
+-- version they saw.
+CREATE TABLE game_events (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  game_id UUID NOT NULL REFERENCES games(id) ON DELETE CASCADE,
+  version INT NOT NULL,
+  type TEXT NOT NULL,
+  actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  payload JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX game_events_game_version_idx ON game_events (game_id, version);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS game_events;
+
+-- +goose StatementEnd
@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TYPE invite_status ADD VALUE IF NOT EXISTS 'expired';
+ALTER TYPE invite_status ADD VALUE IF NOT EXISTS 'revoked';
+
+-- Pending invites lapse after a week unless answered. The sweeper marks them
+-- expired; until then readers treat a past expires_at as expired.
+ALTER TABLE game_invites
+  ADD COLUMN expires_at TIMESTAMPTZ NOT NULL DEFAULT now() + interval '7 days',
+  ADD COLUMN invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  ADD COLUMN responded_at TIMESTAMPTZ;
+
+UPDATE game_invites gi SET invited_by = g.creator_id
+FROM games g
+WHERE g.id = gi.game_id;
+
+-- Every invite is kept as history, so a player may be invited again once an
+-- earlier invite was declined, expired or revoked. Only one invite per player
+-- may be open or accepted at a time.
+ALTER TABLE game_invites DROP CONSTRAINT IF EXISTS game_invites_game_id_user_id_key;
+CREATE UNIQUE INDEX game_invites_open_key ON game_invites (game_id, user_id)
+  WHERE status IN ('pending', 'accepted');
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+-- Enum values cannot be dropped; fold the new states into declined and keep
+-- only the latest invite per player so the old unique key applies again.
+UPDATE game_invites SET status = 'declined' WHERE status::text IN ('expired', 'revoked');
+DELETE FROM game_invites gi
+USING game_invites newer
+WHERE newer.game_id = gi.game_id AND newer.user_id = gi.user_id
+  AND (newer.created_at, newer.id) > (gi.created_at, gi.id);
+
+DROP INDEX IF EXISTS game_invites_open_key;
+ALTER TABLE game_invites ADD CONSTRAINT game_invites_game_id_user_id_key UNIQUE (game_id, user_id);
+
+ALTER TABLE game_invites
+  DROP COLUMN IF EXISTS responded_at,
+  DROP COLUMN IF EXISTS invited_by,
+  DROP COLUMN IF EXISTS expires_at;
+
+-- +goose StatementEnd
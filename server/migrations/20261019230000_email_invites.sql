@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Invites for people without an account. The emailed link carries a signed
+-- token naming the row; registering or logging in with it turns the email
+-- invite into a regular game invite for that account.
+CREATE TABLE email_invites (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  game_id UUID NOT NULL REFERENCES games(id) ON DELETE CASCADE,
+  email TEXT NOT NULL,
+  invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  status invite_status NOT NULL DEFAULT 'pending',
+  expires_at TIMESTAMPTZ NOT NULL DEFAULT now() + interval '7 days',
+  claimed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  game_invite_id UUID REFERENCES game_invites(id) ON DELETE SET NULL,
+  responded_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX email_invites_open_key ON email_invites (game_id, lower(email))
+  WHERE status = 'pending';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS email_invites;
+
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Per-user inbox. Each insert is announced on the 'notifications' channel
+-- with the new row's id, so the server can push it once the inserting
+-- transaction commits.
+CREATE TABLE notifications (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  type TEXT NOT NULL,
+  game_id UUID REFERENCES games(id) ON DELETE CASCADE,
+  template_id UUID REFERENCES game_templates(id) ON DELETE CASCADE,
+  actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  payload JSONB NOT NULL DEFAULT '{}',
+  read_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX notifications_user_created_idx ON notifications (user_id, created_at DESC);
+CREATE INDEX notifications_user_unread_idx ON notifications (user_id) WHERE read_at IS NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS notifications;
+
+-- +goose StatementEnd
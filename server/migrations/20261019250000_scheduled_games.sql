@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Scheduled games wait in 'scheduled' until scheduled_at, when the scheduler
+-- opens their lobby. timezone is the IANA zone the host planned in and is
+-- only used for display and calendar export.
+ALTER TYPE game_status ADD VALUE IF NOT EXISTS 'scheduled' BEFORE 'lobby';
+
+ALTER TABLE games
+  ADD COLUMN scheduled_at TIMESTAMPTZ,
+  ADD COLUMN timezone TEXT,
+  ADD COLUMN reminder_offsets INT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_games_scheduled_at ON games(scheduled_at) WHERE scheduled_at IS NOT NULL;
+
+-- Reminders already sent, by minutes before the start, so each goes out once
+-- even across restarts. Rescheduling a game clears them.
+CREATE TABLE game_reminders (
+  game_id UUID NOT NULL REFERENCES games(id) ON DELETE CASCADE,
+  offset_minutes INT NOT NULL,
+  sent_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (game_id, offset_minutes)
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS game_reminders;
+UPDATE games SET status = 'lobby' WHERE status::text = 'scheduled';
+DROP INDEX IF EXISTS idx_games_scheduled_at;
+ALTER TABLE games
+  DROP COLUMN IF EXISTS reminder_offsets,
+  DROP COLUMN IF EXISTS timezone,
+  DROP COLUMN IF EXISTS scheduled_at;
+
+-- +goose StatementEnd
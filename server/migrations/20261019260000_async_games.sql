@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Async games are played by every participant on their own time. Each player
+-- opens questions one by one and has question_seconds to answer each; the
+-- game finishes once everyone is done or the deadline passes.
+CREATE TYPE game_mode AS ENUM ('live', 'async');
+
+ALTER TABLE games
+  ADD COLUMN mode game_mode NOT NULL DEFAULT 'live',
+  ADD COLUMN deadline TIMESTAMPTZ,
+  ADD COLUMN question_seconds INT;
+
+CREATE INDEX idx_games_async_deadline ON games(deadline) WHERE mode = 'async';
+
+-- When each player opened each question of an async game. The answer window
+-- is measured from here on the server.
+CREATE TABLE question_opens (
+  question_id UUID NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  opened_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (question_id, user_id)
+);
+
+-- What the player typed, and whether the answer came too late to count.
+ALTER TABLE answers
+  ADD COLUMN response TEXT,
+  ADD COLUMN timed_out BOOLEAN NOT NULL DEFAULT false;
+
+-- Set once a player has answered every question of an async game.
+ALTER TABLE game_users ADD COLUMN finished_at TIMESTAMPTZ;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE game_users DROP COLUMN IF EXISTS finished_at;
+ALTER TABLE answers
+  DROP COLUMN IF EXISTS timed_out,
+  DROP COLUMN IF EXISTS response;
+DROP TABLE IF EXISTS question_opens;
+DROP INDEX IF EXISTS idx_games_async_deadline;
+ALTER TABLE games
+  DROP COLUMN IF EXISTS question_seconds,
+  DROP COLUMN IF EXISTS deadline,
+  DROP COLUMN IF EXISTS mode;
+DROP TYPE IF EXISTS game_mode;
+
+-- +goose StatementEnd
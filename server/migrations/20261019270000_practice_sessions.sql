@@ -0,0 +1,53 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Solo practice runs straight off template questions and never creates a
+-- game, so it stays out of game history, stats and leaderboards.
+CREATE TYPE practice_status AS ENUM ('active', 'finished');
+
+CREATE TABLE practice_sessions (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  -- NULL for sessions drawn from the review list, or whose template is gone
+  template_id UUID REFERENCES game_templates(id) ON DELETE SET NULL,
+  status practice_status NOT NULL DEFAULT 'active',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_practice_sessions_user ON practice_sessions(user_id, created_at DESC);
+
+-- The questions of a session in the order they are served. The player
+-- reveals the answer and then grades themselves.
+CREATE TABLE practice_questions (
+  session_id UUID NOT NULL REFERENCES practice_sessions(id) ON DELETE CASCADE,
+  question_id UUID NOT NULL REFERENCES template_questions(id) ON DELETE CASCADE,
+  position INT NOT NULL,
+  revealed_at TIMESTAMPTZ,
+  is_correct BOOLEAN,
+  graded_at TIMESTAMPTZ,
+  PRIMARY KEY (session_id, question_id)
+);
+
+CREATE INDEX idx_practice_questions_question ON practice_questions(question_id);
+
+-- Questions a player missed in practice and has not got right since.
+CREATE TABLE practice_reviews (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  question_id UUID NOT NULL REFERENCES template_questions(id) ON DELETE CASCADE,
+  misses INT NOT NULL DEFAULT 1,
+  last_missed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, question_id)
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS practice_reviews;
+DROP TABLE IF EXISTS practice_questions;
+DROP TABLE IF EXISTS practice_sessions;
+DROP TYPE IF EXISTS practice_status;
+
+-- +goose StatementEnd
@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- An SM-2 schedule per player and missed question. A card points at either a
+-- game question or a template question missed in practice, and goes away with
+-- the question it points at.
+CREATE TABLE study_cards (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  question_id UUID REFERENCES questions(id) ON DELETE CASCADE,
+  template_question_id UUID REFERENCES template_questions(id) ON DELETE CASCADE,
+  ease DOUBLE PRECISION NOT NULL DEFAULT 2.5,
+  interval_days INT NOT NULL DEFAULT 0,
+  repetitions INT NOT NULL DEFAULT 0,
+  lapses INT NOT NULL DEFAULT 0,
+  due_at TIMESTAMPTZ NOT NULL,
+  last_missed_at TIMESTAMPTZ NOT NULL,
+  last_reviewed_at TIMESTAMPTZ,
+  CHECK ((question_id IS NULL) <> (template_question_id IS NULL))
+);
+
+CREATE UNIQUE INDEX study_cards_question_key ON study_cards(user_id, question_id) WHERE question_id IS NOT NULL;
+CREATE UNIQUE INDEX study_cards_template_question_key ON study_cards(user_id, template_question_id) WHERE template_question_id IS NOT NULL;
+CREATE INDEX idx_study_cards_due ON study_cards(user_id, due_at);
+CREATE INDEX idx_study_cards_template_question ON study_cards(template_question_id);
+CREATE INDEX idx_study_cards_question ON study_cards(question_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS study_cards;
+
+-- +goose StatementEnd
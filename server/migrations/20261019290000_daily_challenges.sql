@@ -0,0 +1,60 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- The shared challenge of a UTC day: a fixed set of public template
+-- questions that every player may attempt once against the clock.
+CREATE TABLE daily_challenges (
+  day DATE PRIMARY KEY,
+  seed BIGINT NOT NULL,
+  time_limit_seconds INT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE daily_challenge_questions (
+  day DATE NOT NULL REFERENCES daily_challenges(day) ON DELETE CASCADE,
+  question_id UUID NOT NULL REFERENCES template_questions(id) ON DELETE CASCADE,
+  position INT NOT NULL,
+  PRIMARY KEY (day, question_id)
+);
+
+CREATE INDEX idx_daily_challenge_questions_question ON daily_challenge_questions(question_id);
+
+-- A player's one attempt at a day's challenge. elapsed_ms is set when the
+-- attempt finishes and breaks ties on the leaderboard.
+CREATE TABLE daily_attempts (
+  day DATE NOT NULL REFERENCES daily_challenges(day) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  started_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  finished_at TIMESTAMPTZ,
+  score INT NOT NULL DEFAULT 0,
+  correct INT NOT NULL DEFAULT 0,
+  elapsed_ms INT,
+  PRIMARY KEY (day, user_id)
+);
+
+CREATE INDEX idx_daily_attempts_leaderboard ON daily_attempts(day, score DESC, elapsed_ms) WHERE finished_at IS NOT NULL;
+CREATE INDEX idx_daily_attempts_unfinished ON daily_attempts(started_at) WHERE finished_at IS NULL;
+
+CREATE TABLE daily_answers (
+  day DATE NOT NULL,
+  user_id UUID NOT NULL,
+  question_id UUID NOT NULL REFERENCES template_questions(id) ON DELETE CASCADE,
+  response TEXT NOT NULL DEFAULT '',
+  selected_options UUID[],
+  is_correct BOOLEAN NOT NULL,
+  answered_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (day, user_id, question_id),
+  FOREIGN KEY (day, user_id) REFERENCES daily_attempts(day, user_id) ON DELETE CASCADE
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS daily_answers;
+DROP TABLE IF EXISTS daily_attempts;
+DROP TABLE IF EXISTS daily_challenge_questions;
+DROP TABLE IF EXISTS daily_challenges;
+
+-- +goose StatementEnd
@@ -0,0 +1,84 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TYPE tournament_format AS ENUM ('single_elimination', 'double_elimination', 'round_robin');
+CREATE TYPE tournament_status AS ENUM ('registration', 'in_progress', 'finished');
+-- pending: waiting for earlier matches to decide its players
+-- ready: both players known, the match game is being played
+-- skipped: a grand-final reset that turned out not to be needed
+CREATE TYPE tournament_match_status AS ENUM ('pending', 'ready', 'finished', 'skipped');
+
+-- Every match is a normal game copied from template_id with the stored
+-- round selection, tie-breakers and rules, hosted by the organizer.
+CREATE TABLE tournaments (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  name TEXT NOT NULL,
+  creator_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  template_id UUID REFERENCES game_templates(id) ON DELETE SET NULL,
+  round_ids UUID[] NOT NULL DEFAULT '{}',
+  tie_breakers TEXT[] NOT NULL,
+  rules JSONB NOT NULL DEFAULT '{}'::JSONB,
+  format tournament_format NOT NULL,
+  status tournament_status NOT NULL DEFAULT 'registration',
+  winner_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  started_at TIMESTAMPTZ,
+  finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_tournaments_creator ON tournaments(creator_id, created_at DESC);
+
+CREATE TABLE tournament_participants (
+  tournament_id UUID NOT NULL REFERENCES tournaments(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  seed INT,
+  registered_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (tournament_id, user_id),
+  CONSTRAINT tournament_participants_seed_key UNIQUE (tournament_id, seed) DEFERRABLE INITIALLY DEFERRED
+);
+
+CREATE INDEX idx_tournament_participants_user ON tournament_participants(user_id);
+
+-- A slot is decided once the match feeding it has finished, or from the
+-- start for seeded slots and byes; a decided slot without a player is a bye.
+-- winner_to and loser_to route the players onward.
+CREATE TABLE tournament_matches (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  tournament_id UUID NOT NULL REFERENCES tournaments(id) ON DELETE CASCADE,
+  bracket TEXT NOT NULL CHECK (bracket IN ('winners', 'losers', 'final', 'round_robin')),
+  round INT NOT NULL,
+  position INT NOT NULL,
+  stage INT NOT NULL,
+  status tournament_match_status NOT NULL DEFAULT 'pending',
+  player1_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  player2_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  slot1_decided BOOLEAN NOT NULL DEFAULT false,
+  slot2_decided BOOLEAN NOT NULL DEFAULT false,
+  game_id UUID REFERENCES games(id) ON DELETE SET NULL,
+  winner_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  loser_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  is_draw BOOLEAN NOT NULL DEFAULT false,
+  winner_to UUID REFERENCES tournament_matches(id) DEFERRABLE INITIALLY DEFERRED,
+  winner_slot INT CHECK (winner_slot IN (1, 2)),
+  loser_to UUID REFERENCES tournament_matches(id) DEFERRABLE INITIALLY DEFERRED,
+  loser_slot INT CHECK (loser_slot IN (1, 2)),
+  conditional BOOLEAN NOT NULL DEFAULT false,
+  finished_at TIMESTAMPTZ,
+  UNIQUE (tournament_id, bracket, round, position)
+);
+
+CREATE UNIQUE INDEX tournament_matches_game_key ON tournament_matches(game_id) WHERE game_id IS NOT NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS tournament_matches;
+DROP TABLE IF EXISTS tournament_participants;
+DROP TABLE IF EXISTS tournaments;
+DROP TYPE IF EXISTS tournament_match_status;
+DROP TYPE IF EXISTS tournament_status;
+DROP TYPE IF EXISTS tournament_format;
+
+-- +goose StatementEnd
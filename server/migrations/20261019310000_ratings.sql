@@ -0,0 +1,64 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Casual games are played for fun and leave ratings alone.
+ALTER TABLE games ADD COLUMN casual BOOLEAN NOT NULL DEFAULT false;
+
+-- reset: everyone starts the season at the default rating
+-- soften: ratings carry over, pulled towards the default by soften_factor
+CREATE TYPE season_carry_over AS ENUM ('reset', 'soften');
+
+-- Ratings are kept per season. The open season, the one without an end,
+-- rates the games finished while it runs; starting a new season ends it.
+CREATE TABLE seasons (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  name TEXT NOT NULL,
+  carry_over season_carry_over NOT NULL DEFAULT 'soften',
+  soften_factor DOUBLE PRECISION NOT NULL DEFAULT 0.5 CHECK (soften_factor BETWEEN 0 AND 1),
+  starts_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  ends_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX seasons_open_key ON seasons ((true)) WHERE ends_at IS NULL;
+
+INSERT INTO seasons (name, carry_over) VALUES ('Season 1', 'reset');
+
+CREATE TABLE player_ratings (
+  season_id UUID NOT NULL REFERENCES seasons(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  rating DOUBLE PRECISION NOT NULL,
+  peak DOUBLE PRECISION NOT NULL,
+  games INT NOT NULL DEFAULT 0,
+  wins INT NOT NULL DEFAULT 0,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (season_id, user_id)
+);
+
+CREATE INDEX idx_player_ratings_leaderboard ON player_ratings(season_id, rating DESC);
+
+-- One row per rated player of a finished game.
+CREATE TABLE rating_history (
+  game_id UUID NOT NULL REFERENCES games(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  season_id UUID NOT NULL REFERENCES seasons(id) ON DELETE CASCADE,
+  place INT NOT NULL,
+  rating_before DOUBLE PRECISION NOT NULL,
+  rating_after DOUBLE PRECISION NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (game_id, user_id)
+);
+
+CREATE INDEX idx_rating_history_user ON rating_history(user_id, created_at DESC);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS rating_history;
+DROP TABLE IF EXISTS player_ratings;
+DROP TABLE IF EXISTS seasons;
+DROP TYPE IF EXISTS season_carry_over;
+ALTER TABLE games DROP COLUMN IF EXISTS casual;
+
+-- +goose StatementEnd
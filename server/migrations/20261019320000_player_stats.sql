@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Player statistics start from a user's games and answers.
+CREATE INDEX idx_game_users_user ON game_users(user_id);
+CREATE INDEX idx_answers_user ON answers(user_id, created_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_answers_user;
+DROP INDEX IF EXISTS idx_game_users_user;
+
+-- +goose StatementEnd
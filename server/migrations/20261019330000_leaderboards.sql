@@ -0,0 +1,95 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TYPE leaderboard_period AS ENUM ('all_time', 'monthly', 'weekly');
+
+-- Running totals behind the leaderboards, one row per player and period,
+-- added to whenever a game finishes. Periods start on the first day of the
+-- UTC month or ISO week a game finished in; all-time rows start on
+-- 1970-01-01. score_share_sum adds up each game's share of the points
+-- scored in it.
+CREATE TABLE leaderboard_stats (
+  period leaderboard_period NOT NULL,
+  period_start DATE NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  games INT NOT NULL DEFAULT 0,
+  wins INT NOT NULL DEFAULT 0,
+  score_share_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+  answered INT NOT NULL DEFAULT 0,
+  correct INT NOT NULL DEFAULT 0,
+  PRIMARY KEY (period, period_start, user_id)
+);
+
+CREATE INDEX idx_leaderboard_stats_wins ON leaderboard_stats(period, period_start, wins DESC);
+
+-- Each player's best score on a template and the game it was scored in.
+CREATE TABLE template_best_scores (
+  template_id UUID NOT NULL REFERENCES game_templates(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  score INT NOT NULL,
+  game_id UUID REFERENCES games(id) ON DELETE SET NULL,
+  achieved_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (template_id, user_id)
+);
+
+CREATE INDEX idx_template_best_scores_rank ON template_best_scores(template_id, score DESC);
+
+-- Backfill from the games finished so far
+WITH results AS (
+  SELECT gu.game_id, gu.user_id, g.template_id, g.finish_date,
+    COALESCE(gs.place, gts.place) AS place,
+    COALESCE((SELECT SUM(value::int) FROM jsonb_each_text(gu.round_scores)), 0) AS score
+  FROM game_users gu
+  JOIN games g ON g.id = gu.game_id
+  LEFT JOIN game_standings gs ON gs.game_id = gu.game_id AND gs.user_id = gu.user_id
+  LEFT JOIN game_team_standings gts ON gts.game_id = gu.game_id AND gts.team_id = gu.team_id
+  WHERE g.is_finished AND g.finish_date IS NOT NULL
+),
+answered AS (
+  SELECT r.game_id, a.user_id, COUNT(*) AS answered, COUNT(*) FILTER (WHERE a.is_correct) AS correct
+  FROM answers a
+  JOIN questions q ON q.id = a.question_id
+  JOIN themes t ON t.id = q.theme_id
+  JOIN rounds r ON r.id = t.round_id
+  WHERE a.is_correct IS NOT NULL
+  GROUP BY r.game_id, a.user_id
+),
+shares AS (
+  SELECT r.*,
+    COALESCE(GREATEST(r.score, 0)::float8 / NULLIF(SUM(GREATEST(r.score, 0)) OVER (PARTITION BY r.game_id), 0), 0) AS share,
+    COALESCE(a.answered, 0) AS answered, COALESCE(a.correct, 0) AS correct
+  FROM results r
+  LEFT JOIN answered a ON a.game_id = r.game_id AND a.user_id = r.user_id
+  WHERE r.place IS NOT NULL
+)
+INSERT INTO leaderboard_stats (period, period_start, user_id, games, wins, score_share_sum, answered, correct)
+SELECT p.period, p.period_start, s.user_id, COUNT(*), COUNT(*) FILTER (WHERE s.place = 1),
+  SUM(s.share), SUM(s.answered), SUM(s.correct)
+FROM shares s
+CROSS JOIN LATERAL (VALUES
+  ('all_time'::leaderboard_period, DATE '1970-01-01'),
+  ('monthly'::leaderboard_period, date_trunc('month', s.finish_date AT TIME ZONE 'UTC')::date),
+  ('weekly'::leaderboard_period, date_trunc('week', s.finish_date AT TIME ZONE 'UTC')::date)
+) AS p(period, period_start)
+GROUP BY p.period, p.period_start, s.user_id;
+
+INSERT INTO template_best_scores (template_id, user_id, score, game_id, achieved_at)
+SELECT DISTINCT ON (g.template_id, gu.user_id)
+  g.template_id, gu.user_id,
+  COALESCE((SELECT SUM(value::int) FROM jsonb_each_text(gu.round_scores)), 0) AS score,
+  g.id, g.finish_date
+FROM game_users gu
+JOIN games g ON g.id = gu.game_id
+WHERE g.is_finished AND g.finish_date IS NOT NULL AND g.template_id IS NOT NULL
+ORDER BY g.template_id, gu.user_id, score DESC, g.finish_date;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS template_best_scores;
+DROP TABLE IF EXISTS leaderboard_stats;
+DROP TYPE IF EXISTS leaderboard_period;
+
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- When a player joined a game; the round-robin picker takes turns in this
+-- order. clock_timestamp keeps players added in one transaction in the order
+-- they were added.
+ALTER TABLE game_users ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT clock_timestamp();
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE game_users DROP COLUMN IF EXISTS created_at;
+
+-- +goose StatementEnd
@@ -0,0 +1,86 @@
+// Package rating updates player ratings from the placings of a finished game
+// with a multiplayer Elo: a game is scored as if every player had played a
+// match against every other one.
+package rating
+
+import "math"
+
+const (
+	// Default is the rating of a player new to a season.
+	Default = 1500.0
+	// K scales how far a single game moves a rating. ProvisionalK is used
+	// while a player has played fewer than ProvisionalGames games in the
+	// season, so new players find their level quickly.
+	K                = 32.0
+	ProvisionalK     = 48.0
+	ProvisionalGames = 10
+	// scale is the rating gap at which the stronger player is expected to
+	// score ten times as much as the weaker one.
+	scale = 400.0
+)
+
+// Player is a player's standing before a game and the place they finished
+// it in. Equal places are draws.
+type Player struct {
+	ID     string
+	Rating float64
+	Games  int
+	Place  int
+}
+
+// Expected returns the score a player rated rating is expected to take off
+// an opponent rated opponent, between 0 and 1.
+func Expected(rating, opponent float64) float64 {
+	return 1 / (1 + math.Pow(10, (opponent-rating)/scale))
+}
+
+// Rate returns the new rating of each player, in the order given. Each
+// player scores 1 against everyone placed below them, 0.5 against everyone
+// placed equal and 0 against the rest. The change is K times the difference
+// between actual and expected score, averaged over the opponents so a big
+// game moves ratings no further than a duel. Fewer than two players leave
+// the ratings as they are.
+func Rate(players []Player) []float64 {
+	ratings := make([]float64, len(players))
+	for i, player := range players {
+		ratings[i] = player.Rating
+	}
+	if len(players) < 2 {
+		return ratings
+	}
+
+	for i, player := range players {
+		var diff float64
+		for j, opponent := range players {
+			if i == j {
+				continue
+			}
+			diff += actual(player.Place, opponent.Place) - Expected(player.Rating, opponent.Rating)
+		}
+
+		k := K
+		if player.Games < ProvisionalGames {
+			k = ProvisionalK
+		}
+		ratings[i] = player.Rating + k*diff/float64(len(players)-1)
+	}
+
+	return ratings
+}
+
+func actual(place, opponentPlace int) float64 {
+	switch {
+	case place < opponentPlace:
+		return 1
+	case place == opponentPlace:
+		return 0.5
+	default:
+		return 0
+	}
+}
+
+// Soften pulls a rating carried into a new season towards Default, keeping
+// factor of its distance: 0 resets it and 1 keeps it as it was.
+func Soften(rating, factor float64) float64 {
+	return Default + (rating-Default)*factor
+}
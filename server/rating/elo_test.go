@@ -0,0 +1,151 @@
+package rating
+
+import (
+	"math"
+	"testing"
+)
+
+const epsilon = 1e-9
+
+func TestRateTwoPlayersIsElo(t *testing.T) {
+	tests := []struct {
+		name   string
+		winner float64
+		loser  float64
+		games  int
+		k      float64
+	}{
+		{name: "favourite wins", winner: 1600, loser: 1400, games: ProvisionalGames, k: K},
+		{name: "upset", winner: 1400, loser: 1600, games: ProvisionalGames, k: K},
+		{name: "provisional players", winner: 1500, loser: 1500, games: 0, k: ProvisionalK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Rate([]Player{
+				{ID: "a", Rating: tt.winner, Games: tt.games, Place: 1},
+				{ID: "b", Rating: tt.loser, Games: tt.games, Place: 2},
+			})
+
+			expected := 1 / (1 + math.Pow(10, (tt.loser-tt.winner)/400))
+			want := []float64{tt.winner + tt.k*(1-expected), tt.loser - tt.k*(1-expected)}
+			for i := range want {
+				if math.Abs(got[i]-want[i]) > epsilon {
+					t.Fatalf("Rate() = %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRateIsZeroSum(t *testing.T) {
+	tests := []struct {
+		name    string
+		players []Player
+	}{
+		{
+			name: "distinct places",
+			players: []Player{
+				{ID: "a", Rating: 1720, Place: 3},
+				{ID: "b", Rating: 1480, Place: 1},
+				{ID: "c", Rating: 1350, Place: 2},
+				{ID: "d", Rating: 1610, Place: 4},
+			},
+		},
+		{
+			name: "shared places",
+			players: []Player{
+				{ID: "a", Rating: 1500, Place: 1},
+				{ID: "b", Rating: 1810, Place: 1},
+				{ID: "c", Rating: 1200, Place: 3},
+				{ID: "d", Rating: 1440, Place: 3},
+				{ID: "e", Rating: 1575, Place: 5},
+			},
+		},
+		{
+			name: "everyone tied",
+			players: []Player{
+				{ID: "a", Rating: 1300, Place: 1},
+				{ID: "b", Rating: 1500, Place: 1},
+				{ID: "c", Rating: 1900, Place: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, games := range []int{0, ProvisionalGames} {
+			players := make([]Player, len(tt.players))
+			for i, player := range tt.players {
+				player.Games = games
+				players[i] = player
+			}
+
+			var sum float64
+			for i, rating := range Rate(players) {
+				sum += rating - players[i].Rating
+			}
+			if math.Abs(sum) > epsilon {
+				t.Errorf("%s, %d games: rating changes add up to %v, want 0", tt.name, games, sum)
+			}
+		}
+	}
+}
+
+func TestRateTies(t *testing.T) {
+	t.Run("equal ratings drawing keep their ratings", func(t *testing.T) {
+		got := Rate([]Player{
+			{ID: "a", Rating: 1500, Games: ProvisionalGames, Place: 1},
+			{ID: "b", Rating: 1500, Games: ProvisionalGames, Place: 1},
+		})
+		if got[0] != 1500 || got[1] != 1500 {
+			t.Fatalf("Rate() = %v, want both unchanged", got)
+		}
+	})
+
+	t.Run("a draw moves the favourite down", func(t *testing.T) {
+		got := Rate([]Player{
+			{ID: "a", Rating: 1700, Games: ProvisionalGames, Place: 1},
+			{ID: "b", Rating: 1300, Games: ProvisionalGames, Place: 1},
+		})
+		if got[0] >= 1700 || got[1] <= 1300 {
+			t.Fatalf("Rate() = %v, want the favourite down and the underdog up", got)
+		}
+	})
+
+	t.Run("tied players with equal ratings move alike", func(t *testing.T) {
+		got := Rate([]Player{
+			{ID: "a", Rating: 1500, Games: ProvisionalGames, Place: 1},
+			{ID: "b", Rating: 1500, Games: ProvisionalGames, Place: 1},
+			{ID: "c", Rating: 1500, Games: ProvisionalGames, Place: 3},
+		})
+		if math.Abs(got[0]-got[1]) > epsilon || got[0] <= 1500 || got[2] >= 1500 {
+			t.Fatalf("Rate() = %v, want a and b up alike and c down", got)
+		}
+	})
+}
+
+func TestRateSinglePlayer(t *testing.T) {
+	got := Rate([]Player{{ID: "a", Rating: 1640, Place: 1}})
+	if len(got) != 1 || got[0] != 1640 {
+		t.Fatalf("Rate() = %v, want [1640]", got)
+	}
+}
+
+func TestSoften(t *testing.T) {
+	tests := []struct {
+		rating float64
+		factor float64
+		want   float64
+	}{
+		{rating: 1800, factor: 0, want: Default},
+		{rating: 1800, factor: 1, want: 1800},
+		{rating: 1800, factor: 0.5, want: 1650},
+		{rating: 1300, factor: 0.5, want: 1400},
+	}
+
+	for _, tt := range tests {
+		if got := Soften(tt.rating, tt.factor); math.Abs(got-tt.want) > epsilon {
+			t.Errorf("Soften(%v, %v) = %v, want %v", tt.rating, tt.factor, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+// Package scoring derives player scores from the stored game state. Scores
+// are never trusted from the client: they are always recomputed from answers,
+// question points, the game's house rules, round rules, revealed final-round
+// wagers and audited host adjustments.
+package scoring
+
+import "mindwarp/types"
+
+// Round is the part of a round that affects scoring. NegativePoints is the
+// round's own rule already resolved against the game's rules.
+type Round struct {
+	ID             string
+	NegativePoints bool
+	// IsFinal rounds are scored from wagers only; board answers are ignored.
+	IsFinal bool
+}
+
+// Question is the part of a question that affects scoring.
+type Question struct {
+	ID      string
+	RoundID string
+	Points  int
+	Type    types.QuestionType
+}
+
+// Play records who plays a cat-in-the-bag or auction question. Only the
+// assignee's answer counts, and a non-nil Stake replaces the question's points.
+type Play struct {
+	QuestionID string
+	AssigneeID string
+	Stake      *int
+}
+
+// Answer is a judged answer. Unjudged answers (IsCorrect == nil) score nothing.
+// In team games ContributorID names the teammate who came up with the answer
+// the captain gave; it only affects Contributions. Answers must be passed in
+// the order they were given, which decides who stole a question. Answers given
+// after the question's timer ran out score nothing.
+type Answer struct {
+	QuestionID    string
+	UserID        string
+	ContributorID string
+	IsCorrect     *bool
+	TimeAnswered  *int
+	TimedOut      bool
+}
+
+// Adjustment is a manual score correction made by the host.
+type Adjustment struct {
+	UserID  string
+	RoundID string
+	Points  int
+}
+
+// Wager is a revealed final-round wager. A correct answer wins the wagered
+// amount and a wrong one loses it. Unrevealed wagers must not be passed in.
+type Wager struct {
+	UserID    string
+	RoundID   string
+	Amount    int
+	IsCorrect bool
+}
+
+// Input is everything needed to score a single game. In Independent games,
+// such as async ones, every player plays every question on their own: each
+// answer counts by itself, with no steals and no assigned questions.
+type Input struct {
+	Rules       types.GameRules
+	Independent bool
+	Rounds      []Round
+	Questions   []Question
+	Answers     []Answer
+	Adjustments []Adjustment
+	Wagers      []Wager
+	Plays       []Play
+}
+
+// Scores maps user id -> round id -> points, the same shape as
+// game_users.round_scores.
+type Scores map[string]map[string]int16
+
+func (s Scores) add(userID string, roundID string, points int) {
+	if _, ok := s[userID]; !ok {
+		s[userID] = make(map[string]int16)
+	}
+	s[userID][roundID] += int16(points)
+}
+
+// TotalExcept returns the sum of a user's round scores, leaving out one round.
+func (s Scores) TotalExcept(userID string, roundID string) int {
+	return s.Total(userID) - int(s[userID][roundID])
+}
+
+// Total returns the sum of a user's round scores.
+func (s Scores) Total(userID string) int {
+	total := 0
+	for _, points := range s[userID] {
+		total += int(points)
+	}
+	return total
+}
+
+// Compute scores every answer and adjustment in the input.
+func Compute(in Input) Scores {
+	return compute(in, func(answer Answer) string { return answer.UserID })
+}
+
+// Contributions is Compute with answer points credited to the contributing
+// teammate instead of the captain who gave the answer.
+func Contributions(in Input) Scores {
+	return compute(in, func(answer Answer) string {
+		if answer.ContributorID != "" {
+			return answer.ContributorID
+		}
+		return answer.UserID
+	})
+}
+
+// TeamScores sums the scores of every team member into the team's entry.
+// Players without a team are left out.
+func TeamScores(scores Scores, teamOf map[string]string) Scores {
+	teams := make(Scores)
+	for userID, rounds := range scores {
+		teamID, ok := teamOf[userID]
+		if !ok {
+			continue
+		}
+		for roundID, points := range rounds {
+			teams.add(teamID, roundID, int(points))
+		}
+	}
+	return teams
+}
+
+func compute(in Input, creditTo func(Answer) string) Scores {
+	scores := make(Scores)
+	eachAnswer(in, func(answer Answer, roundID string, points int) {
+		if points != 0 {
+			scores.add(creditTo(answer), roundID, points)
+		}
+	})
+
+	for _, wager := range in.Wagers {
+		if wager.IsCorrect {
+			scores.add(wager.UserID, wager.RoundID, wager.Amount)
+		} else {
+			scores.add(wager.UserID, wager.RoundID, -wager.Amount)
+		}
+	}
+
+	for _, adjustment := range in.Adjustments {
+		scores.add(adjustment.UserID, adjustment.RoundID, adjustment.Points)
+	}
+
+	return scores
+}
+
+// AnswerEffects returns what each player's answer to one question did to
+// their score. Answers that do not count, such as steals under rules that
+// forbid them, are left out.
+func AnswerEffects(in Input, questionID string) map[string]int {
+	effects := make(map[string]int)
+	eachAnswer(in, func(answer Answer, roundID string, points int) {
+		if answer.QuestionID == questionID {
+			effects[answer.UserID] = points
+		}
+	})
+	return effects
+}
+
+// eachAnswer calls fn with the points every counted, judged answer is worth.
+func eachAnswer(in Input, fn func(answer Answer, roundID string, points int)) {
+	rounds := make(map[string]Round, len(in.Rounds))
+	for _, round := range in.Rounds {
+		rounds[round.ID] = round
+	}
+
+	questions := make(map[string]Question, len(in.Questions))
+	for _, question := range in.Questions {
+		questions[question.ID] = question
+	}
+
+	plays := make(map[string]Play, len(in.Plays))
+	for _, play := range in.Plays {
+		plays[play.QuestionID] = play
+	}
+
+	answered := make(map[string]bool)
+	for _, answer := range in.Answers {
+		question, ok := questions[answer.QuestionID]
+		if !ok || rounds[question.RoundID].IsFinal {
+			continue
+		}
+
+		// Without steals only the first player to answer gets to play it
+		first := in.Independent || !answered[question.ID]
+		answered[question.ID] = true
+		if answer.IsCorrect == nil || (!first && !in.Rules.AllowSteal) {
+			continue
+		}
+
+		points := question.Points
+		if play, ok := plays[question.ID]; ok && !in.Independent {
+			if play.AssigneeID != answer.UserID {
+				continue
+			}
+			if play.Stake != nil {
+				points = *play.Stake
+			}
+		}
+
+		switch {
+		case answer.TimedOut:
+			fn(answer, question.RoundID, 0)
+		case *answer.IsCorrect:
+			fn(answer, question.RoundID, points+TimeBonus(in.Rules.TimeBonus, answer.TimeAnswered))
+		case question.Type == types.QuestionNoRisk:
+			fn(answer, question.RoundID, 0)
+		case rounds[question.RoundID].NegativePoints:
+			fn(answer, question.RoundID, -points)
+		default:
+			fn(answer, question.RoundID, 0)
+		}
+	}
+}
+
+// TimeBonus returns the extra points for a correct answer given after
+// timeAnswered seconds: the full bonus for an instant answer, falling linearly
+// to zero at the end of the window. Answers without a time get no bonus.
+func TimeBonus(bonus types.TimeBonus, timeAnswered *int) int {
+	if !bonus.Enabled || timeAnswered == nil || bonus.WindowSeconds <= 0 {
+		return 0
+	}
+	left := bonus.WindowSeconds - max(*timeAnswered, 0)
+	if left <= 0 {
+		return 0
+	}
+	return bonus.MaxPoints * left / bonus.WindowSeconds
+}
+
+// Equal reports whether two score maps hold the same non-zero values. A
+// missing round and a round scored at zero are treated as the same thing.
+func Equal(a Scores, b Scores) bool {
+	return covers(a, b) && covers(b, a)
+}
+
+func covers(a Scores, b Scores) bool {
+	for userID, rounds := range a {
+		for roundID, points := range rounds {
+			if points != 0 && b[userID][roundID] != points {
+				return false
+			}
+		}
+	}
+	return true
+}
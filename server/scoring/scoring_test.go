@@ -0,0 +1,199 @@
+package scoring
+
+import (
+	"mindwarp/types"
+	"testing"
+)
+
+func judged(correct bool) *bool {
+	return &correct
+}
+
+func seconds(n int) *int {
+	return &n
+}
+
+// board is a game with a round that takes points for wrong answers, one that
+// does not, and a final round.
+func board() Input {
+	return Input{
+		Rounds: []Round{
+			{ID: "r1", NegativePoints: true},
+			{ID: "r2"},
+			{ID: "final", IsFinal: true},
+		},
+		Questions: []Question{
+			{ID: "q1", RoundID: "r1", Points: 100, Type: types.QuestionStandard},
+			{ID: "q2", RoundID: "r2", Points: 200, Type: types.QuestionStandard},
+			{ID: "q3", RoundID: "r1", Points: 300, Type: types.QuestionNoRisk},
+			{ID: "q4", RoundID: "r1", Points: 400, Type: types.QuestionAuction},
+			{ID: "qf", RoundID: "final", Points: 0, Type: types.QuestionStandard},
+		},
+	}
+}
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name  string
+		apply func(in *Input)
+		want  Scores
+	}{
+		{
+			name: "correct answer scores the question's points",
+			apply: func(in *Input) {
+				in.Answers = []Answer{{QuestionID: "q1", UserID: "a", IsCorrect: judged(true)}}
+			},
+			want: Scores{"a": {"r1": 100}},
+		},
+		{
+			name: "wrong answer loses the points in a negative round",
+			apply: func(in *Input) {
+				in.Answers = []Answer{{QuestionID: "q1", UserID: "a", IsCorrect: judged(false)}}
+			},
+			want: Scores{"a": {"r1": -100}},
+		},
+		{
+			name: "wrong answer costs nothing without negative points",
+			apply: func(in *Input) {
+				in.Answers = []Answer{{QuestionID: "q2", UserID: "a", IsCorrect: judged(false)}}
+			},
+			want: Scores{},
+		},
+		{
+			name: "wrong answer to a no-risk question costs nothing",
+			apply: func(in *Input) {
+				in.Answers = []Answer{{QuestionID: "q3", UserID: "a", IsCorrect: judged(false)}}
+			},
+			want: Scores{},
+		},
+		{
+			name: "unjudged and timed-out answers score nothing",
+			apply: func(in *Input) {
+				in.Answers = []Answer{
+					{QuestionID: "q1", UserID: "a"},
+					{QuestionID: "q2", UserID: "a", IsCorrect: judged(true), TimedOut: true},
+				}
+			},
+			want: Scores{},
+		},
+		{
+			name: "steal after a miss",
+			apply: func(in *Input) {
+				in.Rules.AllowSteal = true
+				in.Answers = []Answer{
+					{QuestionID: "q1", UserID: "a", IsCorrect: judged(false)},
+					{QuestionID: "q1", UserID: "b", IsCorrect: judged(true)},
+				}
+			},
+			want: Scores{"a": {"r1": -100}, "b": {"r1": 100}},
+		},
+		{
+			name: "no steals without the rule",
+			apply: func(in *Input) {
+				in.Answers = []Answer{
+					{QuestionID: "q1", UserID: "a", IsCorrect: judged(false)},
+					{QuestionID: "q1", UserID: "b", IsCorrect: judged(true)},
+				}
+			},
+			want: Scores{"a": {"r1": -100}},
+		},
+		{
+			name: "auction counts only the winning bidder at their stake",
+			apply: func(in *Input) {
+				in.Rules.AllowSteal = true
+				stake := 700
+				in.Plays = []Play{{QuestionID: "q4", AssigneeID: "b", Stake: &stake}}
+				in.Answers = []Answer{
+					{QuestionID: "q4", UserID: "a", IsCorrect: judged(true)},
+					{QuestionID: "q4", UserID: "b", IsCorrect: judged(false)},
+				}
+			},
+			want: Scores{"b": {"r1": -700}},
+		},
+		{
+			name: "final round scores wagers only",
+			apply: func(in *Input) {
+				in.Answers = []Answer{{QuestionID: "qf", UserID: "a", IsCorrect: judged(true)}}
+				in.Wagers = []Wager{
+					{UserID: "a", RoundID: "final", Amount: 500, IsCorrect: true},
+					{UserID: "b", RoundID: "final", Amount: 300, IsCorrect: false},
+				}
+			},
+			want: Scores{"a": {"final": 500}, "b": {"final": -300}},
+		},
+		{
+			name: "host adjustments are added",
+			apply: func(in *Input) {
+				in.Answers = []Answer{{QuestionID: "q1", UserID: "a", IsCorrect: judged(true)}}
+				in.Adjustments = []Adjustment{
+					{UserID: "a", RoundID: "r1", Points: -150},
+					{UserID: "b", RoundID: "r2", Points: 50},
+				}
+			},
+			want: Scores{"a": {"r1": -50}, "b": {"r2": 50}},
+		},
+		{
+			name: "time bonus on correct answers",
+			apply: func(in *Input) {
+				in.Rules.TimeBonus = types.TimeBonus{Enabled: true, MaxPoints: 50, WindowSeconds: 10}
+				in.Answers = []Answer{
+					{QuestionID: "q1", UserID: "a", IsCorrect: judged(true), TimeAnswered: seconds(4)},
+					{QuestionID: "q2", UserID: "b", IsCorrect: judged(true), TimeAnswered: seconds(20)},
+				}
+			},
+			want: Scores{"a": {"r1": 130}, "b": {"r2": 200}},
+		},
+		{
+			name: "independent play scores every answer",
+			apply: func(in *Input) {
+				in.Independent = true
+				in.Plays = []Play{{QuestionID: "q4", AssigneeID: "b"}}
+				in.Answers = []Answer{
+					{QuestionID: "q1", UserID: "a", IsCorrect: judged(true)},
+					{QuestionID: "q1", UserID: "b", IsCorrect: judged(false)},
+					{QuestionID: "q4", UserID: "a", IsCorrect: judged(true)},
+				}
+			},
+			want: Scores{"a": {"r1": 500}, "b": {"r1": -100}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := board()
+			tt.apply(&in)
+			if got := Compute(in); !Equal(got, tt.want) {
+				t.Fatalf("Compute() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeBonus(t *testing.T) {
+	bonus := types.TimeBonus{Enabled: true, MaxPoints: 100, WindowSeconds: 20}
+
+	tests := []struct {
+		name         string
+		bonus        types.TimeBonus
+		timeAnswered *int
+		want         int
+	}{
+		{name: "disabled", bonus: types.TimeBonus{MaxPoints: 100, WindowSeconds: 20}, timeAnswered: seconds(0), want: 0},
+		{name: "no time", bonus: bonus, timeAnswered: nil, want: 0},
+		{name: "no window", bonus: types.TimeBonus{Enabled: true, MaxPoints: 100}, timeAnswered: seconds(0), want: 0},
+		{name: "instant", bonus: bonus, timeAnswered: seconds(0), want: 100},
+		{name: "negative time counts as instant", bonus: bonus, timeAnswered: seconds(-5), want: 100},
+		{name: "halfway", bonus: bonus, timeAnswered: seconds(10), want: 50},
+		{name: "rounds down", bonus: types.TimeBonus{Enabled: true, MaxPoints: 50, WindowSeconds: 20}, timeAnswered: seconds(7), want: 32},
+		{name: "end of window", bonus: bonus, timeAnswered: seconds(20), want: 0},
+		{name: "after the window", bonus: bonus, timeAnswered: seconds(45), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TimeBonus(tt.bonus, tt.timeAnswered); got != tt.want {
+				t.Fatalf("TimeBonus() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package scoring
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// TieBreaker names a rule used to order players with equal scores.
+type TieBreaker string
+
+const (
+	// TieBreakCorrectAnswers favours the player with more correct answers.
+	TieBreakCorrectAnswers TieBreaker = "correct_answers"
+	// TieBreakAvgTime favours the player with the lower average time_answered.
+	TieBreakAvgTime TieBreaker = "avg_time_answered"
+	// TieBreakSuddenDeath asks the host to play a sudden-death question
+	// between the players still tied for first place.
+	TieBreakSuddenDeath TieBreaker = "sudden_death"
+)
+
+// DefaultTieBreakers is used when a game does not configure its own.
+var DefaultTieBreakers = []TieBreaker{TieBreakCorrectAnswers, TieBreakAvgTime}
+
+// ErrSuddenDeathRequired is returned by Rank when players are tied for first
+// place, the game uses a sudden-death tie-breaker and no winner was supplied.
+var ErrSuddenDeathRequired = errors.New("players are tied for first place, a sudden-death question is required")
+
+// ErrInvalidSuddenDeathWinner is returned by Rank when the supplied winner is
+// not one of the players tied for first place.
+var ErrInvalidSuddenDeathWinner = errors.New("sudden-death winner is not tied for first place")
+
+// ValidateTieBreakers checks that every name is known and used once.
+func ValidateTieBreakers(tieBreakers []TieBreaker) error {
+	seen := make(map[TieBreaker]bool, len(tieBreakers))
+	for _, tieBreaker := range tieBreakers {
+		switch tieBreaker {
+		case TieBreakCorrectAnswers, TieBreakAvgTime, TieBreakSuddenDeath:
+		default:
+			return fmt.Errorf("unknown tie-breaker %q", tieBreaker)
+		}
+		if seen[tieBreaker] {
+			return fmt.Errorf("tie-breaker %q is listed twice", tieBreaker)
+		}
+		seen[tieBreaker] = true
+	}
+	return nil
+}
+
+// PlayerResult is a player's final tally, the input for ranking.
+type PlayerResult struct {
+	UserID          string
+	Score           int
+	CorrectAnswers  int
+	AvgTimeAnswered *float64
+}
+
+// Standing is a player's final position. Players that cannot be separated
+// share a place and the next place is skipped (1, 1, 3).
+type Standing struct {
+	PlayerResult
+	Place          int
+	WonSuddenDeath bool
+}
+
+// Rank orders players by score and then by the tie-breakers in order.
+// suddenDeathWinner is only consulted when the tie-breakers include
+// TieBreakSuddenDeath and several players share first place; it must be one
+// of them. The returned tied slice lists the players sharing first place when
+// ErrSuddenDeathRequired is returned.
+func Rank(players []PlayerResult, tieBreakers []TieBreaker, suddenDeathWinner string) (standings []Standing, tied []string, err error) {
+	standings = make([]Standing, len(players))
+	for i, player := range players {
+		standings[i] = Standing{PlayerResult: player}
+	}
+
+	compare := func(a, b PlayerResult) int {
+		if a.Score != b.Score {
+			return b.Score - a.Score
+		}
+		for _, tieBreaker := range tieBreakers {
+			switch tieBreaker {
+			case TieBreakCorrectAnswers:
+				if a.CorrectAnswers != b.CorrectAnswers {
+					return b.CorrectAnswers - a.CorrectAnswers
+				}
+			case TieBreakAvgTime:
+				if c := compareAvgTime(a.AvgTimeAnswered, b.AvgTimeAnswered); c != 0 {
+					return c
+				}
+			}
+		}
+		return 0
+	}
+
+	sort.SliceStable(standings, func(i, j int) bool {
+		if c := compare(standings[i].PlayerResult, standings[j].PlayerResult); c != 0 {
+			return c < 0
+		}
+		return standings[i].UserID < standings[j].UserID
+	})
+
+	for i := range standings {
+		if i > 0 && compare(standings[i-1].PlayerResult, standings[i].PlayerResult) == 0 {
+			standings[i].Place = standings[i-1].Place
+		} else {
+			standings[i].Place = i + 1
+		}
+	}
+
+	for _, standing := range standings {
+		if standing.Place == 1 {
+			tied = append(tied, standing.UserID)
+		}
+	}
+
+	if len(tied) < 2 || !hasTieBreaker(tieBreakers, TieBreakSuddenDeath) {
+		return standings, nil, nil
+	}
+
+	if suddenDeathWinner == "" {
+		return nil, tied, ErrSuddenDeathRequired
+	}
+
+	winner := -1
+	for i, standing := range standings {
+		if standing.Place == 1 && standing.UserID == suddenDeathWinner {
+			winner = i
+		}
+	}
+	if winner < 0 {
+		return nil, tied, ErrInvalidSuddenDeathWinner
+	}
+
+	// Move the sudden-death winner to the top; the other tied players drop to
+	// a shared second place.
+	standings[0], standings[winner] = standings[winner], standings[0]
+	standings[0].WonSuddenDeath = true
+	for i := 1; i < len(tied); i++ {
+		standings[i].Place = 2
+	}
+
+	return standings, nil, nil
+}
+
+// Winner returns the only player in first place, or "" if first is shared.
+func Winner(standings []Standing) string {
+	if len(standings) == 0 || (len(standings) > 1 && standings[1].Place == 1) {
+		return ""
+	}
+	return standings[0].UserID
+}
+
+// compareAvgTime orders lower averages first; players without any timed
+// answers come last.
+func compareAvgTime(a, b *float64) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	case *a < *b:
+		return -1
+	case *a > *b:
+		return 1
+	}
+	return 0
+}
+
+func hasTieBreaker(tieBreakers []TieBreaker, tieBreaker TieBreaker) bool {
+	for _, t := range tieBreakers {
+		if t == tieBreaker {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,234 @@
+package scoring
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func avg(seconds float64) *float64 {
+	return &seconds
+}
+
+func order(standings []Standing) ([]string, []int) {
+	ids := make([]string, len(standings))
+	places := make([]int, len(standings))
+	for i, standing := range standings {
+		ids[i] = standing.UserID
+		places[i] = standing.Place
+	}
+	return ids, places
+}
+
+func TestRank(t *testing.T) {
+	tests := []struct {
+		name        string
+		players     []PlayerResult
+		tieBreakers []TieBreaker
+		wantOrder   []string
+		wantPlaces  []int
+		wantWinner  string
+	}{
+		{
+			name: "score decides",
+			players: []PlayerResult{
+				{UserID: "a", Score: 100},
+				{UserID: "b", Score: 300},
+				{UserID: "c", Score: 200},
+			},
+			tieBreakers: DefaultTieBreakers,
+			wantOrder:   []string{"b", "c", "a"},
+			wantPlaces:  []int{1, 2, 3},
+			wantWinner:  "b",
+		},
+		{
+			name: "negative scores rank below zero",
+			players: []PlayerResult{
+				{UserID: "a", Score: -200},
+				{UserID: "b", Score: 0},
+				{UserID: "c", Score: -100},
+			},
+			tieBreakers: DefaultTieBreakers,
+			wantOrder:   []string{"b", "c", "a"},
+			wantPlaces:  []int{1, 2, 3},
+			wantWinner:  "b",
+		},
+		{
+			name: "correct answers break a tie",
+			players: []PlayerResult{
+				{UserID: "a", Score: 500, CorrectAnswers: 3, AvgTimeAnswered: avg(2)},
+				{UserID: "b", Score: 500, CorrectAnswers: 4, AvgTimeAnswered: avg(9)},
+			},
+			tieBreakers: DefaultTieBreakers,
+			wantOrder:   []string{"b", "a"},
+			wantPlaces:  []int{1, 2},
+			wantWinner:  "b",
+		},
+		{
+			name: "tie-breakers apply in the order given",
+			players: []PlayerResult{
+				{UserID: "a", Score: 500, CorrectAnswers: 3, AvgTimeAnswered: avg(2)},
+				{UserID: "b", Score: 500, CorrectAnswers: 4, AvgTimeAnswered: avg(9)},
+			},
+			tieBreakers: []TieBreaker{TieBreakAvgTime, TieBreakCorrectAnswers},
+			wantOrder:   []string{"a", "b"},
+			wantPlaces:  []int{1, 2},
+			wantWinner:  "a",
+		},
+		{
+			name: "players without timed answers come last on average time",
+			players: []PlayerResult{
+				{UserID: "a", Score: 500},
+				{UserID: "b", Score: 500, AvgTimeAnswered: avg(30)},
+			},
+			tieBreakers: []TieBreaker{TieBreakAvgTime},
+			wantOrder:   []string{"b", "a"},
+			wantPlaces:  []int{1, 2},
+			wantWinner:  "b",
+		},
+		{
+			name: "an unbroken tie shares first place and skips second",
+			players: []PlayerResult{
+				{UserID: "c", Score: 100},
+				{UserID: "b", Score: 500, CorrectAnswers: 2},
+				{UserID: "a", Score: 500, CorrectAnswers: 2},
+			},
+			tieBreakers: DefaultTieBreakers,
+			wantOrder:   []string{"a", "b", "c"},
+			wantPlaces:  []int{1, 1, 3},
+			wantWinner:  "",
+		},
+		{
+			name: "without tie-breakers equal scores share a place",
+			players: []PlayerResult{
+				{UserID: "a", Score: 500},
+				{UserID: "b", Score: 300, CorrectAnswers: 1},
+				{UserID: "c", Score: 300, CorrectAnswers: 5},
+			},
+			tieBreakers: nil,
+			wantOrder:   []string{"a", "b", "c"},
+			wantPlaces:  []int{1, 2, 2},
+			wantWinner:  "a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standings, tied, err := Rank(tt.players, tt.tieBreakers, "")
+			if err != nil {
+				t.Fatalf("Rank() error = %v, tied %v", err, tied)
+			}
+			gotOrder, gotPlaces := order(standings)
+			if !slices.Equal(gotOrder, tt.wantOrder) || !slices.Equal(gotPlaces, tt.wantPlaces) {
+				t.Fatalf("Rank() = %v at places %v, want %v at places %v", gotOrder, gotPlaces, tt.wantOrder, tt.wantPlaces)
+			}
+			if got := Winner(standings); got != tt.wantWinner {
+				t.Errorf("Winner() = %q, want %q", got, tt.wantWinner)
+			}
+		})
+	}
+}
+
+func TestRankSuddenDeath(t *testing.T) {
+	players := []PlayerResult{
+		{UserID: "a", Score: 500, CorrectAnswers: 2},
+		{UserID: "b", Score: 500, CorrectAnswers: 2},
+		{UserID: "c", Score: 100},
+	}
+	tieBreakers := []TieBreaker{TieBreakCorrectAnswers, TieBreakSuddenDeath}
+
+	tests := []struct {
+		name       string
+		players    []PlayerResult
+		winner     string
+		wantErr    error
+		wantTied   []string
+		wantOrder  []string
+		wantPlaces []int
+	}{
+		{
+			name:     "required when first place is shared",
+			players:  players,
+			wantErr:  ErrSuddenDeathRequired,
+			wantTied: []string{"a", "b"},
+		},
+		{
+			name:       "supplied winner takes first place alone",
+			players:    players,
+			winner:     "b",
+			wantOrder:  []string{"b", "a", "c"},
+			wantPlaces: []int{1, 2, 3},
+		},
+		{
+			name:     "winner must be tied for first",
+			players:  players,
+			winner:   "c",
+			wantErr:  ErrInvalidSuddenDeathWinner,
+			wantTied: []string{"a", "b"},
+		},
+		{
+			name: "not needed without a tie for first",
+			players: []PlayerResult{
+				{UserID: "a", Score: 500},
+				{UserID: "b", Score: 100},
+				{UserID: "c", Score: 100},
+			},
+			wantOrder:  []string{"a", "b", "c"},
+			wantPlaces: []int{1, 2, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standings, tied, err := Rank(tt.players, tieBreakers, tt.winner)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Rank() error = %v, want %v", err, tt.wantErr)
+			}
+			if !slices.Equal(tied, tt.wantTied) {
+				t.Errorf("Rank() tied = %v, want %v", tied, tt.wantTied)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			gotOrder, gotPlaces := order(standings)
+			if !slices.Equal(gotOrder, tt.wantOrder) || !slices.Equal(gotPlaces, tt.wantPlaces) {
+				t.Fatalf("Rank() = %v at places %v, want %v at places %v", gotOrder, gotPlaces, tt.wantOrder, tt.wantPlaces)
+			}
+			if tt.winner != "" && !standings[0].WonSuddenDeath {
+				t.Errorf("Rank() did not mark %q as the sudden-death winner", tt.winner)
+			}
+			if got := Winner(standings); got != tt.wantOrder[0] {
+				t.Errorf("Winner() = %q, want %q", got, tt.wantOrder[0])
+			}
+		})
+	}
+}
+
+func TestWinnerWithoutPlayers(t *testing.T) {
+	if got := Winner(nil); got != "" {
+		t.Fatalf("Winner(nil) = %q, want none", got)
+	}
+}
+
+func TestValidateTieBreakers(t *testing.T) {
+	tests := []struct {
+		name        string
+		tieBreakers []TieBreaker
+		wantErr     bool
+	}{
+		{name: "defaults", tieBreakers: DefaultTieBreakers},
+		{name: "none", tieBreakers: nil},
+		{name: "all", tieBreakers: []TieBreaker{TieBreakSuddenDeath, TieBreakAvgTime, TieBreakCorrectAnswers}},
+		{name: "unknown", tieBreakers: []TieBreaker{"coin_flip"}, wantErr: true},
+		{name: "repeated", tieBreakers: []TieBreaker{TieBreakAvgTime, TieBreakAvgTime}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateTieBreakers(tt.tieBreakers); (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateTieBreakers(%v) error = %v, want error %v", tt.tieBreakers, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package scoring
+
+import "mindwarp/types"
+
+// NextPicker decides who chooses the next question once the current one has
+// been played. players lists the game's players in turn order and answers are
+// the answers to the question just played, in the order they were given.
+// Under types.PickerHost the picker is left to the host and current is
+// returned unchanged.
+func NextPicker(rules types.GameRules, players []string, current string, answers []Answer) string {
+	switch rules.NextPicker {
+	case types.PickerRoundRobin:
+		if len(players) == 0 {
+			return current
+		}
+		for i, player := range players {
+			if player == current {
+				return players[(i+1)%len(players)]
+			}
+		}
+		return players[0]
+	case types.PickerLastCorrect:
+		for i, answer := range answers {
+			if i > 0 && !rules.AllowSteal {
+				break
+			}
+			if answer.IsCorrect != nil && *answer.IsCorrect {
+				return answer.UserID
+			}
+		}
+		return current
+	default:
+		return current
+	}
+}
@@ -0,0 +1,110 @@
+package scoring
+
+import (
+	"mindwarp/types"
+	"testing"
+)
+
+func TestNextPicker(t *testing.T) {
+	players := []string{"a", "b", "c"}
+	missThenSteal := []Answer{
+		{QuestionID: "q1", UserID: "b", IsCorrect: judged(false)},
+		{QuestionID: "q1", UserID: "c", IsCorrect: judged(true)},
+	}
+
+	tests := []struct {
+		name    string
+		rules   types.GameRules
+		players []string
+		current string
+		answers []Answer
+		want    string
+	}{
+		{
+			name:    "round robin passes to the next player",
+			rules:   types.GameRules{NextPicker: types.PickerRoundRobin},
+			players: players,
+			current: "a",
+			want:    "b",
+		},
+		{
+			name:    "round robin wraps around",
+			rules:   types.GameRules{NextPicker: types.PickerRoundRobin},
+			players: players,
+			current: "c",
+			want:    "a",
+		},
+		{
+			name:    "round robin ignores who answered",
+			rules:   types.GameRules{NextPicker: types.PickerRoundRobin, AllowSteal: true},
+			players: players,
+			current: "a",
+			answers: missThenSteal,
+			want:    "b",
+		},
+		{
+			name:    "round robin starts over when the picker left",
+			rules:   types.GameRules{NextPicker: types.PickerRoundRobin},
+			players: players,
+			current: "z",
+			want:    "a",
+		},
+		{
+			name:    "round robin without players keeps the picker",
+			rules:   types.GameRules{NextPicker: types.PickerRoundRobin},
+			current: "a",
+			want:    "a",
+		},
+		{
+			name:    "last correct answer picks next",
+			rules:   types.GameRules{NextPicker: types.PickerLastCorrect},
+			players: players,
+			current: "a",
+			answers: []Answer{{QuestionID: "q1", UserID: "b", IsCorrect: judged(true)}},
+			want:    "b",
+		},
+		{
+			name:    "a steal picks next when steals are allowed",
+			rules:   types.GameRules{NextPicker: types.PickerLastCorrect, AllowSteal: true},
+			players: players,
+			current: "a",
+			answers: missThenSteal,
+			want:    "c",
+		},
+		{
+			name:    "a steal does not count without the rule",
+			rules:   types.GameRules{NextPicker: types.PickerLastCorrect},
+			players: players,
+			current: "a",
+			answers: missThenSteal,
+			want:    "a",
+		},
+		{
+			name:    "nobody correct keeps the picker",
+			rules:   types.GameRules{NextPicker: types.PickerLastCorrect, AllowSteal: true},
+			players: players,
+			current: "a",
+			answers: []Answer{
+				{QuestionID: "q1", UserID: "b", IsCorrect: judged(false)},
+				{QuestionID: "q1", UserID: "c"},
+			},
+			want: "a",
+		},
+		{
+			name:    "the host picks",
+			rules:   types.GameRules{NextPicker: types.PickerHost, AllowSteal: true},
+			players: players,
+			current: "a",
+			answers: missThenSteal,
+			want:    "a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NextPicker(tt.rules, tt.players, tt.current, tt.answers); got != tt.want {
+				t.Fatalf("NextPicker() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// Package srs schedules study cards with the SM-2 spaced-repetition algorithm.
+package srs
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+const (
+	// DefaultEase is the ease factor a new card starts with.
+	DefaultEase = 2.5
+	// MinEase keeps hard cards from being shown ever more often.
+	MinEase = 1.3
+	// MaxQuality is a perfect recall; PassQuality is the lowest passing one.
+	MaxQuality  = 5
+	PassQuality = 3
+)
+
+// ErrInvalidQuality is returned by Review for a grade outside 0..MaxQuality.
+var ErrInvalidQuality = errors.New("quality must be between 0 and 5")
+
+// Card is the schedule of one question for one player.
+type Card struct {
+	Ease         float64
+	IntervalDays int
+	Repetitions  int
+	Due          time.Time
+}
+
+// New returns the schedule of a question missed at missedAt. It is due at
+// once.
+func New(missedAt time.Time) Card {
+	return Card{Ease: DefaultEase, Due: missedAt}
+}
+
+// Review grades a recall of card at now, from 0 (blackout) to 5 (perfect),
+// and returns its next schedule. A failed recall starts the card over with a
+// one day interval; a passing one moves it to 1 day, then 6, then the previous
+// interval times the ease factor.
+func Review(card Card, quality int, now time.Time) (Card, error) {
+	if quality < 0 || quality > MaxQuality {
+		return card, ErrInvalidQuality
+	}
+	if card.Ease == 0 {
+		card.Ease = DefaultEase
+	}
+
+	if quality < PassQuality {
+		card.Repetitions = 0
+		card.IntervalDays = 1
+	} else {
+		switch card.Repetitions {
+		case 0:
+			card.IntervalDays = 1
+		case 1:
+			card.IntervalDays = 6
+		default:
+			card.IntervalDays = int(math.Round(float64(card.IntervalDays) * card.Ease))
+		}
+		card.Repetitions++
+	}
+
+	miss := float64(MaxQuality - quality)
+	card.Ease = max(MinEase, card.Ease+0.1-miss*(0.08+miss*0.02))
+	card.Due = now.AddDate(0, 0, card.IntervalDays)
+	return card, nil
+}
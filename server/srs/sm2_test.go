@@ -0,0 +1,109 @@
+package srs
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestReviewSchedule(t *testing.T) {
+	start := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		qualities []int
+		wantDays  []int
+		wantEase  float64
+	}{
+		{
+			name:      "perfect recalls grow by the ease",
+			qualities: []int{5, 5, 5, 5},
+			wantDays:  []int{1, 6, 16, 45},
+			wantEase:  2.9,
+		},
+		{
+			name:      "a correct but hard recall keeps the interval growing",
+			qualities: []int{3, 3, 3},
+			wantDays:  []int{1, 6, 13},
+			wantEase:  2.08,
+		},
+		{
+			name:      "a failed recall starts the card over",
+			qualities: []int{5, 5, 5, 2, 4},
+			wantDays:  []int{1, 6, 16, 1, 1},
+			wantEase:  2.48,
+		},
+		{
+			name:      "blackouts stop at the minimum ease",
+			qualities: []int{0, 0, 0, 0, 0},
+			wantDays:  []int{1, 1, 1, 1, 1},
+			wantEase:  MinEase,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			card := New(start)
+			now := start
+			for i, quality := range tt.qualities {
+				var err error
+				card, err = Review(card, quality, now)
+				if err != nil {
+					t.Fatalf("Review(%d) error = %v", quality, err)
+				}
+				if card.IntervalDays != tt.wantDays[i] {
+					t.Fatalf("review %d: interval = %d days, want %d", i+1, card.IntervalDays, tt.wantDays[i])
+				}
+				if want := now.AddDate(0, 0, tt.wantDays[i]); !card.Due.Equal(want) {
+					t.Fatalf("review %d: due = %v, want %v", i+1, card.Due, want)
+				}
+				now = card.Due
+			}
+			if math.Abs(card.Ease-tt.wantEase) > 1e-9 {
+				t.Errorf("ease = %v, want %v", card.Ease, tt.wantEase)
+			}
+		})
+	}
+}
+
+func TestReviewCountsRepetitions(t *testing.T) {
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	card := New(now)
+	for _, quality := range []int{4, 5, 3} {
+		card, _ = Review(card, quality, now)
+	}
+	if card.Repetitions != 3 {
+		t.Fatalf("Repetitions = %d after three passes, want 3", card.Repetitions)
+	}
+
+	card, _ = Review(card, 1, now)
+	if card.Repetitions != 0 {
+		t.Fatalf("Repetitions = %d after a failed recall, want 0", card.Repetitions)
+	}
+}
+
+func TestReviewZeroEaseUsesDefault(t *testing.T) {
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	card, err := Review(Card{}, 4, now)
+	if err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+	if card.Ease != DefaultEase {
+		t.Fatalf("ease = %v, want %v", card.Ease, DefaultEase)
+	}
+}
+
+func TestReviewRejectsInvalidQuality(t *testing.T) {
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	card := New(now)
+	for _, quality := range []int{-1, MaxQuality + 1} {
+		got, err := Review(card, quality, now)
+		if !errors.Is(err, ErrInvalidQuality) {
+			t.Errorf("Review(%d) error = %v, want %v", quality, err, ErrInvalidQuality)
+		}
+		if got != card {
+			t.Errorf("Review(%d) changed the card to %+v", quality, got)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+// Package tournament generates tournament brackets and ranks their players.
+package tournament
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Format is how a tournament pairs its players.
+type Format string
+
+const (
+	SingleElimination Format = "single_elimination"
+	DoubleElimination Format = "double_elimination"
+	RoundRobin        Format = "round_robin"
+)
+
+// Bracket names the part of a tournament a match belongs to.
+type Bracket string
+
+const (
+	BracketWinners    Bracket = "winners"
+	BracketLosers     Bracket = "losers"
+	BracketFinal      Bracket = "final"
+	BracketRoundRobin Bracket = "round_robin"
+)
+
+// MinPlayers is the smallest field any format can be played with.
+const MinPlayers = 2
+
+var (
+	ErrUnknownFormat  = errors.New("unknown tournament format")
+	ErrTooFewPlayers  = fmt.Errorf("a tournament needs at least %d players", MinPlayers)
+	ErrTooManyPlayers = errors.New("too many players for one tournament")
+)
+
+// MaxPlayers caps the field so brackets stay a manageable size.
+const MaxPlayers = 256
+
+// Link sends the winner or loser of a match to a slot of a later match.
+// Match indexes the slice returned by Generate; Slot is 0 or 1.
+type Link struct {
+	Match int
+	Slot  int
+}
+
+// Match is one pairing of a generated bracket.
+type Match struct {
+	Bracket  Bracket
+	Round    int
+	Position int
+	// Stage orders the matches in which players can be knocked out; players
+	// knocked out at the same stage share a place.
+	Stage int
+	// Seeds holds the 1-based seeds placed in the match when the bracket is
+	// generated. A slot with seed 0 is fed by an earlier match, or is a bye
+	// when Fed is false.
+	Seeds [2]int
+	Fed   [2]bool
+	// WinnerTo and LoserTo route the players onward; nil ends their run in
+	// this match.
+	WinnerTo *Link
+	LoserTo  *Link
+	// Conditional marks a grand-final reset, played only when the player
+	// coming from the losers' bracket wins the first grand final.
+	Conditional bool
+}
+
+// MaxLosses is how many matches a player may lose before being knocked out.
+// Round-robin players are never knocked out.
+func (f Format) MaxLosses() int {
+	switch f {
+	case SingleElimination:
+		return 1
+	case DoubleElimination:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// Valid tells whether f is a known format.
+func (f Format) Valid() bool {
+	switch f {
+	case SingleElimination, DoubleElimination, RoundRobin:
+		return true
+	}
+	return false
+}
+
+// Generate lays out the matches of a tournament of players seeded 1 to
+// players.
+func Generate(format Format, players int) ([]Match, error) {
+	if !format.Valid() {
+		return nil, ErrUnknownFormat
+	}
+	if players < MinPlayers {
+		return nil, ErrTooFewPlayers
+	}
+	if players > MaxPlayers {
+		return nil, ErrTooManyPlayers
+	}
+
+	switch format {
+	case SingleElimination:
+		matches, _ := elimination(players)
+		return matches, nil
+	case DoubleElimination:
+		return doubleElimination(players), nil
+	default:
+		return roundRobin(players), nil
+	}
+}
+
+// bracketSize is the smallest power of two that fits players.
+func bracketSize(players int) int {
+	size := 1
+	for size < players {
+		size *= 2
+	}
+	return size
+}
+
+// seedOrder lists the seeds of a bracket of size slots top to bottom, so the
+// first round pairs 1 with size, 2 with size-1 and so on, and the top two
+// seeds can only meet in the final.
+func seedOrder(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		next := make([]int, 0, len(order)*2)
+		for _, seed := range order {
+			next = append(next, seed, len(order)*2+1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+// link routes the winner or loser of from into slot of to.
+func link(matches []Match, to int, slot int) *Link {
+	matches[to].Fed[slot] = true
+	return &Link{Match: to, Slot: slot}
+}
+
+// elimination lays out a winners' bracket and returns its matches with the
+// index of the first match of every round. Seeds beyond players are byes.
+func elimination(players int) ([]Match, [][]int) {
+	size := bracketSize(players)
+	order := seedOrder(size)
+
+	var (
+		matches []Match
+		rounds  [][]int
+	)
+	for round, count := 1, size/2; count >= 1; round, count = round+1, count/2 {
+		indexes := make([]int, count)
+		for position := range count {
+			match := Match{Bracket: BracketWinners, Round: round, Position: position, Stage: round}
+			if round == 1 {
+				for slot := range 2 {
+					if seed := order[position*2+slot]; seed <= players {
+						match.Seeds[slot] = seed
+					}
+				}
+			}
+			indexes[position] = len(matches)
+			matches = append(matches, match)
+		}
+		rounds = append(rounds, indexes)
+	}
+
+	for round := 1; round < len(rounds); round++ {
+		for position, index := range rounds[round-1] {
+			matches[index].WinnerTo = link(matches, rounds[round][position/2], position%2)
+		}
+	}
+	return matches, rounds
+}
+
+// doubleElimination adds a losers' bracket and a grand final to a winners'
+// bracket. Losers of winners' round r+1 drop into losers' round 2r, in
+// reverse order every other round to put off rematches. The grand final is
+// followed by a conditional reset.
+func doubleElimination(players int) []Match {
+	matches, winners := elimination(players)
+	rounds := len(winners)
+
+	add := func(match Match) int {
+		matches = append(matches, match)
+		return len(matches) - 1
+	}
+
+	// The champion of the losers' bracket, or the loser of the winners'
+	// final when there are only two players, meets the winners' champion.
+	var (
+		losersChampion = -1
+		losers         []int
+	)
+	for round := 1; round <= 2*(rounds-1); round++ {
+		var count int
+		if round == 1 {
+			count = len(winners[0]) / 2
+		} else {
+			count = len(losers)
+			if round%2 == 1 {
+				count /= 2
+			}
+		}
+
+		indexes := make([]int, count)
+		for position := range count {
+			indexes[position] = add(Match{Bracket: BracketLosers, Round: round, Position: position, Stage: round})
+		}
+
+		switch {
+		case round == 1:
+			for position, index := range winners[0] {
+				matches[index].LoserTo = link(matches, indexes[position/2], position%2)
+			}
+		case round%2 == 0:
+			for position, index := range losers {
+				matches[index].WinnerTo = link(matches, indexes[position], 0)
+			}
+			dropping := winners[round/2]
+			for position, index := range dropping {
+				target := position
+				if (round/2)%2 == 0 {
+					target = len(dropping) - 1 - position
+				}
+				matches[index].LoserTo = link(matches, indexes[target], 1)
+			}
+		default:
+			for position, index := range losers {
+				matches[index].WinnerTo = link(matches, indexes[position/2], position%2)
+			}
+		}
+		losers = indexes
+	}
+	if len(losers) == 1 {
+		losersChampion = losers[0]
+	}
+
+	stage := 2*(rounds-1) + 1
+	final := add(Match{Bracket: BracketFinal, Round: 1, Stage: stage})
+	reset := add(Match{Bracket: BracketFinal, Round: 2, Stage: stage + 1, Conditional: true})
+
+	winnersFinal := winners[rounds-1][0]
+	matches[winnersFinal].WinnerTo = link(matches, final, 0)
+	if losersChampion >= 0 {
+		matches[losersChampion].WinnerTo = link(matches, final, 1)
+	} else {
+		matches[winnersFinal].LoserTo = link(matches, final, 1)
+	}
+	matches[final].WinnerTo = link(matches, reset, 0)
+	matches[final].LoserTo = link(matches, reset, 1)
+
+	return matches
+}
+
+// roundRobin pairs every player with every other once using the circle
+// method. With an odd field one player sits out each round.
+func roundRobin(players int) []Match {
+	seeds := make([]int, 0, players+1)
+	for seed := 1; seed <= players; seed++ {
+		seeds = append(seeds, seed)
+	}
+	if len(seeds)%2 == 1 {
+		seeds = append(seeds, 0)
+	}
+	n := len(seeds)
+
+	var matches []Match
+	for round := 1; round < n; round++ {
+		position := 0
+		for i := range n / 2 {
+			home, away := seeds[i], seeds[n-1-i]
+			if home == 0 || away == 0 {
+				continue
+			}
+			matches = append(matches, Match{
+				Bracket:  BracketRoundRobin,
+				Round:    round,
+				Position: position,
+				Stage:    round,
+				Seeds:    [2]int{home, away},
+			})
+			position++
+		}
+		// Keep the first seed in place and rotate the rest by one.
+		last := seeds[n-1]
+		copy(seeds[2:], seeds[1:n-1])
+		seeds[1] = last
+	}
+	return matches
+}
@@ -0,0 +1,292 @@
+package tournament
+
+import (
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+// fieldSizes are mostly not powers of two, so the brackets need byes.
+var fieldSizes = []int{2, 3, 5, 6, 7, 8, 11, 12, 13, 24, 33}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		players int
+		want    error
+	}{
+		{name: "unknown format", format: "swiss", players: 8, want: ErrUnknownFormat},
+		{name: "too few players", format: SingleElimination, players: 1, want: ErrTooFewPlayers},
+		{name: "too many players", format: DoubleElimination, players: MaxPlayers + 1, want: ErrTooManyPlayers},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Generate(tt.format, tt.players); !errors.Is(err, tt.want) {
+				t.Fatalf("Generate() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeedOrder(t *testing.T) {
+	tests := []struct {
+		size int
+		want []int
+	}{
+		{size: 2, want: []int{1, 2}},
+		{size: 4, want: []int{1, 4, 2, 3}},
+		{size: 8, want: []int{1, 8, 4, 5, 2, 7, 3, 6}},
+	}
+
+	for _, tt := range tests {
+		if got := seedOrder(tt.size); !slices.Equal(got, tt.want) {
+			t.Errorf("seedOrder(%d) = %v, want %v", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateSingleEliminationByes(t *testing.T) {
+	tests := []struct {
+		players int
+		want    [][2]int
+	}{
+		{players: 3, want: [][2]int{{1, 0}, {2, 3}}},
+		{players: 5, want: [][2]int{{1, 0}, {4, 5}, {2, 0}, {3, 0}}},
+		{players: 6, want: [][2]int{{1, 0}, {4, 5}, {2, 0}, {3, 6}}},
+	}
+
+	for _, tt := range tests {
+		matches, err := Generate(SingleElimination, tt.players)
+		if err != nil {
+			t.Fatalf("Generate(%d) error = %v", tt.players, err)
+		}
+		var got [][2]int
+		for _, match := range matches {
+			if match.Round == 1 {
+				got = append(got, match.Seeds)
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Generate(%d) first round = %v, want %v", tt.players, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateEliminationShape(t *testing.T) {
+	for _, format := range []Format{SingleElimination, DoubleElimination} {
+		for _, players := range fieldSizes {
+			matches, err := Generate(format, players)
+			if err != nil {
+				t.Fatalf("Generate(%s, %d) error = %v", format, players, err)
+			}
+
+			size := bracketSize(players)
+			wantMatches := size - 1
+			if format == DoubleElimination {
+				wantMatches = 2*size - 1
+			}
+			if len(matches) != wantMatches {
+				t.Errorf("Generate(%s, %d) made %d matches, want %d", format, players, len(matches), wantMatches)
+			}
+
+			// Every seed is placed once, in the first round, and no
+			// first-round match is two byes
+			var seeds []int
+			for i, match := range matches {
+				if match.Seeds != [2]int{} && (match.Bracket != BracketWinners || match.Round != 1) {
+					t.Errorf("Generate(%s, %d) seeds match %d outside the first round", format, players, i)
+				}
+				if match.Bracket == BracketWinners && match.Round == 1 && match.Seeds == [2]int{} {
+					t.Errorf("Generate(%s, %d) match %d is two byes", format, players, i)
+				}
+				for _, seed := range match.Seeds {
+					if seed != 0 {
+						seeds = append(seeds, seed)
+					}
+				}
+			}
+			slices.Sort(seeds)
+			for i, seed := range seeds {
+				if seed != i+1 || len(seeds) != players {
+					t.Fatalf("Generate(%s, %d) placed seeds %v", format, players, seeds)
+				}
+			}
+
+			// Links only point forward and feed every fed slot exactly once
+			fed := make(map[Link]int)
+			for i, match := range matches {
+				for _, to := range []*Link{match.WinnerTo, match.LoserTo} {
+					if to == nil {
+						continue
+					}
+					if to.Match <= i {
+						t.Errorf("Generate(%s, %d) links match %d back to match %d", format, players, i, to.Match)
+					}
+					fed[*to]++
+				}
+			}
+			for i, match := range matches {
+				for slot := range 2 {
+					want := 0
+					if match.Fed[slot] {
+						want = 1
+					}
+					if got := fed[Link{Match: i, Slot: slot}]; got != want {
+						t.Errorf("Generate(%s, %d) feeds slot %d of match %d %d times, want %d", format, players, slot, i, got, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+// play runs a generated bracket, letting pick choose the winner of every
+// match between two players, and returns everyone's losses and the champion.
+// A player alone in a match goes through without playing.
+func play(matches []Match, pick func(a, b int) int) (map[int]int, int) {
+	slots := make([][2]int, len(matches))
+	for i, match := range matches {
+		slots[i] = match.Seeds
+	}
+
+	losses := make(map[int]int)
+	champion := 0
+	for i, match := range matches {
+		a, b := slots[i][0], slots[i][1]
+		if match.Conditional && losses[b] >= 2 {
+			continue
+		}
+
+		var winner, loser int
+		switch {
+		case a == 0 && b == 0:
+			continue
+		case a == 0:
+			winner = b
+		case b == 0:
+			winner = a
+		default:
+			winner = pick(a, b)
+			loser = a + b - winner
+			losses[loser]++
+		}
+
+		if match.WinnerTo != nil {
+			slots[match.WinnerTo.Match][match.WinnerTo.Slot] = winner
+		} else {
+			champion = winner
+		}
+		if match.LoserTo != nil && loser != 0 {
+			slots[match.LoserTo.Match][match.LoserTo.Slot] = loser
+		}
+	}
+	if champion == 0 {
+		// The grand-final reset was not needed
+		final := matches[len(matches)-2]
+		champion = slots[final.WinnerTo.Match][0]
+	}
+	return losses, champion
+}
+
+func TestGenerateEliminationKnocksOutAllButOne(t *testing.T) {
+	random := rand.New(rand.NewPCG(3, 4))
+	picks := map[string]func(a, b int) int{
+		"top seed wins":    func(a, b int) int { return min(a, b) },
+		"bottom seed wins": func(a, b int) int { return max(a, b) },
+		"random": func(a, b int) int {
+			if random.IntN(2) == 0 {
+				return a
+			}
+			return b
+		},
+	}
+
+	for _, format := range []Format{SingleElimination, DoubleElimination} {
+		for name, pick := range picks {
+			for _, players := range fieldSizes {
+				matches, err := Generate(format, players)
+				if err != nil {
+					t.Fatalf("Generate(%s, %d) error = %v", format, players, err)
+				}
+
+				losses, champion := play(matches, pick)
+				if champion == 0 {
+					t.Fatalf("%s, %d players, %s: no champion", format, players, name)
+				}
+				for seed := 1; seed <= players; seed++ {
+					want := format.MaxLosses()
+					if seed == champion {
+						if losses[seed] >= want {
+							t.Errorf("%s, %d players, %s: champion %d lost %d times", format, players, name, seed, losses[seed])
+						}
+						continue
+					}
+					if losses[seed] != want {
+						t.Errorf("%s, %d players, %s: seed %d lost %d times, want %d", format, players, name, seed, losses[seed], want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateTopSeedsMeetInTheFinal(t *testing.T) {
+	for _, players := range fieldSizes {
+		matches, err := Generate(SingleElimination, players)
+		if err != nil {
+			t.Fatalf("Generate(%d) error = %v", players, err)
+		}
+
+		var finalists []int
+		losses, champion := play(matches, func(a, b int) int {
+			if (a == 1 || a == 2) && (b == 1 || b == 2) {
+				finalists = []int{a, b}
+			}
+			return min(a, b)
+		})
+		if champion != 1 || losses[2] != 1 || len(finalists) != 2 {
+			t.Errorf("Generate(%d): champion %d, finalists %v, want seeds 1 and 2 in the final", players, champion, finalists)
+		}
+	}
+}
+
+func TestGenerateRoundRobin(t *testing.T) {
+	for _, players := range fieldSizes {
+		matches, err := Generate(RoundRobin, players)
+		if err != nil {
+			t.Fatalf("Generate(%d) error = %v", players, err)
+		}
+
+		if want := players * (players - 1) / 2; len(matches) != want {
+			t.Errorf("Generate(%d) made %d matches, want %d", players, len(matches), want)
+		}
+
+		wantRounds := players - 1
+		if players%2 == 1 {
+			wantRounds = players
+		}
+
+		pairs := make(map[[2]int]bool)
+		busy := make(map[[2]int]bool)
+		for _, match := range matches {
+			if match.Round < 1 || match.Round > wantRounds {
+				t.Errorf("Generate(%d) has round %d, want 1 to %d", players, match.Round, wantRounds)
+			}
+			pair := [2]int{min(match.Seeds[0], match.Seeds[1]), max(match.Seeds[0], match.Seeds[1])}
+			if pair[0] < 1 || pair[1] > players || pairs[pair] {
+				t.Errorf("Generate(%d) pairs %v more than once or out of range", players, pair)
+			}
+			pairs[pair] = true
+
+			for _, seed := range match.Seeds {
+				if busy[[2]int{match.Round, seed}] {
+					t.Errorf("Generate(%d) plays seed %d twice in round %d", players, seed, match.Round)
+				}
+				busy[[2]int{match.Round, seed}] = true
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package tournament
+
+import "sort"
+
+// Record sums up one player's matches so far.
+type Record struct {
+	PlayerID     string
+	Seed         int
+	Played       int
+	Wins         int
+	Losses       int
+	Draws        int
+	ScoreFor     int
+	ScoreAgainst int
+	// KnockedOutAt is the Stage of the match that knocked the player out of
+	// an elimination tournament, or 0 while they are still in.
+	KnockedOutAt int
+}
+
+// Standing is a player's position in a tournament. Place is shared by tied
+// players and is 0 while it is still open.
+type Standing struct {
+	Record
+	Points int
+	Place  int
+}
+
+// Points a round-robin player earns per result.
+const (
+	WinPoints  = 2
+	DrawPoints = 1
+)
+
+// Standings ranks the players of a tournament. Round-robin players are
+// ordered by points, then wins, score difference and score. Elimination
+// players still in are ordered above those knocked out, who rank by how late
+// they went out; their places are final as soon as they are knocked out.
+// The last player standing takes first place once finished is set.
+func Standings(format Format, records []Record, finished bool) []Standing {
+	standings := make([]Standing, len(records))
+	for i, record := range records {
+		standings[i] = Standing{Record: record, Points: record.Wins*WinPoints + record.Draws*DrawPoints}
+	}
+
+	if format == RoundRobin {
+		key := func(s Standing) [4]int {
+			return [4]int{s.Points, s.Wins, s.ScoreFor - s.ScoreAgainst, s.ScoreFor}
+		}
+		sort.SliceStable(standings, func(i, j int) bool {
+			a, b := key(standings[i]), key(standings[j])
+			for k := range a {
+				if a[k] != b[k] {
+					return a[k] > b[k]
+				}
+			}
+			return standings[i].Seed < standings[j].Seed
+		})
+		for i := range standings {
+			if i > 0 && key(standings[i]) == key(standings[i-1]) {
+				standings[i].Place = standings[i-1].Place
+			} else {
+				standings[i].Place = i + 1
+			}
+		}
+		return standings
+	}
+
+	// Players still in sort first, as if knocked out after every stage.
+	outAt := func(s Standing) int {
+		if s.KnockedOutAt == 0 {
+			return int(^uint(0) >> 1)
+		}
+		return s.KnockedOutAt
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if outAt(a) != outAt(b) {
+			return outAt(a) > outAt(b)
+		}
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		return a.Seed < b.Seed
+	})
+
+	for i := range standings {
+		switch {
+		case standings[i].KnockedOutAt == 0:
+			if finished {
+				standings[i].Place = i + 1
+			}
+		case i > 0 && standings[i].KnockedOutAt == standings[i-1].KnockedOutAt:
+			standings[i].Place = standings[i-1].Place
+		default:
+			standings[i].Place = i + 1
+		}
+	}
+	return standings
+}
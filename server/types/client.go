@@ -15,16 +15,67 @@ type RoundTimeClient struct {
 }
 
 type AnsweredByClient struct {
-	IsCorrect    bool   `json:"isCorrect"`
-	TimeAnswered uint16 `json:"timeAnswered,omitempty"`
+	IsCorrect       bool     `json:"isCorrect"`
+	TimeAnswered    uint16   `json:"timeAnswered,omitempty"`
+	SelectedOptions []string `json:"selectedOptions,omitempty"`
+	ContributorID   string   `json:"contributorId,omitempty"`
+	Attempt         int      `json:"attempt,omitempty"`
+	TimedOut        bool     `json:"timedOut,omitempty"`
+}
+
+// QuestionOptionClient is one choice of a multiple-choice question. IsCorrect
+// is left out of games until the viewer may see the answer.
+type QuestionOptionClient struct {
+	ID        string `json:"id"`
+	Text      string `json:"text"`
+	IsCorrect *bool  `json:"isCorrect,omitempty"`
+}
+
+// QuestionPlayClient tells who plays a cat-in-the-bag or auction question
+// and, for auctions, for how many points.
+type QuestionPlayClient struct {
+	PickerID   string `json:"pickerId,omitempty"`
+	AssigneeID string `json:"assigneeId"`
+	Stake      *int   `json:"stake,omitempty"`
+}
+
+// MediaClient describes an uploaded file. URLs are relative to the API root.
+type MediaClient struct {
+	ID           string `json:"id"`
+	MimeType     string `json:"mimeType"`
+	Kind         string `json:"kind"`
+	Size         int64  `json:"size"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnailUrl,omitempty"`
+}
+
+// MediaAttachmentClient attaches an uploaded file to a question. Only MediaID
+// and Role are read from requests; the rest is filled in on responses.
+type MediaAttachmentClient struct {
+	MediaID      string `json:"mediaId"`
+	Role         string `json:"role"`
+	MimeType     string `json:"mimeType,omitempty"`
+	Kind         string `json:"kind,omitempty"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+	URL          string `json:"url,omitempty"`
+	ThumbnailURL string `json:"thumbnailUrl,omitempty"`
 }
 
 type QuestionClient struct {
-	Id         string                      `json:"id"`
-	Text       string                      `json:"text"`
-	Answer     string                      `json:"answer"`
-	Points     uint16                      `json:"points"`
-	AnsweredBy map[string]AnsweredByClient `json:"answeredBy"`
+	Id             string                      `json:"id"`
+	Text           string                      `json:"text"`
+	Answer         string                      `json:"answer"`
+	Points         uint16                      `json:"points"`
+	Type           string                      `json:"type,omitempty"`
+	Play           *QuestionPlayClient         `json:"play,omitempty"`
+	Media          []MediaAttachmentClient     `json:"media,omitempty"`
+	Options        []QuestionOptionClient      `json:"options,omitempty"`
+	ShuffleOptions bool                        `json:"shuffleOptions,omitempty"`
+	Resolved       bool                        `json:"resolved,omitempty"`
+	AnsweredBy     map[string]AnsweredByClient `json:"answeredBy"`
 }
 
 type ThemeClient struct {
@@ -33,22 +84,45 @@ type ThemeClient struct {
 	Questions []QuestionClient `json:"questions"`
 }
 
+type TimeBonusClient struct {
+	Enabled       bool `json:"enabled"`
+	MaxPoints     int  `json:"maxPoints,omitempty"`
+	WindowSeconds int  `json:"windowSeconds,omitempty"`
+}
+
+// GameRulesClient carries house rules. In requests every field is optional
+// and only the ones set override the defaults; responses fill in all of them.
+type GameRulesClient struct {
+	NegativePoints *bool            `json:"negativePoints,omitempty"`
+	AllowSteal     *bool            `json:"allowSteal,omitempty"`
+	NextPicker     *string          `json:"nextPicker,omitempty"`
+	TimeBonus      *TimeBonusClient `json:"timeBonus,omitempty"`
+}
+
+type RoundRulesClient struct {
+	NegativePoints *bool `json:"negativePoints,omitempty"`
+}
+
 type RoundClient struct {
-	Id     string            `json:"id"`
-	Name   string            `json:"name"`
-	Ranks  []RoundRankClient `json:"ranks"`
-	Time   RoundTimeClient   `json:"time"`
-	Themes []ThemeClient     `json:"themes"`
+	Id    string            `json:"id"`
+	Name  string            `json:"name"`
+	Ranks []RoundRankClient `json:"ranks"`
+	Time  RoundTimeClient   `json:"time"`
+	Rules RoundRulesClient  `json:"rules"`
+	// IsFinal rounds are played with secret wagers, see FinalRoundClient.
+	IsFinal bool          `json:"isFinal,omitempty"`
+	Themes  []ThemeClient `json:"themes"`
 }
 
 type GameTemplateClient struct {
-	ID          string        `json:"id"`
-	Name        string        `json:"name"`
-	Description string        `json:"description"`
-	IsPublic    bool          `json:"isPublic"`
-	Rounds      []RoundClient `json:"rounds"`
-	CreatorID   string        `json:"creatorId"`
-	CreatedAt   int64         `json:"createdAt"`
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	IsPublic    bool             `json:"isPublic"`
+	Rules       *GameRulesClient `json:"rules,omitempty"`
+	Rounds      []RoundClient    `json:"rounds"`
+	CreatorID   string           `json:"creatorId"`
+	CreatedAt   int64            `json:"createdAt"`
 }
 
 type GameUserClient struct {
@@ -56,6 +130,25 @@ type GameUserClient struct {
 	Name       string           `json:"name"`
 	IsAdmin    bool             `json:"isAdmin,omitempty"`
 	RoundScore map[string]int16 `json:"roundScore"`
+	TeamID     string           `json:"teamId,omitempty"`
+}
+
+// TeamMemberClient is a team member with the answers credited to them.
+type TeamMemberClient struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	IsCaptain      bool   `json:"isCaptain"`
+	CorrectAnswers int    `json:"correctAnswers"`
+	Points         int    `json:"points"`
+}
+
+type TeamClient struct {
+	ID         string             `json:"id"`
+	Name       string             `json:"name"`
+	CaptainID  string             `json:"captainId,omitempty"`
+	Members    []TeamMemberClient `json:"members"`
+	RoundScore map[string]int16   `json:"roundScore"`
+	Score      int                `json:"score"`
 }
 
 type UserClient struct {
@@ -78,21 +171,598 @@ type GameClient struct {
 	CurrentRound     string                  `json:"currentRound"`
 	CurrentQuestion  string                  `json:"currentQuestion"`
 	CurrentUser      string                  `json:"currentUser"`
+	Status           string                  `json:"status,omitempty"`
+	MinPlayers       int                     `json:"minPlayers,omitempty"`
+	Version          int                     `json:"version"`
+	Schedule         *GameScheduleClient     `json:"schedule,omitempty"`
+	Mode             string                  `json:"mode,omitempty"`
+	Async            *AsyncSettingsClient    `json:"async,omitempty"`
+	Casual           bool                    `json:"casual,omitempty"`
 	IsFinished       bool                    `json:"isFinished"`
 	Winner           UserClient              `json:"winner"`
 	FinishDate       int64                   `json:"finishDate,omitempty"`
 	CreatorID        string                  `json:"creatorId"`
 	UnconfirmedUsers []UnconfirmedUserClient `json:"unconfirmedUsers,omitempty"`
+	Teams            []TeamClient            `json:"teams,omitempty"`
+	TieBreakers      []string                `json:"tieBreakers,omitempty"`
+	Rules            *GameRulesClient        `json:"rules,omitempty"`
 	CreatedAt        int64                   `json:"createdAt"`
 }
 
+type GameScheduleClient struct {
+	StartsAt        int64  `json:"startsAt"`
+	Timezone        string `json:"timezone"`
+	ReminderOffsets []int  `json:"reminderOffsets"`
+}
+
+type AsyncSettingsClient struct {
+	Deadline        int64 `json:"deadline"`
+	QuestionSeconds int   `json:"questionSeconds"`
+}
+
+// AsyncQuestionClient is a question of an async game as shown to the player
+// who opened it. ExpiresAt is when the answer window closes.
+type AsyncQuestionClient struct {
+	QuestionID string                  `json:"questionId"`
+	Text       string                  `json:"text"`
+	Points     uint16                  `json:"points"`
+	Type       string                  `json:"type,omitempty"`
+	Media      []MediaAttachmentClient `json:"media,omitempty"`
+	Options    []QuestionOptionClient  `json:"options,omitempty"`
+	OpenedAt   int64                   `json:"openedAt"`
+	ExpiresAt  int64                   `json:"expiresAt"`
+}
+
+// AsyncAnswerClient is the server's verdict on an async answer, with the
+// correct answer revealed to the player who gave it.
+type AsyncAnswerClient struct {
+	QuestionID   string `json:"questionId"`
+	IsCorrect    bool   `json:"isCorrect"`
+	TimedOut     bool   `json:"timedOut"`
+	TimeAnswered int    `json:"timeAnswered"`
+	Points       int    `json:"points"`
+	Answer       string `json:"answer"`
+	GameFinished bool   `json:"gameFinished"`
+}
+
+// AsyncPlayerProgressClient tells how far a player is without giving away
+// their answers.
+type AsyncPlayerProgressClient struct {
+	UserID     string `json:"userId"`
+	Name       string `json:"name"`
+	Answered   int    `json:"answered"`
+	FinishedAt int64  `json:"finishedAt,omitempty"`
+}
+
+type AsyncProgressClient struct {
+	GameID          string                      `json:"gameId"`
+	Status          string                      `json:"status"`
+	Deadline        int64                       `json:"deadline"`
+	QuestionSeconds int                         `json:"questionSeconds"`
+	Questions       int                         `json:"questions"`
+	Players         []AsyncPlayerProgressClient `json:"players"`
+}
+
+// AsyncComparisonClient lines up every player's answer to one question once
+// an async game has finished.
+type AsyncComparisonClient struct {
+	QuestionID string                    `json:"questionId"`
+	Text       string                    `json:"text"`
+	Answer     string                    `json:"answer"`
+	Points     uint16                    `json:"points"`
+	Answers    []AsyncPlayerAnswerClient `json:"answers"`
+}
+
+type AsyncPlayerAnswerClient struct {
+	UserID       string `json:"userId"`
+	Name         string `json:"name"`
+	Response     string `json:"response,omitempty"`
+	IsCorrect    bool   `json:"isCorrect"`
+	TimedOut     bool   `json:"timedOut,omitempty"`
+	TimeAnswered int    `json:"timeAnswered"`
+	Points       int    `json:"points"`
+}
+
 type GameInviteClient struct {
-	ID              string    `json:"id"`
-	GameID          string    `json:"gameId"`
-	UserID          string    `json:"userId"`
-	Status          string    `json:"status"`
-	CreatedAt       time.Time `json:"createdAt,omitempty"`
-	UpdatedAt       time.Time `json:"updatedAt,omitempty"`
-	GameName        string    `json:"gameName"`
-	GameCreatorName string    `json:"gameCreatorName"`
+	ID              string     `json:"id"`
+	GameID          string     `json:"gameId"`
+	UserID          string     `json:"userId"`
+	UserName        string     `json:"userName,omitempty"`
+	Status          string     `json:"status"`
+	InvitedBy       string     `json:"invitedBy,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt,omitempty"`
+	UpdatedAt       time.Time  `json:"updatedAt,omitempty"`
+	ExpiresAt       time.Time  `json:"expiresAt,omitempty"`
+	RespondedAt     *time.Time `json:"respondedAt,omitempty"`
+	GameName        string     `json:"gameName"`
+	GameCreatorName string     `json:"gameCreatorName"`
+}
+
+type EmailInviteClient struct {
+	ID          string     `json:"id"`
+	GameID      string     `json:"gameId"`
+	Email       string     `json:"email"`
+	Status      string     `json:"status"`
+	InvitedBy   string     `json:"invitedBy,omitempty"`
+	ClaimedBy   string     `json:"claimedBy,omitempty"`
+	ExpiresAt   time.Time  `json:"expiresAt"`
+	RespondedAt *time.Time `json:"respondedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+type ScoreAdjustmentClient struct {
+	ID            string `json:"id"`
+	UserID        string `json:"userId"`
+	RoundID       string `json:"roundId"`
+	Points        int16  `json:"points"`
+	Reason        string `json:"reason"`
+	CreatedBy     string `json:"createdBy,omitempty"`
+	CreatedByName string `json:"createdByName,omitempty"`
+	CreatedAt     int64  `json:"createdAt"`
+}
+
+type StandingClient struct {
+	UserID          string   `json:"userId"`
+	Name            string   `json:"name"`
+	Place           int      `json:"place"`
+	Score           int      `json:"score"`
+	CorrectAnswers  int      `json:"correctAnswers"`
+	AvgTimeAnswered *float64 `json:"avgTimeAnswered,omitempty"`
+	WonSuddenDeath  bool     `json:"wonSuddenDeath,omitempty"`
+}
+
+type TeamStandingClient struct {
+	TeamID          string   `json:"teamId"`
+	Name            string   `json:"name"`
+	Place           int      `json:"place"`
+	Score           int      `json:"score"`
+	CorrectAnswers  int      `json:"correctAnswers"`
+	AvgTimeAnswered *float64 `json:"avgTimeAnswered,omitempty"`
+	WonSuddenDeath  bool     `json:"wonSuddenDeath,omitempty"`
+}
+
+// GameResultsClient holds the final ranking. Team games are ranked by team:
+// TeamStandings and WinnerTeam are filled in and the player lists stay empty.
+type GameResultsClient struct {
+	GameID        string               `json:"gameId"`
+	IsFinished    bool                 `json:"isFinished"`
+	FinishDate    int64                `json:"finishDate,omitempty"`
+	Winner        UserClient           `json:"winner"`
+	TieBreakers   []string             `json:"tieBreakers"`
+	Podium        []StandingClient     `json:"podium"`
+	Standings     []StandingClient     `json:"standings"`
+	WinnerTeam    *TeamStandingClient  `json:"winnerTeam,omitempty"`
+	TeamStandings []TeamStandingClient `json:"teamStandings,omitempty"`
+}
+
+// FinalWagerClient is one player's final-round entry. Wager, Answer and
+// IsCorrect are only filled in when the viewer is allowed to see them.
+type FinalWagerClient struct {
+	UserID     string  `json:"userId"`
+	HasAnswer  bool    `json:"hasAnswer"`
+	IsRevealed bool    `json:"isRevealed"`
+	Wager      *int    `json:"wager,omitempty"`
+	Answer     *string `json:"answer,omitempty"`
+	IsCorrect  *bool   `json:"isCorrect,omitempty"`
+}
+
+type FinalRoundClient struct {
+	RoundID    string             `json:"roundId"`
+	QuestionID string             `json:"questionId,omitempty"`
+	Deadline   int64              `json:"deadline,omitempty"`
+	IsOpen     bool               `json:"isOpen"`
+	MaxWager   int                `json:"maxWager"`
+	Wagers     []FinalWagerClient `json:"wagers"`
+}
+
+// AttemptClient is one try at a question. Points is what the attempt did to
+// the player's score under the game's rules.
+type AttemptClient struct {
+	Attempt       int    `json:"attempt"`
+	UserID        string `json:"userId"`
+	Name          string `json:"name"`
+	IsCorrect     *bool  `json:"isCorrect"`
+	TimeAnswered  *int   `json:"timeAnswered,omitempty"`
+	ContributorID string `json:"contributorId,omitempty"`
+	Points        int    `json:"points"`
+	CreatedAt     int64  `json:"createdAt"`
+}
+
+// QuestionAttemptsClient is the attempt sequence of a question. Once Resolved
+// no further attempts are taken.
+type QuestionAttemptsClient struct {
+	QuestionID string          `json:"questionId"`
+	Resolved   bool            `json:"resolved"`
+	ResolvedAt int64           `json:"resolvedAt,omitempty"`
+	Attempts   []AttemptClient `json:"attempts"`
+}
+
+type AuctionBidClient struct {
+	UserID    string `json:"userId"`
+	Name      string `json:"name"`
+	Amount    int    `json:"amount"`
+	CreatedAt int64  `json:"createdAt"`
+}
+
+// GameEventClient is one change to a game. Version is the game version after
+// the change.
+type GameEventClient struct {
+	ID        string         `json:"id"`
+	Version   int            `json:"version"`
+	Type      string         `json:"type"`
+	ActorID   string         `json:"actorId,omitempty"`
+	Payload   map[string]any `json:"payload"`
+	CreatedAt int64          `json:"createdAt"`
+}
+
+type NotificationClient struct {
+	ID         string         `json:"id"`
+	Type       string         `json:"type"`
+	GameID     string         `json:"gameId,omitempty"`
+	GameName   string         `json:"gameName,omitempty"`
+	TemplateID string         `json:"templateId,omitempty"`
+	ActorID    string         `json:"actorId,omitempty"`
+	ActorName  string         `json:"actorName,omitempty"`
+	Payload    map[string]any `json:"payload"`
+	Read       bool           `json:"read"`
+	ReadAt     int64          `json:"readAt,omitempty"`
+	CreatedAt  int64          `json:"createdAt"`
+}
+
+// NotificationPageClient is one page of a user's inbox, newest first.
+type NotificationPageClient struct {
+	Notifications []NotificationClient `json:"notifications"`
+	UnreadCount   int                  `json:"unreadCount"`
+	Total         int                  `json:"total"`
+}
+
+// PracticeSessionClient is a solo practice session with its progress.
+type PracticeSessionClient struct {
+	ID           string `json:"id"`
+	TemplateID   string `json:"templateId,omitempty"`
+	TemplateName string `json:"templateName,omitempty"`
+	Status       string `json:"status"`
+	Total        int    `json:"total"`
+	Graded       int    `json:"graded"`
+	Correct      int    `json:"correct"`
+	CreatedAt    int64  `json:"createdAt"`
+	FinishedAt   int64  `json:"finishedAt,omitempty"`
+}
+
+// PracticeQuestionClient is the question a practice session serves next. The
+// answer, answer media and correct options stay out until it is revealed.
+type PracticeQuestionClient struct {
+	QuestionID string                  `json:"questionId"`
+	Position   int                     `json:"position"`
+	Round      string                  `json:"round"`
+	Theme      string                  `json:"theme"`
+	Text       string                  `json:"text"`
+	Points     int                     `json:"points"`
+	Type       string                  `json:"type,omitempty"`
+	Media      []MediaAttachmentClient `json:"media,omitempty"`
+	Options    []QuestionOptionClient  `json:"options,omitempty"`
+	Answer     string                  `json:"answer,omitempty"`
+	Revealed   bool                    `json:"revealed"`
+}
+
+// ReviewItemClient is a question the player missed in practice.
+type ReviewItemClient struct {
+	QuestionID   string `json:"questionId"`
+	TemplateID   string `json:"templateId"`
+	TemplateName string `json:"templateName"`
+	Theme        string `json:"theme"`
+	Text         string `json:"text"`
+	Answer       string `json:"answer"`
+	Points       int    `json:"points"`
+	Misses       int    `json:"misses"`
+	LastMissedAt int64  `json:"lastMissedAt"`
+}
+
+// StudyCardClient is a missed question on the player's spaced-repetition
+// schedule. Source is "game" for a live or async game question and "practice"
+// for a template question missed in solo practice.
+type StudyCardClient struct {
+	ID             string  `json:"id"`
+	QuestionID     string  `json:"questionId"`
+	Source         string  `json:"source"`
+	Theme          string  `json:"theme"`
+	Text           string  `json:"text"`
+	Answer         string  `json:"answer"`
+	Points         int     `json:"points"`
+	Ease           float64 `json:"ease"`
+	IntervalDays   int     `json:"intervalDays"`
+	Repetitions    int     `json:"repetitions"`
+	Lapses         int     `json:"lapses"`
+	DueAt          int64   `json:"dueAt"`
+	LastReviewedAt int64   `json:"lastReviewedAt,omitempty"`
+}
+
+// StudyQueueClient is a page of the cards due by the end of the player's day.
+type StudyQueueClient struct {
+	Cards    []StudyCardClient `json:"cards"`
+	DueCount int               `json:"dueCount"`
+	Total    int               `json:"total"`
+}
+
+// DailyChallengeClient is the shared challenge of a UTC day. Attempt is the
+// caller's attempt, if they have started one.
+type DailyChallengeClient struct {
+	Day              string              `json:"day"`
+	QuestionCount    int                 `json:"questionCount"`
+	TimeLimitSeconds int                 `json:"timeLimitSeconds"`
+	Attempt          *DailyAttemptClient `json:"attempt,omitempty"`
+}
+
+// DailyQuestionClient is a question of a daily challenge as shown to a player
+// during their attempt. Answers and correct options are revealed once the
+// attempt finishes.
+type DailyQuestionClient struct {
+	QuestionID string                  `json:"questionId"`
+	Theme      string                  `json:"theme"`
+	Text       string                  `json:"text"`
+	Points     int                     `json:"points"`
+	Type       string                  `json:"type,omitempty"`
+	Media      []MediaAttachmentClient `json:"media,omitempty"`
+	Options    []QuestionOptionClient  `json:"options,omitempty"`
+	Answered   bool                    `json:"answered"`
+	IsCorrect  *bool                   `json:"isCorrect,omitempty"`
+	Answer     string                  `json:"answer,omitempty"`
+}
+
+// DailyAttemptClient is a player's one attempt at a daily challenge.
+type DailyAttemptClient struct {
+	Day        string                `json:"day"`
+	StartedAt  int64                 `json:"startedAt"`
+	ExpiresAt  int64                 `json:"expiresAt"`
+	FinishedAt int64                 `json:"finishedAt,omitempty"`
+	Score      int                   `json:"score"`
+	Correct    int                   `json:"correct"`
+	ElapsedMs  int                   `json:"elapsedMs,omitempty"`
+	Questions  []DailyQuestionClient `json:"questions"`
+}
+
+// DailyAnswerClient is the server's verdict on a daily challenge answer.
+type DailyAnswerClient struct {
+	QuestionID      string `json:"questionId"`
+	IsCorrect       bool   `json:"isCorrect"`
+	Points          int    `json:"points"`
+	AttemptFinished bool   `json:"attemptFinished"`
+}
+
+// DailyLeaderboardEntryClient is a finished attempt ranked by score, then by
+// how quickly it was done.
+type DailyLeaderboardEntryClient struct {
+	Rank      int    `json:"rank"`
+	UserID    string `json:"userId"`
+	Name      string `json:"name"`
+	Score     int    `json:"score"`
+	Correct   int    `json:"correct"`
+	ElapsedMs int    `json:"elapsedMs"`
+}
+
+// DailyLeaderboardClient is a page of a day's leaderboard with the caller's
+// own entry, wherever it ranks.
+type DailyLeaderboardClient struct {
+	Day     string                        `json:"day"`
+	Total   int                           `json:"total"`
+	Entries []DailyLeaderboardEntryClient `json:"entries"`
+	Me      *DailyLeaderboardEntryClient  `json:"me,omitempty"`
+}
+
+// TournamentSummaryClient is a tournament in a list.
+type TournamentSummaryClient struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Format       string `json:"format"`
+	Status       string `json:"status"`
+	CreatorID    string `json:"creatorId"`
+	Participants int    `json:"participants"`
+	CreatedAt    int64  `json:"createdAt"`
+}
+
+// TournamentClient is a tournament with its field and bracket.
+type TournamentClient struct {
+	ID           string                        `json:"id"`
+	Name         string                        `json:"name"`
+	Format       string                        `json:"format"`
+	Status       string                        `json:"status"`
+	Creator      UserClient                    `json:"creator"`
+	TemplateID   string                        `json:"templateId,omitempty"`
+	TemplateName string                        `json:"templateName,omitempty"`
+	Winner       *UserClient                   `json:"winner,omitempty"`
+	CreatedAt    int64                         `json:"createdAt"`
+	StartedAt    int64                         `json:"startedAt,omitempty"`
+	FinishedAt   int64                         `json:"finishedAt,omitempty"`
+	Participants []TournamentParticipantClient `json:"participants"`
+	Matches      []TournamentMatchClient       `json:"matches"`
+}
+
+type TournamentParticipantClient struct {
+	UserID string `json:"userId"`
+	Name   string `json:"name"`
+	Seed   int    `json:"seed,omitempty"`
+}
+
+// TournamentMatchClient is one match of a bracket. A player left empty in a
+// finished match was a bye; a match whose players are still open is pending.
+type TournamentMatchClient struct {
+	ID       string      `json:"id"`
+	Bracket  string      `json:"bracket"`
+	Round    int         `json:"round"`
+	Position int         `json:"position"`
+	Status   string      `json:"status"`
+	Player1  *UserClient `json:"player1,omitempty"`
+	Player2  *UserClient `json:"player2,omitempty"`
+	Score1   *int        `json:"score1,omitempty"`
+	Score2   *int        `json:"score2,omitempty"`
+	GameID   string      `json:"gameId,omitempty"`
+	WinnerID string      `json:"winnerId,omitempty"`
+	IsDraw   bool        `json:"isDraw"`
+}
+
+// TournamentStandingClient is a player's position in a tournament. Place is
+// 0 while it is still open.
+type TournamentStandingClient struct {
+	Place        int    `json:"place"`
+	UserID       string `json:"userId"`
+	Name         string `json:"name"`
+	Seed         int    `json:"seed"`
+	Played       int    `json:"played"`
+	Wins         int    `json:"wins"`
+	Losses       int    `json:"losses"`
+	Draws        int    `json:"draws"`
+	Points       int    `json:"points"`
+	ScoreFor     int    `json:"scoreFor"`
+	ScoreAgainst int    `json:"scoreAgainst"`
+	Eliminated   bool   `json:"eliminated"`
+}
+
+// SeasonClient is a rating season. The current season has no end.
+type SeasonClient struct {
+	ID           string  `json:"id"`
+	Name         string  `json:"name"`
+	CarryOver    string  `json:"carryOver"`
+	SoftenFactor float64 `json:"softenFactor"`
+	StartsAt     int64   `json:"startsAt"`
+	EndsAt       int64   `json:"endsAt,omitempty"`
+}
+
+// RatingEntryClient is a player's rating in a season, rounded to whole
+// points.
+type RatingEntryClient struct {
+	Rank   int    `json:"rank"`
+	UserID string `json:"userId"`
+	Name   string `json:"name"`
+	Rating int    `json:"rating"`
+	Peak   int    `json:"peak"`
+	Games  int    `json:"games"`
+	Wins   int    `json:"wins"`
+}
+
+// RatingLeaderboardClient is a page of a season's ratings with the caller's
+// own entry, wherever it ranks.
+type RatingLeaderboardClient struct {
+	Season  SeasonClient        `json:"season"`
+	Total   int                 `json:"total"`
+	Entries []RatingEntryClient `json:"entries"`
+	Me      *RatingEntryClient  `json:"me,omitempty"`
+}
+
+// RatingChangeClient is how one finished game moved a player's rating.
+type RatingChangeClient struct {
+	GameID    string `json:"gameId"`
+	GameName  string `json:"gameName"`
+	Place     int    `json:"place"`
+	Before    int    `json:"before"`
+	After     int    `json:"after"`
+	Change    int    `json:"change"`
+	CreatedAt int64  `json:"createdAt"`
+}
+
+// PlayerRatingClient is a player's standing in a season and a page of the
+// games that got them there, newest first. Rating is absent until they
+// finish a rated game in the season.
+type PlayerRatingClient struct {
+	Season  SeasonClient         `json:"season"`
+	Rating  *RatingEntryClient   `json:"rating,omitempty"`
+	History []RatingChangeClient `json:"history"`
+}
+
+// PlayerStatsClient sums up a player's finished games. Times are in the
+// unit answers are timed in and absent until the player has a timed answer.
+type PlayerStatsClient struct {
+	UserID               string                     `json:"userId"`
+	Name                 string                     `json:"name"`
+	GamesPlayed          int                        `json:"gamesPlayed"`
+	GamesWon             int                        `json:"gamesWon"`
+	WinRate              float64                    `json:"winRate"`
+	Answered             int                        `json:"answered"`
+	Correct              int                        `json:"correct"`
+	Accuracy             float64                    `json:"accuracy"`
+	AvgTimeAnswered      *float64                   `json:"avgTimeAnswered,omitempty"`
+	MedianTimeAnswered   *float64                   `json:"medianTimeAnswered,omitempty"`
+	LongestCorrectStreak int                        `json:"longestCorrectStreak"`
+	Themes               []ThemeStatsClient         `json:"themes"`
+	Rounds               []RoundPositionStatsClient `json:"rounds"`
+	FavouriteTemplates   []TemplateStatsClient      `json:"favouriteTemplates"`
+	HeadToHead           []HeadToHeadClient         `json:"headToHead"`
+}
+
+// ThemeStatsClient is a player's accuracy on questions of themes sharing a
+// name, compared case-insensitively.
+type ThemeStatsClient struct {
+	Theme    string  `json:"theme"`
+	Answered int     `json:"answered"`
+	Correct  int     `json:"correct"`
+	Accuracy float64 `json:"accuracy"`
+}
+
+// RoundPositionStatsClient is what a player scored in the rounds at one
+// position of their games, 0 being the first round.
+type RoundPositionStatsClient struct {
+	Position  int     `json:"position"`
+	Games     int     `json:"games"`
+	Points    int     `json:"points"`
+	AvgPoints float64 `json:"avgPoints"`
+}
+
+type TemplateStatsClient struct {
+	TemplateID string `json:"templateId"`
+	Name       string `json:"name"`
+	Played     int    `json:"played"`
+	Won        int    `json:"won"`
+}
+
+// HeadToHeadClient is a player's record against one opponent over the
+// finished games they played against each other; finishing higher wins.
+type HeadToHeadClient struct {
+	UserID string `json:"userId"`
+	Name   string `json:"name"`
+	Games  int    `json:"games"`
+	Wins   int    `json:"wins"`
+	Losses int    `json:"losses"`
+	Draws  int    `json:"draws"`
+}
+
+// LeaderboardEntryClient is a player's record over a leaderboard's period.
+type LeaderboardEntryClient struct {
+	Rank       int     `json:"rank"`
+	UserID     string  `json:"userId"`
+	Name       string  `json:"name"`
+	Games      int     `json:"games"`
+	Wins       int     `json:"wins"`
+	ScoreShare float64 `json:"scoreShare"`
+	Accuracy   float64 `json:"accuracy"`
+}
+
+// LeaderboardClient is a page of a leaderboard with the caller's own entry,
+// wherever it ranks. PeriodStart is the first day of a monthly or weekly
+// period.
+type LeaderboardClient struct {
+	Period      string                   `json:"period"`
+	Metric      string                   `json:"metric"`
+	PeriodStart string                   `json:"periodStart,omitempty"`
+	Total       int                      `json:"total"`
+	Entries     []LeaderboardEntryClient `json:"entries"`
+	Me          *LeaderboardEntryClient  `json:"me,omitempty"`
+}
+
+// TemplateScoreClient is a player's best score on a template.
+type TemplateScoreClient struct {
+	Rank       int    `json:"rank"`
+	UserID     string `json:"userId"`
+	Name       string `json:"name"`
+	Score      int    `json:"score"`
+	GameID     string `json:"gameId,omitempty"`
+	AchievedAt int64  `json:"achievedAt"`
+}
+
+// TemplateLeaderboardClient is a page of a template's best scores with the
+// caller's own, wherever it ranks.
+type TemplateLeaderboardClient struct {
+	TemplateID   string                `json:"templateId"`
+	TemplateName string                `json:"templateName"`
+	Total        int                   `json:"total"`
+	Entries      []TemplateScoreClient `json:"entries"`
+	Me           *TemplateScoreClient  `json:"me,omitempty"`
 }
@@ -1,6 +1,9 @@
 package types
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type UserServer struct {
 	ID        string    `json:"id"`
@@ -17,6 +20,7 @@ type GameTemplateServer struct {
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
 	IsPublic    bool      `json:"is_public"`
+	Rules       GameRules `json:"rules"`
 	CreatedAt   time.Time `json:"created_at"`
 }
 
@@ -32,12 +36,128 @@ type RankSettings struct {
 	IsSelected bool   `json:"is_selected"`
 }
 
+// NextPicker decides who chooses the next question.
+type NextPicker string
+
+const (
+	// PickerLastCorrect hands the board to whoever answered the last question
+	// correctly; after a question nobody got right the picker stays the same.
+	PickerLastCorrect NextPicker = "last_correct"
+	// PickerRoundRobin passes the board to the next player in turn.
+	PickerRoundRobin NextPicker = "round_robin"
+	// PickerHost lets the host choose every question.
+	PickerHost NextPicker = "host"
+)
+
+// TimeBonus awards extra points for fast correct answers: MaxPoints for an
+// instant answer, falling linearly to nothing at WindowSeconds.
+type TimeBonus struct {
+	Enabled       bool `json:"enabled"`
+	MaxPoints     int  `json:"max_points"`
+	WindowSeconds int  `json:"window_seconds"`
+}
+
+// GameRules are the house rules of a game. Templates hold defaults and games
+// store the rules they were created with.
+type GameRules struct {
+	NegativePoints bool       `json:"negative_points"`
+	AllowSteal     bool       `json:"allow_steal"`
+	NextPicker     NextPicker `json:"next_picker"`
+	TimeBonus      TimeBonus  `json:"time_bonus"`
+}
+
+// DefaultGameRules are the classic rules, used for templates and games that
+// were stored before rules existed.
+func DefaultGameRules() GameRules {
+	return GameRules{
+		NegativePoints: true,
+		AllowSteal:     true,
+		NextPicker:     PickerLastCorrect,
+	}
+}
+
+// Validate checks the rules are complete and within bounds.
+func (r GameRules) Validate() error {
+	switch r.NextPicker {
+	case PickerLastCorrect, PickerRoundRobin, PickerHost:
+	default:
+		return fmt.Errorf("unknown next picker rule %q", r.NextPicker)
+	}
+
+	if r.TimeBonus.Enabled {
+		if r.TimeBonus.MaxPoints < 1 || r.TimeBonus.MaxPoints > 1000 {
+			return fmt.Errorf("time bonus must be between 1 and 1000 points")
+		}
+		if r.TimeBonus.WindowSeconds < 1 || r.TimeBonus.WindowSeconds > 600 {
+			return fmt.Errorf("time bonus window must be between 1 and 600 seconds")
+		}
+	}
+	return nil
+}
+
+// Apply returns the rules with the fields set in override replaced. A nil
+// override keeps the rules as they are.
+func (r GameRules) Apply(override *GameRulesClient) GameRules {
+	if override == nil {
+		return r
+	}
+	if override.NegativePoints != nil {
+		r.NegativePoints = *override.NegativePoints
+	}
+	if override.AllowSteal != nil {
+		r.AllowSteal = *override.AllowSteal
+	}
+	if override.NextPicker != nil {
+		r.NextPicker = NextPicker(*override.NextPicker)
+	}
+	if override.TimeBonus != nil {
+		r.TimeBonus = TimeBonus{
+			Enabled:       override.TimeBonus.Enabled,
+			MaxPoints:     override.TimeBonus.MaxPoints,
+			WindowSeconds: override.TimeBonus.WindowSeconds,
+		}
+	}
+	return r
+}
+
+// Client returns the rules with every field filled in.
+func (r GameRules) Client() *GameRulesClient {
+	nextPicker := string(r.NextPicker)
+	return &GameRulesClient{
+		NegativePoints: &r.NegativePoints,
+		AllowSteal:     &r.AllowSteal,
+		NextPicker:     &nextPicker,
+		TimeBonus: &TimeBonusClient{
+			Enabled:       r.TimeBonus.Enabled,
+			MaxPoints:     r.TimeBonus.MaxPoints,
+			WindowSeconds: r.TimeBonus.WindowSeconds,
+		},
+	}
+}
+
+// RoundRules holds per-round scoring rules. Nil fields fall back to the
+// game's rules, so older rounds stored as '{}' keep the game's behaviour.
+type RoundRules struct {
+	NegativePoints *bool `json:"negative_points,omitempty"`
+}
+
+// HasNegativePoints reports whether a wrong answer costs the question's points
+// in this round, given the game's rules.
+func (r RoundRules) HasNegativePoints(game GameRules) bool {
+	if r.NegativePoints == nil {
+		return game.NegativePoints
+	}
+	return *r.NegativePoints
+}
+
 type TemplateRoundServer struct {
 	ID             string         `json:"id"`
 	GameTemplateID string         `json:"game_template_id"`
 	Name           string         `json:"name"`
 	TimeSettings   TimeSettings   `json:"time_settings"`
 	RankSettings   []RankSettings `json:"rank_settings"`
+	Rules          RoundRules     `json:"rules"`
+	IsFinal        bool           `json:"is_final"`
 	Position       uint16         `json:"position"`
 }
 
@@ -48,27 +168,169 @@ type TemplateThemeServer struct {
 	Position uint16 `json:"position"`
 }
 
+// QuestionType selects the special rules a question is played with.
+type QuestionType string
+
+const (
+	QuestionStandard QuestionType = "standard"
+	// QuestionCatInBag is handed by the picker to another player to answer.
+	QuestionCatInBag QuestionType = "cat_in_bag"
+	// QuestionAuction goes to the highest bidder, who plays for the bid.
+	QuestionAuction QuestionType = "auction"
+	// QuestionNoRisk costs nothing when answered wrong.
+	QuestionNoRisk QuestionType = "no_risk"
+	// QuestionMultipleChoice offers options and is graded automatically.
+	QuestionMultipleChoice QuestionType = "multiple_choice"
+)
+
+// ParseQuestionType validates a client-supplied type; empty means standard.
+func ParseQuestionType(value string) (QuestionType, error) {
+	switch t := QuestionType(value); t {
+	case "":
+		return QuestionStandard, nil
+	case QuestionStandard, QuestionCatInBag, QuestionAuction, QuestionNoRisk, QuestionMultipleChoice:
+		return t, nil
+	}
+	return "", fmt.Errorf("unknown question type %q", value)
+}
+
+// GameStatus is where a game is in its lifecycle.
+type GameStatus string
+
+const (
+	// GameScheduled is waiting for its start time; its lobby opens then.
+	GameScheduled GameStatus = "scheduled"
+	// GameLobby is waiting for players to accept their invites.
+	GameLobby GameStatus = "lobby"
+	// GameInProgress is being played.
+	GameInProgress GameStatus = "in_progress"
+	// GamePaused is on hold; nothing can be answered until it is resumed.
+	GamePaused GameStatus = "paused"
+	// GameFinished has been ranked and has a winner.
+	GameFinished GameStatus = "finished"
+	// GameAbandoned was given up by the host or left untouched for too long.
+	GameAbandoned GameStatus = "abandoned"
+)
+
+// CanBecome reports whether a game may move from s to next. Finished and
+// abandoned games are final.
+func (s GameStatus) CanBecome(next GameStatus) bool {
+	switch next {
+	case GameLobby:
+		return s == GameScheduled
+	case GameInProgress:
+		return s == GameLobby || s == GamePaused
+	case GamePaused:
+		return s == GameInProgress
+	case GameFinished:
+		return s == GameInProgress || s == GamePaused
+	case GameAbandoned:
+		return s == GameScheduled || s == GameLobby || s == GameInProgress || s == GamePaused
+	}
+	return false
+}
+
+// GameMode says how a game is played.
+type GameMode string
+
+const (
+	// GameModeLive is played together, with the host judging every answer.
+	GameModeLive GameMode = "live"
+	// GameModeAsync is played by every participant on their own time against
+	// per-question timers. Answers are judged by the server and stay hidden
+	// until the game finishes.
+	GameModeAsync GameMode = "async"
+)
+
+// MediaRole says whether an attachment belongs to the question or is shown
+// with the answer.
+type MediaRole string
+
+const (
+	MediaRoleQuestion MediaRole = "question"
+	MediaRoleAnswer   MediaRole = "answer"
+)
+
+// ParseMediaRole validates a client-supplied role; empty means question.
+func ParseMediaRole(value string) (MediaRole, error) {
+	switch r := MediaRole(value); r {
+	case "":
+		return MediaRoleQuestion, nil
+	case MediaRoleQuestion, MediaRoleAnswer:
+		return r, nil
+	}
+	return "", fmt.Errorf("unknown media role %q", value)
+}
+
+type MediaServer struct {
+	ID          string    `json:"id"`
+	MimeType    string    `json:"mime_type"`
+	Kind        string    `json:"kind"`
+	Size        int64     `json:"size"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	ThumbnailID *string   `json:"thumbnail_id"`
+	UploadedBy  string    `json:"uploaded_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type MediaAttachmentServer struct {
+	MediaID  string    `json:"media_id"`
+	Role     MediaRole `json:"role"`
+	Position uint16    `json:"position"`
+}
+
+// QuestionOptionServer is one choice of a multiple-choice question.
+type QuestionOptionServer struct {
+	ID        string `json:"id"`
+	Text      string `json:"text"`
+	IsCorrect bool   `json:"is_correct"`
+	Position  uint16 `json:"position"`
+}
+
+// TemplateQuestionServer is a question of a template. With ShuffleOptions set,
+// the options are put in a new random order for every game created from it.
 type TemplateQuestionServer struct {
-	ID       string `json:"id"`
-	ThemeID  string `json:"theme_id"`
-	Text     string `json:"text"`
-	Answer   string `json:"answer"`
-	Points   uint16 `json:"points"`
-	Position uint16 `json:"position"`
+	ID             string                  `json:"id"`
+	ThemeID        string                  `json:"theme_id"`
+	Text           string                  `json:"text"`
+	Answer         string                  `json:"answer"`
+	Points         uint16                  `json:"points"`
+	Type           QuestionType            `json:"question_type"`
+	Media          []MediaAttachmentServer `json:"media"`
+	Options        []QuestionOptionServer  `json:"options"`
+	ShuffleOptions bool                    `json:"shuffle_options"`
+	Position       uint16                  `json:"position"`
 }
 
 type GameServer struct {
-	ID                string    `json:"id"`
-	Name              string    `json:"name"`
-	CreatorID         string    `json:"creator_id"`
-	TemplateID        string    `json:"template_id"`
-	IsFinished        bool      `json:"is_finished"`
-	WinnerID          string    `json:"winner_id"`
-	FinishDate        time.Time `json:"finish_date"`
-	CurrentRoundID    string    `json:"current_round_id"`
-	CurrentQuestionID string    `json:"current_question_id"`
-	CurrentUserID     string    `json:"current_user_id"`
-	CreatedAt         time.Time `json:"created_at"`
+	ID                string         `json:"id"`
+	Name              string         `json:"name"`
+	CreatorID         string         `json:"creator_id"`
+	TemplateID        string         `json:"template_id"`
+	IsFinished        bool           `json:"is_finished"`
+	WinnerID          string         `json:"winner_id"`
+	FinishDate        time.Time      `json:"finish_date"`
+	CurrentRoundID    string         `json:"current_round_id"`
+	CurrentQuestionID string         `json:"current_question_id"`
+	CurrentUserID     string         `json:"current_user_id"`
+	TieBreakers       []string       `json:"tie_breakers"`
+	Rules             GameRules      `json:"rules"`
+	Status            GameStatus     `json:"status"`
+	MinPlayers        int            `json:"min_players"`
+	Version           int            `json:"version"`
+	Schedule          *GameSchedule  `json:"schedule,omitempty"`
+	Mode              GameMode       `json:"mode"`
+	Async             *AsyncSettings `json:"async,omitempty"`
+	Casual            bool           `json:"casual"`
+	CreatedAt         time.Time      `json:"created_at"`
+}
+
+// TemplateCopyOptions tailor a game created from a template. RoundIDs picks a
+// subset of the template's rounds, in template order; empty copies them all.
+type TemplateCopyOptions struct {
+	RoundIDs      []string `json:"round_ids"`
+	ShuffleThemes bool     `json:"shuffle_themes"`
 }
 
 type RoundServer struct {
@@ -77,6 +339,8 @@ type RoundServer struct {
 	Name         string         `json:"name"`
 	TimeSettings TimeSettings   `json:"time_settings"`
 	RankSettings []RankSettings `json:"rank_settings"`
+	Rules        RoundRules     `json:"rules"`
+	IsFinal      bool           `json:"is_final"`
 	Position     uint16         `json:"position"`
 }
 
@@ -88,12 +352,26 @@ type ThemeServer struct {
 }
 
 type QuestionServer struct {
-	ID        string    `json:"id"`
-	ThemeID   string    `json:"theme_id"`
-	Text      string    `json:"text"`
-	Answer    string    `json:"answer"`
-	Points    uint16    `json:"points"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         string                  `json:"id"`
+	ThemeID    string                  `json:"theme_id"`
+	Text       string                  `json:"text"`
+	Answer     string                  `json:"answer"`
+	Points     uint16                  `json:"points"`
+	Type       QuestionType            `json:"question_type"`
+	Media      []MediaAttachmentServer `json:"media"`
+	Options    []QuestionOptionServer  `json:"options"`
+	ResolvedAt *time.Time              `json:"resolved_at"`
+	CreatedAt  time.Time               `json:"created_at"`
+}
+
+// AttemptServer is a judged attempt at a question, recorded by the host.
+type AttemptServer struct {
+	GameID        string `json:"game_id"`
+	QuestionID    string `json:"question_id"`
+	UserID        string `json:"user_id"`
+	IsCorrect     bool   `json:"is_correct"`
+	TimeAnswered  *int   `json:"time_answered"`
+	ContributorID string `json:"contributor_id"`
 }
 
 type GameUserServer struct {
@@ -103,19 +381,282 @@ type GameUserServer struct {
 }
 
 type AnswerServer struct {
-	ID           string    `json:"id"`
-	QuestionID   string    `json:"question_id"`
-	UserID       string    `json:"user_id"`
-	IsCorrect    bool      `json:"is_correct"`
-	TimeAnswered uint16    `json:"time_answered,omitempty"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID              string    `json:"id"`
+	QuestionID      string    `json:"question_id"`
+	UserID          string    `json:"user_id"`
+	IsCorrect       bool      `json:"is_correct"`
+	TimeAnswered    uint16    `json:"time_answered,omitempty"`
+	SelectedOptions []string  `json:"selected_options,omitempty"`
+	ContributorID   string    `json:"contributor_id,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
 }
 
+type InviteStatus string
+
+const (
+	InvitePending  InviteStatus = "pending"
+	InviteAccepted InviteStatus = "accepted"
+	InviteDeclined InviteStatus = "declined"
+	InviteExpired  InviteStatus = "expired"
+	InviteRevoked  InviteStatus = "revoked"
+)
+
 type GameInviteServer struct {
+	ID          string     `json:"id"`
+	GameID      string     `json:"game_id"`
+	UserID      string     `json:"user_id"`
+	Status      string     `json:"status"`
+	InvitedBy   string     `json:"invited_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+}
+
+// EmailInviteServer invites someone by email who may not have an account
+// yet. Once claimed it points at the regular invite it became.
+type EmailInviteServer struct {
+	ID           string     `json:"id"`
+	GameID       string     `json:"game_id"`
+	Email        string     `json:"email"`
+	InvitedBy    string     `json:"invited_by,omitempty"`
+	Status       string     `json:"status"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	ClaimedBy    string     `json:"claimed_by,omitempty"`
+	GameInviteID string     `json:"game_invite_id,omitempty"`
+	RespondedAt  *time.Time `json:"responded_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type ScoreAdjustmentServer struct {
 	ID        string    `json:"id"`
 	GameID    string    `json:"game_id"`
 	UserID    string    `json:"user_id"`
-	Status    string    `json:"status"`
+	RoundID   string    `json:"round_id"`
+	Points    int16     `json:"points"`
+	Reason    string    `json:"reason"`
+	CreatedBy string    `json:"created_by"`
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
 }
+
+type GameEventType string
+
+const (
+	EventQuestionSelected GameEventType = "question_selected"
+	EventAnswerRecorded   GameEventType = "answer_recorded"
+	EventAnswerCleared    GameEventType = "answer_cleared"
+	EventScoreAdjusted    GameEventType = "score_adjusted"
+	EventPickerSet        GameEventType = "picker_set"
+	EventGameRenamed      GameEventType = "game_renamed"
+)
+
+// GameMutation identifies a focused change to a game. Version is the game
+// version the caller last read; zero skips the check.
+type GameMutation struct {
+	GameID  string
+	ActorID string
+	Version int
+}
+
+type GameEventServer struct {
+	ID        string         `json:"id"`
+	GameID    string         `json:"game_id"`
+	Version   int            `json:"version"`
+	Type      GameEventType  `json:"type"`
+	ActorID   string         `json:"actor_id,omitempty"`
+	Payload   map[string]any `json:"payload"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+func (e GameEventServer) Client() GameEventClient {
+	return GameEventClient{
+		ID:        e.ID,
+		Version:   e.Version,
+		Type:      string(e.Type),
+		ActorID:   e.ActorID,
+		Payload:   e.Payload,
+		CreatedAt: e.CreatedAt.UnixMilli(),
+	}
+}
+
+// GameSchedule is when a scheduled game starts. ReminderOffsets are minutes
+// before the start at which players are reminded.
+type GameSchedule struct {
+	StartsAt        time.Time `json:"starts_at"`
+	Timezone        string    `json:"timezone"`
+	ReminderOffsets []int     `json:"reminder_offsets"`
+}
+
+func (s *GameSchedule) Client() *GameScheduleClient {
+	if s == nil {
+		return nil
+	}
+	return &GameScheduleClient{
+		StartsAt:        s.StartsAt.UnixMilli(),
+		Timezone:        s.Timezone,
+		ReminderOffsets: s.ReminderOffsets,
+	}
+}
+
+// AsyncSettings bound an async game: every question must be answered within
+// QuestionSeconds of opening it, and the game finishes at Deadline at the
+// latest.
+type AsyncSettings struct {
+	Deadline        time.Time `json:"deadline"`
+	QuestionSeconds int       `json:"question_seconds"`
+}
+
+func (s *AsyncSettings) Client() *AsyncSettingsClient {
+	if s == nil {
+		return nil
+	}
+	return &AsyncSettingsClient{
+		Deadline:        s.Deadline.UnixMilli(),
+		QuestionSeconds: s.QuestionSeconds,
+	}
+}
+
+// ScheduledGameServer is what calendar export needs to know about a game.
+type ScheduledGameServer struct {
+	ID       string
+	Name     string
+	HostName string
+	Status   GameStatus
+	Schedule GameSchedule
+}
+
+type NotificationType string
+
+const (
+	NotificationInviteReceived NotificationType = "invite_received"
+	NotificationInviteAccepted NotificationType = "invite_accepted"
+	NotificationYourTurn       NotificationType = "your_turn"
+	NotificationGameFinished   NotificationType = "game_finished"
+	NotificationTemplateForked NotificationType = "template_forked"
+	NotificationTemplateRated  NotificationType = "template_rated"
+	NotificationGameReminder   NotificationType = "game_reminder"
+	NotificationLobbyOpen      NotificationType = "lobby_open"
+	NotificationMatchReady     NotificationType = "match_ready"
+)
+
+type NotificationServer struct {
+	ID         string           `json:"id"`
+	UserID     string           `json:"user_id"`
+	Type       NotificationType `json:"type"`
+	GameID     string           `json:"game_id,omitempty"`
+	TemplateID string           `json:"template_id,omitempty"`
+	ActorID    string           `json:"actor_id,omitempty"`
+	ActorName  string           `json:"actor_name,omitempty"`
+	GameName   string           `json:"game_name,omitempty"`
+	Payload    map[string]any   `json:"payload"`
+	ReadAt     *time.Time       `json:"read_at,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+}
+
+func (n NotificationServer) Client() NotificationClient {
+	client := NotificationClient{
+		ID:         n.ID,
+		Type:       string(n.Type),
+		GameID:     n.GameID,
+		GameName:   n.GameName,
+		TemplateID: n.TemplateID,
+		ActorID:    n.ActorID,
+		ActorName:  n.ActorName,
+		Payload:    n.Payload,
+		Read:       n.ReadAt != nil,
+		CreatedAt:  n.CreatedAt.UnixMilli(),
+	}
+	if n.ReadAt != nil {
+		client.ReadAt = n.ReadAt.UnixMilli()
+	}
+	return client
+}
+
+type PracticeStatus string
+
+const (
+	PracticeActive   PracticeStatus = "active"
+	PracticeFinished PracticeStatus = "finished"
+)
+
+// PracticeOptions pick the questions of a practice session: the template's
+// questions, optionally limited to some rounds, or with FromReview the
+// player's review list instead. Limit caps the number of questions.
+type PracticeOptions struct {
+	UserID     string   `json:"user_id"`
+	TemplateID string   `json:"template_id"`
+	RoundIDs   []string `json:"round_ids"`
+	FromReview bool     `json:"from_review"`
+	Shuffle    bool     `json:"shuffle"`
+	Limit      int      `json:"limit"`
+}
+
+type TournamentStatus string
+
+const (
+	TournamentRegistration TournamentStatus = "registration"
+	TournamentInProgress   TournamentStatus = "in_progress"
+	TournamentFinished     TournamentStatus = "finished"
+)
+
+type TournamentMatchStatus string
+
+const (
+	MatchPending  TournamentMatchStatus = "pending"
+	MatchReady    TournamentMatchStatus = "ready"
+	MatchFinished TournamentMatchStatus = "finished"
+	MatchSkipped  TournamentMatchStatus = "skipped"
+)
+
+// TournamentServer is a new tournament. Its matches are games created from
+// TemplateID, limited to RoundIDs when set, with TieBreakers and Rules.
+type TournamentServer struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	CreatorID   string    `json:"creator_id"`
+	TemplateID  string    `json:"template_id"`
+	RoundIDs    []string  `json:"round_ids"`
+	TieBreakers []string  `json:"tie_breakers"`
+	Rules       GameRules `json:"rules"`
+	Format      string    `json:"format"`
+}
+
+// SeasonCarryOver says what happens to ratings when a season starts.
+type SeasonCarryOver string
+
+const (
+	// SeasonReset starts everyone at the default rating.
+	SeasonReset SeasonCarryOver = "reset"
+	// SeasonSoften carries ratings over, pulled towards the default.
+	SeasonSoften SeasonCarryOver = "soften"
+)
+
+// SeasonServer is a new rating season. SoftenFactor is the share of its
+// distance from the default a carried-over rating keeps.
+type SeasonServer struct {
+	ID           string          `json:"id"`
+	Name         string          `json:"name"`
+	CarryOver    SeasonCarryOver `json:"carry_over"`
+	SoftenFactor float64         `json:"soften_factor"`
+}
+
+type LeaderboardPeriod string
+
+const (
+	PeriodAllTime LeaderboardPeriod = "all_time"
+	PeriodMonthly LeaderboardPeriod = "monthly"
+	PeriodWeekly  LeaderboardPeriod = "weekly"
+)
+
+// LeaderboardMetric is what a leaderboard ranks players by.
+type LeaderboardMetric string
+
+const (
+	// MetricWins counts the games won, shared first places included.
+	MetricWins LeaderboardMetric = "wins"
+	// MetricScoreShare averages the share of each game's points a player
+	// scored.
+	MetricScoreShare LeaderboardMetric = "score_share"
+	// MetricAccuracy is the share of answers that were right.
+	MetricAccuracy LeaderboardMetric = "accuracy"
+)
@@ -8,7 +8,6 @@ import { BiRegularShowAlt } from 'solid-icons/bi'
 import SearchComponent, { SearchItem } from './Search'
 import { useNavigate } from '@solidjs/router'
 import { FiEdit } from 'solid-icons/fi'
-import { createUUID } from '../pages/CreateGame'
 import { Confirm } from './Confirm'
 import { calculateUserGameScore, isEmptyObject } from '../utils'
 
@@ -83,11 +82,10 @@ const shuffleText = (text: string) => {
 const GameInfo = <T extends GameTemplate | Game>(props: GameInfoProps<T>) => {
   const navigate = useNavigate()
   const { get: getGameTemplateInfo } = useApi('game_templates/info')
-  const { get: getGameInfo } = useApi('games')
+  const { get: getGameInfo, post: createGame } = useApi('games')
   const [showAnswers, setShowAnswers] = createSignal<Record<Round['id'], boolean>>({})
   const [showQuestions, setShowQuestions] = createSignal<Record<Round['id'], boolean>>({})
   const { get: getUsersSearch } = useApi('users?search=')
-  const { post: createGame } = useApi('games/create')
   const [users, setUsers] = createSignal<User[]>(
     props.entity && 'users' in props.entity ? props.entity.users : [props.user]
   )
@@ -167,8 +165,15 @@ const GameInfo = <T extends GameTemplate | Game>(props: GameInfoProps<T>) => {
     return response.data ?? []
   }
 
-  const onGameCreate = async (fullEntity: T) => {
-    const response = await createGame<T>(fullEntity)
+  // The server copies the rounds, themes and questions from the template
+  const onGameCreate = async (template: T) => {
+    const response = await createGame<{ id: string }>({
+      templateId: template.id,
+      name: template.name,
+      invitees: users()
+        .filter((user) => user.id !== props.user.id)
+        .map((user) => user.id),
+    })
     if (response.data) {
       navigate(`/games/me`)
     }
@@ -178,42 +183,13 @@ const GameInfo = <T extends GameTemplate | Game>(props: GameInfoProps<T>) => {
     props.onStart?.(entity()!)
   }
 
-  const updateEntityId = (entity: T) => {
-    const fullEntity = {
-      ...entity!,
-      creatorId: props.user.id,
-      users: users(),
-      templateId: entity.id,
-    }
-
-    const updatedEntity = {
-      ...fullEntity,
-      id: createUUID(),
-      rounds: entity.rounds.map((round) => ({
-        ...round,
-        id: createUUID(),
-        themes: round.themes.map((theme) => ({
-          ...theme,
-          id: createUUID(),
-          questions: theme.questions.map((question) => ({
-            ...question,
-            id: createUUID(),
-          })),
-        })),
-      })),
-    }
-    return updatedEntity
-  }
-
   const onMainButtonClick = async () => {
     if (!entity()) {
       return
     }
 
-    const updatedEntity = updateEntityId(entity()!)
-
     if (props.type === 'template') {
-      onGameCreate(updatedEntity)
+      onGameCreate(entity()!)
     } else {
       onGameStart()
     }